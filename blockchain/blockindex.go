@@ -232,6 +232,9 @@ type blockIndex struct {
 	sync.RWMutex
 	index map[chainhash.Hash]*blockNode
 	dirty map[*blockNode]struct{}
+
+	// tips houses the nodes which do not have any children in the index.
+	tips map[*blockNode]struct{}
 }
 
 // newBlockIndex returns a new empty instance of a block index.  The index will
@@ -243,6 +246,7 @@ func newBlockIndex(db database.DB, chainParams *chaincfg.Params) *blockIndex {
 		chainParams: chainParams,
 		index:       make(map[chainhash.Hash]*blockNode),
 		dirty:       make(map[*blockNode]struct{}),
+		tips:        make(map[*blockNode]struct{}),
 	}
 }
 
@@ -279,11 +283,45 @@ func (bi *blockIndex) AddNode(node *blockNode) {
 }
 
 // addNode adds the provided node to the block index, but does not mark it as
-// dirty. This can be used while initializing the block index.
+// dirty. This can be used while initializing the block index.  The node
+// replaces its parent in the set of tips, so parents must be added before
+// their children.
 //
 // This function is NOT safe for concurrent access.
 func (bi *blockIndex) addNode(node *blockNode) {
 	bi.index[node.hash] = node
+	delete(bi.tips, node.parent)
+	bi.tips[node] = struct{}{}
+}
+
+// Tips returns the nodes in the block index which do not have any children.
+//
+// This function is safe for concurrent access.
+func (bi *blockIndex) Tips() []*blockNode {
+	bi.RLock()
+	tips := make([]*blockNode, 0, len(bi.tips))
+	for node := range bi.tips {
+		tips = append(tips, node)
+	}
+	bi.RUnlock()
+	return tips
+}
+
+// filterNodes returns the nodes in the block index for which the passed filter
+// returns true.  The filter is invoked with the index lock held, so it must
+// only access the immutable fields of the nodes.
+//
+// This function is safe for concurrent access.
+func (bi *blockIndex) filterNodes(filter func(node *blockNode) bool) []*blockNode {
+	var nodes []*blockNode
+	bi.RLock()
+	for _, node := range bi.index {
+		if filter(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	bi.RUnlock()
+	return nodes
 }
 
 // NodeStatus provides concurrent-safe access to the status field of a node.
@@ -8,6 +8,7 @@ package blockchain
 import (
 	"container/list"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -126,6 +127,17 @@ type BlockChain struct {
 	index     *blockIndex
 	bestChain *chainView
 
+	// utxoCache caches the unspent transaction output set of the end of
+	// the main chain and batches the writes of its modifications to the
+	// database.  It has its own lock, however it is always accessed with
+	// the chain lock held.
+	utxoCache *utxoCache
+
+	// pruneTarget is the size in bytes the stored blocks are pruned down
+	// to once they are buried deep enough.  A value of zero disables
+	// pruning.
+	pruneTarget uint64
+
 	// These fields are related to handling of orphan blocks.  They are
 	// protected by a combination of the chain lock and the orphan lock.
 	orphanLock   sync.RWMutex
@@ -607,6 +619,13 @@ func (b *BlockChain) connectBlock(node *blockNode, block *btcutil.Block,
 	state := newBestState(node, blockSize, blockWeight, numTxns,
 		curTotalTxns+numTxns, node.CalcPastMedianTime())
 
+	// Determine whether the name registrations made by the block are
+	// recorded in the name registry.
+	namesActive, err := b.namesActive(node.parent)
+	if err != nil {
+		return err
+	}
+
 	// Atomically insert info into the database.
 	err = b.db.Update(func(dbTx database.Tx) error {
 		// Update best block state.
@@ -622,14 +641,6 @@ func (b *BlockChain) connectBlock(node *blockNode, block *btcutil.Block,
 			return err
 		}
 
-		// Update the utxo set using the state of the utxo view.  This
-		// entails removing all of the utxos spent and adding the new
-		// ones created by the block.
-		err = dbPutUtxoView(dbTx, view)
-		if err != nil {
-			return err
-		}
-
 		// Update the transaction spend journal by adding a record for
 		// the block that contains all txos spent by it.
 		err = dbPutSpendJournalEntry(dbTx, block.Hash(), stxos)
@@ -637,6 +648,14 @@ func (b *BlockChain) connectBlock(node *blockNode, block *btcutil.Block,
 			return err
 		}
 
+		// Record the names registered by the block.
+		if namesActive {
+			err = dbConnectNameRegistrations(dbTx, block)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Allow the index manager to call each of the currently active
 		// optional indexes with the block being connected so they can
 		// update themselves accordingly.
@@ -653,13 +672,35 @@ func (b *BlockChain) connectBlock(node *blockNode, block *btcutil.Block,
 		return err
 	}
 
+	// Update the utxo set using the state of the utxo view.  This entails
+	// removing all of the utxos spent and adding the new ones created by
+	// the block.  The modifications are held by the utxo cache until it is
+	// flushed.
+	b.utxoCache.commit(view)
+
 	// Prune fully spent entries and mark all entries in the view unmodified
-	// now that the modifications have been committed to the database.
+	// now that the modifications have been committed to the utxo cache.
 	view.commit()
 
 	// This node is now the end of the best chain.
 	b.bestChain.SetTip(node)
 
+	// Write the utxo cache to the database when it is full or has not been
+	// flushed for a while.  A failure is not fatal since the cache keeps
+	// the modifications and the flush is attempted again after the next
+	// block.
+	if err := b.utxoCache.maybeFlush(&node.hash); err != nil {
+		log.Errorf("Unable to flush the utxo cache: %v", err)
+	}
+
+	// Remove the oldest blocks from the database when pruning.  A failure
+	// is not fatal either since the blocks are pruned after the next block.
+	if b.pruneTarget != 0 {
+		if err := b.pruneBlocks(); err != nil {
+			log.Errorf("Unable to prune blocks: %v", err)
+		}
+	}
+
 	// Update the state for the best block.  Notice how this replaces the
 	// entire struct instead of updating the existing one.  This effectively
 	// allows the old version to act as a snapshot which callers can use
@@ -682,6 +723,9 @@ func (b *BlockChain) connectBlock(node *blockNode, block *btcutil.Block,
 // disconnectBlock handles disconnecting the passed node/block from the end of
 // the main (best) chain.
 //
+// The utxo set is updated in the database directly rather than through the
+// utxo cache, so the cache MUST have been flushed beforehand.
+//
 // This function MUST be called with the chain state lock held (for writes).
 func (b *BlockChain) disconnectBlock(node *blockNode, block *btcutil.Block, view *UtxoViewpoint) error {
 	// Make sure the node being disconnected is the end of the best chain.
@@ -741,6 +785,10 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *btcutil.Block, view
 		if err != nil {
 			return err
 		}
+		err = dbPutUtxoState(dbTx, &prevNode.hash)
+		if err != nil {
+			return err
+		}
 
 		// Before we delete the spend journal entry for this back,
 		// we'll fetch it as is so the indexers can utilize if needed.
@@ -756,6 +804,12 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *btcutil.Block, view
 			return err
 		}
 
+		// Remove the names registered by the block.
+		err = dbDisconnectNameRegistrations(dbTx, block)
+		if err != nil {
+			return err
+		}
+
 		// Allow the index manager to call each of the currently active
 		// optional indexes with the block being disconnected so they
 		// can update themselves accordingly.
@@ -772,8 +826,11 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *btcutil.Block, view
 		return err
 	}
 
-	// Prune fully spent entries and mark all entries in the view unmodified
-	// now that the modifications have been committed to the database.
+	// Drop the entries the view modified from the utxo cache since they are
+	// stale now, then prune fully spent entries and mark all entries in the
+	// view unmodified now that the modifications have been committed to the
+	// database.
+	b.utxoCache.evictView(view, &prevNode.hash)
 	view.commit()
 
 	// This node's parent is now the end of the best chain.
@@ -848,6 +905,14 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 		}
 	}
 
+	// Disconnecting blocks relies on the utxo set in the database, so write
+	// the modifications held by the utxo cache first.
+	if detachNodes.Len() != 0 {
+		if err := b.utxoCache.flush(&tip.hash); err != nil {
+			return err
+		}
+	}
+
 	// Track the old and new best chains heads.
 	oldBest := tip
 	newBest := tip
@@ -886,7 +951,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 
 		// Load all of the utxos referenced by the block that aren't
 		// already in the view.
-		err = view.fetchInputUtxos(b.db, block)
+		err = view.fetchInputUtxos(b.utxoCache, block)
 		if err != nil {
 			return err
 		}
@@ -933,6 +998,10 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 	// at least a couple of ways accomplish that rollback, but both involve
 	// tweaking the chain and/or database.  This approach catches these
 	// issues before ever modifying the chain.
+	//
+	// The name registry is not updated until the blocks are connected, so
+	// the names registered by the attached blocks are tracked separately.
+	names := make(nameView)
 	for e := attachNodes.Front(); e != nil; e = e.Next() {
 		n := e.Value.(*blockNode)
 
@@ -951,9 +1020,17 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 
 		// Skip checks if node has already been fully validated. Although
 		// checkConnectBlock gets skipped, we still need to update the UTXO
-		// view.
+		// and name views.
 		if b.index.NodeStatus(n).KnownValid() {
-			err = view.fetchInputUtxos(b.db, block)
+			active, err := b.namesActive(n.parent)
+			if err != nil {
+				return err
+			}
+			if active {
+				names.connectBlock(block)
+			}
+
+			err = view.fetchInputUtxos(b.utxoCache, block)
 			if err != nil {
 				return err
 			}
@@ -974,7 +1051,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 		// In the case the block is determined to be invalid due to a
 		// rule violation, mark it as invalid and mark all of its
 		// descendants as having an invalid ancestor.
-		err = b.checkConnectBlock(n, block, view, nil)
+		err = b.checkConnectBlock(n, block, view, names, nil)
 		if err != nil {
 			if _, ok := err.(RuleError); ok {
 				b.index.SetStatusFlags(n, statusValidateFailed)
@@ -1005,7 +1082,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 
 		// Load all of the utxos referenced by the block that aren't
 		// already in the view.
-		err := view.fetchInputUtxos(b.db, block)
+		err := view.fetchInputUtxos(b.utxoCache, block)
 		if err != nil {
 			return err
 		}
@@ -1032,7 +1109,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 
 		// Load all of the utxos referenced by the block that aren't
 		// already in the view.
-		err := view.fetchInputUtxos(b.db, block)
+		err := view.fetchInputUtxos(b.utxoCache, block)
 		if err != nil {
 			return err
 		}
@@ -1110,7 +1187,7 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *btcutil.Block, fla
 		view.SetBestHash(parentHash)
 		stxos := make([]SpentTxOut, 0, countSpentOutputs(block))
 		if !fastAdd {
-			err := b.checkConnectBlock(node, block, view, &stxos)
+			err := b.checkConnectBlock(node, block, view, nil, &stxos)
 			if err == nil {
 				b.index.SetStatusFlags(node, statusValid)
 			} else if _, ok := err.(RuleError); ok {
@@ -1131,7 +1208,7 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *btcutil.Block, fla
 		// utxos, spend them, and add the new utxos being created by
 		// this block.
 		if fastAdd {
-			err := view.fetchInputUtxos(b.db, block)
+			err := view.fetchInputUtxos(b.utxoCache, block)
 			if err != nil {
 				return false, err
 			}
@@ -1215,6 +1292,337 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *btcutil.Block, fla
 	return err == nil, err
 }
 
+// descendants returns the known descendants of the passed node ordered by
+// height.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) descendants(node *blockNode) []*blockNode {
+	nodes := b.index.filterNodes(func(n *blockNode) bool {
+		return n.height > node.height
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].height < nodes[j].height
+	})
+
+	// Since the nodes are ordered by height, the parent of a descendant is
+	// always seen before it.
+	subtree := map[*blockNode]struct{}{node: {}}
+	descendants := nodes[:0]
+	for _, n := range nodes {
+		if _, ok := subtree[n.parent]; ok {
+			subtree[n] = struct{}{}
+			descendants = append(descendants, n)
+		}
+	}
+	return descendants
+}
+
+// bestValidChainTip returns the node with the most cumulative work which is
+// able to become the tip of the main chain.  That is, neither it nor any of its
+// ancestors are known to be invalid and the blocks which are not in the main
+// chain all have their data stored.  The most recent valid node of the main
+// chain is preferred over other nodes with the same work.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) bestValidChainTip() *blockNode {
+	best := b.bestChain.Tip()
+	for best.parent != nil && b.index.NodeStatus(best).KnownInvalid() {
+		best = best.parent
+	}
+
+	// Consider the nodes with more work, most work first.
+	nodes := b.index.filterNodes(func(n *blockNode) bool {
+		return n.workSum.Cmp(best.workSum) > 0
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].workSum.Cmp(nodes[j].workSum) > 0
+	})
+	for _, n := range nodes {
+		fork := b.bestChain.FindFork(n)
+		if fork == nil || b.index.NodeStatus(fork).KnownInvalid() {
+			continue
+		}
+		valid := true
+		for iterNode := n; iterNode != fork; iterNode = iterNode.parent {
+			status := b.index.NodeStatus(iterNode)
+			if status.KnownInvalid() || !status.HaveData() {
+				valid = false
+				break
+			}
+		}
+		if valid {
+			return n
+		}
+	}
+
+	return best
+}
+
+// activateBestChain reorganizes the chain so the node returned by
+// bestValidChainTip becomes its tip.  Should a block fail to connect due to a
+// rule violation, it is marked invalid and the next best chain is tried
+// instead.
+//
+// This function may modify node statuses in the block index without flushing.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) activateBestChain() error {
+	for {
+		tip := b.bestChain.Tip()
+		best := b.bestValidChainTip()
+		if best == tip {
+			return nil
+		}
+
+		// Blocks are only disconnected when the new tip is an ancestor
+		// of the current one.
+		var detachNodes, attachNodes *list.List
+		if b.bestChain.Contains(best) {
+			detachNodes, attachNodes = list.New(), list.New()
+			for n := tip; n != best; n = n.parent {
+				detachNodes.PushBack(n)
+			}
+		} else {
+			detachNodes, attachNodes = b.getReorganizeNodes(best)
+		}
+
+		// The block which failed to connect and its descendants,
+		// including the new tip, were marked invalid, so it will not be
+		// selected again.
+		err := b.reorganizeChain(detachNodes, attachNodes)
+		if _, ok := err.(RuleError); ok &&
+			b.index.NodeStatus(best).KnownInvalid() {
+
+			log.Warnf("Unable to reorganize to block %v: %v",
+				best.hash, err)
+			continue
+		}
+		return err
+	}
+}
+
+// flushIndexAfter flushes any block index changes to the database and returns
+// the passed error, or the flush error when the passed error is nil.
+func (b *BlockChain) flushIndexAfter(err error) error {
+	writeErr := b.index.flushToDB()
+	if err != nil {
+		if writeErr != nil {
+			log.Warnf("Error flushing block index changes to disk: %v",
+				writeErr)
+		}
+		return err
+	}
+	return writeErr
+}
+
+// InvalidateBlock marks the block identified by the passed hash as having
+// failed validation and its descendants as having an invalid ancestor.  When
+// the block is in the main chain, the chain is reorganized to the valid chain
+// with the most cumulative work, even though it has less work than the current
+// one.  The status is kept in the block index, so it persists across restarts
+// until the block is reconsidered with ReconsiderBlock.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) InvalidateBlock(hash *chainhash.Hash) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	node := b.index.LookupNode(hash)
+	if node == nil {
+		return fmt.Errorf("block %s is not known", hash)
+	}
+	if node.parent == nil {
+		return fmt.Errorf("block %s is the genesis block", hash)
+	}
+
+	log.Infof("Invalidating block %v (height %d)", hash, node.height)
+	b.index.SetStatusFlags(node, statusValidateFailed)
+	for _, n := range b.descendants(node) {
+		b.index.SetStatusFlags(n, statusInvalidAncestor)
+	}
+
+	return b.flushIndexAfter(b.activateBestChain())
+}
+
+// ReconsiderBlock removes the invalid status from the block identified by the
+// passed hash along with its ancestors and descendants, undoing
+// InvalidateBlock.  The chain is then reorganized to the valid chain with the
+// most cumulative work.  Any of the blocks which really are invalid are marked
+// as such again should they fail to connect.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ReconsiderBlock(hash *chainhash.Hash) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	node := b.index.LookupNode(hash)
+	if node == nil {
+		return fmt.Errorf("block %s is not known", hash)
+	}
+
+	log.Infof("Reconsidering block %v (height %d)", hash, node.height)
+	const invalidFlags = statusValidateFailed | statusInvalidAncestor
+	for n := node; n != nil; n = n.parent {
+		if b.index.NodeStatus(n).KnownInvalid() {
+			b.index.UnsetStatusFlags(n, invalidFlags)
+		}
+	}
+	for _, n := range b.descendants(node) {
+		if b.index.NodeStatus(n).KnownInvalid() {
+			b.index.UnsetStatusFlags(n, invalidFlags)
+		}
+	}
+
+	return b.flushIndexAfter(b.activateBestChain())
+}
+
+// PreciousBlock treats the block identified by the passed hash as if it had
+// been received before the other blocks with the same cumulative work.  The
+// chain is reorganized so the block becomes its tip when it has at least as
+// much work as the current tip, otherwise nothing is done.  Later blocks which
+// extend another chain beyond the work of the block cause a reorganization as
+// usual.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) PreciousBlock(hash *chainhash.Hash) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	node := b.index.LookupNode(hash)
+	if node == nil {
+		return fmt.Errorf("block %s is not known", hash)
+	}
+	if b.index.NodeStatus(node).KnownInvalid() {
+		return fmt.Errorf("block %s is known to be invalid", hash)
+	}
+	if b.bestChain.Contains(node) ||
+		node.workSum.Cmp(b.bestChain.Tip().workSum) < 0 {
+
+		return nil
+	}
+
+	detachNodes, attachNodes := b.getReorganizeNodes(node)
+	if attachNodes.Len() == 0 {
+		return b.flushIndexAfter(fmt.Errorf("block %s has an invalid "+
+			"ancestor", hash))
+	}
+	log.Infof("REORGANIZE: Block %v is precious.", node.hash)
+	return b.flushIndexAfter(b.reorganizeChain(detachNodes, attachNodes))
+}
+
+// ChainTipStatus describes the validation state of the branch which ends at a
+// chain tip.  The statuses are ordered from the most to the least valid branch.
+type ChainTipStatus byte
+
+const (
+	// ChainTipActive indicates the tip of the main chain.
+	ChainTipActive ChainTipStatus = iota
+
+	// ChainTipValidFork indicates the tip of a side chain whose blocks
+	// have all been fully validated.
+	ChainTipValidFork
+
+	// ChainTipValidHeaders indicates the tip of a side chain whose blocks
+	// are all stored, but have not all been fully validated.
+	ChainTipValidHeaders
+
+	// ChainTipHeadersOnly indicates the tip of a side chain which is
+	// missing the data of some of its blocks.
+	ChainTipHeadersOnly
+
+	// ChainTipInvalid indicates the tip of a side chain with a block
+	// which is known to be invalid.
+	ChainTipInvalid
+)
+
+// chainTipStatusStrings is a map of chain tip statuses back to the names used
+// by the getchaintips RPC.
+var chainTipStatusStrings = map[ChainTipStatus]string{
+	ChainTipActive:       "active",
+	ChainTipValidFork:    "valid-fork",
+	ChainTipValidHeaders: "valid-headers",
+	ChainTipHeadersOnly:  "headers-only",
+	ChainTipInvalid:      "invalid",
+}
+
+// String returns the ChainTipStatus as a human-readable name.
+func (s ChainTipStatus) String() string {
+	if str, ok := chainTipStatusStrings[s]; ok {
+		return str
+	}
+	return fmt.Sprintf("Unknown ChainTipStatus (%d)", byte(s))
+}
+
+// ChainTip describes the tip of a branch of the block chain.
+type ChainTip struct {
+	// Hash is the hash of the block at the tip of the branch.
+	Hash chainhash.Hash
+
+	// Height is the height of the block at the tip of the branch.
+	Height int32
+
+	// BranchLen is the number of blocks in the branch which are not in
+	// the main chain.  It is zero for the tip of the main chain.
+	BranchLen int32
+
+	// Status is the validation state of the branch.
+	Status ChainTipStatus
+}
+
+// ChainTips returns the tips of all known branches of the block chain,
+// including the tip of the main chain, ordered by height, highest first, and
+// then by status.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ChainTips() []ChainTip {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	// The tip of the main chain may have children when they are invalid,
+	// so it is added separately.
+	bestTip := b.bestChain.Tip()
+	tips := []ChainTip{{
+		Hash:   bestTip.hash,
+		Height: bestTip.height,
+		Status: ChainTipActive,
+	}}
+	for _, node := range b.index.Tips() {
+		if b.bestChain.Contains(node) {
+			continue
+		}
+
+		// The status of the branch is that of its least valid block.
+		fork := b.bestChain.FindFork(node)
+		status := ChainTipValidFork
+		for n := node; n != fork && status != ChainTipInvalid; n = n.parent {
+			nodeStatus := b.index.NodeStatus(n)
+			switch {
+			case nodeStatus.KnownInvalid():
+				status = ChainTipInvalid
+			case !nodeStatus.HaveData() && status < ChainTipHeadersOnly:
+				status = ChainTipHeadersOnly
+			case !nodeStatus.KnownValid() && status < ChainTipValidHeaders:
+				status = ChainTipValidHeaders
+			}
+		}
+
+		tips = append(tips, ChainTip{
+			Hash:      node.hash,
+			Height:    node.height,
+			BranchLen: node.height - fork.height,
+			Status:    status,
+		})
+	}
+
+	sort.Slice(tips, func(i, j int) bool {
+		if tips[i].Height != tips[j].Height {
+			return tips[i].Height > tips[j].Height
+		}
+		return tips[i].Status < tips[j].Status
+	})
+	return tips
+}
+
 // isCurrent returns whether or not the chain believes it is current.  Several
 // factors are used to guess, but the key factors that allow the chain to
 // believe it is current are:
@@ -1711,6 +2119,17 @@ type Config struct {
 	// This field can be nil if the caller is not interested in using a
 	// signature cache.
 	HashCache *txscript.HashCache
+
+	// UtxoCacheMaxSize is the maximum number of bytes of memory the utxo
+	// cache may use before its modifications are written to the database.
+	// A value of zero writes the modifications of every block.
+	UtxoCacheMaxSize uint64
+
+	// Prune is the size in bytes the blocks stored in the database are
+	// pruned down to.  Only blocks buried by at least MinBlocksToKeep
+	// blocks are pruned, so more space may be used.  A value of zero
+	// keeps all blocks.
+	Prune uint64
 }
 
 // New returns a BlockChain instance using the provided configuration details.
@@ -1745,6 +2164,13 @@ func New(config *Config) (*BlockChain, error) {
 	}
 
 	params := config.ChainParams
+	if params.DifficultyAlgorithm == chaincfg.DiffAlgoLWMA &&
+		params.DifficultyWindow < 1 {
+
+		return nil, AssertError("blockchain.New difficulty window " +
+			"must be positive")
+	}
+
 	targetTimespan := int64(params.TargetTimespan / time.Second)
 	targetTimePerBlock := int64(params.TargetTimePerBlock / time.Second)
 	adjustmentFactor := params.RetargetAdjustmentFactor
@@ -1760,6 +2186,8 @@ func New(config *Config) (*BlockChain, error) {
 		maxRetargetTimespan: targetTimespan * adjustmentFactor,
 		blocksPerRetarget:   int32(targetTimespan / targetTimePerBlock),
 		index:               newBlockIndex(config.DB, params),
+		utxoCache:           newUtxoCache(config.DB, config.UtxoCacheMaxSize),
+		pruneTarget:         config.Prune,
 		hashCache:           config.HashCache,
 		bestChain:           newChainView(nil),
 		orphans:             make(map[chainhash.Hash]*orphanBlock),
@@ -1780,6 +2208,12 @@ func New(config *Config) (*BlockChain, error) {
 		return nil, err
 	}
 
+	// Recover the utxo set when the utxo cache was not flushed before the
+	// node last shut down.
+	if err := b.initUtxoState(config.Interrupt); err != nil {
+		return nil, err
+	}
+
 	// Initialize and catch up all of the currently active optional indexes
 	// as needed.
 	if config.IndexManager != nil {
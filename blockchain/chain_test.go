@@ -111,6 +111,223 @@ func TestHaveBlock(t *testing.T) {
 	}
 }
 
+// TestInvalidateReconsiderBlock ensures the InvalidateBlock, ReconsiderBlock
+// and PreciousBlock APIs reorganize the chain as expected and persist the
+// status of the blocks.
+func TestInvalidateReconsiderBlock(t *testing.T) {
+	// Load up blocks such that there is a side chain.
+	// (genesis block) -> 1 -> 2 -> 3 -> 4
+	//                          \-> 3a
+	testFiles := []string{
+		"blk_0_to_4.dat.bz2",
+		"blk_3A.dat.bz2",
+	}
+
+	var blocks []*btcutil.Block
+	for _, file := range testFiles {
+		blockTmp, err := loadBlocks(file)
+		if err != nil {
+			t.Fatalf("Error loading file: %v\n", err)
+		}
+		blocks = append(blocks, blockTmp...)
+	}
+
+	// Create a new database and chain instance to run tests against.
+	chain, teardownFunc, err := chainSetup("invalidateblock",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+
+	// Since we're not dealing with the real block chain, set the coinbase
+	// maturity to 1.
+	chain.TstSetCoinbaseMaturity(1)
+
+	for i := 1; i < len(blocks); i++ {
+		_, _, err := chain.ProcessBlock(blocks[i], BFNone)
+		if err != nil {
+			t.Fatalf("ProcessBlock fail on block %v: %v\n", i, err)
+		}
+	}
+	block3, block4, block3a := blocks[3].Hash(), blocks[4].Hash(),
+		blocks[5].Hash()
+
+	tests := []struct {
+		name   string
+		f      func(*chainhash.Hash) error
+		hash   *chainhash.Hash
+		tip    *chainhash.Hash
+		status blockStatus
+	}{
+		// Invalidating a main chain block reorganizes to the side chain
+		// with the most work.
+		{"invalidate 3", chain.InvalidateBlock, block3, block3a,
+			statusDataStored | statusValid | statusValidateFailed},
+		// With both chains invalid the tip is the fork point.
+		{"invalidate 3a", chain.InvalidateBlock, block3a, blocks[2].Hash(),
+			statusDataStored | statusValid | statusValidateFailed},
+		// Reconsidering a block restores its descendants.
+		{"reconsider 3", chain.ReconsiderBlock, block3, block4,
+			statusDataStored | statusValid},
+		{"reconsider 3a", chain.ReconsiderBlock, block3a, block4,
+			statusDataStored | statusValid},
+		// The main chain is preferred when blocks have the same work.
+		{"invalidate 4", chain.InvalidateBlock, block4, block3,
+			statusDataStored | statusValid | statusValidateFailed},
+		// A precious block with the same work becomes the tip.
+		{"precious 3a", chain.PreciousBlock, block3a, block3a,
+			statusDataStored | statusValid},
+		{"precious 3", chain.PreciousBlock, block3, block3,
+			statusDataStored | statusValid},
+		{"reconsider 4", chain.ReconsiderBlock, block4, block4,
+			statusDataStored | statusValid},
+		// A precious block with less work does nothing.
+		{"precious 3a less work", chain.PreciousBlock, block3a, block4,
+			statusDataStored | statusValid},
+	}
+	for _, test := range tests {
+		if err := test.f(test.hash); err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if tip := chain.BestSnapshot().Hash; tip != *test.tip {
+			t.Fatalf("%s: wrong tip - got %v, want %v", test.name,
+				tip, test.tip)
+		}
+		node := chain.index.LookupNode(test.hash)
+		if status := chain.index.NodeStatus(node); status != test.status {
+			t.Fatalf("%s: wrong status - got %v, want %v",
+				test.name, status, test.status)
+		}
+	}
+
+	// Invalid blocks can not be made precious and the genesis block can
+	// not be invalidated.
+	if err := chain.InvalidateBlock(block3); err != nil {
+		t.Fatalf("InvalidateBlock: unexpected error: %v", err)
+	}
+	if err := chain.PreciousBlock(block4); err == nil {
+		t.Fatal("PreciousBlock: did not fail for an invalid block")
+	}
+	if err := chain.InvalidateBlock(blocks[0].Hash()); err == nil {
+		t.Fatal("InvalidateBlock: did not fail for the genesis block")
+	}
+
+	// The status of the blocks is persisted in the block index.
+	reloaded, err := New(&Config{
+		DB:          chain.db,
+		ChainParams: chain.chainParams,
+		TimeSource:  NewMedianTime(),
+	})
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	if tip := reloaded.BestSnapshot().Hash; tip != *block3a {
+		t.Fatalf("reloaded chain has wrong tip - got %v, want %v", tip,
+			block3a)
+	}
+	node := reloaded.index.LookupNode(block4)
+	if !reloaded.index.NodeStatus(node).KnownInvalid() {
+		t.Fatalf("reloaded chain has lost the status of block %v", block4)
+	}
+}
+
+// TestChainTips ensures ChainTips reports the tips of the known branches of
+// the chain with the expected status.
+func TestChainTips(t *testing.T) {
+	// Load up blocks such that there is a side chain.
+	// (genesis block) -> 1 -> 2 -> 3 -> 4
+	//                          \-> 3a
+	testFiles := []string{
+		"blk_0_to_4.dat.bz2",
+		"blk_3A.dat.bz2",
+	}
+
+	var blocks []*btcutil.Block
+	for _, file := range testFiles {
+		blockTmp, err := loadBlocks(file)
+		if err != nil {
+			t.Fatalf("Error loading file: %v\n", err)
+		}
+		blocks = append(blocks, blockTmp...)
+	}
+
+	// Create a new database and chain instance to run tests against.
+	chain, teardownFunc, err := chainSetup("chaintips",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+
+	// Since we're not dealing with the real block chain, set the coinbase
+	// maturity to 1.
+	chain.TstSetCoinbaseMaturity(1)
+
+	for i := 1; i < len(blocks); i++ {
+		_, _, err := chain.ProcessBlock(blocks[i], BFNone)
+		if err != nil {
+			t.Fatalf("ProcessBlock fail on block %v: %v\n", i, err)
+		}
+	}
+	block3, block4, block3a := blocks[3].Hash(), blocks[4].Hash(),
+		blocks[5].Hash()
+
+	tests := []struct {
+		name   string
+		update func() error
+		want   []ChainTip
+	}{
+		{
+			// The side chain block was never connected, so it has
+			// not been fully validated.
+			name:   "initial",
+			update: func() error { return nil },
+			want: []ChainTip{
+				{*block4, 4, 0, ChainTipActive},
+				{*block3a, 3, 1, ChainTipValidHeaders},
+			},
+		},
+		{
+			// The tip of the main chain is reported even though it
+			// has an invalid child.
+			name:   "invalidate 4",
+			update: func() error { return chain.InvalidateBlock(block4) },
+			want: []ChainTip{
+				{*block4, 4, 1, ChainTipInvalid},
+				{*block3, 3, 0, ChainTipActive},
+				{*block3a, 3, 1, ChainTipValidHeaders},
+			},
+		},
+		{
+			name:   "precious 3a",
+			update: func() error { return chain.PreciousBlock(block3a) },
+			want: []ChainTip{
+				{*block4, 4, 2, ChainTipInvalid},
+				{*block3a, 3, 0, ChainTipActive},
+			},
+		},
+		{
+			name:   "reconsider 4",
+			update: func() error { return chain.ReconsiderBlock(block4) },
+			want: []ChainTip{
+				{*block4, 4, 0, ChainTipActive},
+				{*block3a, 3, 1, ChainTipValidFork},
+			},
+		},
+	}
+	for _, test := range tests {
+		if err := test.update(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		tips := chain.ChainTips()
+		if !reflect.DeepEqual(tips, test.want) {
+			t.Fatalf("%s: mismatched tips - got %+v, want %+v",
+				test.name, tips, test.want)
+		}
+	}
+}
+
 // TestCalcSequenceLock tests the LockTimeToSequence function, and the
 // CalcSequenceLock method of a Chain instance. The tests exercise several
 // combinations of inputs to the CalcSequenceLock function in order to ensure
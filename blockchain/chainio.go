@@ -67,6 +67,10 @@ var (
 	// unspent transaction output set.
 	utxoSetBucketName = []byte("utxosetv2")
 
+	// utxoStateKeyName is the name of the db key used to store the hash of
+	// the block the utxo set in the database was last flushed at.
+	utxoStateKeyName = []byte("utxostate")
+
 	// byteOrder is the preferred byte order used for serializing numeric
 	// fields for storage in the database.
 	byteOrder = binary.LittleEndian
@@ -771,8 +775,15 @@ func dbFetchUtxoEntry(dbTx database.Tx, outpoint wire.OutPoint) (*UtxoEntry, err
 // particular, only the entries that have been marked as modified are written
 // to the database.
 func dbPutUtxoView(dbTx database.Tx, view *UtxoViewpoint) error {
+	return dbPutUtxoEntries(dbTx, view.entries)
+}
+
+// dbPutUtxoEntries uses an existing database transaction to update the utxo
+// set in the database with the passed entries.  Only the entries that have been
+// marked as modified are written, and those which are spent are removed.
+func dbPutUtxoEntries(dbTx database.Tx, entries map[wire.OutPoint]*UtxoEntry) error {
 	utxoBucket := dbTx.Metadata().Bucket(utxoSetBucketName)
-	for outpoint, entry := range view.entries {
+	for outpoint, entry := range entries {
 		// No need to update the database if the entry was not modified.
 		if entry == nil || !entry.isModified() {
 			continue
@@ -809,6 +820,34 @@ func dbPutUtxoView(dbTx database.Tx, view *UtxoViewpoint) error {
 	return nil
 }
 
+// dbFetchUtxoState uses an existing database transaction to fetch the hash of
+// the block the utxo set in the database was last flushed at.  Nil is returned
+// when the database predates the utxo cache and thus has no such record.
+func dbFetchUtxoState(dbTx database.Tx) (*chainhash.Hash, error) {
+	serialized := dbTx.Metadata().Get(utxoStateKeyName)
+	if serialized == nil {
+		return nil, nil
+	}
+	if len(serialized) != chainhash.HashSize {
+		return nil, database.Error{
+			ErrorCode:   database.ErrCorruption,
+			Description: "corrupt utxo state",
+		}
+	}
+
+	var hash chainhash.Hash
+	copy(hash[:], serialized)
+	return &hash, nil
+}
+
+// dbPutUtxoState uses an existing database transaction to record the hash of
+// the block the utxo set in the database reflects.
+func dbPutUtxoState(dbTx database.Tx, hash *chainhash.Hash) error {
+	serialized := make([]byte, chainhash.HashSize)
+	copy(serialized, hash[:])
+	return dbTx.Metadata().Put(utxoStateKeyName, serialized)
+}
+
 // -----------------------------------------------------------------------------
 // The block index consists of two buckets with an entry for every block in the
 // main chain.  One bucket is for the hash to height mapping and the other is
@@ -1073,6 +1112,12 @@ func (b *BlockChain) createChainState() error {
 			return err
 		}
 
+		// Create the bucket that houses the name registry.
+		_, err = meta.CreateBucket(nameRegistryBucketName)
+		if err != nil {
+			return err
+		}
+
 		// Save the genesis block to the block index database.
 		err = dbStoreBlockNode(dbTx, node)
 		if err != nil {
@@ -1092,6 +1137,12 @@ func (b *BlockChain) createChainState() error {
 			return err
 		}
 
+		// The empty utxo set reflects the genesis block.
+		err = dbPutUtxoState(dbTx, &node.hash)
+		if err != nil {
+			return err
+		}
+
 		// Store the genesis block into the database.
 		return dbStoreBlock(dbTx, genesisBlock)
 	})
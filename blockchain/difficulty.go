@@ -8,9 +8,17 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/jadeblaquiere/cttd/chaincfg"
 	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
 )
 
+const (
+	// lwmaMaxSolveTimeFactor is the multiple of the target time per block
+	// that the solve time of a single block is limited to when calculating
+	// the difficulty with the DiffAlgoLWMA algorithm.
+	lwmaMaxSolveTimeFactor = 6
+)
+
 var (
 	// bigOne is 1 represented as a big.Int.  It is defined here to avoid
 	// the overhead of creating it multiple times.
@@ -157,6 +165,12 @@ func CalcWork(bits uint32) *big.Int {
 // verify that claimed proof of work by a block is sane as compared to a
 // known good checkpoint.
 func (b *BlockChain) calcEasiestDifficulty(bits uint32, duration time.Duration) uint32 {
+	// Per-block algorithms may ease the difficulty with every block, so
+	// there is no useful bound short of the proof of work limit.
+	if b.chainParams.DifficultyAlgorithm != chaincfg.DiffAlgoBitcoin {
+		return b.chainParams.PowLimitBits
+	}
+
 	// Convert types used in the calculations below.
 	durationVal := int64(duration / time.Second)
 	adjustmentFactor := big.NewInt(b.chainParams.RetargetAdjustmentFactor)
@@ -224,6 +238,14 @@ func (b *BlockChain) calcNextRequiredDifficulty(lastNode *blockNode, newBlockTim
 		return b.chainParams.PowLimitBits, nil
 	}
 
+	// The blocks before the difficulty algorithm height use the Bitcoin
+	// retarget rules below regardless of the configured algorithm.
+	if b.chainParams.DifficultyAlgorithm == chaincfg.DiffAlgoLWMA &&
+		lastNode.height+1 >= b.chainParams.DifficultyAlgorithmHeight {
+
+		return b.calcNextLWMADifficulty(lastNode, newBlockTime)
+	}
+
 	// Return the previous block's difficulty requirements if this block
 	// is not at a difficulty retarget interval.
 	if (lastNode.height+1)%b.blocksPerRetarget != 0 {
@@ -299,6 +321,102 @@ func (b *BlockChain) calcNextRequiredDifficulty(lastNode *blockNode, newBlockTim
 	return newTargetBits, nil
 }
 
+// calcLWMATarget calculates the target for the block following the passed
+// window of blocks using a linearly weighted moving average of their solve
+// times.  The bits of the blocks in the window are ordered oldest first and
+// timestamps holds one more entry than bits since the solve time of the oldest
+// block is measured from its parent.
+//
+// The next target is the average target of the window scaled by the weighted
+// sum of the solve times over the weighted sum expected when every block takes
+// exactly targetSpacing seconds, where the solve time of the i-th block has
+// weight i.  Out of order timestamps are treated as following the previous
+// timestamp by one second and solve times are limited to lwmaMaxSolveTimeFactor
+// times the target spacing, so that a few dishonest timestamps can not swing
+// the difficulty far.  The weighted sum is also limited to no less than a tenth
+// of the expected sum.
+func calcLWMATarget(timestamps []int64, bits []uint32, targetSpacing int64, powLimit *big.Int) *big.Int {
+	n := int64(len(bits))
+	expectedSum := n * (n + 1) / 2 * targetSpacing
+	maxSolveTime := lwmaMaxSolveTimeFactor * targetSpacing
+
+	var weightedSum int64
+	sumTargets := new(big.Int)
+	prevTimestamp := timestamps[0]
+	for i := int64(1); i <= n; i++ {
+		timestamp := timestamps[i]
+		if timestamp <= prevTimestamp {
+			timestamp = prevTimestamp + 1
+		}
+		solveTime := timestamp - prevTimestamp
+		if solveTime > maxSolveTime {
+			solveTime = maxSolveTime
+		}
+		prevTimestamp = timestamp
+
+		weightedSum += i * solveTime
+		sumTargets.Add(sumTargets, CompactToBig(bits[i-1]))
+	}
+	if weightedSum < expectedSum/10 {
+		weightedSum = expectedSum / 10
+	}
+
+	// Calculate new target difficulty as:
+	//  (sumTargets / n) * (weightedSum / expectedSum)
+	// The division is done last to retain precision.
+	newTarget := new(big.Int).Mul(sumTargets, big.NewInt(weightedSum))
+	newTarget.Div(newTarget, big.NewInt(n*expectedSum))
+
+	// Limit new value to the proof of work limit.
+	if newTarget.Cmp(powLimit) > 0 {
+		newTarget.Set(powLimit)
+	}
+	return newTarget
+}
+
+// calcNextLWMADifficulty calculates the required difficulty for the block
+// after the passed previous block node using the DiffAlgoLWMA algorithm.  The
+// proof of work limit is required until the chain is long enough to fill the
+// difficulty window.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) calcNextLWMADifficulty(lastNode *blockNode, newBlockTime time.Time) (uint32, error) {
+	// For networks that support it, allow minimum difficulty blocks once
+	// too much time has elapsed without mining a block.
+	if b.chainParams.ReduceMinDifficulty {
+		reductionTime := int64(b.chainParams.MinDiffReductionTime /
+			time.Second)
+		if newBlockTime.Unix() > lastNode.timestamp+reductionTime {
+			return b.chainParams.PowLimitBits, nil
+		}
+	}
+
+	window := b.chainParams.DifficultyWindow
+	if lastNode.height < window {
+		return b.chainParams.PowLimitBits, nil
+	}
+
+	// Collect the timestamps and bits of the window, oldest first, along
+	// with the timestamp of the parent of the oldest block.
+	timestamps := make([]int64, window+1)
+	bits := make([]uint32, window)
+	iterNode := lastNode
+	for i := window; i > 0; i-- {
+		timestamps[i] = iterNode.timestamp
+		bits[i-1] = iterNode.bits
+		iterNode = iterNode.parent
+	}
+	if iterNode == nil {
+		return 0, AssertError("unable to obtain difficulty window blocks")
+	}
+	timestamps[0] = iterNode.timestamp
+
+	targetSpacing := int64(b.chainParams.TargetTimePerBlock / time.Second)
+	newTarget := calcLWMATarget(timestamps, bits, targetSpacing,
+		b.chainParams.PowLimit)
+	return BigToCompact(newTarget), nil
+}
+
 // CalcNextRequiredDifficulty calculates the required difficulty for the block
 // after the end of the current best chain based on the difficulty retarget
 // rules.
@@ -73,3 +73,82 @@ func TestCalcWork(t *testing.T) {
 		}
 	}
 }
+
+// TestCalcLWMATarget ensures calcLWMATarget calculates the expected target for
+// a one minute target spacing.
+func TestCalcLWMATarget(t *testing.T) {
+	powLimit := new(big.Int).Sub(new(big.Int).Lsh(bigOne, 248), bigOne)
+	tests := []struct {
+		name       string
+		timestamps []int64
+		bits       []uint32
+		powLimit   *big.Int
+		want       uint32
+	}{
+		{
+			name:       "on target",
+			timestamps: []int64{0, 60, 120, 180, 240},
+			bits:       []uint32{0x1d00ffff, 0x1d00ffff, 0x1d00ffff, 0x1d00ffff},
+			powLimit:   powLimit,
+			want:       0x1d00ffff,
+		},
+		{
+			name:       "twice as slow",
+			timestamps: []int64{0, 120, 240, 360, 480},
+			bits:       []uint32{0x1d00ffff, 0x1d00ffff, 0x1d00ffff, 0x1d00ffff},
+			powLimit:   powLimit,
+			want:       0x1d01fffe,
+		},
+		{
+			name:       "fast blocks limited",
+			timestamps: []int64{0, 1, 2, 3, 4},
+			bits:       []uint32{0x1d00ffff, 0x1d00ffff, 0x1d00ffff, 0x1d00ffff},
+			powLimit:   powLimit,
+			want:       0x1c199980,
+		},
+		{
+			name:       "out of order timestamp",
+			timestamps: []int64{0, 60, 50, 170, 230},
+			bits:       []uint32{0x1d00ffff, 0x1d00ffff, 0x1d00ffff, 0x1d00ffff},
+			powLimit:   powLimit,
+			want:       0x1d010c5e,
+		},
+		{
+			name:       "long solve time limited",
+			timestamps: []int64{0, 60, 120, 180, 3780},
+			bits:       []uint32{0x1d00ffff, 0x1d00ffff, 0x1d00ffff, 0x1d00ffff},
+			powLimit:   powLimit,
+			want:       0x1d02fffd,
+		},
+		{
+			name:       "recent blocks weighted more",
+			timestamps: []int64{0, 30, 60, 90, 210},
+			bits:       []uint32{0x1d00ffff, 0x1d00ffff, 0x1d00ffff, 0x1d00ffff},
+			powLimit:   powLimit,
+			want:       0x1d011998,
+		},
+		{
+			name:       "average target",
+			timestamps: []int64{0, 60, 120, 180, 240},
+			bits:       []uint32{0x1d00ffff, 0x1c7fff80, 0x1d00ffff, 0x1c7fff80},
+			powLimit:   powLimit,
+			want:       0x1d00bfff,
+		},
+		{
+			name:       "proof of work limit",
+			timestamps: []int64{0, 360, 720, 1080, 1440},
+			bits:       []uint32{0x1e7fff00, 0x1e7fff00, 0x1e7fff00, 0x1e7fff00},
+			powLimit:   CompactToBig(0x1e7fff00),
+			want:       0x1e7fff00,
+		},
+	}
+
+	for _, test := range tests {
+		got := BigToCompact(calcLWMATarget(test.timestamps, test.bits, 60,
+			test.powLimit))
+		if got != test.want {
+			t.Errorf("calcLWMATarget (%s): got %08x want %08x",
+				test.name, got, test.want)
+		}
+	}
+}
@@ -209,6 +209,20 @@ const (
 	// manually computed witness commitment.
 	ErrWitnessCommitmentMismatch
 
+	// ErrBadNameRegistration indicates a transaction output has the form
+	// of an OP_REGISTERNAME name registration but the name or owner key is
+	// invalid.
+	ErrBadNameRegistration
+
+	// ErrNameOwnership indicates a transaction output registers a name
+	// which is currently registered to a different owner key.
+	ErrNameOwnership
+
+	// ErrNameRenewal indicates a transaction output registers a name
+	// which is already registered to the same key without spending the
+	// output of its current registration.
+	ErrNameRenewal
+
 	// ErrPreviousBlockUnknown indicates that the previous block is not known.
 	ErrPreviousBlockUnknown
 
@@ -264,6 +278,9 @@ var errorCodeStrings = map[ErrorCode]string{
 	ErrUnexpectedWitness:         "ErrUnexpectedWitness",
 	ErrInvalidWitnessCommitment:  "ErrInvalidWitnessCommitment",
 	ErrWitnessCommitmentMismatch: "ErrWitnessCommitmentMismatch",
+	ErrBadNameRegistration:       "ErrBadNameRegistration",
+	ErrNameOwnership:             "ErrNameOwnership",
+	ErrNameRenewal:               "ErrNameRenewal",
 	ErrPreviousBlockUnknown:      "ErrPreviousBlockUnknown",
 	ErrInvalidAncestorBlock:      "ErrInvalidAncestorBlock",
 	ErrPrevBlockNotBest:          "ErrPrevBlockNotBest",
@@ -55,6 +55,9 @@ func TestErrorCodeStringer(t *testing.T) {
 		{ErrUnexpectedWitness, "ErrUnexpectedWitness"},
 		{ErrInvalidWitnessCommitment, "ErrInvalidWitnessCommitment"},
 		{ErrWitnessCommitmentMismatch, "ErrWitnessCommitmentMismatch"},
+		{ErrBadNameRegistration, "ErrBadNameRegistration"},
+		{ErrNameOwnership, "ErrNameOwnership"},
+		{ErrNameRenewal, "ErrNameRenewal"},
 		{ErrPreviousBlockUnknown, "ErrPreviousBlockUnknown"},
 		{ErrInvalidAncestorBlock, "ErrInvalidAncestorBlock"},
 		{ErrPrevBlockNotBest, "ErrPrevBlockNotBest"},
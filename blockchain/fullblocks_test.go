@@ -146,6 +146,61 @@ func TestFullBlocks(t *testing.T) {
 	}
 	defer teardownFunc()
 
+	runFullBlockTests(t, chain, tests)
+}
+
+// TestFullBlocksLWMA ensures all tests generated by the fullblocktests package
+// for the DiffAlgoLWMA difficulty algorithm have the expected result when
+// processed via ProcessBlock.
+func TestFullBlocksLWMA(t *testing.T) {
+	tests, err := fullblocktests.GenerateLWMA()
+	if err != nil {
+		t.Fatalf("failed to generate tests: %v", err)
+	}
+
+	// Create a new database and chain instance to run tests against.
+	params := chaincfg.RegressionNetParams
+	params.DifficultyAlgorithm = chaincfg.DiffAlgoLWMA
+	params.DifficultyWindow = fullblocktests.LWMADifficultyWindow
+	chain, teardownFunc, err := chainSetup("fullblocklwmatest", &params)
+	if err != nil {
+		t.Errorf("Failed to setup chain instance: %v", err)
+		return
+	}
+	defer teardownFunc()
+
+	runFullBlockTests(t, chain, tests)
+}
+
+// TestFullBlocksLWMASwitch ensures all tests generated by the fullblocktests
+// package for the switch to the DiffAlgoLWMA difficulty algorithm have the
+// expected result when processed via ProcessBlock.
+func TestFullBlocksLWMASwitch(t *testing.T) {
+	tests, err := fullblocktests.GenerateLWMASwitch()
+	if err != nil {
+		t.Fatalf("failed to generate tests: %v", err)
+	}
+
+	// Create a new database and chain instance to run tests against.
+	params := chaincfg.RegressionNetParams
+	params.DifficultyAlgorithm = chaincfg.DiffAlgoLWMA
+	params.DifficultyAlgorithmHeight = fullblocktests.LWMASwitchHeight
+	params.DifficultyWindow = fullblocktests.LWMADifficultyWindow
+	chain, teardownFunc, err := chainSetup("fullblocklwmaswitchtest",
+		&params)
+	if err != nil {
+		t.Errorf("Failed to setup chain instance: %v", err)
+		return
+	}
+	defer teardownFunc()
+
+	runFullBlockTests(t, chain, tests)
+}
+
+// runFullBlockTests processes the passed tests generated by the
+// fullblocktests package via ProcessBlock and ensures they have the expected
+// result.
+func runFullBlockTests(t *testing.T, chain *blockchain.BlockChain, tests [][]fullblocktests.TestInstance) {
 	// testAcceptedBlock attempts to process the block in the provided test
 	// instance and ensures that it was accepted according to the flags
 	// specified in the test.
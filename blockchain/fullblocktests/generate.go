@@ -15,6 +15,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"runtime"
 	"time"
 
@@ -2045,6 +2046,251 @@ func Generate(includeLargeReorg bool) (tests [][]TestInstance, err error) {
 	}
 	accepted()
 
+	// ---------------------------------------------------------------------
+	// OP_REGISTERNAK tests.
+	// ---------------------------------------------------------------------
+
+	// nakScript returns a public key script which registers the provided
+	// serialized network access key.
+	//
+	// spendNAKScript adds a transaction to the block which spends the
+	// output of the spending transaction, thereby executing its script.
+	nakScript := func(nak []byte) []byte {
+		return append(pushDataScript(nak), txscript.OP_REGISTERNAK,
+			txscript.OP_DROP, txscript.OP_TRUE)
+	}
+	spendNAKScript := func(b *wire.MsgBlock) {
+		b.AddTransaction(createSpendTxForTx(b.Transactions[1], lowFee))
+	}
+	nakPrivKey, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x4e}, 32))
+	nak, err := txscript.NewNAK(nakPrivKey, time.Unix(1500000000, 0),
+		time.Unix(1600000000, 0))
+	if err != nil {
+		panic(err)
+	}
+
+	// Create a block that spends an output registering a validly signed
+	// network access key.
+	//
+	//   ... -> b81(27) -> b82(28)
+	g.nextBlock("b82", outs[28], replaceSpendScript(nakScript(
+		nak.Serialize())), spendNAKScript)
+	accepted()
+
+	// Create a block that spends an output registering a network access
+	// key with an unsupported version.
+	//
+	//   ... -> b82(28) -> b83(29)
+	malformedNAK := nak.Serialize()
+	malformedNAK[0] = txscript.NAKVersion + 1
+	g.nextBlock("b83", outs[29], replaceSpendScript(nakScript(
+		malformedNAK)), spendNAKScript)
+	rejected(blockchain.ErrScriptValidation)
+
+	// Create a block that spends an output registering a network access
+	// key without a signature.
+	//
+	//   ... -> b82(28) -> b84(29)
+	g.setTip("b82")
+	unsignedNAK := nak.Serialize()
+	unsignedNAK = unsignedNAK[:len(unsignedNAK)-len(nak.Signature.Serialize())]
+	g.nextBlock("b84", outs[29], replaceSpendScript(nakScript(
+		unsignedNAK)), spendNAKScript)
+	rejected(blockchain.ErrScriptValidation)
+
+	// Create a block that spends an output registering a network access
+	// key signed by a different key.
+	//
+	//   ... -> b82(28) -> b85(29)
+	g.setTip("b82")
+	forgedPrivKey, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x4f}, 32))
+	forgedNAK := *nak
+	forgedNAK.Signature, err = forgedPrivKey.Sign(forgedNAK.SigHash())
+	if err != nil {
+		panic(err)
+	}
+	g.nextBlock("b85", outs[29], replaceSpendScript(nakScript(
+		forgedNAK.Serialize())), spendNAKScript)
+	rejected(blockchain.ErrScriptValidation)
+
+	// Create a block that spends an output registering a network access
+	// key whose validity period was modified after it was signed.
+	//
+	//   ... -> b82(28) -> b86(29)
+	g.setTip("b82")
+	extendedNAK := *nak
+	extendedNAK.NotAfter = nak.NotAfter.Add(time.Hour * 24 * 365)
+	g.nextBlock("b86", outs[29], replaceSpendScript(nakScript(
+		extendedNAK.Serialize())), spendNAKScript)
+	rejected(blockchain.ErrScriptValidation)
+
+	// ---------------------------------------------------------------------
+	// OP_REGISTERNAME tests.
+	// ---------------------------------------------------------------------
+
+	// nameScript returns a public key script which registers the provided
+	// name to the provided compressed public key.
+	//
+	// additionalNameOutput returns a function that itself takes a block and
+	// modifies it by adding an output registering the provided name to the
+	// provided key to the spending transaction.
+	//
+	// spendNameOutput returns a function that itself takes a block and
+	// modifies it by adding an input spending the registration made by the
+	// spending transaction of the provided block to the spending
+	// transaction, as required to renew the name.
+	nameScript := func(name string, owner []byte) []byte {
+		return append(pushDataScript([]byte(name), owner),
+			txscript.OP_REGISTERNAME, txscript.OP_2DROP,
+			txscript.OP_TRUE)
+	}
+	additionalNameOutput := func(name string, owner []byte) func(*wire.MsgBlock) {
+		return func(b *wire.MsgBlock) {
+			b.Transactions[1].AddTxOut(wire.NewTxOut(0,
+				nameScript(name, owner)))
+		}
+	}
+	spendNameOutput := func(regBlock *wire.MsgBlock) func(*wire.MsgBlock) {
+		return func(b *wire.MsgBlock) {
+			regOut := makeSpendableOutForTx(regBlock.Transactions[1], 0)
+			b.Transactions[1].AddTxIn(&wire.TxIn{
+				PreviousOutPoint: regOut.prevOut,
+				Sequence:         wire.MaxTxInSequenceNum,
+			})
+		}
+	}
+	_, ownerA := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x41}, 32))
+	_, ownerB := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x42}, 32))
+	keyA := ownerA.SerializeCompressed()
+	keyB := ownerB.SerializeCompressed()
+
+	// Create a block that registers a new name.
+	//
+	//   ... -> b82(28) -> b87(29)
+	g.setTip("b82")
+	b87 := g.nextBlock("b87", outs[29], replaceSpendScript(nameScript(
+		"alice", keyA)))
+	accepted()
+
+	// Create a block that registers the name to a different key.
+	//
+	//   ... -> b87(29) -> b88(30)
+	g.nextBlock("b88", outs[30], replaceSpendScript(nameScript("alice",
+		keyB)))
+	rejected(blockchain.ErrNameOwnership)
+
+	// Create a block that renews the name with the key that owns it by
+	// spending its registration.
+	//
+	//   ... -> b87(29) -> b89(30)
+	g.setTip("b87")
+	g.nextBlock("b89", outs[30], replaceSpendScript(nameScript("alice",
+		keyA)), spendNameOutput(b87))
+	accepted()
+
+	// Create a block that registers an invalid name.
+	//
+	//   ... -> b89(30) -> b90(31)
+	g.nextBlock("b90", outs[31], replaceSpendScript(nameScript("Alice",
+		keyA)))
+	rejected(blockchain.ErrBadNameRegistration)
+
+	// Create a block that registers a new name to two different keys.
+	//
+	//   ... -> b89(30) -> b91(31)
+	g.setTip("b89")
+	g.nextBlock("b91", outs[31], replaceSpendScript(nameScript("bob",
+		keyA)), additionalNameOutput("bob", keyB))
+	rejected(blockchain.ErrNameOwnership)
+
+	// Create a side chain that registers the name to a different key
+	// before the registration in the main chain and ensure the name moves
+	// to the new key once the side chain becomes the main chain.
+	//
+	//   ... -> b82(28) -> b87(29) -> b89(30)
+	//                 \-> b92(29) -> b93(30) -> b94(31)
+	g.setTip("b82")
+	b92 := g.nextBlock("b92", outs[29], replaceSpendScript(nameScript(
+		"alice", keyB)))
+	acceptedToSideChainWithExpectedTip("b89")
+
+	b93 := g.nextBlock("b93", outs[30], replaceSpendScript(nameScript(
+		"alice", keyB)), spendNameOutput(b92))
+	acceptedToSideChainWithExpectedTip("b89")
+	nameExpiryHeight := g.tipHeight + g.params.NameExpiryBlocks
+
+	g.nextBlock("b94", outs[31])
+	accepted()
+
+	// Create a block that registers the name to the key which owned it
+	// prior to the reorganization.
+	//
+	//   ... -> b94(31) -> b95(32)
+	g.nextBlock("b95", outs[32], replaceSpendScript(nameScript("alice",
+		keyA)))
+	rejected(blockchain.ErrNameOwnership)
+
+	// Create a block that renews the name with the key that owns it
+	// without spending its registration.
+	//
+	//   ... -> b94(31) -> b96(32)
+	g.setTip("b94")
+	g.nextBlock("b96", outs[32], replaceSpendScript(nameScript("alice",
+		keyB)))
+	rejected(blockchain.ErrNameRenewal)
+
+	// Create a block that spends the registration of the name to register
+	// it to a different key.
+	//
+	//   ... -> b94(31) -> b97(32)
+	g.setTip("b94")
+	g.nextBlock("b97", outs[32], replaceSpendScript(nameScript("alice",
+		keyA)), spendNameOutput(b93))
+	rejected(blockchain.ErrNameOwnership)
+
+	// Extend the main chain to two blocks prior to the one for which the
+	// registration of the name expires.
+	//
+	//   ... -> b94(31) -> bn0 -> bn1 -> ... -> bn#
+	g.setTip("b94")
+	for i := 0; g.tipHeight < nameExpiryHeight-2; i++ {
+		g.nextBlock(fmt.Sprintf("bn%d", i), nil)
+		accepted()
+	}
+	lastNameBlockName := g.tipName
+
+	// Create a block that registers the name to a different key in the
+	// last block before the registration of the name expires.
+	//
+	//   ... -> bn# -> b98(32)
+	g.nextBlock("b98", outs[32], replaceSpendScript(nameScript("alice",
+		keyA)))
+	rejected(blockchain.ErrNameOwnership)
+
+	// Create a block that registers the name to a different key once the
+	// registration of the name has expired.
+	//
+	//   ... -> bn# -> b99 -> b100(32)
+	g.setTip(lastNameBlockName)
+	g.nextBlock("b99", nil)
+	accepted()
+
+	g.nextBlock("b100", outs[32], replaceSpendScript(nameScript("alice",
+		keyA)))
+	accepted()
+
+	// Create a block that renews the name with the key whose registration
+	// expired.
+	//
+	//   ... -> b100(32) -> b101(33)
+	g.nextBlock("b101", outs[33], replaceSpendScript(nameScript("alice",
+		keyB)), spendNameOutput(b93))
+	rejected(blockchain.ErrNameOwnership)
+
 	// ---------------------------------------------------------------------
 	// Large block re-org test.
 	// ---------------------------------------------------------------------
@@ -2055,8 +2301,8 @@ func Generate(includeLargeReorg bool) (tests [][]TestInstance, err error) {
 
 	// Ensure the tip the re-org test builds on is the best chain tip.
 	//
-	//   ... -> b81(27) -> ...
-	g.setTip("b81")
+	//   ... -> b100(32) -> ...
+	g.setTip("b100")
 
 	// Collect all of the spendable coinbase outputs from the previous
 	// collection point up to the current tip.
@@ -2133,3 +2379,336 @@ func Generate(includeLargeReorg bool) (tests [][]TestInstance, err error) {
 
 	return tests, nil
 }
+
+// lwmaBits returns the bits required for a block with the passed timestamp
+// which extends the current tip when the DiffAlgoLWMA difficulty algorithm is
+// in use.
+//
+// NOTE: This intentionally does not use the implementation in the blockchain
+// package so the tests are able to detect changes to it.
+func (g *testGenerator) lwmaBits(ts time.Time) uint32 {
+	params := g.params
+	if params.ReduceMinDifficulty &&
+		ts.Sub(g.tip.Header.Timestamp) > params.MinDiffReductionTime {
+
+		return params.PowLimitBits
+	}
+	n := int64(params.DifficultyWindow)
+	if int64(g.tipHeight) < n {
+		return params.PowLimitBits
+	}
+
+	// Collect the blocks in the window along with the parent of the oldest
+	// one, oldest first.
+	window := make([]*wire.MsgBlock, n+1)
+	block := g.tip
+	for i := n; i >= 0; i-- {
+		window[i] = block
+		block = g.blocks[block.Header.PrevBlock]
+	}
+
+	// Weight the solve time of each block by its position in the window.
+	spacing := int64(params.TargetTimePerBlock / time.Second)
+	var weightedSum int64
+	sumTargets := new(big.Int)
+	prevTime := window[0].Header.Timestamp.Unix()
+	for i := int64(1); i <= n; i++ {
+		blockTime := window[i].Header.Timestamp.Unix()
+		if blockTime <= prevTime {
+			blockTime = prevTime + 1
+		}
+		solveTime := blockTime - prevTime
+		if solveTime > 6*spacing {
+			solveTime = 6 * spacing
+		}
+		prevTime = blockTime
+
+		weightedSum += i * solveTime
+		sumTargets.Add(sumTargets,
+			blockchain.CompactToBig(window[i].Header.Bits))
+	}
+	expectedSum := n * (n + 1) / 2 * spacing
+	if weightedSum < expectedSum/10 {
+		weightedSum = expectedSum / 10
+	}
+
+	target := sumTargets.Mul(sumTargets, big.NewInt(weightedSum))
+	target.Div(target, big.NewInt(n*expectedSum))
+	if target.Cmp(params.PowLimit) > 0 {
+		target.Set(params.PowLimit)
+	}
+	return blockchain.BigToCompact(target)
+}
+
+// GenerateLWMA returns a slice of tests that can be used to exercise the
+// DiffAlgoLWMA difficulty algorithm.  Unlike the tests returned by Generate,
+// the tests must be run against the regression test network parameters with
+// the DifficultyAlgorithm set to DiffAlgoLWMA and the DifficultyWindow set to
+// LWMADifficultyWindow.
+func GenerateLWMA() (tests [][]TestInstance, err error) {
+	// In order to simplify the generation code which really should never
+	// fail unless the test code itself is broken, panics are used
+	// internally.  This deferred func ensures any panics don't escape the
+	// generator by replacing the named error return with the underlying
+	// panic error.
+	defer func() {
+		if r := recover(); r != nil {
+			tests = nil
+
+			switch rt := r.(type) {
+			case string:
+				err = errors.New(rt)
+			case error:
+				err = rt
+			default:
+				err = errors.New("Unknown panic")
+			}
+		}
+	}()
+
+	g, err := makeTestGenerator(lwmaRegressionNetParams)
+	if err != nil {
+		return nil, err
+	}
+
+	// accepted creates and appends a single acceptBlock test instance for
+	// the current tip which expects the block to be accepted to the main
+	// chain.
+	//
+	// rejected creates and appends a single rejectBlock test instance for
+	// the current tip.
+	accepted := func() {
+		tests = append(tests, []TestInstance{AcceptedBlock{g.tipName,
+			g.tip, g.tipHeight, true, false}})
+	}
+	rejected := func(code blockchain.ErrorCode) {
+		tests = append(tests, []TestInstance{RejectedBlock{g.tipName,
+			g.tip, g.tipHeight, code}})
+	}
+
+	// nextLWMABlock creates a block which extends the current tip and was
+	// solved the passed duration after it.  The block carries the bits
+	// required by the difficulty algorithm unless a munge function
+	// changes them.  The chain starts a day in the past so the blocks are
+	// not rejected for being too far in the future.
+	spacing := g.params.TargetTimePerBlock
+	start := time.Unix(time.Now().Add(-24*time.Hour).Unix(), 0)
+	nextLWMABlock := func(blockName string, solveTime time.Duration, mungers ...func(*wire.MsgBlock)) {
+		ts := start
+		if g.tipHeight > 0 {
+			ts = g.tip.Header.Timestamp.Add(solveTime)
+		}
+		bits := g.lwmaBits(ts)
+		mungers = append([]func(*wire.MsgBlock){func(b *wire.MsgBlock) {
+			b.Header.Timestamp = ts
+			b.Header.Bits = bits
+		}}, mungers...)
+		g.nextBlock(blockName, nil, mungers...)
+	}
+
+	// ---------------------------------------------------------------------
+	// The proof of work limit is required until the difficulty window is
+	// full, after which blocks found on target keep it there.
+	//
+	//   genesis -> bl1 -> ... -> bl#
+	// ---------------------------------------------------------------------
+
+	for i := 1; i <= LWMADifficultyWindow+1; i++ {
+		nextLWMABlock(fmt.Sprintf("bl%d", i), spacing)
+		if g.tip.Header.Bits != g.params.PowLimitBits {
+			panic(fmt.Sprintf("block %s has bits %08x instead of the "+
+				"proof of work limit", g.tipName, g.tip.Header.Bits))
+		}
+		accepted()
+	}
+
+	// ---------------------------------------------------------------------
+	// Fast blocks raise the difficulty of every block which follows them.
+	//
+	//   ... -> bl# -> blf1 -> blf2 -> blf3
+	// ---------------------------------------------------------------------
+
+	for i := 1; i <= 3; i++ {
+		prevBits := g.tip.Header.Bits
+		nextLWMABlock(fmt.Sprintf("blf%d", i), spacing/10)
+		if i > 1 && g.tip.Header.Bits >= prevBits {
+			panic(fmt.Sprintf("block %s has bits %08x which are not "+
+				"harder than %08x", g.tipName, g.tip.Header.Bits,
+				prevBits))
+		}
+		accepted()
+	}
+
+	// Create blocks which claim the difficulty of the previous block and
+	// the proof of work limit instead of the required difficulty.
+	//
+	//   ... -> blf3
+	//               \-> blbad1
+	//               \-> blbad2
+	blf3Bits := g.tip.Header.Bits
+	nextLWMABlock("blbad1", spacing/10, func(b *wire.MsgBlock) {
+		b.Header.Bits = blf3Bits
+	})
+	rejected(blockchain.ErrUnexpectedDifficulty)
+
+	g.setTip("blf3")
+	nextLWMABlock("blbad2", spacing/10, func(b *wire.MsgBlock) {
+		b.Header.Bits = g.params.PowLimitBits
+	})
+	rejected(blockchain.ErrUnexpectedDifficulty)
+
+	// ---------------------------------------------------------------------
+	// Slow blocks lower the difficulty of every block which follows them.
+	//
+	//   ... -> blf3 -> bls1 -> bls2 -> bls3
+	// ---------------------------------------------------------------------
+
+	g.setTip("blf3")
+	for i := 1; i <= 3; i++ {
+		prevBits := g.tip.Header.Bits
+		nextLWMABlock(fmt.Sprintf("bls%d", i), 2*spacing)
+		if i > 1 && g.tip.Header.Bits <= prevBits {
+			panic(fmt.Sprintf("block %s has bits %08x which are not "+
+				"easier than %08x", g.tipName, g.tip.Header.Bits,
+				prevBits))
+		}
+		accepted()
+	}
+
+	// ---------------------------------------------------------------------
+	// Minimum difficulty blocks are required once the reduction time has
+	// elapsed without a block.
+	//
+	//   ... -> bls3 -> blm1
+	//               \-> blmbad1
+	// ---------------------------------------------------------------------
+
+	bls3Bits := g.tip.Header.Bits
+	reductionTime := g.params.MinDiffReductionTime + time.Second
+	nextLWMABlock("blmbad1", reductionTime, func(b *wire.MsgBlock) {
+		b.Header.Bits = bls3Bits
+	})
+	rejected(blockchain.ErrUnexpectedDifficulty)
+
+	g.setTip("bls3")
+	nextLWMABlock("blm1", reductionTime)
+	if g.tip.Header.Bits != g.params.PowLimitBits {
+		panic(fmt.Sprintf("block %s has bits %08x instead of the proof "+
+			"of work limit", g.tipName, g.tip.Header.Bits))
+	}
+	accepted()
+
+	return tests, nil
+}
+
+// GenerateLWMASwitch returns a slice of tests that can be used to exercise the
+// switch from the DiffAlgoBitcoin difficulty algorithm to DiffAlgoLWMA at the
+// DifficultyAlgorithmHeight.  The tests must be run against the parameters
+// described by GenerateLWMA with the DifficultyAlgorithmHeight set to
+// LWMASwitchHeight.
+func GenerateLWMASwitch() (tests [][]TestInstance, err error) {
+	// In order to simplify the generation code which really should never
+	// fail unless the test code itself is broken, panics are used
+	// internally.  This deferred func ensures any panics don't escape the
+	// generator by replacing the named error return with the underlying
+	// panic error.
+	defer func() {
+		if r := recover(); r != nil {
+			tests = nil
+
+			switch rt := r.(type) {
+			case string:
+				err = errors.New(rt)
+			case error:
+				err = rt
+			default:
+				err = errors.New("Unknown panic")
+			}
+		}
+	}()
+
+	g, err := makeTestGenerator(lwmaSwitchRegressionNetParams)
+	if err != nil {
+		return nil, err
+	}
+
+	// accepted creates and appends a single acceptBlock test instance for
+	// the current tip which expects the block to be accepted to the main
+	// chain.
+	//
+	// rejected creates and appends a single rejectBlock test instance for
+	// the current tip.
+	accepted := func() {
+		tests = append(tests, []TestInstance{AcceptedBlock{g.tipName,
+			g.tip, g.tipHeight, true, false}})
+	}
+	rejected := func(code blockchain.ErrorCode) {
+		tests = append(tests, []TestInstance{RejectedBlock{g.tipName,
+			g.tip, g.tipHeight, code}})
+	}
+
+	// nextFastBlock creates a block which extends the current tip, was
+	// solved a tenth of the target time per block after it and carries
+	// the passed bits.  The chain starts a day in the past so the blocks
+	// are not rejected for being too far in the future.
+	start := time.Unix(time.Now().Add(-24*time.Hour).Unix(), 0)
+	nextFastBlock := func(blockName string, bits func(ts time.Time) uint32) {
+		ts := start
+		if g.tipHeight > 0 {
+			ts = g.tip.Header.Timestamp.Add(
+				g.params.TargetTimePerBlock / 10)
+		}
+		g.nextBlock(blockName, nil, func(b *wire.MsgBlock) {
+			b.Header.Timestamp = ts
+			b.Header.Bits = bits(ts)
+		})
+	}
+	powLimitBits := func(time.Time) uint32 {
+		return g.params.PowLimitBits
+	}
+
+	// ---------------------------------------------------------------------
+	// The regression test network never retargets this early, so the
+	// blocks before the switch height require the proof of work limit
+	// even though they are found fast enough for the LWMA algorithm to
+	// raise the difficulty.
+	//
+	//   genesis -> bsw1 -> ... -> bsw#
+	//                          \-> bswbad1
+	// ---------------------------------------------------------------------
+
+	for i := int32(1); i < LWMASwitchHeight-1; i++ {
+		nextFastBlock(fmt.Sprintf("bsw%d", i), powLimitBits)
+		accepted()
+	}
+
+	prevName := g.tipName
+	nextFastBlock("bswbad1", g.lwmaBits)
+	if g.tip.Header.Bits == g.params.PowLimitBits {
+		panic(fmt.Sprintf("block %s has the bits of the proof of work "+
+			"limit", g.tipName))
+	}
+	rejected(blockchain.ErrUnexpectedDifficulty)
+
+	g.setTip(prevName)
+	nextFastBlock(fmt.Sprintf("bsw%d", LWMASwitchHeight-1), powLimitBits)
+	accepted()
+
+	// ---------------------------------------------------------------------
+	// The block at the switch height requires the difficulty calculated
+	// by the LWMA algorithm from the blocks before the switch.
+	//
+	//   ... -> bsw# -> bsw#+1
+	//              \-> bswbad2
+	// ---------------------------------------------------------------------
+
+	prevName = g.tipName
+	nextFastBlock("bswbad2", powLimitBits)
+	rejected(blockchain.ErrUnexpectedDifficulty)
+
+	g.setTip(prevName)
+	nextFastBlock(fmt.Sprintf("bsw%d", LWMASwitchHeight), g.lwmaBits)
+	accepted()
+
+	return tests, nil
+}
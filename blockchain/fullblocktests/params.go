@@ -113,6 +113,7 @@ var regressionNetParams = &chaincfg.Params{
 	ReduceMinDifficulty:      true,
 	MinDiffReductionTime:     time.Minute * 20, // TargetTimePerBlock * 2
 	GenerateSupported:        true,
+	NameExpiryBlocks:         20,
 
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: nil,
@@ -133,3 +134,32 @@ var regressionNetParams = &chaincfg.Params{
 	// address generation.
 	HDCoinType: 1,
 }
+
+// LWMADifficultyWindow is the difficulty window of the regression test network
+// parameters used by GenerateLWMA.
+const LWMADifficultyWindow = 6
+
+// lwmaRegressionNetParams defines the network parameters for the regression
+// test network with the difficulty of each block calculated by the
+// DiffAlgoLWMA algorithm.
+var lwmaRegressionNetParams = func() *chaincfg.Params {
+	params := *regressionNetParams
+	params.DifficultyAlgorithm = chaincfg.DiffAlgoLWMA
+	params.DifficultyWindow = LWMADifficultyWindow
+	return &params
+}()
+
+// LWMASwitchHeight is the height of the first block whose difficulty is
+// calculated by the DiffAlgoLWMA algorithm in the regression test network
+// parameters used by GenerateLWMASwitch.  It is past the first full difficulty
+// window so the algorithms require different bits at the switch.
+const LWMASwitchHeight = LWMADifficultyWindow + 4
+
+// lwmaSwitchRegressionNetParams defines the network parameters for the
+// regression test network with the difficulty algorithm switched from
+// DiffAlgoBitcoin to DiffAlgoLWMA at LWMASwitchHeight.
+var lwmaSwitchRegressionNetParams = func() *chaincfg.Params {
+	params := *lwmaRegressionNetParams
+	params.DifficultyAlgorithmHeight = LWMASwitchHeight
+	return &params
+}()
@@ -617,13 +617,13 @@ func (idx *AddrIndex) NeedsInputs() bool {
 	return true
 }
 
-// Init is only provided to satisfy the Indexer interface as there is nothing to
-// initialize for this index.
+// Init ensures the blocks the index locates transactions in have not been
+// pruned from the database as there is nothing else to initialize for this
+// index.
 //
 // This is part of the Indexer interface.
 func (idx *AddrIndex) Init() error {
-	// Nothing to do.
-	return nil
+	return requireFullHistory(idx.db, addrIndexName)
 }
 
 // Key returns the database key to use for the index as a byte slice.
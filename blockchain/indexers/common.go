@@ -10,6 +10,7 @@ package indexers
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 
 	"github.com/jadeblaquiere/cttd/blockchain"
 	"github.com/jadeblaquiere/cttd/database"
@@ -63,6 +64,23 @@ type Indexer interface {
 	DisconnectBlock(database.Tx, *btcutil.Block, []blockchain.SpentTxOut) error
 }
 
+// requireFullHistory returns an error when blocks have been pruned from the
+// passed database since the named index needs all of them.
+func requireFullHistory(db database.DB, indexName string) error {
+	return db.View(func(dbTx database.Tx) error {
+		pruned, err := dbTx.BeenPruned()
+		if err != nil {
+			return err
+		}
+		if pruned {
+			return fmt.Errorf("the %s requires the full block history "+
+				"which is not available since blocks have been "+
+				"pruned from the database", indexName)
+		}
+		return nil
+	})
+}
+
 // AssertError identifies an error that indicates an internal code consistency
 // issue and should be treated as a critical and unrecoverable error.
 type AssertError string
@@ -394,6 +394,17 @@ func (m *Manager) Init(chain *blockchain.BlockChain, interrupt <-chan struct{})
 		return nil
 	}
 
+	// The blocks needed to catch up the indexes which are behind are not
+	// available once blocks have been pruned.
+	for i, indexer := range m.enabledIndexes {
+		if indexerHeights[i] < bestHeight {
+			err := requireFullHistory(m.db, indexer.Name())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	// Create a progress logger for the indexing process below.
 	progressLogger := newBlockProgressLogger("Indexed", log)
 
@@ -0,0 +1,405 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/blockchain"
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+	"github.com/jadeblaquiere/cttd/database"
+	"github.com/jadeblaquiere/cttd/txscript"
+	"github.com/jadeblaquiere/cttd/wire"
+	"github.com/jadeblaquiere/cttutil"
+)
+
+const (
+	// nakIndexName is the human-readable name for the index.
+	nakIndexName = "network access key index"
+
+	// nakRegistrationHeaderSize is the size of the fixed portion of a
+	// serialized network access key registration.
+	nakRegistrationHeaderSize = 4 + chainhash.HashSize + 4 + 4 + 1
+)
+
+var (
+	// nakIndexKey is the key of the network access key index and the db
+	// bucket used to house it.
+	nakIndexKey = []byte("nakidx")
+)
+
+// -----------------------------------------------------------------------------
+// The network access key index consists of an entry for every public key which
+// has been registered as a network access key with OP_REGISTERNAK in the main
+// chain.  Each entry holds every registration of the key ordered by height and
+// then by position within the block.
+//
+// A registration is live until the output which registers it is spent, which
+// revokes the key.  The height of the block which spent the output is kept so
+// the revocation can be undone when the block is disconnected.
+//
+// The serialized format for keys and values in the network access key bucket
+// is:
+//   <compressed pubkey> = <registration>...
+//
+//   Field           Type              Size
+//   pubkey          []byte            33 bytes
+//   registrations   []registration    variable
+//
+// The serialized format of a registration is:
+//
+//   Field           Type              Size
+//   height          uint32            4 bytes
+//   tx hash         chainhash.Hash    32 bytes
+//   output index    uint32            4 bytes
+//   revoked height  uint32            4 bytes (zero when unrevoked)
+//   nak length      uint8             1 byte
+//   nak             []byte            variable
+// -----------------------------------------------------------------------------
+
+// NAKRegistration describes an output which registers a network access key
+// with OP_REGISTERNAK.
+type NAKRegistration struct {
+	// NAK is the registered network access key.
+	NAK *txscript.NetworkAccessKey
+
+	// OutPoint identifies the registering output.
+	OutPoint wire.OutPoint
+
+	// Height is the height of the block containing the registration.
+	Height int32
+
+	// RevokedHeight is the height of the block which spent the registering
+	// output, revoking the key, or zero when the key has not been revoked.
+	RevokedHeight int32
+}
+
+// Revoked returns whether the registering output has been spent.
+func (reg *NAKRegistration) Revoked() bool {
+	return reg.RevokedHeight != 0
+}
+
+// Active returns whether the registered key is usable at the passed time,
+// meaning it has not been revoked and the time is within its validity period.
+func (reg *NAKRegistration) Active(t time.Time) bool {
+	return !reg.Revoked() && !t.Before(reg.NAK.NotBefore) &&
+		t.Before(reg.NAK.NotAfter)
+}
+
+// serializeNAKRegistrations returns the passed registrations serialized
+// according to the format described above.
+func serializeNAKRegistrations(regs []NAKRegistration) []byte {
+	var serialized []byte
+	for _, reg := range regs {
+		nak := reg.NAK.Serialize()
+		var buf [nakRegistrationHeaderSize]byte
+		byteOrder.PutUint32(buf[0:4], uint32(reg.Height))
+		copy(buf[4:36], reg.OutPoint.Hash[:])
+		byteOrder.PutUint32(buf[36:40], reg.OutPoint.Index)
+		byteOrder.PutUint32(buf[40:44], uint32(reg.RevokedHeight))
+		buf[44] = uint8(len(nak))
+		serialized = append(serialized, buf[:]...)
+		serialized = append(serialized, nak...)
+	}
+	return serialized
+}
+
+// deserializeNAKRegistrations decodes the passed serialized registrations
+// according to the format described above.
+func deserializeNAKRegistrations(serialized []byte) ([]NAKRegistration, error) {
+	var regs []NAKRegistration
+	for len(serialized) > 0 {
+		if len(serialized) < nakRegistrationHeaderSize {
+			return nil, errDeserialize("unexpected end of data for " +
+				"serialized network access key registration")
+		}
+		var reg NAKRegistration
+		reg.Height = int32(byteOrder.Uint32(serialized[0:4]))
+		copy(reg.OutPoint.Hash[:], serialized[4:36])
+		reg.OutPoint.Index = byteOrder.Uint32(serialized[36:40])
+		reg.RevokedHeight = int32(byteOrder.Uint32(serialized[40:44]))
+		nakLen := int(serialized[44])
+		serialized = serialized[nakRegistrationHeaderSize:]
+		if len(serialized) < nakLen {
+			return nil, errDeserialize("unexpected end of data for " +
+				"serialized network access key")
+		}
+
+		nak, err := txscript.ParseNAK(serialized[:nakLen])
+		if err != nil {
+			return nil, errDeserialize(err.Error())
+		}
+		reg.NAK = nak
+		serialized = serialized[nakLen:]
+		regs = append(regs, reg)
+	}
+	return regs, nil
+}
+
+// dbFetchNAKRegistrations returns the registrations of the passed compressed
+// public key from the index.  Nil is returned when the key has never been
+// registered.
+func dbFetchNAKRegistrations(dbTx database.Tx, pubKey []byte) ([]NAKRegistration, error) {
+	serialized := dbTx.Metadata().Bucket(nakIndexKey).Get(pubKey)
+	if serialized == nil {
+		return nil, nil
+	}
+	return deserializeNAKRegistrations(serialized)
+}
+
+// dbPutNAKRegistrations stores the registrations of the passed compressed
+// public key in the index, removing the entry for the key entirely when there
+// are none.
+func dbPutNAKRegistrations(dbTx database.Tx, pubKey []byte, regs []NAKRegistration) error {
+	bucket := dbTx.Metadata().Bucket(nakIndexKey)
+	if len(regs) == 0 {
+		return bucket.Delete(pubKey)
+	}
+	return bucket.Put(pubKey, serializeNAKRegistrations(regs))
+}
+
+// NAKEntry describes a registered network access key and all of its
+// registrations.
+type NAKEntry struct {
+	PubKey        []byte
+	Registrations []NAKRegistration
+}
+
+// NAKIndex implements an index of the network access keys registered with
+// OP_REGISTERNAK in the main chain, including when they were revoked.
+type NAKIndex struct {
+	db database.DB
+}
+
+// Ensure the NAKIndex type implements the Indexer interface.
+var _ Indexer = (*NAKIndex)(nil)
+
+// Ensure the NAKIndex type implements the NeedsInputser interface.
+var _ NeedsInputser = (*NAKIndex)(nil)
+
+// NeedsInputs signals that the index requires the referenced inputs in order
+// to properly create the index.
+//
+// This implements the NeedsInputser interface.
+func (idx *NAKIndex) NeedsInputs() bool {
+	return true
+}
+
+// Init is only provided to satisfy the Indexer interface as there is nothing
+// to initialize for this index.
+//
+// This is part of the Indexer interface.
+func (idx *NAKIndex) Init() error {
+	return nil
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *NAKIndex) Key() []byte {
+	return nakIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *NAKIndex) Name() string {
+	return nakIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs
+// to be created for the first time.  It creates the bucket for the network
+// access key index.
+//
+// This is part of the Indexer interface.
+func (idx *NAKIndex) Create(dbTx database.Tx) error {
+	_, err := dbTx.Metadata().CreateBucket(nakIndexKey)
+	return err
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer marks the registrations whose
+// outputs the block spends as revoked and adds the registrations made by the
+// block.
+//
+// This is part of the Indexer interface.
+func (idx *NAKIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	height := block.Height()
+	stxoIndex := 0
+	for txIdx, tx := range block.Transactions() {
+		// Coinbases do not reference any inputs.
+		if txIdx != 0 {
+			for _, txIn := range tx.MsgTx().TxIn {
+				pkScript := stxos[stxoIndex].PkScript
+				stxoIndex++
+
+				nak, err := txscript.ExtractNAKRegistration(pkScript)
+				if err != nil || nak == nil {
+					continue
+				}
+				pubKey := nak.PubKey.SerializeCompressed()
+				regs, err := dbFetchNAKRegistrations(dbTx, pubKey)
+				if err != nil {
+					return err
+				}
+				for i := range regs {
+					if regs[i].OutPoint == txIn.PreviousOutPoint {
+						regs[i].RevokedHeight = height
+					}
+				}
+				err = dbPutNAKRegistrations(dbTx, pubKey, regs)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for i, txOut := range tx.MsgTx().TxOut {
+			nak, err := txscript.ExtractNAKRegistration(txOut.PkScript)
+			if err != nil || nak == nil {
+				continue
+			}
+			pubKey := nak.PubKey.SerializeCompressed()
+			regs, err := dbFetchNAKRegistrations(dbTx, pubKey)
+			if err != nil {
+				return err
+			}
+			regs = append(regs, NAKRegistration{
+				NAK:      nak,
+				OutPoint: *wire.NewOutPoint(tx.Hash(), uint32(i)),
+				Height:   height,
+			})
+			err = dbPutNAKRegistrations(dbTx, pubKey, regs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the registrations
+// made by the block and restores the registrations it revoked.
+//
+// This is part of the Indexer interface.
+func (idx *NAKIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	// Collect every key the block registered or revoked.  Since blocks are
+	// disconnected from the tip, everything at or above the height of the
+	// block was done by it.
+	pubKeys := make(map[string]struct{})
+	addKey := func(pkScript []byte) {
+		nak, err := txscript.ExtractNAKRegistration(pkScript)
+		if err != nil || nak == nil {
+			return
+		}
+		pubKeys[string(nak.PubKey.SerializeCompressed())] = struct{}{}
+	}
+	for _, stxo := range stxos {
+		addKey(stxo.PkScript)
+	}
+	for _, tx := range block.Transactions() {
+		for _, txOut := range tx.MsgTx().TxOut {
+			addKey(txOut.PkScript)
+		}
+	}
+
+	height := block.Height()
+	for pubKey := range pubKeys {
+		regs, err := dbFetchNAKRegistrations(dbTx, []byte(pubKey))
+		if err != nil {
+			return err
+		}
+		for len(regs) > 0 && regs[len(regs)-1].Height >= height {
+			regs = regs[:len(regs)-1]
+		}
+		for i := range regs {
+			if regs[i].RevokedHeight >= height {
+				regs[i].RevokedHeight = 0
+			}
+		}
+		err = dbPutNAKRegistrations(dbTx, []byte(pubKey), regs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FetchNAK returns the registrations of the passed compressed public key in
+// the main chain ordered by height and then by position within the block.  Nil
+// is returned for both the registrations and the error when the key has never
+// been registered.
+//
+// This function is safe for concurrent access.
+func (idx *NAKIndex) FetchNAK(pubKey []byte) ([]NAKRegistration, error) {
+	var regs []NAKRegistration
+	err := idx.db.View(func(dbTx database.Tx) error {
+		var err error
+		regs, err = dbFetchNAKRegistrations(dbTx, pubKey)
+		return err
+	})
+	return regs, err
+}
+
+// ListNAKs returns up to limit registered network access keys, ordered by
+// their compressed public keys, which sort after the passed public key.  An
+// empty after starts the listing from the first key.  A limit of zero or less
+// places no bound on the number of keys returned.
+//
+// This function is safe for concurrent access.
+func (idx *NAKIndex) ListNAKs(after []byte, limit int) ([]NAKEntry, error) {
+	var entries []NAKEntry
+	err := idx.db.View(func(dbTx database.Tx) error {
+		cursor := dbTx.Metadata().Bucket(nakIndexKey).Cursor()
+		ok := cursor.First()
+		if len(after) > 0 {
+			ok = cursor.Seek(after)
+		}
+		for ; ok; ok = cursor.Next() {
+			if limit > 0 && len(entries) >= limit {
+				break
+			}
+			if bytes.Equal(cursor.Key(), after) {
+				continue
+			}
+
+			regs, err := deserializeNAKRegistrations(cursor.Value())
+			if err != nil {
+				return err
+			}
+			pubKey := make([]byte, len(cursor.Key()))
+			copy(pubKey, cursor.Key())
+			entries = append(entries, NAKEntry{
+				PubKey:        pubKey,
+				Registrations: regs,
+			})
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// NewNAKIndex returns a new instance of an indexer that is used to create a
+// mapping of the network access keys registered in the block chain to their
+// registrations.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewNAKIndex(db database.DB) *NAKIndex {
+	return &NAKIndex{db: db}
+}
+
+// DropNAKIndex drops the network access key index from the provided database
+// if it exists.
+func DropNAKIndex(db database.DB, interrupt <-chan struct{}) error {
+	return dropIndex(db, nakIndexKey, nakIndexName, interrupt)
+}
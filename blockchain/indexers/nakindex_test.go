@@ -0,0 +1,211 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/blockchain"
+	"github.com/jadeblaquiere/cttd/btcec"
+	"github.com/jadeblaquiere/cttd/database"
+	_ "github.com/jadeblaquiere/cttd/database/ffldb"
+	"github.com/jadeblaquiere/cttd/txscript"
+	"github.com/jadeblaquiere/cttd/wire"
+	"github.com/jadeblaquiere/cttutil"
+)
+
+// testNAKScript returns a network access key signed by a deterministic private
+// key along with a public key script registering it.
+func testNAKScript(t *testing.T) (*txscript.NetworkAccessKey, []byte) {
+	privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat(
+		[]byte{0x4e}, 32))
+	nak, err := txscript.NewNAK(privKey, time.Unix(1500000000, 0),
+		time.Unix(1600000000, 0))
+	if err != nil {
+		t.Fatalf("NewNAK: unexpected error %v", err)
+	}
+	pkScript, err := txscript.NewScriptBuilder().AddData(nak.Serialize()).
+		AddOp(txscript.OP_REGISTERNAK).AddOp(txscript.OP_DROP).
+		AddOp(txscript.OP_TRUE).Script()
+	if err != nil {
+		t.Fatalf("Script: unexpected error %v", err)
+	}
+	return nak, pkScript
+}
+
+// TestNAKRegistrationSerialization ensures network access key registrations
+// round trip through their serialized form and truncated entries are
+// rejected.
+func TestNAKRegistrationSerialization(t *testing.T) {
+	nak, _ := testNAKScript(t)
+	regs := []NAKRegistration{
+		{
+			NAK:      nak,
+			OutPoint: wire.OutPoint{Index: 1},
+			Height:   100,
+		},
+		{
+			NAK:           nak,
+			OutPoint:      wire.OutPoint{Index: 0xffffffff},
+			Height:        200,
+			RevokedHeight: 300,
+		},
+	}
+
+	serialized := serializeNAKRegistrations(regs)
+	got, err := deserializeNAKRegistrations(serialized)
+	if err != nil {
+		t.Fatalf("deserializeNAKRegistrations: unexpected error %v", err)
+	}
+	if len(got) != len(regs) {
+		t.Fatalf("deserializeNAKRegistrations: wrong number of "+
+			"registrations - got %d, want %d", len(got), len(regs))
+	}
+	for i := range regs {
+		if got[i].OutPoint != regs[i].OutPoint ||
+			got[i].Height != regs[i].Height ||
+			got[i].RevokedHeight != regs[i].RevokedHeight ||
+			!bytes.Equal(got[i].NAK.Serialize(), nak.Serialize()) {
+
+			t.Fatalf("deserializeNAKRegistrations: mismatched "+
+				"registration %d - got %v, want %v", i, got[i],
+				regs[i])
+		}
+	}
+
+	for _, truncated := range [][]byte{
+		serialized[:nakRegistrationHeaderSize-1],
+		serialized[:len(serialized)-1],
+	} {
+		_, err := deserializeNAKRegistrations(truncated)
+		if !isDeserializeErr(err) {
+			t.Fatalf("deserializeNAKRegistrations: wrong error "+
+				"for truncated entry - got %v", err)
+		}
+	}
+}
+
+// TestNAKIndexConnectDisconnect ensures the network access key index records
+// registrations and revocations as blocks are connected and undoes them as the
+// blocks are disconnected.
+func TestNAKIndexConnectDisconnect(t *testing.T) {
+	dbPath, err := ioutil.TempDir("", "nakindex")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error %v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	db, err := database.Create("ffldb", filepath.Join(dbPath, "db"),
+		wire.SimNet)
+	if err != nil {
+		t.Fatalf("database.Create: unexpected error %v", err)
+	}
+	defer db.Close()
+
+	idx := NewNAKIndex(db)
+	err = db.Update(func(dbTx database.Tx) error {
+		return idx.Create(dbTx)
+	})
+	if err != nil {
+		t.Fatalf("Create: unexpected error %v", err)
+	}
+
+	nak, pkScript := testNAKScript(t)
+	pubKey := nak.PubKey.SerializeCompressed()
+
+	// newBlock returns a block at the passed height with a coinbase and
+	// the passed transaction.
+	newBlock := func(height int32, tx *wire.MsgTx) *btcutil.Block {
+		coinbase := wire.NewMsgTx(1)
+		coinbase.AddTxIn(&wire.TxIn{
+			SignatureScript: []byte{byte(height)},
+		})
+		coinbase.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_TRUE}))
+		msgBlock := wire.NewMsgBlock(&wire.BlockHeader{})
+		msgBlock.AddTransaction(coinbase)
+		msgBlock.AddTransaction(tx)
+		block := btcutil.NewBlock(msgBlock)
+		block.SetHeight(height)
+		return block
+	}
+
+	// Block 1 registers the key and block 2 revokes it by spending the
+	// registering output.
+	regTx := wire.NewMsgTx(1)
+	regTx.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{Index: 7}})
+	regTx.AddTxOut(wire.NewTxOut(1000, pkScript))
+	regBlock := newBlock(1, regTx)
+	regStxos := []blockchain.SpentTxOut{{PkScript: []byte{txscript.OP_TRUE}}}
+
+	regOutPoint := wire.OutPoint{Hash: regTx.TxHash(), Index: 0}
+	revokeTx := wire.NewMsgTx(1)
+	revokeTx.AddTxIn(&wire.TxIn{PreviousOutPoint: regOutPoint})
+	revokeTx.AddTxOut(wire.NewTxOut(900, []byte{txscript.OP_TRUE}))
+	revokeBlock := newBlock(2, revokeTx)
+	revokeStxos := []blockchain.SpentTxOut{{Amount: 1000, PkScript: pkScript}}
+
+	// checkRegs ensures the key has a single registration with the passed
+	// revoked height or no registrations when wantRegs is false.
+	checkRegs := func(desc string, wantRegs bool, revokedHeight int32) {
+		regs, err := idx.FetchNAK(pubKey)
+		if err != nil {
+			t.Fatalf("%s: FetchNAK: unexpected error %v", desc, err)
+		}
+		if !wantRegs {
+			if len(regs) != 0 {
+				t.Fatalf("%s: unexpected registrations %v", desc,
+					regs)
+			}
+			return
+		}
+		if len(regs) != 1 || regs[0].OutPoint != regOutPoint ||
+			regs[0].Height != 1 {
+
+			t.Fatalf("%s: unexpected registrations %v", desc, regs)
+		}
+		if regs[0].RevokedHeight != revokedHeight {
+			t.Fatalf("%s: wrong revoked height - got %d, want %d",
+				desc, regs[0].RevokedHeight, revokedHeight)
+		}
+
+		active := regs[0].Active(time.Unix(1550000000, 0))
+		if active != (revokedHeight == 0) {
+			t.Fatalf("%s: wrong active state %v", desc, active)
+		}
+	}
+
+	steps := []struct {
+		desc          string
+		connect       bool
+		block         *btcutil.Block
+		stxos         []blockchain.SpentTxOut
+		wantRegs      bool
+		revokedHeight int32
+	}{
+		{"connect registration", true, regBlock, regStxos, true, 0},
+		{"connect revocation", true, revokeBlock, revokeStxos, true, 2},
+		{"disconnect revocation", false, revokeBlock, revokeStxos,
+			true, 0},
+		{"disconnect registration", false, regBlock, regStxos, false,
+			0},
+	}
+	for _, step := range steps {
+		err := db.Update(func(dbTx database.Tx) error {
+			if step.connect {
+				return idx.ConnectBlock(dbTx, step.block,
+					step.stxos)
+			}
+			return idx.DisconnectBlock(dbTx, step.block, step.stxos)
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", step.desc, err)
+		}
+		checkRegs(step.desc, step.wantRegs, step.revokedHeight)
+	}
+}
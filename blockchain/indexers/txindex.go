@@ -291,10 +291,15 @@ var _ Indexer = (*TxIndex)(nil)
 
 // Init initializes the hash-based transaction index.  In particular, it finds
 // the highest used block ID and stores it for later use when connecting or
-// disconnecting blocks.
+// disconnecting blocks.  The index refuses to run once blocks have been pruned
+// since it locates transactions in the stored blocks.
 //
 // This is part of the Indexer interface.
 func (idx *TxIndex) Init() error {
+	if err := requireFullHistory(idx.db, txIndexName); err != nil {
+		return err
+	}
+
 	// Find the latest known block id field for the internal block id
 	// index and initialize it.  This is done because it's a lot more
 	// efficient to do a single search at initialize time than it is to
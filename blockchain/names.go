@@ -0,0 +1,453 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jadeblaquiere/cttd/chaincfg"
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+	"github.com/jadeblaquiere/cttd/database"
+	"github.com/jadeblaquiere/cttd/txscript"
+	"github.com/jadeblaquiere/cttd/wire"
+	"github.com/jadeblaquiere/cttutil"
+)
+
+// NameRegistration describes an output which registers a name with
+// OP_REGISTERNAME.
+type NameRegistration struct {
+	// Owner is the compressed public key the name is registered to.
+	Owner []byte
+
+	// OutPoint identifies the registering output.
+	OutPoint wire.OutPoint
+
+	// Height is the height of the block containing the registration.
+	Height int32
+}
+
+const (
+	// nameRegistrationSize is the size of a serialized name registration.
+	nameRegistrationSize = 4 + chainhash.HashSize + 4 + 33
+)
+
+var (
+	// nameRegistryBucketName is the name of the db bucket used to house
+	// the registrations of the names registered with OP_REGISTERNAME.
+	nameRegistryBucketName = []byte("nameregistry")
+)
+
+// -----------------------------------------------------------------------------
+// The name registry consists of an entry for every name registered with
+// OP_REGISTERNAME in the main chain while the DeploymentCTExtended deployment
+// is active.  Each entry holds every registration of the name ordered by height
+// and then by position within the block, which is what is needed to determine
+// the owner of the name, and allows the registrations made by a block to be
+// removed when it is disconnected.
+//
+// The serialized format for keys and values in the name registry bucket is:
+//   <name> = <registration>...
+//
+//   Field           Type              Size
+//   name            []byte            variable
+//   registrations   []registration    73 bytes each
+//
+// The serialized format of a registration is:
+//
+//   Field           Type              Size
+//   height          uint32            4 bytes
+//   tx hash         chainhash.Hash    32 bytes
+//   output index    uint32            4 bytes
+//   owner           compressed pubkey 33 bytes
+//   -----
+//   Total: 73 bytes
+// -----------------------------------------------------------------------------
+
+// serializeNameRegistrations returns the passed registrations serialized
+// according to the format described above.
+func serializeNameRegistrations(regs []NameRegistration) []byte {
+	serialized := make([]byte, 0, len(regs)*nameRegistrationSize)
+	for _, reg := range regs {
+		var buf [nameRegistrationSize]byte
+		byteOrder.PutUint32(buf[0:4], uint32(reg.Height))
+		copy(buf[4:36], reg.OutPoint.Hash[:])
+		byteOrder.PutUint32(buf[36:40], reg.OutPoint.Index)
+		copy(buf[40:], reg.Owner)
+		serialized = append(serialized, buf[:]...)
+	}
+	return serialized
+}
+
+// deserializeNameRegistrations decodes the passed serialized registrations
+// according to the format described above.
+func deserializeNameRegistrations(serialized []byte) ([]NameRegistration, error) {
+	if len(serialized)%nameRegistrationSize != 0 {
+		return nil, errDeserialize("unexpected length for serialized " +
+			"name registrations")
+	}
+
+	regs := make([]NameRegistration, 0, len(serialized)/nameRegistrationSize)
+	for offset := 0; offset < len(serialized); offset += nameRegistrationSize {
+		buf := serialized[offset : offset+nameRegistrationSize]
+		var reg NameRegistration
+		reg.Height = int32(byteOrder.Uint32(buf[0:4]))
+		copy(reg.OutPoint.Hash[:], buf[4:36])
+		reg.OutPoint.Index = byteOrder.Uint32(buf[36:40])
+		reg.Owner = make([]byte, 33)
+		copy(reg.Owner, buf[40:])
+		regs = append(regs, reg)
+	}
+	return regs, nil
+}
+
+// dbFetchNameRegistrations returns the registrations of the passed name from
+// the name registry.  Nil is returned when the name has never been registered.
+func dbFetchNameRegistrations(dbTx database.Tx, name []byte) ([]NameRegistration, error) {
+	serialized := dbTx.Metadata().Bucket(nameRegistryBucketName).Get(name)
+	if serialized == nil {
+		return nil, nil
+	}
+	return deserializeNameRegistrations(serialized)
+}
+
+// dbPutNameRegistrations stores the registrations of the passed name in the
+// name registry, removing the entry for the name entirely when there are none.
+func dbPutNameRegistrations(dbTx database.Tx, name []byte, regs []NameRegistration) error {
+	bucket := dbTx.Metadata().Bucket(nameRegistryBucketName)
+	if len(regs) == 0 {
+		return bucket.Delete(name)
+	}
+	return bucket.Put(name, serializeNameRegistrations(regs))
+}
+
+// dbConnectNameRegistrations appends every name registration made by the
+// passed block, which must have its height set, to the registrations of the
+// name in the name registry.
+func dbConnectNameRegistrations(dbTx database.Tx, block *btcutil.Block) error {
+	for name, blockRegs := range BlockNameRegistrations(block) {
+		regs, err := dbFetchNameRegistrations(dbTx, []byte(name))
+		if err != nil {
+			return err
+		}
+		regs = append(regs, blockRegs...)
+		err = dbPutNameRegistrations(dbTx, []byte(name), regs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbDisconnectNameRegistrations removes the name registrations made by the
+// passed block, which must have its height set, from the name registry.  They
+// are the most recent registrations of each name the block registers.
+func dbDisconnectNameRegistrations(dbTx database.Tx, block *btcutil.Block) error {
+	for name := range BlockNameRegistrations(block) {
+		regs, err := dbFetchNameRegistrations(dbTx, []byte(name))
+		if err != nil {
+			return err
+		}
+		for len(regs) > 0 && regs[len(regs)-1].Height >= block.Height() {
+			regs = regs[:len(regs)-1]
+		}
+		err = dbPutNameRegistrations(dbTx, []byte(name), regs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// namesActive returns whether the name registrations made by the block after
+// the passed node are enforced and recorded in the name registry, which is the
+// case once the DeploymentCTExtended deployment that defines OP_REGISTERNAME is
+// active.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) namesActive(prevNode *blockNode) (bool, error) {
+	state, err := b.deploymentState(prevNode, chaincfg.DeploymentCTExtended)
+	if err != nil {
+		return false, err
+	}
+	return state == ThresholdActive, nil
+}
+
+// createNameRegistry creates the name registry bucket in a database which
+// predates it and records the name registrations made by the blocks in the
+// main chain for which the DeploymentCTExtended deployment is active.  This
+// requires those blocks, so it fails when they have been pruned.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) createNameRegistry(interrupt <-chan struct{}) error {
+	log.Infof("Creating the name registry...")
+	return b.db.Update(func(dbTx database.Tx) error {
+		_, err := dbTx.Metadata().CreateBucket(nameRegistryBucketName)
+		if err != nil {
+			return err
+		}
+
+		tip := b.bestChain.Tip()
+		for height := int32(1); height <= tip.height; height++ {
+			if interruptRequested(interrupt) {
+				return errInterruptRequested
+			}
+
+			node := b.bestChain.NodeByHeight(height)
+			active, err := b.namesActive(node.parent)
+			if err != nil {
+				return err
+			}
+			if !active {
+				continue
+			}
+
+			block, err := dbFetchBlockByNode(dbTx, node)
+			if err != nil {
+				return fmt.Errorf("unable to load block %v to "+
+					"create the name registry: %v", node.hash,
+					err)
+			}
+			err = dbConnectNameRegistrations(dbTx, block)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FetchNameRegistrations returns the registrations of the passed name in the
+// main chain ordered by height and then by position within the block.  Nil is
+// returned for both the registrations and the error when the name has never
+// been registered.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) FetchNameRegistrations(name []byte) ([]NameRegistration, error) {
+	var regs []NameRegistration
+	err := b.db.View(func(dbTx database.Tx) error {
+		var err error
+		regs, err = dbFetchNameRegistrations(dbTx, name)
+		return err
+	})
+	return regs, err
+}
+
+// NameEntry describes a registered name and all of its registrations.
+type NameEntry struct {
+	Name          string
+	Registrations []NameRegistration
+}
+
+// ListNames returns up to limit registered names, in lexicographical order,
+// which sort after the passed name.  An empty after starts the listing from the
+// first name.  A limit of zero or less places no bound on the number of names
+// returned.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ListNames(after []byte, limit int) ([]NameEntry, error) {
+	var entries []NameEntry
+	err := b.db.View(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(nameRegistryBucketName)
+		cursor := bucket.Cursor()
+		ok := cursor.First()
+		if len(after) > 0 {
+			ok = cursor.Seek(after)
+		}
+		for ; ok; ok = cursor.Next() {
+			if limit > 0 && len(entries) >= limit {
+				break
+			}
+			if bytes.Equal(cursor.Key(), after) {
+				continue
+			}
+
+			regs, err := deserializeNameRegistrations(cursor.Value())
+			if err != nil {
+				return err
+			}
+			entries = append(entries, NameEntry{
+				Name:          string(cursor.Key()),
+				Registrations: regs,
+			})
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// NameExpiryHeight returns the height of the first block for which the passed
+// registration has expired given the number of blocks registrations remain
+// valid for as defined by the NameExpiryBlocks chain parameter.  Zero is
+// returned when registrations never expire.
+func NameExpiryHeight(reg *NameRegistration, expiryBlocks int32) int32 {
+	if expiryBlocks <= 0 {
+		return 0
+	}
+	return reg.Height + expiryBlocks
+}
+
+// nameExpired returns whether the passed registration has expired as of the
+// block at the passed height.
+func nameExpired(reg *NameRegistration, expiryBlocks, height int32) bool {
+	expiryHeight := NameExpiryHeight(reg, expiryBlocks)
+	return expiryHeight != 0 && height >= expiryHeight
+}
+
+// CurrentNameRegistration returns the registration which holds a name as of
+// the block at the passed height given the registrations of the name ordered
+// by height and then by position within the block and the number of blocks registrations remain
+// valid for.
+//
+// A registration of a name which is not registered, or whose registration has
+// expired, claims the name for its owner.  Subsequent registrations by the
+// owner before the name expires renew it, which requires spending the output
+// of the registration being renewed.  Nil is returned if the name is not
+// registered as of the passed height.
+func CurrentNameRegistration(regs []NameRegistration, expiryBlocks, height int32) *NameRegistration {
+	var current *NameRegistration
+	for i := range regs {
+		reg := &regs[i]
+		if current == nil ||
+			nameExpired(current, expiryBlocks, reg.Height) ||
+			bytes.Equal(reg.Owner, current.Owner) {
+
+			current = reg
+		}
+	}
+	if current == nil || nameExpired(current, expiryBlocks, height) {
+		return nil
+	}
+	return current
+}
+
+// BlockNameRegistrations returns the name registrations made by the outputs of
+// the passed block, keyed by name, ordered by their position within the
+// block.  Malformed registrations are ignored since they are rejected when
+// the block is validated.
+func BlockNameRegistrations(block *btcutil.Block) map[string][]NameRegistration {
+	var regs map[string][]NameRegistration
+	for _, tx := range block.Transactions() {
+		for i, txOut := range tx.MsgTx().TxOut {
+			name, owner, err := txscript.ExtractNameRegistration(
+				txOut.PkScript)
+			if err != nil || name == nil {
+				continue
+			}
+
+			if regs == nil {
+				regs = make(map[string][]NameRegistration)
+			}
+			regs[string(name)] = append(regs[string(name)],
+				NameRegistration{
+					Owner:    owner,
+					OutPoint: *wire.NewOutPoint(tx.Hash(), uint32(i)),
+					Height:   block.Height(),
+				})
+		}
+	}
+	return regs
+}
+
+// nameView houses name registrations made by blocks which have been validated
+// but are not yet reflected by the name registry, such as the blocks attached
+// earlier in a reorganization.  Only blocks for which names are active add
+// their registrations to the view.
+type nameView map[string][]NameRegistration
+
+// connectBlock adds the name registrations made by the passed block, which
+// must have its height set, to the view.
+func (view nameView) connectBlock(block *btcutil.Block) {
+	for name, regs := range BlockNameRegistrations(block) {
+		view[name] = append(view[name], regs...)
+	}
+}
+
+// checkNameRegistrations ensures the name registrations in the passed block
+// are well formed and, on the chain ending with the block's parent, either
+// register names which are not currently registered or renew names by spending
+// the current registration and registering them to the same key.  The
+// registrations of the block are added to the passed view, which must contain
+// the registrations of any blocks between the fork point with the main chain
+// and the block.
+//
+// The name registry reflects the current main chain, so registrations it
+// returns above the fork point belong to blocks which are about to be
+// disconnected and are ignored.
+//
+// This function MUST only be called when names are active for the block as
+// determined by namesActive.
+func (b *BlockChain) checkNameRegistrations(node *blockNode, block *btcutil.Block, view nameView) error {
+	forkHeight := b.bestChain.FindFork(node).height
+	expiryBlocks := b.chainParams.NameExpiryBlocks
+
+	return b.db.View(func(dbTx database.Tx) error {
+		for _, tx := range block.Transactions() {
+			for i, txOut := range tx.MsgTx().TxOut {
+				name, owner, err := txscript.ExtractNameRegistration(
+					txOut.PkScript)
+				if err != nil {
+					str := fmt.Sprintf("output %d of transaction "+
+						"%v has an invalid name registration: "+
+						"%v", i, tx.Hash(), err)
+					return ruleError(ErrBadNameRegistration, str)
+				}
+				if name == nil {
+					continue
+				}
+
+				regs, err := dbFetchNameRegistrations(dbTx,
+					name)
+				if err != nil {
+					return err
+				}
+				for len(regs) > 0 &&
+					regs[len(regs)-1].Height > forkHeight {
+
+					regs = regs[:len(regs)-1]
+				}
+				regs = append(regs, view[string(name)]...)
+
+				current := CurrentNameRegistration(regs,
+					expiryBlocks, node.height)
+				if current != nil && !bytes.Equal(current.Owner, owner) {
+					str := fmt.Sprintf("output %d of transaction "+
+						"%v registers name %q which is "+
+						"owned by %x", i, tx.Hash(), name,
+						current.Owner)
+					return ruleError(ErrNameOwnership, str)
+				}
+				if current != nil &&
+					!spendsOutPoint(tx, current.OutPoint) {
+
+					str := fmt.Sprintf("output %d of transaction "+
+						"%v renews name %q without spending "+
+						"its current registration %v", i,
+						tx.Hash(), name, current.OutPoint)
+					return ruleError(ErrNameRenewal, str)
+				}
+
+				view[string(name)] = append(view[string(name)],
+					NameRegistration{
+						Owner:    owner,
+						OutPoint: *wire.NewOutPoint(tx.Hash(), uint32(i)),
+						Height:   node.height,
+					})
+			}
+		}
+		return nil
+	})
+}
+
+// spendsOutPoint returns whether the passed transaction has an input which
+// spends the passed outpoint.
+func spendsOutPoint(tx *btcutil.Tx, outPoint wire.OutPoint) bool {
+	for _, txIn := range tx.MsgTx().TxIn {
+		if txIn.PreviousOutPoint == outPoint {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/jadeblaquiere/cttd/btcec"
+	"github.com/jadeblaquiere/cttd/chaincfg"
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+	"github.com/jadeblaquiere/cttd/database"
+	"github.com/jadeblaquiere/cttd/txscript"
+	"github.com/jadeblaquiere/cttd/wire"
+	"github.com/jadeblaquiere/cttutil"
+)
+
+// TestCurrentNameRegistration ensures the registration holding a name is
+// determined correctly as names are claimed, renewed and expire.
+func TestCurrentNameRegistration(t *testing.T) {
+	keyA := append([]byte{0x02}, bytes.Repeat([]byte{0x0a}, 32)...)
+	keyB := append([]byte{0x03}, bytes.Repeat([]byte{0x0b}, 32)...)
+	reg := func(owner []byte, height int32) NameRegistration {
+		return NameRegistration{
+			Owner:    owner,
+			OutPoint: wire.OutPoint{Index: uint32(height)},
+			Height:   height,
+		}
+	}
+
+	// Registrations where key A claims the name at height 100, renews it
+	// at height 105, lets it expire and key B claims it at height 120.
+	regs := []NameRegistration{
+		reg(keyA, 100),
+		reg(keyA, 105),
+		reg(keyB, 120),
+	}
+
+	tests := []struct {
+		name         string
+		regs         []NameRegistration
+		expiryBlocks int32
+		height       int32
+		want         int // index of the expected registration or -1
+	}{
+		{"never registered", nil, 10, 100, -1},
+		{"claimed", regs[:1], 10, 101, 0},
+		{"last block before expiry", regs[:1], 10, 109, 0},
+		{"expired", regs[:1], 10, 110, -1},
+		{"renewed", regs[:2], 10, 110, 1},
+		{"renewal expired", regs[:2], 10, 115, -1},
+		{"claimed after expiry", regs, 10, 121, 2},
+		{"claim expired", regs, 10, 130, -1},
+		{"no expiry", regs[:2], 0, 1000000, 1},
+	}
+
+	for _, test := range tests {
+		got := CurrentNameRegistration(test.regs, test.expiryBlocks,
+			test.height)
+		if test.want < 0 {
+			if got != nil {
+				t.Errorf("%s: unexpected registration %v",
+					test.name, got)
+			}
+			continue
+		}
+		want := test.regs[test.want]
+		if got == nil || got.OutPoint != want.OutPoint {
+			t.Errorf("%s: wrong registration - got %v, want %v",
+				test.name, got, want)
+		}
+	}
+}
+
+// TestNameRegistrationSerialization ensures name registrations round trip
+// through their serialized form and malformed entries are rejected.
+func TestNameRegistrationSerialization(t *testing.T) {
+	owner := append([]byte{0x02}, bytes.Repeat([]byte{0x11}, 32)...)
+	regs := []NameRegistration{
+		{
+			Owner: owner,
+			OutPoint: wire.OutPoint{
+				Hash:  chainhash.Hash{0x01, 0x02},
+				Index: 1,
+			},
+			Height: 100,
+		},
+		{
+			Owner: owner,
+			OutPoint: wire.OutPoint{
+				Hash:  chainhash.Hash{0x03},
+				Index: 0xffffffff,
+			},
+			Height: 2016,
+		},
+	}
+
+	serialized := serializeNameRegistrations(regs)
+	if len(serialized) != len(regs)*nameRegistrationSize {
+		t.Fatalf("serializeNameRegistrations: wrong length - got %d, "+
+			"want %d", len(serialized), len(regs)*nameRegistrationSize)
+	}
+
+	got, err := deserializeNameRegistrations(serialized)
+	if err != nil {
+		t.Fatalf("deserializeNameRegistrations: unexpected error %v",
+			err)
+	}
+	if !reflect.DeepEqual(got, regs) {
+		t.Fatalf("deserializeNameRegistrations: mismatched "+
+			"registrations - got %v, want %v", got, regs)
+	}
+
+	_, err = deserializeNameRegistrations(serialized[:len(serialized)-1])
+	if !isDeserializeErr(err) {
+		t.Fatalf("deserializeNameRegistrations: wrong error for "+
+			"truncated entry - got %v", err)
+	}
+}
+
+// TestNameRegistryConnectDisconnect ensures the name registry tracks the
+// registration holding a name as it is claimed, renewed, expires and is claimed
+// by another key, and restores it as the blocks are disconnected.
+func TestNameRegistryConnectDisconnect(t *testing.T) {
+	chain, teardownFunc, err := chainSetup("nameregistry",
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+
+	const expiryBlocks = 10
+	_, pubKeyA := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat(
+		[]byte{0x0a}, 32))
+	_, pubKeyB := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat(
+		[]byte{0x0b}, 32))
+	keyA := pubKeyA.SerializeCompressed()
+	keyB := pubKeyB.SerializeCompressed()
+
+	// newBlock returns a block at the passed height with a coinbase and a
+	// transaction spending the passed outpoint which registers alice to
+	// the passed key.
+	newBlock := func(height int32, spend wire.OutPoint, owner []byte) *btcutil.Block {
+		coinbase := wire.NewMsgTx(1)
+		coinbase.AddTxIn(&wire.TxIn{
+			SignatureScript: []byte{byte(height)},
+		})
+		coinbase.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_TRUE}))
+		pkScript, err := txscript.NewScriptBuilder().
+			AddData([]byte("alice")).AddData(owner).
+			AddOp(txscript.OP_REGISTERNAME).AddOp(txscript.OP_2DROP).
+			AddOp(txscript.OP_TRUE).Script()
+		if err != nil {
+			t.Fatalf("Script: unexpected error %v", err)
+		}
+		tx := wire.NewMsgTx(1)
+		tx.AddTxIn(&wire.TxIn{PreviousOutPoint: spend})
+		tx.AddTxOut(wire.NewTxOut(1000, pkScript))
+		msgBlock := wire.NewMsgBlock(&wire.BlockHeader{})
+		msgBlock.AddTransaction(coinbase)
+		msgBlock.AddTransaction(tx)
+		block := btcutil.NewBlock(msgBlock)
+		block.SetHeight(height)
+		return block
+	}
+	regOutPoint := func(block *btcutil.Block) wire.OutPoint {
+		return wire.OutPoint{Hash: *block.Transactions()[1].Hash()}
+	}
+
+	// Key A claims the name at height 1 and renews it at height 5 by
+	// spending the registration.  The renewal expires at height 15, after
+	// which key B claims the name at height 20.
+	claimBlock := newBlock(1, wire.OutPoint{Index: 7}, keyA)
+	renewBlock := newBlock(5, regOutPoint(claimBlock), keyA)
+	reclaimBlock := newBlock(20, wire.OutPoint{Index: 8}, keyB)
+
+	// checkName ensures the registration holding the name as of the block
+	// after the passed height is the one made by the passed block, or
+	// that the name is not registered when the block is nil.
+	checkName := func(desc string, height int32, want *btcutil.Block) {
+		regs, err := chain.FetchNameRegistrations([]byte("alice"))
+		if err != nil {
+			t.Fatalf("%s: FetchNameRegistrations: unexpected error %v",
+				desc, err)
+		}
+		got := CurrentNameRegistration(regs, expiryBlocks, height+1)
+		if want == nil {
+			if got != nil {
+				t.Fatalf("%s: unexpected registration %v", desc,
+					got)
+			}
+			return
+		}
+		if got == nil || got.OutPoint != regOutPoint(want) {
+			t.Fatalf("%s: wrong registration - got %v, want %v",
+				desc, got, regOutPoint(want))
+		}
+	}
+
+	steps := []struct {
+		desc    string
+		connect bool
+		block   *btcutil.Block
+		height  int32
+		want    *btcutil.Block
+	}{
+		{"connect claim", true, claimBlock, 1, claimBlock},
+		{"connect renewal", true, renewBlock, 5, renewBlock},
+		{"renewal expired", true, nil, 14, nil},
+		{"connect reclaim", true, reclaimBlock, 20, reclaimBlock},
+		{"disconnect reclaim", false, reclaimBlock, 19, nil},
+		{"disconnect renewal", false, renewBlock, 4, claimBlock},
+		{"disconnect claim", false, claimBlock, 0, nil},
+	}
+	for _, step := range steps {
+		if step.block != nil {
+			err := chain.db.Update(func(dbTx database.Tx) error {
+				if step.connect {
+					return dbConnectNameRegistrations(dbTx,
+						step.block)
+				}
+				return dbDisconnectNameRegistrations(dbTx,
+					step.block)
+			})
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", step.desc,
+					err)
+			}
+		}
+		checkName(step.desc, step.height, step.want)
+	}
+}
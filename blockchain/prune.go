@@ -0,0 +1,87 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+	"github.com/jadeblaquiere/cttd/database"
+)
+
+// MinBlocksToKeep is the number of blocks at the end of the main chain which
+// are never pruned.  Their spend journal entries are kept along with them, so
+// the chain can still be reorganized up to this depth when pruning.
+const MinBlocksToKeep = 288
+
+// pruneBlocks removes the oldest blocks from the database while the stored
+// blocks use more than the prune target, along with their spend journal
+// entries.  Only blocks buried by at least MinBlocksToKeep blocks which the utxo
+// set in the database already reflects are pruned, so the blocks replayed to
+// recover the utxo set after an unexpected shutdown are kept as well.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) pruneBlocks() error {
+	b.utxoCache.mtx.Lock()
+	flushedNode := b.index.LookupNode(&b.utxoCache.lastFlushHash)
+	b.utxoCache.mtx.Unlock()
+	if flushedNode == nil {
+		return nil
+	}
+	pruneHeight := b.bestChain.Tip().height - MinBlocksToKeep
+	if flushedNode.height < pruneHeight {
+		pruneHeight = flushedNode.height
+	}
+	if pruneHeight <= 0 {
+		return nil
+	}
+
+	var pruned []chainhash.Hash
+	err := b.db.Update(func(dbTx database.Tx) error {
+		var err error
+		pruned, err = dbTx.PruneBlocks(b.pruneTarget,
+			func(hash *chainhash.Hash) bool {
+				node := b.index.LookupNode(hash)
+				return node == nil || node.height <= pruneHeight
+			})
+		if err != nil {
+			return err
+		}
+
+		// The spend journal entries of the pruned blocks are no longer
+		// needed since the blocks can't be disconnected without them.
+		for i := range pruned {
+			err := dbRemoveSpendJournalEntry(dbTx, &pruned[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || len(pruned) == 0 {
+		return err
+	}
+
+	log.Debugf("Pruned %d blocks up to height %d", len(pruned), pruneHeight)
+	for i := range pruned {
+		if node := b.index.LookupNode(&pruned[i]); node != nil {
+			b.index.UnsetStatusFlags(node, statusDataStored)
+		}
+	}
+	return b.index.flushToDB()
+}
+
+// BeenPruned returns whether or not blocks have ever been pruned from the
+// database, in which case the full history of the chain is no longer
+// available.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) BeenPruned() (bool, error) {
+	var pruned bool
+	err := b.db.View(func(dbTx database.Tx) error {
+		var err error
+		pruned, err = dbTx.BeenPruned()
+		return err
+	})
+	return pruned, err
+}
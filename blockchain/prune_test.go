@@ -0,0 +1,173 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/chaincfg"
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+	"github.com/jadeblaquiere/cttd/database"
+	"github.com/jadeblaquiere/cttd/txscript"
+	"github.com/jadeblaquiere/cttd/wire"
+	"github.com/jadeblaquiere/cttutil"
+)
+
+// pruneTestDB wraps a database so the blocks the chain prunes are recorded
+// instead of removed, which allows testing which blocks are pruned without
+// filling up block files.
+type pruneTestDB struct {
+	database.DB
+	stored []chainhash.Hash
+	pruned int
+}
+
+// Update wraps the transactions of the underlying database.
+func (db *pruneTestDB) Update(fn func(dbTx database.Tx) error) error {
+	return db.DB.Update(func(dbTx database.Tx) error {
+		return fn(&pruneTestTx{Tx: dbTx, db: db})
+	})
+}
+
+// pruneTestTx is a transaction of a pruneTestDB.
+type pruneTestTx struct {
+	database.Tx
+	db *pruneTestDB
+}
+
+// PruneBlocks prunes the stored blocks in order until one of them must not be
+// pruned regardless of the target size.
+func (tx *pruneTestTx) PruneBlocks(targetSize uint64, canPrune func(hash *chainhash.Hash) bool) ([]chainhash.Hash, error) {
+	db := tx.db
+	start := db.pruned
+	for db.pruned < len(db.stored) && canPrune(&db.stored[db.pruned]) {
+		db.pruned++
+	}
+	return db.stored[start:db.pruned], nil
+}
+
+// nextTestBlock returns a solved block extending the end of the main chain
+// which only contains a coinbase paying to an OP_TRUE script.
+func nextTestBlock(t *testing.T, chain *BlockChain) *btcutil.Block {
+	tip := chain.bestChain.Tip()
+	height := tip.height + 1
+	coinbaseScript, err := txscript.NewScriptBuilder().
+		AddInt64(int64(height)).AddInt64(0).Script()
+	if err != nil {
+		t.Fatalf("unable to create coinbase script: %v", err)
+	}
+	coinbase := wire.NewMsgTx(1)
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&chainhash.Hash{},
+			wire.MaxPrevOutIndex),
+		Sequence:        wire.MaxTxInSequenceNum,
+		SignatureScript: coinbaseScript,
+	})
+	coinbase.AddTxOut(&wire.TxOut{
+		Value:    CalcBlockSubsidy(height, chain.chainParams),
+		PkScript: []byte{txscript.OP_TRUE},
+	})
+	merkles := BuildMerkleTreeStore([]*btcutil.Tx{btcutil.NewTx(coinbase)},
+		false)
+
+	block := wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:    1,
+			PrevBlock:  tip.hash,
+			MerkleRoot: *merkles[len(merkles)-1],
+			Bits:       chain.chainParams.PowLimitBits,
+			Timestamp:  time.Unix(tip.timestamp, 0).Add(time.Second),
+		},
+		Transactions: []*wire.MsgTx{coinbase},
+	}
+	target := CompactToBig(block.Header.Bits)
+	for {
+		hash := block.Header.BlockHash()
+		if HashToBig(&hash).Cmp(target) <= 0 {
+			break
+		}
+		block.Header.Nonce++
+	}
+	return btcutil.NewBlock(&block)
+}
+
+// TestPruneBlocks ensures only blocks which are buried deep enough and which
+// the utxo set in the database reflects are pruned along with their spend
+// journal entries.
+func TestPruneBlocks(t *testing.T) {
+	chain, teardownFunc, err := chainSetup("prunetest",
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+
+	// Hold the modifications of the utxo set in the cache so the utxo set
+	// in the database keeps reflecting the genesis block.
+	db := &pruneTestDB{
+		DB:     chain.db,
+		stored: []chainhash.Hash{*chain.chainParams.GenesisHash},
+	}
+	chain.db = db
+	chain.pruneTarget = 1
+	chain.utxoCache.maxTotalMemoryUsage = 1 << 30
+
+	processBlocks := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			block := nextTestBlock(t, chain)
+			_, _, err := chain.ProcessBlock(block, BFNone)
+			if err != nil {
+				t.Fatalf("ProcessBlock: unexpected error: %v", err)
+			}
+			db.stored = append(db.stored, *block.Hash())
+		}
+	}
+
+	// Blocks which are buried deep enough are kept until the utxo set in
+	// the database reflects them since they are needed to recover it
+	// otherwise.
+	processBlocks(MinBlocksToKeep + 10)
+	if db.pruned != 0 {
+		t.Fatalf("pruned %d blocks before the utxo cache was flushed",
+			db.pruned)
+	}
+
+	// Once the cache has been flushed, the blocks buried by at least
+	// MinBlocksToKeep blocks are pruned after the next block.
+	if err := chain.FlushUtxoCache(); err != nil {
+		t.Fatalf("FlushUtxoCache: unexpected error: %v", err)
+	}
+	processBlocks(1)
+	tip := chain.bestChain.Tip()
+	wantPruned := int(tip.height-MinBlocksToKeep) + 1
+	if db.pruned != wantPruned {
+		t.Fatalf("pruned %d blocks, want %d", db.pruned, wantPruned)
+	}
+
+	// The pruned blocks no longer have data or a spend journal entry while
+	// the remaining ones still do.
+	err = chain.db.View(func(dbTx database.Tx) error {
+		spendBucket := dbTx.Metadata().Bucket(spendJournalBucketName)
+		for i := 1; i < len(db.stored); i++ {
+			hash := &db.stored[i]
+			status := chain.index.NodeStatus(chain.index.LookupNode(hash))
+			havePruned := !status.HaveData()
+			haveJournal := spendBucket.Get(hash[:]) != nil
+			if wantPruned := i < db.pruned; havePruned != wantPruned ||
+				haveJournal == wantPruned {
+
+				t.Errorf("block %d: data pruned %v, spend journal "+
+					"entry %v, want pruned %v", i, havePruned,
+					haveJournal, wantPruned)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View: unexpected error: %v", err)
+	}
+}
@@ -302,6 +302,17 @@ func (b *BlockChain) deploymentState(prevNode *blockNode, deploymentID uint32) (
 	}
 
 	deployment := &b.chainParams.Deployments[deploymentID]
+
+	// Deployments with an activation height are not voted on and are
+	// active for every block from that height on.
+	if deployment.ActivationHeight > 0 {
+		activationHeight := deployment.ActivationHeight
+		if prevNode == nil || prevNode.height+1 < activationHeight {
+			return ThresholdDefined, nil
+		}
+		return ThresholdActive, nil
+	}
+
 	checker := deploymentChecker{deployment: deployment, chain: b}
 	cache := &b.deploymentCaches[deploymentID]
 
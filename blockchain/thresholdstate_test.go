@@ -7,6 +7,7 @@ package blockchain
 import (
 	"testing"
 
+	"github.com/jadeblaquiere/cttd/chaincfg"
 	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
 )
 
@@ -132,3 +133,38 @@ nextTest:
 		}
 	}
 }
+
+// TestDeploymentActivationHeight ensures deployments with an activation height
+// are active for every block from that height on and defined before it.
+func TestDeploymentActivationHeight(t *testing.T) {
+	t.Parallel()
+
+	params := chaincfg.RegressionNetParams
+	params.Deployments[chaincfg.DeploymentCTExtended].ActivationHeight = 5
+	chain := newFakeChain(&params)
+	nodes := chainedNodes(chain.bestChain.Genesis(), 6)
+
+	tests := []struct {
+		name     string
+		prevNode *blockNode
+		want     ThresholdState
+	}{
+		{"no parent", nil, ThresholdDefined},
+		{"genesis parent", chain.bestChain.Genesis(), ThresholdDefined},
+		{"last block before activation", nodes[2], ThresholdDefined},
+		{"activation block", nodes[3], ThresholdActive},
+		{"after activation", nodes[5], ThresholdActive},
+	}
+	for _, test := range tests {
+		state, err := chain.deploymentState(test.prevNode,
+			chaincfg.DeploymentCTExtended)
+		if err != nil {
+			t.Fatalf("%s: deploymentState: unexpected error %v",
+				test.name, err)
+		}
+		if state != test.want {
+			t.Errorf("%s: got state %v, want %v", test.name, state,
+				test.want)
+		}
+	}
+}
@@ -581,7 +581,11 @@ func upgradeUtxoSetToV2(db database.DB, interrupt <-chan struct{}) error {
 func (b *BlockChain) maybeUpgradeDbBuckets(interrupt <-chan struct{}) error {
 	// Load or create bucket versions as needed.
 	var utxoSetVersion uint32
+	var haveNameRegistry bool
 	err := b.db.Update(func(dbTx database.Tx) error {
+		haveNameRegistry = dbTx.Metadata().Bucket(
+			nameRegistryBucketName) != nil
+
 		// Load the utxo set version from the database or create it and
 		// initialize it to version 1 if it doesn't exist.
 		var err error
@@ -600,5 +604,12 @@ func (b *BlockChain) maybeUpgradeDbBuckets(interrupt <-chan struct{}) error {
 		}
 	}
 
+	// Create the name registry if the database predates it.
+	if !haveNameRegistry {
+		if err := b.createNameRegistry(interrupt); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
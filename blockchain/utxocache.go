@@ -0,0 +1,341 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+	"github.com/jadeblaquiere/cttd/database"
+	"github.com/jadeblaquiere/cttd/wire"
+	"github.com/jadeblaquiere/cttutil"
+)
+
+const (
+	// utxoFlushPeriodicInterval is the maximum amount of time the utxo
+	// cache holds modifications before they are written to the database.
+	utxoFlushPeriodicInterval = 5 * time.Minute
+
+	// cachedUtxoEntryOverhead is the approximate number of bytes used by
+	// each entry of the utxo cache excluding its public key script.  It
+	// accounts for the entry itself, the outpoint it is keyed by, and the
+	// bookkeeping of the map which holds it.
+	cachedUtxoEntryOverhead = 40 + chainhash.HashSize + 4 + 16
+)
+
+// cachedEntrySize returns the approximate number of bytes used by the passed
+// entry while it is held by the utxo cache.
+func cachedEntrySize(entry *UtxoEntry) uint64 {
+	return cachedUtxoEntryOverhead + uint64(len(entry.pkScript))
+}
+
+// utxoCache is a write-back cache which sits between the chain and the utxo
+// set in the database.  The modifications made by connected blocks are
+// committed to the cache and only written to the database when the cache is
+// flushed, which happens when it grows beyond its memory budget, periodically,
+// and on shutdown.
+//
+// Cached entries marked modified have not been written to the database yet.
+// Spent entries remain in the cache until the next flush removes them from the
+// database.
+//
+// Every flush records the hash of the block the utxo set in the database
+// reflects, so the modifications lost when the node does not shut down cleanly
+// are recovered by replaying the main chain blocks connected after that block.
+type utxoCache struct {
+	db                  database.DB
+	maxTotalMemoryUsage uint64
+
+	// The following fields are protected by the mutex since the cache is
+	// also populated by lookups which only hold the chain lock for reads.
+	mtx              sync.Mutex
+	entries          map[wire.OutPoint]*UtxoEntry
+	totalMemoryUsage uint64
+	lastFlushHash    chainhash.Hash
+	lastFlushTime    time.Time
+}
+
+// newUtxoCache returns an empty utxo cache for the utxo set in the passed
+// database which is flushed whenever its entries use more than the passed
+// number of bytes.  A budget of zero writes the modifications of every block.
+func newUtxoCache(db database.DB, maxTotalMemoryUsage uint64) *utxoCache {
+	return &utxoCache{
+		db:                  db,
+		maxTotalMemoryUsage: maxTotalMemoryUsage,
+		entries:             make(map[wire.OutPoint]*UtxoEntry),
+		lastFlushTime:       time.Now(),
+	}
+}
+
+// putEntry adds the passed entry to the cache, replacing any existing entry
+// for the outpoint.
+//
+// This function MUST be called with the cache lock held.
+func (c *utxoCache) putEntry(outpoint wire.OutPoint, entry *UtxoEntry) {
+	if cached, ok := c.entries[outpoint]; ok {
+		c.totalMemoryUsage -= cachedEntrySize(cached)
+	}
+	c.entries[outpoint] = entry
+	c.totalMemoryUsage += cachedEntrySize(entry)
+}
+
+// fetchEntries adds the requested outputs to the passed view.  Outputs which
+// are not cached are loaded from the database and added to the cache.  The
+// view receives copies of the cached entries so it is free to modify them, and
+// spent or missing outputs result in a nil entry in the view.
+func (c *utxoCache) fetchEntries(view *UtxoViewpoint, outpoints map[wire.OutPoint]struct{}) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var missing []wire.OutPoint
+	for outpoint := range outpoints {
+		cached, ok := c.entries[outpoint]
+		if !ok {
+			missing = append(missing, outpoint)
+			continue
+		}
+		if cached.IsSpent() {
+			view.entries[outpoint] = nil
+			continue
+		}
+
+		// Modifications which have not been written to the database
+		// yet are not modifications from the point of view of the view.
+		entry := cached.Clone()
+		entry.packedFlags &^= tfModified
+		view.entries[outpoint] = entry
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return c.db.View(func(dbTx database.Tx) error {
+		for _, outpoint := range missing {
+			entry, err := dbFetchUtxoEntry(dbTx, outpoint)
+			if err != nil {
+				return err
+			}
+			if entry != nil {
+				c.putEntry(outpoint, entry)
+				entry = entry.Clone()
+			}
+
+			view.entries[outpoint] = entry
+		}
+
+		return nil
+	})
+}
+
+// fetchEntry returns a copy of the requested output.  Both the entry and the
+// error are nil when the output is spent or does not exist.
+func (c *utxoCache) fetchEntry(outpoint wire.OutPoint) (*UtxoEntry, error) {
+	view := NewUtxoViewpoint()
+	err := c.fetchEntries(view, map[wire.OutPoint]struct{}{outpoint: {}})
+	if err != nil {
+		return nil, err
+	}
+	return view.entries[outpoint], nil
+}
+
+// commit merges the entries modified by the passed view into the cache where
+// they are held until the next flush writes them to the database.
+func (c *utxoCache) commit(view *UtxoViewpoint) {
+	c.mtx.Lock()
+	for outpoint, entry := range view.entries {
+		if entry == nil || !entry.isModified() {
+			continue
+		}
+
+		// The public key script of an output created by a block refers
+		// to the memory of the whole block, so it is copied in order to
+		// keep the block from being retained by the cache.  Spent
+		// entries only need to be kept until they are removed from the
+		// database, so they do not keep a script at all.
+		cached := &UtxoEntry{
+			amount:      entry.amount,
+			blockHeight: entry.blockHeight,
+			packedFlags: entry.packedFlags,
+		}
+		if !entry.IsSpent() {
+			cached.pkScript = make([]byte, len(entry.pkScript))
+			copy(cached.pkScript, entry.pkScript)
+		}
+		c.putEntry(outpoint, cached)
+	}
+	c.mtx.Unlock()
+}
+
+// evictView removes the entries modified by the passed view from the cache
+// after the view has been written to the database directly along with the
+// passed hash of the block the utxo set now reflects.
+//
+// The cache MUST have been flushed before the view was written since any
+// modifications it held for the entries would be lost otherwise.
+func (c *utxoCache) evictView(view *UtxoViewpoint, bestHash *chainhash.Hash) {
+	c.mtx.Lock()
+	for outpoint, entry := range view.entries {
+		if entry == nil || !entry.isModified() {
+			continue
+		}
+		if cached, ok := c.entries[outpoint]; ok {
+			c.totalMemoryUsage -= cachedEntrySize(cached)
+			delete(c.entries, outpoint)
+		}
+	}
+	c.lastFlushHash = *bestHash
+	c.mtx.Unlock()
+}
+
+// flush writes the modified entries to the database along with the passed hash
+// of the block the utxo set reflects once they are written.  The cache is
+// emptied when it has reached its memory budget, otherwise the unspent entries
+// are kept.  Nothing is written when the utxo set in the database already
+// reflects the block.
+func (c *utxoCache) flush(bestHash *chainhash.Hash) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// The cache only holds modifications when blocks were connected after
+	// the last flush.
+	if c.lastFlushHash == *bestHash {
+		return nil
+	}
+
+	err := c.db.Update(func(dbTx database.Tx) error {
+		if err := dbPutUtxoEntries(dbTx, c.entries); err != nil {
+			return err
+		}
+		return dbPutUtxoState(dbTx, bestHash)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Flushed %d utxo cache entries (%d bytes) at block %v",
+		len(c.entries), c.totalMemoryUsage, bestHash)
+
+	if c.totalMemoryUsage >= c.maxTotalMemoryUsage {
+		c.entries = make(map[wire.OutPoint]*UtxoEntry)
+		c.totalMemoryUsage = 0
+	} else {
+		for outpoint, entry := range c.entries {
+			if entry.IsSpent() {
+				c.totalMemoryUsage -= cachedEntrySize(entry)
+				delete(c.entries, outpoint)
+				continue
+			}
+
+			entry.packedFlags &^= tfModified
+		}
+	}
+	c.lastFlushHash = *bestHash
+	c.lastFlushTime = time.Now()
+	return nil
+}
+
+// maybeFlush flushes the cache when it has reached its memory budget or when
+// the periodic flush interval has passed since the last flush.
+func (c *utxoCache) maybeFlush(bestHash *chainhash.Hash) error {
+	c.mtx.Lock()
+	needFlush := c.totalMemoryUsage >= c.maxTotalMemoryUsage ||
+		time.Since(c.lastFlushTime) >= utxoFlushPeriodicInterval
+	c.mtx.Unlock()
+	if !needFlush {
+		return nil
+	}
+
+	return c.flush(bestHash)
+}
+
+// initUtxoState brings the utxo set in the database up to date with the best
+// chain.  The set falls behind when the node does not shut down cleanly, in
+// which case the main chain blocks connected after the last flush of the utxo
+// cache are replayed.
+func (b *BlockChain) initUtxoState(interrupt <-chan struct{}) error {
+	var stateHash *chainhash.Hash
+	err := b.db.View(func(dbTx database.Tx) error {
+		var err error
+		stateHash, err = dbFetchUtxoState(dbTx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The utxo set was written on every block before the utxo cache was
+	// introduced, so it reflects the best chain when there is no record.
+	tip := b.bestChain.Tip()
+	if stateHash == nil {
+		err := b.db.Update(func(dbTx database.Tx) error {
+			return dbPutUtxoState(dbTx, &tip.hash)
+		})
+		if err != nil {
+			return err
+		}
+		stateHash = &tip.hash
+	}
+
+	node := b.index.LookupNode(stateHash)
+	if node == nil || !b.bestChain.Contains(node) {
+		return AssertError(fmt.Sprintf("utxo set reflects block %v "+
+			"which is not in the main chain", stateHash))
+	}
+	b.utxoCache.lastFlushHash = node.hash
+	if node == tip {
+		return nil
+	}
+
+	log.Infof("Replaying %d blocks to bring the utxo set up to date "+
+		"(height %d to %d)", tip.height-node.height, node.height+1,
+		tip.height)
+	for n := b.bestChain.Next(node); n != nil; n = b.bestChain.Next(n) {
+		if interruptRequested(interrupt) {
+			return errInterruptRequested
+		}
+
+		var block *btcutil.Block
+		err := b.db.View(func(dbTx database.Tx) error {
+			var err error
+			block, err = dbFetchBlockByNode(dbTx, n)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		// The block was fully validated when it was connected, so only
+		// its effect on the utxo set is needed.
+		view := NewUtxoViewpoint()
+		view.SetBestHash(&n.parent.hash)
+		if err := view.fetchInputUtxos(b.utxoCache, block); err != nil {
+			return err
+		}
+		if err := view.connectTransactions(block, nil); err != nil {
+			return err
+		}
+		b.utxoCache.commit(view)
+
+		if err := b.utxoCache.maybeFlush(&n.hash); err != nil {
+			return err
+		}
+	}
+
+	return b.utxoCache.flush(&tip.hash)
+}
+
+// FlushUtxoCache writes the modifications held by the utxo cache to the
+// database.  It is intended to be called on shutdown so the utxo set does not
+// need to be recovered on the next start.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) FlushUtxoCache() error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	return b.utxoCache.flush(&b.bestChain.Tip().hash)
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/jadeblaquiere/cttd/chaincfg"
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+	"github.com/jadeblaquiere/cttd/database"
+	"github.com/jadeblaquiere/cttd/wire"
+	"github.com/jadeblaquiere/cttutil"
+)
+
+// dbUtxoSet returns the serialized utxo set and the utxo state stored in the
+// passed database.
+func dbUtxoSet(t *testing.T, db database.DB) (map[string]string, *chainhash.Hash) {
+	utxos := make(map[string]string)
+	var state *chainhash.Hash
+	err := db.View(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(utxoSetBucketName)
+		err := bucket.ForEach(func(k, v []byte) error {
+			utxos[string(k)] = string(v)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		state, err = dbFetchUtxoState(dbTx)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unable to load utxo set: %v", err)
+	}
+	return utxos, state
+}
+
+// TestUtxoCache ensures the utxo set written through the utxo cache matches the
+// one written on every block, including when the node does not shut down
+// cleanly and when blocks are disconnected while the cache holds
+// modifications.
+func TestUtxoCache(t *testing.T) {
+	// Load up blocks such that there is a side chain.
+	// (genesis block) -> 1 -> 2 -> 3 -> 4
+	//                          \-> 3a
+	testFiles := []string{
+		"blk_0_to_4.dat.bz2",
+		"blk_3A.dat.bz2",
+	}
+
+	var blocks []*btcutil.Block
+	for _, file := range testFiles {
+		blockTmp, err := loadBlocks(file)
+		if err != nil {
+			t.Fatalf("Error loading file: %v\n", err)
+		}
+		blocks = append(blocks, blockTmp...)
+	}
+	block3, block4 := blocks[3].Hash(), blocks[4].Hash()
+
+	// Record the utxo set of a reference chain which writes it on every
+	// block after connecting the blocks, invalidating block 3 so the side
+	// chain becomes the main chain, and reconsidering it again.
+	ref, teardownFunc, err := chainSetup("utxocacheref",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	ref.TstSetCoinbaseMaturity(1)
+	for i := 1; i < len(blocks); i++ {
+		_, _, err := ref.ProcessBlock(blocks[i], BFNone)
+		if err != nil {
+			t.Fatalf("ProcessBlock fail on block %v: %v\n", i, err)
+		}
+	}
+	connected, _ := dbUtxoSet(t, ref.db)
+	if err := ref.InvalidateBlock(block3); err != nil {
+		t.Fatalf("InvalidateBlock: unexpected error: %v", err)
+	}
+	invalidated, _ := dbUtxoSet(t, ref.db)
+	if err := ref.ReconsiderBlock(block3); err != nil {
+		t.Fatalf("ReconsiderBlock: unexpected error: %v", err)
+	}
+	teardownFunc()
+
+	// Create a chain which holds all of its modifications in the cache.
+	chain, teardownFunc, err := chainSetup("utxocache",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+	chain.utxoCache = newUtxoCache(chain.db, 1<<30)
+	chain.utxoCache.lastFlushHash = chain.bestChain.Tip().hash
+	chain.TstSetCoinbaseMaturity(1)
+	for i := 1; i < len(blocks); i++ {
+		_, _, err := chain.ProcessBlock(blocks[i], BFNone)
+		if err != nil {
+			t.Fatalf("ProcessBlock fail on block %v: %v\n", i, err)
+		}
+	}
+
+	// The outputs of the connected blocks are only available from the
+	// cache.
+	coinbase := wire.OutPoint{Hash: *blocks[4].Transactions()[0].Hash()}
+	entry, err := chain.FetchUtxoEntry(coinbase)
+	if err != nil || entry == nil || entry.BlockHeight() != 4 {
+		t.Fatalf("FetchUtxoEntry: wrong cached entry %v: %v", entry, err)
+	}
+	_, state := dbUtxoSet(t, chain.db)
+	if *state != *blocks[0].Hash() {
+		t.Fatalf("utxo set was flushed at %v before the cache was full",
+			state)
+	}
+
+	assertUtxoSet := func(name string, want map[string]string, wantState *chainhash.Hash) {
+		t.Helper()
+		got, state := dbUtxoSet(t, chain.db)
+		if *state != *wantState {
+			t.Fatalf("%s: utxo set reflects block %v, want %v", name,
+				state, wantState)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %d utxos, want %d", name, len(got),
+				len(want))
+		}
+		for k, v := range want {
+			if got[k] != v {
+				t.Fatalf("%s: mismatched utxo %x", name, k)
+			}
+		}
+	}
+
+	// Reloading the chain without flushing the cache, as happens when the
+	// node does not shut down cleanly, replays the blocks connected since
+	// the last flush.
+	chain, err = New(&Config{
+		DB:               chain.db,
+		ChainParams:      chain.chainParams,
+		TimeSource:       NewMedianTime(),
+		UtxoCacheMaxSize: 1 << 30,
+	})
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	assertUtxoSet("replay", connected, block4)
+
+	// Disconnecting blocks flushes the modifications held by the cache
+	// first and writes the utxo set directly, while connecting blocks
+	// leaves the modifications in the cache until it is flushed.
+	if err := chain.InvalidateBlock(block3); err != nil {
+		t.Fatalf("InvalidateBlock: unexpected error: %v", err)
+	}
+	_, state = dbUtxoSet(t, chain.db)
+	if *state != *blocks[2].Hash() {
+		t.Fatalf("invalidate: utxo set reflects block %v, want the "+
+			"fork point %v", state, blocks[2].Hash())
+	}
+	if err := chain.FlushUtxoCache(); err != nil {
+		t.Fatalf("FlushUtxoCache: unexpected error: %v", err)
+	}
+	assertUtxoSet("invalidate", invalidated, blocks[5].Hash())
+
+	if err := chain.ReconsiderBlock(block3); err != nil {
+		t.Fatalf("ReconsiderBlock: unexpected error: %v", err)
+	}
+	_, state = dbUtxoSet(t, chain.db)
+	if *state == *block4 {
+		t.Fatal("utxo set was flushed before the cache was full")
+	}
+	if err := chain.FlushUtxoCache(); err != nil {
+		t.Fatalf("FlushUtxoCache: unexpected error: %v", err)
+	}
+	assertUtxoSet("reconsider", connected, block4)
+}
@@ -864,7 +864,7 @@ func (b *BlockChain) checkBIP0030(node *blockNode, block *btcutil.Block, view *U
 			fetchSet[prevOut] = struct{}{}
 		}
 	}
-	err := view.fetchUtxos(b.db, fetchSet)
+	err := view.fetchUtxos(b.utxoCache, fetchSet)
 	if err != nil {
 		return err
 	}
@@ -996,6 +996,10 @@ func CheckTransactionInputs(tx *btcutil.Tx, txHeight int32, utxoView *UtxoViewpo
 // represent the state of the chain as if the block were actually connected and
 // consequently the best hash for the view is also updated to passed block.
 //
+// Once names are active, the names registered by the block are likewise added to the passed name view, which must hold the registrations
+// of any blocks being attached ahead of it in a reorganization.  It may be nil
+// when the block extends the main chain.
+//
 // An example of some of the checks performed are ensuring connecting the block
 // would not cause any duplicate transaction hashes for old transactions that
 // aren't already fully spent, double spends, exceeding the maximum allowed
@@ -1010,7 +1014,7 @@ func CheckTransactionInputs(tx *btcutil.Tx, txHeight int32, utxoView *UtxoViewpo
 // with that node.
 //
 // This function MUST be called with the chain state lock held (for writes).
-func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, view *UtxoViewpoint, stxos *[]SpentTxOut) error {
+func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, view *UtxoViewpoint, names nameView, stxos *[]SpentTxOut) error {
 	// If the side chain blocks end up in the database, a call to
 	// CheckBlockSanity should be done here in case a previous version
 	// allowed a block that is no longer valid.  However, since the
@@ -1060,7 +1064,7 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, vi
 	//
 	// These utxo entries are needed for verification of things such as
 	// transaction inputs, counting pay-to-script-hashes, and scripts.
-	err := view.fetchInputUtxos(b.db, block)
+	err := view.fetchInputUtxos(b.utxoCache, block)
 	if err != nil {
 		return err
 	}
@@ -1243,6 +1247,29 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, vi
 		scriptFlags |= txscript.ScriptStrictMultiSig
 	}
 
+	// Enforce the ciphrtxt extended opcodes once the deployment which
+	// defines them is active.
+	ctExtendedState, err := b.deploymentState(node.parent,
+		chaincfg.DeploymentCTExtended)
+	if err != nil {
+		return err
+	}
+	if ctExtendedState == ThresholdActive {
+		scriptFlags |= txscript.ScriptVerifyCTExtended
+	}
+
+	// Reject names registered to a key other than their current owner once
+	// the deployment which defines OP_REGISTERNAME is active.
+	if ctExtendedState == ThresholdActive {
+		if names == nil {
+			names = make(nameView)
+		}
+		err := b.checkNameRegistrations(node, block, names)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Now that the inexpensive checks are done and have passed, verify the
 	// transactions are actually allowed to spend the coins by running the
 	// expensive ECDSA signature check scripts.  Doing this last helps
@@ -1299,5 +1326,5 @@ func (b *BlockChain) CheckConnectBlockTemplate(block *btcutil.Block) error {
 	view := NewUtxoViewpoint()
 	view.SetBestHash(&tip.hash)
 	newNode := newBlockNode(&header, tip)
-	return b.checkConnectBlock(newNode, block, view, nil)
+	return b.checkConnectBlock(newNode, block, view, nil, nil)
 }
@@ -203,14 +203,12 @@ func (b *BlockChain) calcNextBlockVersion(prevNode *blockNode) (int32, error) {
 	// activation at the next threshold window change.
 	expectedVersion := uint32(vbTopBits)
 	for id := 0; id < len(b.chainParams.Deployments); id++ {
-		deployment := &b.chainParams.Deployments[id]
-		cache := &b.deploymentCaches[id]
-		checker := deploymentChecker{deployment: deployment, chain: b}
-		state, err := b.thresholdState(prevNode, checker, cache)
+		state, err := b.deploymentState(prevNode, uint32(id))
 		if err != nil {
 			return 0, err
 		}
 		if state == ThresholdStarted || state == ThresholdLockedIn {
+			deployment := &b.chainParams.Deployments[id]
 			expectedVersion |= uint32(1) << deployment.BitNumber
 		}
 	}
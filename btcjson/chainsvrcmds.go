@@ -388,6 +388,24 @@ func NewGetMempoolInfoCmd() *GetMempoolInfoCmd {
 	return &GetMempoolInfoCmd{}
 }
 
+// GetMessageInfoCmd defines the getmessageinfo JSON-RPC command.
+type GetMessageInfoCmd struct{}
+
+// NewGetMessageInfoCmd returns a new instance which can be used to issue a
+// getmessageinfo JSON-RPC command.
+func NewGetMessageInfoCmd() *GetMessageInfoCmd {
+	return &GetMessageInfoCmd{}
+}
+
+// GetMessageStoreInfoCmd defines the getmessagestoreinfo JSON-RPC command.
+type GetMessageStoreInfoCmd struct{}
+
+// NewGetMessageStoreInfoCmd returns a new instance which can be used to issue
+// a getmessagestoreinfo JSON-RPC command.
+func NewGetMessageStoreInfoCmd() *GetMessageStoreInfoCmd {
+	return &GetMessageStoreInfoCmd{}
+}
+
 // GetMiningInfoCmd defines the getmininginfo JSON-RPC command.
 type GetMiningInfoCmd struct{}
 
@@ -397,6 +415,32 @@ func NewGetMiningInfoCmd() *GetMiningInfoCmd {
 	return &GetMiningInfoCmd{}
 }
 
+// GetNAKCmd defines the getnak JSON-RPC command.
+type GetNAKCmd struct {
+	PubKey string
+}
+
+// NewGetNAKCmd returns a new instance which can be used to issue a getnak
+// JSON-RPC command.
+func NewGetNAKCmd(pubKey string) *GetNAKCmd {
+	return &GetNAKCmd{
+		PubKey: pubKey,
+	}
+}
+
+// GetNameCmd defines the getname JSON-RPC command.
+type GetNameCmd struct {
+	Name string
+}
+
+// NewGetNameCmd returns a new instance which can be used to issue a getname
+// JSON-RPC command.
+func NewGetNameCmd(name string) *GetNameCmd {
+	return &GetNameCmd{
+		Name: name,
+	}
+}
+
 // GetNetworkInfoCmd defines the getnetworkinfo JSON-RPC command.
 type GetNetworkInfoCmd struct{}
 
@@ -571,6 +615,42 @@ func NewInvalidateBlockCmd(blockHash string) *InvalidateBlockCmd {
 	}
 }
 
+// ListNAKsCmd defines the listnaks JSON-RPC command.
+type ListNAKsCmd struct {
+	After *string `jsonrpcdefault:"\"\""`
+	Count *int    `jsonrpcdefault:"1000"`
+}
+
+// NewListNAKsCmd returns a new instance which can be used to issue a listnaks
+// JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewListNAKsCmd(after *string, count *int) *ListNAKsCmd {
+	return &ListNAKsCmd{
+		After: after,
+		Count: count,
+	}
+}
+
+// ListNamesCmd defines the listnames JSON-RPC command.
+type ListNamesCmd struct {
+	After *string `jsonrpcdefault:"\"\""`
+	Count *int    `jsonrpcdefault:"1000"`
+}
+
+// NewListNamesCmd returns a new instance which can be used to issue a
+// listnames JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewListNamesCmd(after *string, count *int) *ListNamesCmd {
+	return &ListNamesCmd{
+		After: after,
+		Count: count,
+	}
+}
+
 // PingCmd defines the ping JSON-RPC command.
 type PingCmd struct{}
 
@@ -799,7 +879,11 @@ func init() {
 	MustRegisterCmd("getinfo", (*GetInfoCmd)(nil), flags)
 	MustRegisterCmd("getmempoolentry", (*GetMempoolEntryCmd)(nil), flags)
 	MustRegisterCmd("getmempoolinfo", (*GetMempoolInfoCmd)(nil), flags)
+	MustRegisterCmd("getmessageinfo", (*GetMessageInfoCmd)(nil), flags)
+	MustRegisterCmd("getmessagestoreinfo", (*GetMessageStoreInfoCmd)(nil), flags)
 	MustRegisterCmd("getmininginfo", (*GetMiningInfoCmd)(nil), flags)
+	MustRegisterCmd("getnak", (*GetNAKCmd)(nil), flags)
+	MustRegisterCmd("getname", (*GetNameCmd)(nil), flags)
 	MustRegisterCmd("getnetworkinfo", (*GetNetworkInfoCmd)(nil), flags)
 	MustRegisterCmd("getnettotals", (*GetNetTotalsCmd)(nil), flags)
 	MustRegisterCmd("getnetworkhashps", (*GetNetworkHashPSCmd)(nil), flags)
@@ -812,6 +896,8 @@ func init() {
 	MustRegisterCmd("getwork", (*GetWorkCmd)(nil), flags)
 	MustRegisterCmd("help", (*HelpCmd)(nil), flags)
 	MustRegisterCmd("invalidateblock", (*InvalidateBlockCmd)(nil), flags)
+	MustRegisterCmd("listnaks", (*ListNAKsCmd)(nil), flags)
+	MustRegisterCmd("listnames", (*ListNamesCmd)(nil), flags)
 	MustRegisterCmd("ping", (*PingCmd)(nil), flags)
 	MustRegisterCmd("preciousblock", (*PreciousBlockCmd)(nil), flags)
 	MustRegisterCmd("reconsiderblock", (*ReconsiderBlockCmd)(nil), flags)
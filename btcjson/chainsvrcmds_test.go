@@ -440,6 +440,28 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getmempoolinfo","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetMempoolInfoCmd{},
 		},
+		{
+			name: "getmessageinfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmessageinfo")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMessageInfoCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getmessageinfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetMessageInfoCmd{},
+		},
+		{
+			name: "getmessagestoreinfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmessagestoreinfo")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMessageStoreInfoCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getmessagestoreinfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetMessageStoreInfoCmd{},
+		},
 		{
 			name: "getmininginfo",
 			newCmd: func() (interface{}, error) {
@@ -451,6 +473,32 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getmininginfo","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetMiningInfoCmd{},
 		},
+		{
+			name: "getnak",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getnak", "02abcd")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetNAKCmd("02abcd")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getnak","params":["02abcd"],"id":1}`,
+			unmarshalled: &btcjson.GetNAKCmd{
+				PubKey: "02abcd",
+			},
+		},
+		{
+			name: "getname",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getname", "alice")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetNameCmd("alice")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getname","params":["alice"],"id":1}`,
+			unmarshalled: &btcjson.GetNameCmd{
+				Name: "alice",
+			},
+		},
 		{
 			name: "getnetworkinfo",
 			newCmd: func() (interface{}, error) {
@@ -716,6 +764,64 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "123",
 			},
 		},
+		{
+			name: "listnaks",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("listnaks")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewListNAKsCmd(nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listnaks","params":[],"id":1}`,
+			unmarshalled: &btcjson.ListNAKsCmd{
+				After: btcjson.String(""),
+				Count: btcjson.Int(1000),
+			},
+		},
+		{
+			name: "listnaks optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("listnaks", "02abcd", 10)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewListNAKsCmd(btcjson.String("02abcd"),
+					btcjson.Int(10))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listnaks","params":["02abcd",10],"id":1}`,
+			unmarshalled: &btcjson.ListNAKsCmd{
+				After: btcjson.String("02abcd"),
+				Count: btcjson.Int(10),
+			},
+		},
+		{
+			name: "listnames",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("listnames")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewListNamesCmd(nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listnames","params":[],"id":1}`,
+			unmarshalled: &btcjson.ListNamesCmd{
+				After: btcjson.String(""),
+				Count: btcjson.Int(1000),
+			},
+		},
+		{
+			name: "listnames optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("listnames", "alice", 10)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewListNamesCmd(btcjson.String("alice"),
+					btcjson.Int(10))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listnames","params":["alice",10],"id":1}`,
+			unmarshalled: &btcjson.ListNamesCmd{
+				After: btcjson.String("alice"),
+				Count: btcjson.Int(10),
+			},
+		},
 		{
 			name: "ping",
 			newCmd: func() (interface{}, error) {
@@ -56,11 +56,16 @@ type CreateMultiSigResult struct {
 
 // DecodeScriptResult models the data returned from the decodescript command.
 type DecodeScriptResult struct {
-	Asm       string   `json:"asm"`
-	ReqSigs   int32    `json:"reqSigs,omitempty"`
-	Type      string   `json:"type"`
-	Addresses []string `json:"addresses,omitempty"`
-	P2sh      string   `json:"p2sh,omitempty"`
+	Asm          string   `json:"asm"`
+	ReqSigs      int32    `json:"reqSigs,omitempty"`
+	Type         string   `json:"type"`
+	Addresses    []string `json:"addresses,omitempty"`
+	P2sh         string   `json:"p2sh,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	NameOwner    string   `json:"nameowner,omitempty"`
+	NAKPubKey    string   `json:"nakpubkey,omitempty"`
+	NAKNotBefore int64    `json:"naknotbefore,omitempty"`
+	NAKNotAfter  int64    `json:"naknotafter,omitempty"`
 }
 
 // GetAddedNodeInfoResultAddr models the data of the addresses portion of the
@@ -173,6 +178,15 @@ type GetBlockTemplateResult struct {
 	RejectReasion string   `json:"reject-reason,omitempty"`
 }
 
+// GetChainTipsResult models the data returned from the getchaintips command
+// for each known chain tip.
+type GetChainTipsResult struct {
+	Height    int32  `json:"height"`
+	Hash      string `json:"hash"`
+	BranchLen int32  `json:"branchlen"`
+	Status    string `json:"status"`
+}
+
 // GetMempoolEntryResult models the data returned from the getmempoolentry
 // command.
 type GetMempoolEntryResult struct {
@@ -199,6 +213,47 @@ type GetMempoolInfoResult struct {
 	Bytes int64 `json:"bytes"`
 }
 
+// NAKRegistrationResult models a single registration of a network access key
+// as returned by the getnak and listnaks commands.  Status is one of pending,
+// active, expired or revoked.  RevokedHeight is only set for revoked
+// registrations.
+type NAKRegistrationResult struct {
+	NAK           string `json:"nak"`
+	TxID          string `json:"txid"`
+	Vout          uint32 `json:"vout"`
+	Height        int32  `json:"height"`
+	NotBefore     int64  `json:"notbefore"`
+	NotAfter      int64  `json:"notafter"`
+	Status        string `json:"status"`
+	RevokedHeight int32  `json:"revokedheight,omitempty"`
+}
+
+// NAKResult models the data returned from the getnak command and the entries
+// returned from the listnaks command.
+type NAKResult struct {
+	PubKey        string                  `json:"pubkey"`
+	Active        bool                    `json:"active"`
+	Registrations []NAKRegistrationResult `json:"registrations"`
+}
+
+// NameRegistrationResult models a single registration of a name as returned
+// by the getname and listnames commands.
+type NameRegistrationResult struct {
+	Owner  string `json:"owner"`
+	TxID   string `json:"txid"`
+	Vout   uint32 `json:"vout"`
+	Height int32  `json:"height"`
+}
+
+// NameResult models the data returned from the getname command and the
+// entries returned from the listnames command.
+type NameResult struct {
+	Name          string                   `json:"name"`
+	Owner         string                   `json:"owner"`
+	ExpiryHeight  int32                    `json:"expiryheight,omitempty"`
+	Registrations []NameRegistrationResult `json:"registrations"`
+}
+
 // NetworksResult models the networks data from the getnetworkinfo command.
 type NetworksResult struct {
 	Name                      string `json:"name"`
@@ -548,3 +603,50 @@ type ValidateAddressChainResult struct {
 	IsValid bool   `json:"isvalid"`
 	Address string `json:"address,omitempty"`
 }
+
+// GetMessageInfoResult models the data returned from the getmessageinfo
+// command.  Rejected counts the messages which violated each message
+// acceptance rule keyed by the name of the rule.
+type GetMessageInfoResult struct {
+	Since          int64             `json:"since"`
+	Accepted       uint64            `json:"accepted"`
+	Duplicates     uint64            `json:"duplicates"`
+	Invalid        uint64            `json:"invalid"`
+	TotalRejected  uint64            `json:"totalrejected"`
+	Rejected       map[string]uint64 `json:"rejected"`
+	RecentAccepted uint64            `json:"recentaccepted"`
+	RateWindow     int64             `json:"ratewindow"`
+	IngestRate     float64           `json:"ingestrate"`
+}
+
+// GetMessageStoreInfoResult models the data returned from the
+// getmessagestoreinfo command.  Oldest and Newest are omitted when the
+// message store is empty.
+type GetMessageStoreInfoResult struct {
+	Messages       int    `json:"messages"`
+	Bytes          int64  `json:"bytes"`
+	Oldest         int64  `json:"oldest,omitempty"`
+	Newest         int64  `json:"newest,omitempty"`
+	MaxMessageSize uint32 `json:"maxmsgsize"`
+	MinExpire      int64  `json:"minexpire"`
+	MaxExpire      int64  `json:"maxexpire"`
+	PowBits        uint8  `json:"powbits"`
+	Retention      int64  `json:"retention"`
+	MaxStoreSize   int64  `json:"maxstoresize"`
+}
+
+// MessageHeaderResult models the parsed header of a ciphrtxt message.  The
+// points and nonce are hex encoded.
+type MessageHeaderResult struct {
+	Hash       string `json:"hash"`
+	Version    uint16 `json:"version"`
+	Size       uint32 `json:"size"`
+	Time       int64  `json:"time"`
+	TimeUsec   int64  `json:"timeusec"`
+	Expire     int64  `json:"expire"`
+	ExpireTime int64  `json:"expiretime"`
+	I          string `json:"i"`
+	J          string `json:"j"`
+	K          string `json:"k"`
+	Nonce      string `json:"nonce"`
+}
@@ -57,6 +57,32 @@ func NewNotifyNewTransactionsCmd(verbose *bool) *NotifyNewTransactionsCmd {
 	}
 }
 
+// NotifyNewMessagesCmd defines the notifynewmessages JSON-RPC command.
+type NotifyNewMessagesCmd struct {
+	Tags *[]string
+}
+
+// NewNotifyNewMessagesCmd returns a new instance which can be used to issue
+// a notifynewmessages JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewNotifyNewMessagesCmd(tags *[]string) *NotifyNewMessagesCmd {
+	return &NotifyNewMessagesCmd{
+		Tags: tags,
+	}
+}
+
+// StopNotifyNewMessagesCmd defines the stopnotifynewmessages JSON-RPC
+// command.
+type StopNotifyNewMessagesCmd struct{}
+
+// NewStopNotifyNewMessagesCmd returns a new instance which can be used to
+// issue a stopnotifynewmessages JSON-RPC command.
+func NewStopNotifyNewMessagesCmd() *StopNotifyNewMessagesCmd {
+	return &StopNotifyNewMessagesCmd{}
+}
+
 // SessionCmd defines the session JSON-RPC command.
 type SessionCmd struct{}
 
@@ -228,11 +254,13 @@ func init() {
 	MustRegisterCmd("authenticate", (*AuthenticateCmd)(nil), flags)
 	MustRegisterCmd("loadtxfilter", (*LoadTxFilterCmd)(nil), flags)
 	MustRegisterCmd("notifyblocks", (*NotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("notifynewmessages", (*NotifyNewMessagesCmd)(nil), flags)
 	MustRegisterCmd("notifynewtransactions", (*NotifyNewTransactionsCmd)(nil), flags)
 	MustRegisterCmd("notifyreceived", (*NotifyReceivedCmd)(nil), flags)
 	MustRegisterCmd("notifyspent", (*NotifySpentCmd)(nil), flags)
 	MustRegisterCmd("session", (*SessionCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyblocks", (*StopNotifyBlocksCmd)(nil), flags)
+	MustRegisterCmd("stopnotifynewmessages", (*StopNotifyNewMessagesCmd)(nil), flags)
 	MustRegisterCmd("stopnotifynewtransactions", (*StopNotifyNewTransactionsCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyspent", (*StopNotifySpentCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyreceived", (*StopNotifyReceivedCmd)(nil), flags)
@@ -89,6 +89,43 @@ func TestChainSvrWsCmds(t *testing.T) {
 				Verbose: btcjson.Bool(true),
 			},
 		},
+		{
+			name: "notifynewmessages",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifynewmessages")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyNewMessagesCmd(nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"notifynewmessages","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyNewMessagesCmd{
+				Tags: nil,
+			},
+		},
+		{
+			name: "notifynewmessages optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifynewmessages", []string{"02ab", "03"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyNewMessagesCmd(&[]string{"02ab", "03"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"notifynewmessages","params":[["02ab","03"]],"id":1}`,
+			unmarshalled: &btcjson.NotifyNewMessagesCmd{
+				Tags: &[]string{"02ab", "03"},
+			},
+		},
+		{
+			name: "stopnotifynewmessages",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifynewmessages")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyNewMessagesCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifynewmessages","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyNewMessagesCmd{},
+		},
 		{
 			name: "stopnotifynewtransactions",
 			newCmd: func() (interface{}, error) {
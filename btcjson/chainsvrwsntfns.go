@@ -75,6 +75,11 @@ const (
 	// from the chain server that inform a client that a transaction that
 	// matches the loaded filter was accepted by the mempool.
 	RelevantTxAcceptedNtfnMethod = "relevanttxaccepted"
+
+	// MessageAcceptedNtfnMethod is the method used for notifications from
+	// the chain server that a ciphrtxt message has been accepted into the
+	// message store.
+	MessageAcceptedNtfnMethod = "messageaccepted"
 )
 
 // BlockConnectedNtfn defines the blockconnected JSON-RPC notification.
@@ -285,6 +290,19 @@ func NewRelevantTxAcceptedNtfn(txHex string) *RelevantTxAcceptedNtfn {
 	return &RelevantTxAcceptedNtfn{Transaction: txHex}
 }
 
+// MessageAcceptedNtfn defines the messageaccepted JSON-RPC notification.
+type MessageAcceptedNtfn struct {
+	Header MessageHeaderResult
+}
+
+// NewMessageAcceptedNtfn returns a new instance which can be used to issue a
+// messageaccepted JSON-RPC notification.
+func NewMessageAcceptedNtfn(header MessageHeaderResult) *MessageAcceptedNtfn {
+	return &MessageAcceptedNtfn{
+		Header: header,
+	}
+}
+
 func init() {
 	// The commands in this file are only usable by websockets and are
 	// notifications.
@@ -301,4 +319,5 @@ func init() {
 	MustRegisterCmd(TxAcceptedNtfnMethod, (*TxAcceptedNtfn)(nil), flags)
 	MustRegisterCmd(TxAcceptedVerboseNtfnMethod, (*TxAcceptedVerboseNtfn)(nil), flags)
 	MustRegisterCmd(RelevantTxAcceptedNtfnMethod, (*RelevantTxAcceptedNtfn)(nil), flags)
+	MustRegisterCmd(MessageAcceptedNtfnMethod, (*MessageAcceptedNtfn)(nil), flags)
 }
@@ -225,6 +225,44 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Transaction: "001122",
 			},
 		},
+		{
+			name: "messageaccepted",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("messageaccepted", `{"hash":"123","version":512,"size":256,"time":1500000000,"timeusec":1500000000000000,"expire":3600,"expiretime":1500003600,"i":"02aa","j":"02bb","k":"02cc","nonce":"0000000000000001"}`)
+			},
+			staticNtfn: func() interface{} {
+				header := btcjson.MessageHeaderResult{
+					Hash:       "123",
+					Version:    512,
+					Size:       256,
+					Time:       1500000000,
+					TimeUsec:   1500000000000000,
+					Expire:     3600,
+					ExpireTime: 1500003600,
+					I:          "02aa",
+					J:          "02bb",
+					K:          "02cc",
+					Nonce:      "0000000000000001",
+				}
+				return btcjson.NewMessageAcceptedNtfn(header)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"messageaccepted","params":[{"hash":"123","version":512,"size":256,"time":1500000000,"timeusec":1500000000000000,"expire":3600,"expiretime":1500003600,"i":"02aa","j":"02bb","k":"02cc","nonce":"0000000000000001"}],"id":null}`,
+			unmarshalled: &btcjson.MessageAcceptedNtfn{
+				Header: btcjson.MessageHeaderResult{
+					Hash:       "123",
+					Version:    512,
+					Size:       256,
+					Time:       1500000000,
+					TimeUsec:   1500000000000000,
+					Expire:     3600,
+					ExpireTime: 1500003600,
+					I:          "02aa",
+					J:          "02bb",
+					K:          "02cc",
+					Nonce:      "0000000000000001",
+				},
+			},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
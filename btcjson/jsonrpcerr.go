@@ -72,6 +72,9 @@ const (
 	ErrRPCOutOfRange        RPCErrorCode = -1
 	ErrRPCNoTxInfo          RPCErrorCode = -5
 	ErrRPCNoCFIndex         RPCErrorCode = -5
+	ErrRPCNameNotFound      RPCErrorCode = -5
+	ErrRPCNoNAKIndex        RPCErrorCode = -5
+	ErrRPCNAKNotFound       RPCErrorCode = -5
 	ErrRPCNoNewestBlockInfo RPCErrorCode = -5
 	ErrRPCInvalidTxVout     RPCErrorCode = -5
 	ErrRPCRawTxString       RPCErrorCode = -32602
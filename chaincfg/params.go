@@ -6,6 +6,7 @@ package chaincfg
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"strings"
@@ -80,6 +81,11 @@ type ConsensusDeployment struct {
 	// ExpireTime is the median block time after which the attempted
 	// deployment expires.
 	ExpireTime uint64
+
+	// ActivationHeight, when greater than zero, is the height of the first
+	// block the deployment is active for.  Such deployments are not voted
+	// on, so BitNumber, StartTime and ExpireTime are ignored.
+	ActivationHeight int32
 }
 
 // Constants that define the deployment offset in the deployments field of the
@@ -100,6 +106,11 @@ const (
 	// includes the deployment of BIPS 141, 142, 144, 145, 147 and 173.
 	DeploymentSegwit
 
+	// DeploymentCTExtended defines the rule change deployment ID for the
+	// ciphrtxt extended opcodes OP_REGISTERNAK and OP_REGISTERNAME, which
+	// redefine OP_NOP7 and OP_NOP8.
+	DeploymentCTExtended
+
 	// NOTE: DefinedDeployments must always come last since it is used to
 	// determine how many defined deployments there currently are.
 
@@ -107,6 +118,37 @@ const (
 	DefinedDeployments
 )
 
+// DifficultyAlgorithm identifies the algorithm used to calculate the required
+// difficulty of each block.
+type DifficultyAlgorithm uint8
+
+const (
+	// DiffAlgoBitcoin retargets the difficulty once every TargetTimespan
+	// worth of blocks, limiting each adjustment by RetargetAdjustmentFactor.
+	// This is the algorithm used by Bitcoin and the default.
+	DiffAlgoBitcoin DifficultyAlgorithm = iota
+
+	// DiffAlgoLWMA adjusts the difficulty of every block using a linearly
+	// weighted moving average of the solve times of the previous
+	// DifficultyWindow blocks, so more recent blocks count for more.
+	DiffAlgoLWMA
+)
+
+// diffAlgoStrings is a map of difficulty algorithms back to their constant
+// names for pretty printing.
+var diffAlgoStrings = map[DifficultyAlgorithm]string{
+	DiffAlgoBitcoin: "DiffAlgoBitcoin",
+	DiffAlgoLWMA:    "DiffAlgoLWMA",
+}
+
+// String returns the DifficultyAlgorithm as a human-readable name.
+func (a DifficultyAlgorithm) String() string {
+	if s, ok := diffAlgoStrings[a]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown DifficultyAlgorithm (%d)", uint8(a))
+}
+
 // Params defines a Bitcoin network by its parameters.  These parameters may be
 // used by Bitcoin applications to differentiate networks as well as addresses
 // and keys for one network from those intended for use on another network.
@@ -152,6 +194,23 @@ type Params struct {
 	// is reduced.
 	SubsidyReductionInterval int32
 
+	// DifficultyAlgorithm is the algorithm used to calculate the required
+	// difficulty of each block from DifficultyAlgorithmHeight on.
+	DifficultyAlgorithm DifficultyAlgorithm
+
+	// DifficultyAlgorithmHeight is the height of the first block whose
+	// required difficulty is calculated with DifficultyAlgorithm.  The
+	// difficulty of the blocks before it is calculated with
+	// DiffAlgoBitcoin, which allows switching the algorithm of an existing
+	// chain.  Zero uses DifficultyAlgorithm from the genesis block on.
+	DifficultyAlgorithmHeight int32
+
+	// DifficultyWindow is the number of previous blocks considered when
+	// calculating the required difficulty of a block.
+	//
+	// NOTE: This only applies to DiffAlgoLWMA.
+	DifficultyWindow int32
+
 	// TargetTimespan is the desired amount of time that should elapse
 	// before the block difficulty requirement is examined to determine how
 	// it should be changed in order to maintain the desired block
@@ -182,6 +241,20 @@ type Params struct {
 	// GenerateSupported specifies whether or not CPU mining is allowed.
 	GenerateSupported bool
 
+	// CtMsgPowBits is the number of leading zero bits required in the
+	// double SHA-256 hash of a ciphrtxt message header, which commits to
+	// the ciphertext, before the message is accepted into the message
+	// store.  A value of zero disables the
+	// proof of work requirement.
+	CtMsgPowBits uint8
+
+	// NameExpiryBlocks is the number of blocks a name registered with
+	// OP_REGISTERNAME remains registered for.  Once it expires, the name
+	// may be registered by any key.  The owner may renew the name before
+	// it expires by spending the registering output in a transaction which
+	// registers the name again.  A value of zero disables expiry.
+	NameExpiryBlocks int32
+
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints []Checkpoint
 
@@ -255,6 +328,8 @@ var MainNetParams = Params{
 	ReduceMinDifficulty:      false,
 	MinDiffReductionTime:     0,
 	GenerateSupported:        false,
+	CtMsgPowBits:             0,
+	NameExpiryBlocks:         52560, // ~1 year
 
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: []Checkpoint{
@@ -300,6 +375,11 @@ var MainNetParams = Params{
 			StartTime:  1479168000, // November 15, 2016 UTC
 			ExpireTime: 1510704000, // November 15, 2017 UTC.
 		},
+		DeploymentCTExtended: {
+			BitNumber:  2,
+			StartTime:  math.MaxInt64, // Never available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
 	},
 
 	// Mempool parameters
@@ -350,6 +430,8 @@ var RegressionNetParams = Params{
 	ReduceMinDifficulty:      true,
 	MinDiffReductionTime:     time.Minute * 20, // TargetTimePerBlock * 2
 	GenerateSupported:        true,
+	CtMsgPowBits:             0,
+	NameExpiryBlocks:         20, // Short so expiry can be exercised by tests
 
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: nil,
@@ -376,6 +458,9 @@ var RegressionNetParams = Params{
 			StartTime:  0,             // Always available for vote
 			ExpireTime: math.MaxInt64, // Never expires.
 		},
+		DeploymentCTExtended: {
+			ActivationHeight: 1, // Always active on regtest
+		},
 	},
 
 	// Mempool parameters
@@ -429,6 +514,8 @@ var TestNet3Params = Params{
 	ReduceMinDifficulty:      true,
 	MinDiffReductionTime:     time.Minute * 20, // TargetTimePerBlock * 2
 	GenerateSupported:        false,
+	CtMsgPowBits:             0,
+	NameExpiryBlocks:         52560, // ~1 year
 
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: []Checkpoint{
@@ -467,6 +554,11 @@ var TestNet3Params = Params{
 			StartTime:  1462060800, // May 1, 2016 UTC
 			ExpireTime: 1493596800, // May 1, 2017 UTC.
 		},
+		DeploymentCTExtended: {
+			BitNumber:  2,
+			StartTime:  math.MaxInt64, // Never available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
 	},
 
 	// Mempool parameters
@@ -521,6 +613,8 @@ var SimNetParams = Params{
 	ReduceMinDifficulty:      true,
 	MinDiffReductionTime:     time.Minute * 20, // TargetTimePerBlock * 2
 	GenerateSupported:        true,
+	CtMsgPowBits:             0,
+	NameExpiryBlocks:         1008, // ~1 week
 
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: nil,
@@ -547,6 +641,11 @@ var SimNetParams = Params{
 			StartTime:  0,             // Always available for vote
 			ExpireTime: math.MaxInt64, // Never expires.
 		},
+		DeploymentCTExtended: {
+			BitNumber:  2,
+			StartTime:  0,             // Always available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
 	},
 
 	// Mempool parameters
@@ -589,13 +688,23 @@ var CtBlueNetParams = Params{
 	BIP0065Height:            0, // Always active on ctbluenet
 	BIP0066Height:            0, // Always active on ctbluenet
 	CoinbaseMaturity:         100,
-	SubsidyReductionInterval: -10800, // 1 min blocks - 1 week = 60*24*7
-	TargetTimespan:           time.Hour * 2,       // 2 hours
-	TargetTimePerBlock:       time.Minute * 1,     // 1 minute
-	RetargetAdjustmentFactor: 4,                   // 25% less, 400% more
+	SubsidyReductionInterval: -10800,          // 1 min blocks - 1 week = 60*24*7
+	DifficultyWindow:         60,              // 1 hour
+	TargetTimespan:           time.Hour * 2,   // 2 hours
+	TargetTimePerBlock:       time.Minute * 1, // 1 minute
+	RetargetAdjustmentFactor: 4,               // 25% less, 400% more
 	ReduceMinDifficulty:      false,
 	MinDiffReductionTime:     0,
 	GenerateSupported:        true,
+	CtMsgPowBits:             20,     // ~1M hashes per message
+	NameExpiryBlocks:         525600, // ~1 year
+
+	// The difficulty is retargeted with DiffAlgoBitcoin until a height to
+	// switch to DiffAlgoLWMA is agreed with the ctbluenet miners.  It must
+	// be above the chain tip when the release defining it is deployed,
+	// with enough margin for nodes to upgrade, so the existing blocks keep
+	// validating under the rules they were mined with.
+	DifficultyAlgorithm: DiffAlgoBitcoin,
 
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: nil,
@@ -622,6 +731,17 @@ var CtBlueNetParams = Params{
 			StartTime:  0,             // Always available for vote
 			ExpireTime: math.MaxInt64, // Never expires.
 		},
+		DeploymentCTExtended: {
+			// Not active until an activation height is agreed with
+			// the ctbluenet miners.  It must be above the chain tip
+			// when the release defining it is deployed, with enough
+			// margin for nodes to upgrade, so the blocks mined
+			// before the extended opcodes were defined keep
+			// validating under the rules they were mined with.
+			BitNumber:  2,
+			StartTime:  math.MaxInt64, // Never available for vote
+			ExpireTime: math.MaxInt64, // Never expires
+		},
 	},
 
 	// Mempool parameters
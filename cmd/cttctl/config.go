@@ -31,6 +31,8 @@ var (
 	btcwalletHomeDir      = btcutil.AppDataDir("cttwallet", false)
 	defaultConfigFile     = filepath.Join(btcctlHomeDir, "cttctl.conf")
 	defaultRPCServer      = "localhost"
+	defaultRESTServer     = "localhost"
+	defaultRESTPort       = "17764"
 	defaultRPCCertFile    = filepath.Join(btcdHomeDir, "rpc.cert")
 	defaultWalletCertFile = filepath.Join(btcwalletHomeDir, "rpc.cert")
 )
@@ -86,6 +88,11 @@ func listCommands() {
 		}
 		fmt.Println()
 	}
+
+	fmt.Println("REST Server Commands (--restserver):")
+	for _, usage := range restCommandUsages {
+		fmt.Println(usage)
+	}
 }
 
 // config defines the configuration options for btcctl.
@@ -108,6 +115,8 @@ type config struct {
 	CtBlueNet     bool   `long:"bluenet" description:"Connect to the simulation test network"`
 	TLSSkipVerify bool   `long:"skipverify" description:"Do not verify tls certificates (not recommended!)"`
 	Wallet        bool   `long:"wallet" description:"Connect to wallet"`
+	RESTServer    string `long:"restserver" description:"REST server to connect to for the msg and peers commands (default port: 17764)"`
+	RESTToken     string `long:"resttoken" default-mask:"-" description:"API token for REST write requests"`
 }
 
 // normalizeAddress returns addr with the passed default port appended if
@@ -162,8 +171,8 @@ func cleanAndExpandPath(path string) string {
 	return filepath.Clean(os.ExpandEnv(path))
 }
 
-// loadConfig initializes and parses the config using a config file and command
-// line options.
+// loadConfig initializes and parses the config using a config file and the
+// passed command line options.
 //
 // The configuration proceeds as follows:
 // 	1) Start with a default config with sane settings
@@ -174,12 +183,13 @@ func cleanAndExpandPath(path string) string {
 // The above results in functioning properly without any config settings
 // while still allowing the user to override settings with config files and
 // command line options.  Command line options always take precedence.
-func loadConfig() (*config, []string, error) {
+func loadConfig(args []string) (*config, []string, error) {
 	// Default config.
 	cfg := config{
 		ConfigFile: defaultConfigFile,
 		RPCServer:  defaultRPCServer,
 		RPCCert:    defaultRPCCertFile,
+		RESTServer: defaultRESTServer,
 	}
 
 	// Pre-parse the command line options to see if an alternative config
@@ -188,7 +198,7 @@ func loadConfig() (*config, []string, error) {
 	// they will be caught by the final parse below.
 	preCfg := cfg
 	preParser := flags.NewParser(&preCfg, flags.HelpFlag)
-	_, err := preParser.Parse()
+	_, err := preParser.ParseArgs(args)
 	if err != nil {
 		if e, ok := err.(*flags.Error); ok && e.Type == flags.ErrHelp {
 			fmt.Fprintln(os.Stderr, err)
@@ -245,7 +255,7 @@ func loadConfig() (*config, []string, error) {
 	}
 
 	// Parse command line options again to ensure they take precedence.
-	remainingArgs, err := parser.Parse()
+	remainingArgs, err := parser.ParseArgs(args)
 	if err != nil {
 		if e, ok := err.(*flags.Error); !ok || e.Type != flags.ErrHelp {
 			fmt.Fprintln(os.Stderr, usageMessage)
@@ -286,6 +296,12 @@ func loadConfig() (*config, []string, error) {
 	cfg.RPCServer = normalizeAddress(cfg.RPCServer, cfg.TestNet3,
 		cfg.SimNet, cfg.CtBlueNet, cfg.Wallet)
 
+	// Add the default port to the REST server if needed.
+	if _, _, err := net.SplitHostPort(cfg.RESTServer); err != nil {
+		cfg.RESTServer = net.JoinHostPort(cfg.RESTServer,
+			defaultRESTPort)
+	}
+
 	return &cfg, remainingArgs, nil
 }
 
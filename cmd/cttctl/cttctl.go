@@ -47,18 +47,35 @@ func usage(errorMessage string) {
 }
 
 func main() {
-	cfg, args, err := loadConfig()
+	// The options of the msg list command are parsed by the command
+	// itself, so they are split off before parsing the cttctl options.
+	cmdLine, msgListArgs := splitMsgListArgs(os.Args[1:])
+	cfg, args, err := loadConfig(cmdLine)
 	if err != nil {
 		os.Exit(1)
 	}
+	args = append(args, msgListArgs...)
 	if len(args) < 1 {
 		usage("No command specified")
 		os.Exit(1)
 	}
 
+	// Commands for the message store and peers are issued against the REST
+	// server.
+	method := args[0]
+	if isRESTCommand(method) {
+		err := runRESTCommand(cfg, args)
+		if err != nil && err != errRESTUsage {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Ensure the specified method identifies a valid registered command and
 	// is one of the usable types.
-	method := args[0]
 	usageFlags, err := btcjson.MethodUsageFlags(method)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Unrecognized command '%s'\n", method)
@@ -0,0 +1,287 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// restCommandUsages is the one-line usage of the commands which are issued
+// against the REST server rather than the JSON-RPC server.
+var restCommandUsages = []string{
+	"msg post <file|->",
+	"msg get <msgid> (<file>)",
+	"msg list (--since=<time>) (--until=<time>) (--limit=<n>) (--cursor=<cursor>)",
+	"peers",
+}
+
+// isRESTCommand returns whether the passed command is handled by the REST
+// server.
+func isRESTCommand(method string) bool {
+	return method == "msg" || method == "peers"
+}
+
+// splitMsgListArgs splits the passed command line arguments after a msg list
+// command since the options which follow it are parsed by msgList rather than
+// as cttctl options.  The second slice is nil when there is no msg list
+// command.
+func splitMsgListArgs(args []string) ([]string, []string) {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "msg" && args[i+1] == "list" {
+			return args[:i+2], args[i+2:]
+		}
+	}
+	return args, nil
+}
+
+// restUsage displays the usage of the REST commands.
+func restUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	for _, usage := range restCommandUsages {
+		fmt.Fprintf(os.Stderr, "  %s\n", usage)
+	}
+}
+
+// restURL returns the URL of the passed REST API path on the configured REST
+// server.
+func restURL(cfg *config, path string, query url.Values) string {
+	protocol := "http"
+	if !cfg.NoTLS {
+		protocol = "https"
+	}
+	u := url.URL{
+		Scheme:   protocol,
+		Host:     cfg.RESTServer,
+		Path:     "/api/v1/" + path,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// sendRESTRequest sends a request to the REST server described in the passed
+// config struct and returns the body of the response.  Unsuccessful responses
+// are returned as an error carrying the error message of the server.
+func sendRESTRequest(cfg *config, method, url string, body io.Reader) ([]byte, error) {
+	httpRequest, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Close = true
+	if body != nil {
+		httpRequest.Header.Set("Content-Type", "application/octet-stream")
+	}
+	if cfg.RESTToken != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+cfg.RESTToken)
+	}
+
+	// The REST server shares the TLS certificate of the RPC server, so the
+	// same client configuration applies.
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	httpResponse, err := httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the raw bytes and close the response.
+	respBytes, err := ioutil.ReadAll(httpResponse.Body)
+	httpResponse.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading reply: %v", err)
+	}
+
+	if httpResponse.StatusCode < 200 || httpResponse.StatusCode >= 300 {
+		// The server reports errors as a JSON object with an error
+		// field.  Fall back to the raw body or the status when it
+		// does not.
+		var restErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(respBytes, &restErr) == nil && restErr.Error != "" {
+			return nil, errors.New(restErr.Error)
+		}
+		if len(respBytes) == 0 {
+			return nil, fmt.Errorf("%d %s", httpResponse.StatusCode,
+				http.StatusText(httpResponse.StatusCode))
+		}
+		return nil, fmt.Errorf("%s", respBytes)
+	}
+	return respBytes, nil
+}
+
+// printJSON displays the passed JSON reply indented.
+func printJSON(reply []byte) error {
+	var dst bytes.Buffer
+	if err := json.Indent(&dst, reply, "", "  "); err != nil {
+		return fmt.Errorf("failed to format result: %v", err)
+	}
+	fmt.Println(dst.String())
+	return nil
+}
+
+// parseTimeArg parses a time given either as unix seconds or in RFC3339
+// format and returns it as unix seconds.
+func parseTimeArg(s string) (string, error) {
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return s, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return "", fmt.Errorf("invalid time %q: must be unix seconds "+
+			"or RFC3339", s)
+	}
+	return strconv.FormatInt(t.Unix(), 10), nil
+}
+
+// msgPost posts the message read from the passed file, or stdin when the
+// file is "-", to the REST server.
+func msgPost(cfg *config, args []string) error {
+	if len(args) != 1 {
+		return errRESTUsage
+	}
+
+	var msg []byte
+	var err error
+	if args[0] == "-" {
+		msg, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		msg, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read message: %v", err)
+	}
+
+	_, err = sendRESTRequest(cfg, "POST", restURL(cfg, "messages/", nil),
+		bytes.NewReader(msg))
+	return err
+}
+
+// msgGet fetches the message with the passed id and writes it to the passed
+// file or stdout.
+func msgGet(cfg *config, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errRESTUsage
+	}
+	if _, err := hex.DecodeString(args[0]); err != nil {
+		return fmt.Errorf("invalid message id %q", args[0])
+	}
+
+	msg, err := sendRESTRequest(cfg, "GET",
+		restURL(cfg, "messages/"+args[0], nil), nil)
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		return ioutil.WriteFile(args[1], msg, 0644)
+	}
+	_, err = os.Stdout.Write(msg)
+	return err
+}
+
+// msgList lists the hashes of the stored messages.
+func msgList(cfg *config, args []string) error {
+	fs := flag.NewFlagSet("msg list", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	since := fs.String("since", "", "")
+	until := fs.String("until", "", "")
+	limit := fs.Int("limit", 0, "")
+	cursor := fs.String("cursor", "", "")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errRESTUsage
+	}
+
+	query := url.Values{}
+	timeParams := []struct {
+		name  string
+		value string
+	}{
+		{"since", *since},
+		{"until", *until},
+	}
+	for _, param := range timeParams {
+		if param.value == "" {
+			continue
+		}
+		secs, err := parseTimeArg(param.value)
+		if err != nil {
+			return err
+		}
+		query.Set(param.name, secs)
+	}
+	if *limit > 0 {
+		query.Set("limit", strconv.Itoa(*limit))
+	}
+	if *cursor != "" {
+		query.Set("cursor", *cursor)
+	}
+
+	reply, err := sendRESTRequest(cfg, "GET",
+		restURL(cfg, "messages/", query), nil)
+	if err != nil {
+		return err
+	}
+	return printJSON(reply)
+}
+
+// listPeers lists the peers connected to the server.
+func listPeers(cfg *config, args []string) error {
+	if len(args) != 0 {
+		return errRESTUsage
+	}
+	reply, err := sendRESTRequest(cfg, "GET", restURL(cfg, "peers/", nil),
+		nil)
+	if err != nil {
+		return err
+	}
+	return printJSON(reply)
+}
+
+// errRESTUsage is returned by the REST commands when they are invoked with
+// invalid arguments.
+var errRESTUsage = errors.New("invalid arguments")
+
+// runRESTCommand issues the REST command described by args, which starts
+// with the command name, against the REST server.
+func runRESTCommand(cfg *config, args []string) error {
+	var err error
+	switch {
+	case args[0] == "peers":
+		err = listPeers(cfg, args[1:])
+
+	case len(args) < 2:
+		err = errRESTUsage
+
+	case args[1] == "post":
+		err = msgPost(cfg, args[2:])
+
+	case args[1] == "get":
+		err = msgGet(cfg, args[2:])
+
+	case args[1] == "list":
+		err = msgList(cfg, args[2:])
+
+	default:
+		err = errRESTUsage
+	}
+	if err == errRESTUsage {
+		fmt.Fprintf(os.Stderr, "%s command: %v\n", args[0], err)
+		restUsage()
+	}
+	return err
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/jadeblaquiere/cttd/chaincfg"
 	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
 	"github.com/jadeblaquiere/cttd/connmgr"
+	"github.com/jadeblaquiere/cttd/ctmsg"
 	"github.com/jadeblaquiere/cttd/database"
 	_ "github.com/jadeblaquiere/cttd/database/ffldb"
 	"github.com/jadeblaquiere/cttd/mempool"
@@ -47,7 +48,11 @@ const (
 	defaultMaxRPCClients         = 10
 	defaultMaxRPCWebsockets      = 25
 	defaultMaxRPCConcurrentReqs  = 20
+	defaultMaxRESTClients        = 100
+	defaultRESTRateLimit         = 20
+	defaultRESTByteRateLimit     = 1024
 	defaultDbType                = "ffldb"
+	defaultCtMsgStore            = "ctgo"
 	defaultFreeTxRelayLimit      = 15.0
 	defaultTrickleInterval       = peer.DefaultTrickleInterval
 	defaultBlockMinSize          = 0
@@ -62,6 +67,8 @@ const (
 	defaultMaxOrphanTransactions = 100
 	defaultMaxOrphanTxSize       = 100000
 	defaultSigCacheMaxSize       = 100000
+	defaultUtxoCacheMaxSizeMiB   = 250
+	minPruneTargetMiB            = 550
 	sampleConfigFilename         = "sample-cttd.conf"
 	defaultTxIndex               = false
 	defaultAddrIndex             = false
@@ -73,6 +80,7 @@ var (
 	defaultCtmxDir     = filepath.Join(defaultHomeDir, defaultCtmxDirname)
 	defaultDataDir     = filepath.Join(defaultHomeDir, defaultDataDirname)
 	knownDbTypes       = database.SupportedDrivers()
+	knownCtMsgStores   = []string{"ctgo", "db"}
 	defaultRPCKeyFile  = filepath.Join(defaultHomeDir, "rpc.key")
 	defaultRPCCertFile = filepath.Join(defaultHomeDir, "rpc.cert")
 	defaultLogDir      = filepath.Join(defaultHomeDir, defaultLogDirname)
@@ -98,6 +106,12 @@ type config struct {
 	ShowVersion          bool          `short:"V" long:"version" description:"Display version information and exit"`
 	ConfigFile           string        `short:"C" long:"configfile" description:"Path to configuration file"`
 	CtmxDir              string        `long:"ctmxdir" description:"Directory to store ciphrtxt message database"`
+	CtMaxMsgSize         uint32        `long:"ctmaxmsgsize" description:"Maximum size in bytes of an accepted ciphrtxt message, including the header"`
+	CtMinMsgExpire       time.Duration `long:"ctminmsgexpire" description:"Minimum lifetime of an accepted ciphrtxt message.  Valid time units are {s, m, h}"`
+	CtMaxMsgExpire       time.Duration `long:"ctmaxmsgexpire" description:"Maximum lifetime of an accepted ciphrtxt message.  Valid time units are {s, m, h}"`
+	CtRetention          time.Duration `long:"ctretention" description:"Evict stored ciphrtxt messages older than this, even if they have not expired -- 0 keeps messages until they expire.  Valid time units are {s, m, h}"`
+	CtMaxStoreSize       uint64        `long:"ctmaxstoresize" description:"Evict the oldest ciphrtxt messages once the message store exceeds this size in MiB -- 0 disables the limit"`
+	CtMsgStore           string        `long:"ctmsgstore" description:"Backend for the ciphrtxt message store {ctgo, db} -- db keeps messages in the block database"`
 	DataDir              string        `short:"b" long:"datadir" description:"Directory to store data"`
 	LogDir               string        `long:"logdir" description:"Directory to log output."`
 	AddPeers             []string      `short:"a" long:"addpeer" description:"Add a peer to connect with at startup"`
@@ -121,6 +135,11 @@ type config struct {
 	RPCMaxConcurrentReqs int           `long:"rpcmaxconcurrentreqs" description:"Max number of concurrent RPC requests that may be processed concurrently"`
 	RPCQuirks            bool          `long:"rpcquirks" description:"Mirror some JSON-RPC quirks of Bitcoin Core -- NOTE: Discouraged unless interoperability issues need to be worked around"`
 	RESTListeners        []string      `long:"restlisten" description:"Add an interface/port to listen for REST connections (default port bluenet: 17764)"`
+	RESTDisableTLS       bool          `long:"restnotls" description:"Disable TLS for the REST server (e.g. when it is served behind a TLS terminating proxy)"`
+	RESTTokens           []string      `long:"resttoken" default-mask:"-" description:"Add an API token required for REST write requests -- NOTE: REST write requests are not authenticated if no tokens are specified"`
+	RESTMaxClients       int           `long:"restmaxclients" description:"Max number of concurrent REST requests -- 0 disables the limit"`
+	RESTRateLimit        float64       `long:"restratelimit" description:"Max number of REST requests per second from each IP address -- 0 disables the limit"`
+	RESTByteRateLimit    float64       `long:"restbytelimit" description:"Max number of KiB per second transferred by REST requests from each IP address -- 0 disables the limit"`
 	DisableRPC           bool          `long:"norpc" description:"Disable built-in RPC server -- NOTE: The RPC server is disabled by default if no rpcuser/rpcpass or rpclimituser/rpclimitpass is specified"`
 	DisableTLS           bool          `long:"notls" description:"Disable TLS for the RPC server -- NOTE: This is only allowed if the RPC server is bound to localhost"`
 	DisableDNSSeed       bool          `long:"nodnsseed" description:"Disable DNS seeding for peers"`
@@ -161,11 +180,15 @@ type config struct {
 	NoCFilters           bool          `long:"nocfilters" description:"Disable committed filtering (CF) support"`
 	DropCfIndex          bool          `long:"dropcfindex" description:"Deletes the index used for committed filtering (CF) support from the database on start up and then exits."`
 	SigCacheMaxSize      uint          `long:"sigcachemaxsize" description:"The maximum number of entries in the signature verification cache"`
+	UtxoCacheMaxSizeMiB  uint          `long:"utxocachemaxsize" description:"The maximum size in MiB of the UTXO cache -- Modifications of the UTXO set are written to the database once it is full"`
+	Prune                uint64        `long:"prune" description:"Delete the oldest blocks once they are buried by 288 blocks to keep the stored blocks below the given size in MiB -- The node no longer serves the full block chain and can not maintain the transaction and address indexes (0 disables pruning, the minimum is 550)"`
 	BlocksOnly           bool          `long:"blocksonly" description:"Do not accept transactions from remote peers."`
 	TxIndex              bool          `long:"txindex" description:"Maintain a full hash-based transaction index which makes all transactions available via the getrawtransaction RPC"`
 	DropTxIndex          bool          `long:"droptxindex" description:"Deletes the hash-based transaction index from the database on start up and then exits."`
 	AddrIndex            bool          `long:"addrindex" description:"Maintain a full address-based transaction index which makes the searchrawtransactions RPC available"`
 	DropAddrIndex        bool          `long:"dropaddrindex" description:"Deletes the address-based transaction index from the database on start up and then exits."`
+	NAKIndex             bool          `long:"nakindex" description:"Maintain an index of the network access keys registered with OP_REGISTERNAK which makes the getnak and listnaks RPCs available"`
+	DropNAKIndex         bool          `long:"dropnakindex" description:"Deletes the network access key index from the database on start up and then exits."`
 	RelayNonStd          bool          `long:"relaynonstd" description:"Relay non-standard transactions regardless of the default settings for the active network."`
 	RejectNonStd         bool          `long:"rejectnonstd" description:"Reject non-standard transactions regardless of the default settings for the active network."`
 	lookup               func(string) ([]net.IP, error)
@@ -216,6 +239,18 @@ func validLogLevel(logLevel string) bool {
 	return false
 }
 
+// validCtMsgStore returns whether or not backend is a supported ciphrtxt
+// message store backend.
+func validCtMsgStore(backend string) bool {
+	for _, known := range knownCtMsgStores {
+		if backend == known {
+			return true
+		}
+	}
+
+	return false
+}
+
 // supportedSubsystems returns a sorted slice of the supported subsystems for
 // logging purposes.
 func supportedSubsystems() []string {
@@ -416,7 +451,14 @@ func loadConfig() (*config, []string, error) {
 		RPCMaxClients:        defaultMaxRPCClients,
 		RPCMaxWebsockets:     defaultMaxRPCWebsockets,
 		RPCMaxConcurrentReqs: defaultMaxRPCConcurrentReqs,
+		RESTMaxClients:       defaultMaxRESTClients,
+		RESTRateLimit:        defaultRESTRateLimit,
+		RESTByteRateLimit:    defaultRESTByteRateLimit,
 		CtmxDir:              defaultCtmxDir,
+		CtMaxMsgSize:         ctmsg.DefaultMaxMessageSize,
+		CtMinMsgExpire:       ctmsg.DefaultMinExpire,
+		CtMaxMsgExpire:       ctmsg.DefaultMaxExpire,
+		CtMsgStore:           defaultCtMsgStore,
 		DataDir:              defaultDataDir,
 		LogDir:               defaultLogDir,
 		DbType:               defaultDbType,
@@ -432,6 +474,7 @@ func loadConfig() (*config, []string, error) {
 		BlockPrioritySize:    mempool.DefaultBlockPrioritySize,
 		MaxOrphanTxs:         defaultMaxOrphanTransactions,
 		SigCacheMaxSize:      defaultSigCacheMaxSize,
+		UtxoCacheMaxSizeMiB:  defaultUtxoCacheMaxSizeMiB,
 		Generate:             defaultGenerate,
 		TxIndex:              defaultTxIndex,
 		AddrIndex:            defaultAddrIndex,
@@ -656,6 +699,39 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// The ciphrtxt message size must at least allow for the header.
+	if cfg.CtMaxMsgSize < ctmsg.MessageHeaderSize {
+		str := "%s: The ctmaxmsgsize option may not be less than %d " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, ctmsg.MessageHeaderSize,
+			cfg.CtMaxMsgSize)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The ciphrtxt message lifetime range must be valid.
+	if cfg.CtMinMsgExpire < time.Second ||
+		cfg.CtMaxMsgExpire < cfg.CtMinMsgExpire {
+		str := "%s: The ctminmsgexpire option may not be less than 1s " +
+			"or greater than ctmaxmsgexpire -- parsed [%v, %v]"
+		err := fmt.Errorf(str, funcName, cfg.CtMinMsgExpire,
+			cfg.CtMaxMsgExpire)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Validate the ciphrtxt message store backend.
+	if !validCtMsgStore(cfg.CtMsgStore) {
+		str := "%s: The specified message store backend [%v] is " +
+			"invalid -- supported backends %v"
+		err := fmt.Errorf(str, funcName, cfg.CtMsgStore, knownCtMsgStores)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Validate any given whitelisted IP addresses and networks.
 	if len(cfg.Whitelists) > 0 {
 		var ip net.IP
@@ -770,6 +846,32 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Validate the REST server limits.
+	if cfg.RESTMaxClients < 0 {
+		str := "%s: The restmaxclients option may not be less than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.RESTMaxClients)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.RESTRateLimit < 0 {
+		str := "%s: The restratelimit option may not be less than 0 " +
+			"-- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.RESTRateLimit)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.RESTByteRateLimit < 0 {
+		str := "%s: The restbytelimit option may not be less than 0 " +
+			"-- parsed [%v]"
+		err := fmt.Errorf(str, funcName, cfg.RESTByteRateLimit)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Default RPC to listen on localhost only.
 	if cfg.CtBlueNet && len(cfg.RESTListeners) == 0 {
 		addrs, err := net.LookupHost("localhost")
@@ -883,6 +985,16 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// --nakindex and --dropnakindex do not mix.
+	if cfg.NAKIndex && cfg.DropNAKIndex {
+		err := fmt.Errorf("%s: the --nakindex and --dropnakindex "+
+			"options may not be activated at the same time",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// --addrindex and --droptxindex do not mix.
 	if cfg.AddrIndex && cfg.DropTxIndex {
 		err := fmt.Errorf("%s: the --addrindex and --droptxindex "+
@@ -895,6 +1007,29 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Ensure the prune target leaves room for the blocks which are never
+	// pruned.
+	if cfg.Prune != 0 && cfg.Prune < minPruneTargetMiB {
+		str := "%s: the prune target of %d MiB is below the minimum " +
+			"of %d MiB"
+		err := fmt.Errorf(str, funcName, cfg.Prune, minPruneTargetMiB)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune and --txindex (or --addrindex) do not mix since the indexes
+	// require the full block history.
+	if cfg.Prune != 0 && (cfg.TxIndex || cfg.AddrIndex) {
+		err := fmt.Errorf("%s: the --prune option may not be "+
+			"activated along with the --txindex or --addrindex "+
+			"options because the indexes require all blocks",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Check mining addresses are valid and saved parsed versions.
 	cfg.miningAddrs = make([]btcutil.Address, 0, len(cfg.MiningAddrs))
 	for _, strAddr := range cfg.MiningAddrs {
@@ -0,0 +1,140 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/jadeblaquiere/ctclient/ctgo"
+)
+
+// CtgoStore is a Store backed by the message store of the ctgo library.
+// Messages are identified by their ctgo payload hash, the id ctgo clients
+// sharing the store already use, rather than by MessageHash.
+type CtgoStore struct {
+	ms *ctgo.MessageStore
+}
+
+// Ensure CtgoStore implements the Store interface.
+var _ Store = (*CtgoStore)(nil)
+
+// OpenCtgoStore opens (creating it if needed) the ctgo message store rooted
+// at the passed directory.
+func OpenCtgoStore(rootDir string) (*CtgoStore, error) {
+	if len(rootDir) == 0 {
+		return nil, ErrNoRootDir
+	}
+	ms, err := ctgo.OpenMessageStore(rootDir)
+	if err != nil {
+		return nil, errors.New("ctmsg:OpenCtgoStore OpenMessageStore " +
+			"failed : " + err.Error())
+	}
+	return &CtgoStore{ms: ms}, nil
+}
+
+// openCiphertext opens the file holding the serialized message identified by
+// the passed hash.
+func (s *CtgoStore) openCiphertext(hash []byte) (*os.File, error) {
+	mf := s.ms.GetMessage(hash)
+	if mf == nil {
+		return nil, ErrMessageNotFound
+	}
+	cfile, err := mf.CiphertextFile()
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+	return cfile, nil
+}
+
+// FetchMessage returns the serialized message identified by the passed hash.
+//
+// This is part of the Store interface.
+func (s *CtgoStore) FetchMessage(hash []byte) ([]byte, error) {
+	cfile, err := s.openCiphertext(hash)
+	if err != nil {
+		return nil, err
+	}
+	defer cfile.Close()
+	return ioutil.ReadAll(cfile)
+}
+
+// FetchHeader returns the header of the message identified by the passed hash
+// without reading the remainder of the message.
+//
+// This is part of the Store interface.
+func (s *CtgoStore) FetchHeader(hash []byte) (*MessageHeader, error) {
+	cfile, err := s.openCiphertext(hash)
+	if err != nil {
+		return nil, err
+	}
+	defer cfile.Close()
+
+	var hdr MessageHeader
+	if err := hdr.Deserialize(cfile); err != nil {
+		return nil, err
+	}
+	return &hdr, nil
+}
+
+// StoreMessage adds the passed serialized message to the store.  The message
+// is staged in a temporary file since ctgo only ingests message files.
+//
+// This is part of the Store interface.
+func (s *CtgoStore) StoreMessage(msg []byte) ([]byte, error) {
+	file, err := ioutil.TempFile("", "MStoreg")
+	if err != nil {
+		return nil, errors.New("ctmsg:StoreMessage TempFile failed : " +
+			err.Error())
+	}
+	filename := file.Name()
+	defer os.Remove(filename)
+
+	_, err = file.Write(msg)
+	file.Close()
+	if err != nil {
+		return nil, errors.New("ctmsg:StoreMessage Write failed : " +
+			err.Error())
+	}
+
+	mf, err := ctgo.NewMessageFile(filename)
+	if err != nil {
+		return nil, ErrInvalidMessage
+	}
+	hash := mf.PayloadHash()
+	if s.ms.GetMessage(hash) != nil {
+		return hash, ErrDuplicateMessage
+	}
+	if err := s.ms.IngestMessageFile(mf); err != nil {
+		return nil, errors.New("ctmsg:StoreMessage IngestMessageFile " +
+			"failed : " + err.Error())
+	}
+	return hash, nil
+}
+
+// ListHashesForInterval returns the hashes of the stored messages with a
+// timestamp in the range [start, end].
+//
+// This is part of the Store interface.
+func (s *CtgoStore) ListHashesForInterval(start, end time.Time) ([][]byte, error) {
+	return s.ms.ListHashesForInterval(start, end)
+}
+
+// DeleteMessage removes the message identified by the passed hash.
+//
+// This is part of the Store interface.
+func (s *CtgoStore) DeleteMessage(hash []byte) error {
+	return s.ms.DeleteMessage(hash)
+}
+
+// Close closes the ctgo message store.
+//
+// This is part of the Store interface.
+func (s *CtgoStore) Close() error {
+	s.ms.Close()
+	return nil
+}
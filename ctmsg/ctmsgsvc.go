@@ -5,44 +5,286 @@
 package ctmsg
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
 	//"fmt"
 	//"runtime/pprof"
-
-	"github.com/jadeblaquiere/ctclient/ctgo"
 )
 
 var (
 	//ErrNoRootDir is used to indicate that MessageStoreRootDir must be
 	//specified in the configuration.
 	ErrNoRootDir = errors.New("Config: Dial cannot be nil")
+
+	// ErrInvalidMessage is used to indicate that a message could not be
+	// parsed as a valid ciphrtxt message.
+	ErrInvalidMessage = errors.New("ctmsg: invalid message")
+
+	// ErrDuplicateMessage is used to indicate that a message is already
+	// present in the message store.
+	ErrDuplicateMessage = errors.New("ctmsg: duplicate message")
+
+	// ErrMessageNotFound is used to indicate that the requested message is
+	// not present in the message store.
+	ErrMessageNotFound = errors.New("ctmsg: message not found")
 )
 
 type Config struct {
+	// Store is the backend holding the messages.  When it is nil a ctgo
+	// message store rooted at MessageStoreRootDir is opened.  The store is
+	// closed along with the service.
+	Store Store
+
 	MessageStoreRootDir string
 	//SectorRing          uint
 	//SectorStart         uint
+
+	// Policy defines the rules messages must satisfy to be accepted into
+	// the message store.  Unset fields take their default values.
+	Policy MessagePolicy
+
+	// PruneInterval is the amount of time between runs of the message
+	// pruner.  DefaultPruneInterval is used when it is zero.
+	PruneInterval time.Duration
+
+	// RetentionWindow is the maximum age of a stored message, regardless
+	// of its expiry time.  Zero disables the retention window.
+	RetentionWindow time.Duration
+
+	// MaxStoreSize is the maximum total size in bytes of the stored
+	// messages.  The oldest messages are evicted once it is exceeded.
+	// Zero disables the quota.
+	MaxStoreSize int64
 }
 
 type CiphrtxtMsgSvc struct {
-	MStore *ctgo.MessageStore
+	// MStore is the backend holding the stored messages.
+	MStore Store
+	policy MessagePolicy
+
+	pruneInterval time.Duration
+	retention     time.Duration
+	maxStoreSize  int64
+
+	// ingestLock serializes the duplicate check and ingestion of new
+	// messages so a message is only ever announced once.
+	ingestLock sync.Mutex
+
+	// totals describes the stored messages.  When pruning is set, the
+	// headers of the messages stored since the pruner listed the store are
+	// also collected in storedWhilePruning so the pruner can refresh the
+	// totals once it is done.  These fields are protected by ingestLock.
+	totals             storeTotals
+	pruning            bool
+	storedWhilePruning []*MessageHeader
+
+	// quotaCutoff is the timestamp of the newest message evicted to stay
+	// within the store size quota.  Older messages are refused so they
+	// aren't fetched from peers again only to be evicted on the next run
+	// of the pruner.  It is protected by cutoffLock.
+	cutoffLock  sync.Mutex
+	quotaCutoff time.Time
+
+	// ingestCounters tracks the outcome of every ingested message.
+	ingestCounters *ingestCounters
+
+	notificationsLock sync.RWMutex
+	notifications     []NotificationCallback
+
+	started  int32
+	shutdown int32
+	wg       sync.WaitGroup
+	quit     chan struct{}
 }
 
+// Start begins the background pruning of expired and excess messages.
+func (ctms *CiphrtxtMsgSvc) Start() {
+	// Already started?
+	if atomic.AddInt32(&ctms.started, 1) != 1 {
+		return
+	}
+
+	ctms.wg.Add(1)
+	go ctms.pruneHandler()
+}
+
+// Close stops the background pruning, if it was started, and closes the
+// message store.
 func (ctms *CiphrtxtMsgSvc) Close() {
+	if atomic.AddInt32(&ctms.shutdown, 1) != 1 {
+		return
+	}
+
+	close(ctms.quit)
+	ctms.wg.Wait()
+
 	log.Info("closing ciphrtxt message store database")
-	ctms.MStore.Close()
+	if err := ctms.MStore.Close(); err != nil {
+		log.Errorf("Unable to close message store: %v", err)
+	}
 }
 
-func New(cfg *Config) (*CiphrtxtMsgSvc, error) {
-	if len(cfg.MessageStoreRootDir) == 0 {
-		return nil, ErrNoRootDir
+// HaveMessage returns whether or not the message identified by the passed
+// hash is present in the message store.
+func (ctms *CiphrtxtMsgSvc) HaveMessage(hash []byte) bool {
+	_, err := ctms.MStore.FetchHeader(hash)
+	return err == nil
+}
+
+// FetchMessage returns the serialized message identified by the passed hash.
+// ErrMessageNotFound is returned if the message is not in the store.
+func (ctms *CiphrtxtMsgSvc) FetchMessage(hash []byte) ([]byte, error) {
+	return ctms.MStore.FetchMessage(hash)
+}
+
+// FetchHeader returns the header of the stored message identified by the
+// passed hash without reading the remainder of the message.
+// ErrMessageNotFound is returned if the message is not in the store.
+func (ctms *CiphrtxtMsgSvc) FetchHeader(hash []byte) (*MessageHeader, error) {
+	return ctms.MStore.FetchHeader(hash)
+}
+
+// Policy returns the message acceptance policy in use by the service.
+func (ctms *CiphrtxtMsgSvc) Policy() MessagePolicy {
+	return ctms.policy
+}
+
+// ReadHeader reads and validates the header of a serialized message from r
+// against the message policy.  No more than MessageHeaderSize bytes are read
+// from r, so callers can refuse an unacceptable message before receiving its
+// body.  Any rule violation is returned as a RuleError.
+func (ctms *CiphrtxtMsgSvc) ReadHeader(r io.Reader) (*MessageHeader, error) {
+	var hdr MessageHeader
+	err := hdr.Deserialize(r)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		str := fmt.Sprintf("message is shorter than the %d byte header",
+			MessageHeaderSize)
+		return nil, ruleError(ErrMessageTooSmall, str)
 	}
-	ms, err := ctgo.OpenMessageStore(cfg.MessageStoreRootDir)
 	if err != nil {
-		return nil, errors.New("ctmsg:New OpenMessageStore failed : " + err.Error())
+		return nil, err
+	}
+
+	now := time.Now()
+	err = ctms.policy.CheckHeader(&hdr, now)
+	if err != nil {
+		return nil, err
+	}
+	err = ctms.checkMessageAge(&hdr, now)
+	if err != nil {
+		return nil, err
+	}
+	return &hdr, nil
+}
+
+// IngestMessage reads a serialized message from r and adds it to the message
+// store.  The outcome is counted in the statistics returned by IngestStats.
+// See ingestMessage for details.
+func (ctms *CiphrtxtMsgSvc) IngestMessage(r io.Reader) ([]byte, error) {
+	hash, err := ctms.ingestMessage(r)
+	ctms.ingestCounters.record(err, time.Now())
+	return hash, err
+}
+
+// ingestMessage reads a serialized message from r and adds it to the message
+// store.  The header is read and validated against the message policy before
+// the remainder of the message is consumed, and exactly the number of bytes
+// announced by the header must follow.  The proof of work is checked once the
+// whole message has been read.  Rule violations are returned as a
+// RuleError.  Subscribers are notified with NTMessageAccepted once the message
+// has been stored.  The hash of the message is returned on success, and
+// ErrDuplicateMessage is returned (along with the hash) if the message was
+// already present.
+func (ctms *CiphrtxtMsgSvc) ingestMessage(r io.Reader) ([]byte, error) {
+	hdr, err := ctms.ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the header followed by the body, which is limited to the
+	// length announced in the header.  The policy bounds the message
+	// length, so the message is buffered in memory.
+	var msg bytes.Buffer
+	msg.Grow(int(hdr.MsgLen))
+	body := io.MultiReader(bytes.NewReader(hdr.Bytes()), r)
+	n, err := io.CopyN(&msg, body, int64(hdr.MsgLen))
+	if err == io.EOF {
+		str := fmt.Sprintf("message is %d bytes, header announces %d",
+			n, hdr.MsgLen)
+		return nil, ruleError(ErrBadMessageLength, str)
+	}
+	if err != nil {
+		return nil, errors.New("ctmsg:IngestMessage Copy failed : " +
+			err.Error())
+	}
+
+	// Refuse messages with trailing data.
+	var extra [1]byte
+	if _, err := io.ReadFull(r, extra[:]); err == nil {
+		str := fmt.Sprintf("message is longer than the %d bytes "+
+			"announced by its header", hdr.MsgLen)
+		return nil, ruleError(ErrBadMessageLength, str)
+	}
+
+	// The proof of work commits to the ciphertext, so it can only be
+	// checked once the whole message has been received.
+	err = ctms.policy.CheckProofOfWork(hdr, msg.Bytes()[MessageHeaderSize:])
+	if err != nil {
+		return nil, err
+	}
+
+	ctms.ingestLock.Lock()
+	hash, err := ctms.MStore.StoreMessage(msg.Bytes())
+	if err == nil {
+		ctms.countStored(hdr)
+	}
+	ctms.ingestLock.Unlock()
+	if err != nil {
+		return hash, err
+	}
+
+	log.Debugf("Accepted message %x", hash)
+	ctms.sendNotification(NTMessageAccepted, &AcceptedMessage{
+		Hash:   hash,
+		Header: hdr,
+	})
+	return hash, nil
+}
+
+// New returns a message service for the message store described by the
+// passed configuration.
+func New(cfg *Config) (*CiphrtxtMsgSvc, error) {
+	ms := cfg.Store
+	var err error
+	if ms == nil {
+		ms, err = OpenCtgoStore(cfg.MessageStoreRootDir)
+		if err != nil {
+			return nil, err
+		}
 	}
 	ctms := new(CiphrtxtMsgSvc)
 	ctms.MStore = ms
+	ctms.policy = cfg.Policy.withDefaults()
+	ctms.pruneInterval = cfg.PruneInterval
+	if ctms.pruneInterval <= 0 {
+		ctms.pruneInterval = DefaultPruneInterval
+	}
+	ctms.retention = cfg.RetentionWindow
+	ctms.maxStoreSize = cfg.MaxStoreSize
+	ctms.ingestCounters = newIngestCounters(time.Now())
+	ctms.totals, err = ctms.scanStoreTotals()
+	if err != nil {
+		if cfg.Store == nil {
+			ms.Close()
+		}
+		return nil, err
+	}
+	ctms.quit = make(chan struct{})
 	log.Info("ciphrtxt message store database opened")
 	return ctms, nil
 }
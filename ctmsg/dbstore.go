@@ -0,0 +1,225 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/database"
+)
+
+var (
+	// dbMessagesBucketName is the name of the database bucket which maps
+	// the hash of each stored message to the serialized message.
+	dbMessagesBucketName = []byte("ctmsgs")
+
+	// dbTimeIndexBucketName is the name of the database bucket which
+	// indexes the stored messages by time.  The keys are the timestamp of
+	// the message in microseconds since the unix epoch, serialized big
+	// endian, followed by the hash of the message.  The values are empty.
+	dbTimeIndexBucketName = []byte("ctmsgtimeidx")
+)
+
+// dbTimeIndexKey returns the time index key for a message with the passed
+// timestamp and hash.
+func dbTimeIndexKey(usec uint64, hash []byte) []byte {
+	key := make([]byte, 8+len(hash))
+	binary.BigEndian.PutUint64(key, usec)
+	copy(key[8:], hash)
+	return key
+}
+
+// DBStore is a Store which keeps messages in a database.DB, such as the block
+// database of the node.  Messages are identified by MessageHash.
+type DBStore struct {
+	db database.DB
+}
+
+// Ensure DBStore implements the OrderedStore interface.
+var _ OrderedStore = (*DBStore)(nil)
+
+// NewDBStore returns a message store which keeps messages in the passed
+// database, creating the buckets it needs if they do not already exist.  The
+// database is not closed when the store is closed since it is typically shared
+// with the rest of the node.
+func NewDBStore(db database.DB) (*DBStore, error) {
+	err := db.Update(func(dbTx database.Tx) error {
+		meta := dbTx.Metadata()
+		if _, err := meta.CreateBucketIfNotExists(dbMessagesBucketName); err != nil {
+			return err
+		}
+		_, err := meta.CreateBucketIfNotExists(dbTimeIndexBucketName)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &DBStore{db: db}, nil
+}
+
+// FetchMessage returns the serialized message identified by the passed hash.
+//
+// This is part of the Store interface.
+func (s *DBStore) FetchMessage(hash []byte) ([]byte, error) {
+	var msg []byte
+	err := s.db.View(func(dbTx database.Tx) error {
+		v := dbTx.Metadata().Bucket(dbMessagesBucketName).Get(hash)
+		if v == nil {
+			return ErrMessageNotFound
+		}
+
+		// The value is only valid for the life of the transaction.
+		msg = make([]byte, len(v))
+		copy(msg, v)
+		return nil
+	})
+	return msg, err
+}
+
+// FetchHeader returns the header of the message identified by the passed
+// hash.
+//
+// This is part of the Store interface.
+func (s *DBStore) FetchHeader(hash []byte) (*MessageHeader, error) {
+	var hdr MessageHeader
+	err := s.db.View(func(dbTx database.Tx) error {
+		v := dbTx.Metadata().Bucket(dbMessagesBucketName).Get(hash)
+		if v == nil {
+			return ErrMessageNotFound
+		}
+		return hdr.FromBytes(v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hdr, nil
+}
+
+// StoreMessage adds the passed serialized message to the store.
+//
+// This is part of the Store interface.
+func (s *DBStore) StoreMessage(msg []byte) ([]byte, error) {
+	hdr, err := parseStoredMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := MessageHash(msg)
+	err = s.db.Update(func(dbTx database.Tx) error {
+		meta := dbTx.Metadata()
+		msgs := meta.Bucket(dbMessagesBucketName)
+		if msgs.Get(hash) != nil {
+			return ErrDuplicateMessage
+		}
+		if err := msgs.Put(hash, msg); err != nil {
+			return err
+		}
+		key := dbTimeIndexKey(timeKey(hdr.MsgTime), hash)
+		return meta.Bucket(dbTimeIndexBucketName).Put(key, nil)
+	})
+	if err == ErrDuplicateMessage {
+		return hash, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+// ListHashesForInterval returns the hashes of the stored messages with a
+// timestamp in the range [start, end].
+//
+// This is part of the Store interface.
+func (s *DBStore) ListHashesForInterval(start, end time.Time) ([][]byte, error) {
+	if end.Before(start) {
+		return nil, nil
+	}
+
+	var hashes [][]byte
+	startKey, endKey := timeKey(start), timeKey(end)
+	err := s.db.View(func(dbTx database.Tx) error {
+		cursor := dbTx.Metadata().Bucket(dbTimeIndexBucketName).Cursor()
+		seek := dbTimeIndexKey(startKey, nil)
+		for ok := cursor.Seek(seek); ok; ok = cursor.Next() {
+			key := cursor.Key()
+			if binary.BigEndian.Uint64(key) > endKey {
+				break
+			}
+			hash := make([]byte, len(key)-8)
+			copy(hash, key[8:])
+			hashes = append(hashes, hash)
+		}
+		return nil
+	})
+	return hashes, err
+}
+
+// ForEachHash calls fn with the timestamp and hash of each stored message with
+// a timestamp in the range [start, end] in order of timestamp and then hash,
+// which is the order of the time index.
+//
+// This is part of the OrderedStore interface.
+func (s *DBStore) ForEachHash(start, end time.Time, fn func(usec uint64, hash []byte) bool) error {
+	if end.Before(start) {
+		return nil
+	}
+
+	startKey, endKey := timeKey(start), timeKey(end)
+	return s.db.View(func(dbTx database.Tx) error {
+		cursor := dbTx.Metadata().Bucket(dbTimeIndexBucketName).Cursor()
+		seek := dbTimeIndexKey(startKey, nil)
+		for ok := cursor.Seek(seek); ok; ok = cursor.Next() {
+			key := cursor.Key()
+			usec := binary.BigEndian.Uint64(key)
+			if usec > endKey {
+				break
+			}
+
+			// The key is only valid for the life of the
+			// transaction.
+			hash := make([]byte, len(key)-8)
+			copy(hash, key[8:])
+			if !fn(usec, hash) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteMessage removes the message identified by the passed hash.  Removing
+// a message which is not stored is not an error.
+//
+// This is part of the Store interface.
+func (s *DBStore) DeleteMessage(hash []byte) error {
+	return s.db.Update(func(dbTx database.Tx) error {
+		meta := dbTx.Metadata()
+		msgs := meta.Bucket(dbMessagesBucketName)
+		v := msgs.Get(hash)
+		if v == nil {
+			return nil
+		}
+		var hdr MessageHeader
+		if err := hdr.FromBytes(v); err != nil {
+			return fmt.Errorf("stored message %x is corrupt: %v", hash,
+				err)
+		}
+
+		key := dbTimeIndexKey(timeKey(hdr.MsgTime), hash)
+		if err := meta.Bucket(dbTimeIndexBucketName).Delete(key); err != nil {
+			return err
+		}
+		return msgs.Delete(hash)
+	})
+}
+
+// Close does nothing since the database is owned by the caller.
+//
+// This is part of the Store interface.
+func (s *DBStore) Close() error {
+	return nil
+}
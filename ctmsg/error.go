@@ -0,0 +1,107 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"fmt"
+)
+
+// ErrorCode identifies a kind of error.
+type ErrorCode int
+
+// These constants are used to identify a specific RuleError.
+const (
+	// ErrBadMagic indicates the message does not begin with the expected
+	// magic bytes.
+	ErrBadMagic ErrorCode = iota
+
+	// ErrBadVersion indicates the message version is not supported.
+	ErrBadVersion
+
+	// ErrMessageTooSmall indicates the message length in the header is
+	// smaller than the header itself.
+	ErrMessageTooSmall
+
+	// ErrMessageTooLarge indicates the message length in the header
+	// exceeds the maximum allowed by policy.
+	ErrMessageTooLarge
+
+	// ErrBadMessageLength indicates the number of bytes received does
+	// not match the message length in the header.
+	ErrBadMessageLength
+
+	// ErrBadExpiry indicates the message lifetime is outside of the range
+	// allowed by policy.
+	ErrBadExpiry
+
+	// ErrExpired indicates the message has already expired.
+	ErrExpired
+
+	// ErrTimeTooNew indicates the message timestamp is too far in the
+	// future.
+	ErrTimeTooNew
+
+	// ErrTimeTooOld indicates the message is older than the messages kept
+	// in the message store, either because it is older than the retention
+	// window or because older messages were evicted to stay within the
+	// store size quota.
+	ErrTimeTooOld
+
+	// ErrInsufficientWork indicates the proof of work hash of the message
+	// does not satisfy the proof of work requirement.
+	ErrInsufficientWork
+
+	// ErrBadCiphertext indicates the message ciphertext is malformed, does
+	// not match the header or fails to decrypt.
+	ErrBadCiphertext
+
+	// ErrNotRecipient indicates the message is not addressed to the key
+	// used to decrypt it.
+	ErrNotRecipient
+)
+
+// Map of ErrorCode values back to their constant names for pretty printing.
+var errorCodeStrings = map[ErrorCode]string{
+	ErrBadMagic:         "ErrBadMagic",
+	ErrBadVersion:       "ErrBadVersion",
+	ErrMessageTooSmall:  "ErrMessageTooSmall",
+	ErrMessageTooLarge:  "ErrMessageTooLarge",
+	ErrBadMessageLength: "ErrBadMessageLength",
+	ErrBadExpiry:        "ErrBadExpiry",
+	ErrExpired:          "ErrExpired",
+	ErrTimeTooNew:       "ErrTimeTooNew",
+	ErrTimeTooOld:       "ErrTimeTooOld",
+	ErrInsufficientWork: "ErrInsufficientWork",
+	ErrBadCiphertext:    "ErrBadCiphertext",
+	ErrNotRecipient:     "ErrNotRecipient",
+}
+
+// String returns the ErrorCode as a human-readable name.
+func (e ErrorCode) String() string {
+	if s := errorCodeStrings[e]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("Unknown ErrorCode (%d)", int(e))
+}
+
+// RuleError identifies a rule violation.  It is used to indicate that a
+// message was rejected due to one of the message acceptance rules.  The
+// caller can use type assertions to determine if a failure was specifically
+// due to a rule violation and access the ErrorCode field to ascertain the
+// specific reason for the rule violation.
+type RuleError struct {
+	ErrorCode   ErrorCode // Describes the kind of error
+	Description string    // Human readable description of the issue
+}
+
+// Error satisfies the error interface and prints human-readable errors.
+func (e RuleError) Error() string {
+	return e.Description
+}
+
+// ruleError creates an RuleError given a set of arguments.
+func ruleError(c ErrorCode, desc string) RuleError {
+	return RuleError{ErrorCode: c, Description: desc}
+}
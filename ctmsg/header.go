@@ -0,0 +1,137 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// MessageVersion is the current ciphrtxt message format version.  The
+	// proof of work of messages of this version commits to the ciphertext.
+	MessageVersion uint16 = 0x0201
+
+	// HeaderWorkMessageVersion is the message format version of existing
+	// ciphrtxt clients.  The proof of work of messages of this version
+	// only covers the header.  It is accepted along with MessageVersion
+	// while clients move to the current version.
+	HeaderWorkMessageVersion uint16 = 0x0200
+
+	// PointSize is the size of a serialized (compressed) elliptic curve
+	// point in the message header.
+	PointSize = 33
+
+	// MessageHeaderSize is the size of a serialized message header:
+	// magic 3 bytes + version 2 bytes + message length 4 bytes + message
+	// time 8 bytes + expire 4 bytes + I, J, K points 3 * 33 bytes + nonce
+	// 8 bytes.
+	MessageHeaderSize = 128
+)
+
+// MessageMagic identifies the start of a serialized ciphrtxt message.
+var MessageMagic = [3]byte{'C', 'T', 'M'}
+
+// MessageHeader defines the fixed size header which precedes the ciphertext
+// of every ciphrtxt message.  All integers are serialized big endian.
+type MessageHeader struct {
+	// Version is the message format version.
+	Version uint16
+
+	// MsgLen is the total length of the message in bytes, including the
+	// header.
+	MsgLen uint32
+
+	// MsgTime is the time the message was created with microsecond
+	// precision.
+	MsgTime time.Time
+
+	// Expire is the lifetime of the message relative to MsgTime.  Its
+	// serialized precision is one second.
+	Expire time.Duration
+
+	// I, J and K are the ephemeral points used by recipients to detect
+	// messages addressed to them without decrypting the payload.
+	I [PointSize]byte
+	J [PointSize]byte
+	K [PointSize]byte
+
+	// Nonce is varied by the sender to satisfy the proof of work
+	// requirement on the header.
+	Nonce uint64
+}
+
+// ExpireTime returns the time at which the message expires.
+func (h *MessageHeader) ExpireTime() time.Time {
+	return h.MsgTime.Add(h.Expire)
+}
+
+// Serialize encodes the header to w.
+func (h *MessageHeader) Serialize(w io.Writer) error {
+	var buf [MessageHeaderSize]byte
+	h.serializeTo(buf[:])
+	_, err := w.Write(buf[:])
+	return err
+}
+
+// Bytes returns the serialized header.
+func (h *MessageHeader) Bytes() []byte {
+	buf := make([]byte, MessageHeaderSize)
+	h.serializeTo(buf)
+	return buf
+}
+
+// serializeTo encodes the header into buf, which must be at least
+// MessageHeaderSize bytes.
+func (h *MessageHeader) serializeTo(buf []byte) {
+	copy(buf[0:3], MessageMagic[:])
+	binary.BigEndian.PutUint16(buf[3:5], h.Version)
+	binary.BigEndian.PutUint32(buf[5:9], h.MsgLen)
+	usec := h.MsgTime.UnixNano() / int64(time.Microsecond)
+	binary.BigEndian.PutUint64(buf[9:17], uint64(usec))
+	binary.BigEndian.PutUint32(buf[17:21], uint32(h.Expire/time.Second))
+	copy(buf[21:54], h.I[:])
+	copy(buf[54:87], h.J[:])
+	copy(buf[87:120], h.K[:])
+	binary.BigEndian.PutUint64(buf[120:128], h.Nonce)
+}
+
+// Deserialize decodes a header from r into the receiver.  A RuleError with
+// ErrBadMagic is returned when the data does not begin with MessageMagic.
+func (h *MessageHeader) Deserialize(r io.Reader) error {
+	var buf [MessageHeaderSize]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return err
+	}
+	return h.FromBytes(buf[:])
+}
+
+// FromBytes decodes a serialized header into the receiver.
+func (h *MessageHeader) FromBytes(buf []byte) error {
+	if len(buf) < MessageHeaderSize {
+		str := fmt.Sprintf("message header is %d bytes, want %d",
+			len(buf), MessageHeaderSize)
+		return ruleError(ErrMessageTooSmall, str)
+	}
+	if buf[0] != MessageMagic[0] || buf[1] != MessageMagic[1] ||
+		buf[2] != MessageMagic[2] {
+
+		str := fmt.Sprintf("message has bad magic %x", buf[0:3])
+		return ruleError(ErrBadMagic, str)
+	}
+
+	h.Version = binary.BigEndian.Uint16(buf[3:5])
+	h.MsgLen = binary.BigEndian.Uint32(buf[5:9])
+	usec := int64(binary.BigEndian.Uint64(buf[9:17]))
+	h.MsgTime = time.Unix(0, usec*int64(time.Microsecond))
+	h.Expire = time.Duration(binary.BigEndian.Uint32(buf[17:21])) * time.Second
+	copy(h.I[:], buf[21:54])
+	copy(h.J[:], buf[54:87])
+	copy(h.K[:], buf[87:120])
+	h.Nonce = binary.BigEndian.Uint64(buf[120:128])
+	return nil
+}
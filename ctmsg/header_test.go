@@ -0,0 +1,84 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testHeader returns a well formed message header created at the passed time.
+func testHeader(now time.Time) *MessageHeader {
+	h := &MessageHeader{
+		Version: MessageVersion,
+		MsgLen:  MessageHeaderSize + 64,
+		MsgTime: now.Truncate(time.Microsecond),
+		Expire:  24 * time.Hour,
+		Nonce:   0x0102030405060708,
+	}
+	for i := 0; i < PointSize; i++ {
+		h.I[i] = byte(i)
+		h.J[i] = byte(i + 1)
+		h.K[i] = byte(i + 2)
+	}
+	return h
+}
+
+// TestMessageHeaderSerialize ensures message headers round trip through
+// serialization and that malformed headers are rejected.
+func TestMessageHeaderSerialize(t *testing.T) {
+	h := testHeader(time.Unix(1500000000, 123456000))
+
+	var buf bytes.Buffer
+	if err := h.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error %v", err)
+	}
+	if buf.Len() != MessageHeaderSize {
+		t.Fatalf("Serialize: wrong size - got %d, want %d", buf.Len(),
+			MessageHeaderSize)
+	}
+	if !bytes.Equal(buf.Bytes(), h.Bytes()) {
+		t.Fatalf("Bytes: mismatched serialization - got %x, want %x",
+			h.Bytes(), buf.Bytes())
+	}
+
+	var got MessageHeader
+	if err := got.Deserialize(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Deserialize: unexpected error %v", err)
+	}
+	if !got.MsgTime.Equal(h.MsgTime) {
+		t.Fatalf("Deserialize: wrong time - got %v, want %v",
+			got.MsgTime, h.MsgTime)
+	}
+	got.MsgTime = h.MsgTime
+	if !reflect.DeepEqual(&got, h) {
+		t.Fatalf("Deserialize: mismatched header - got %+v, want %+v",
+			got, h)
+	}
+
+	// A short header must fail to deserialize.
+	err := got.Deserialize(bytes.NewReader(buf.Bytes()[:10]))
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("Deserialize: wrong error for short header - got %v, "+
+			"want %v", err, io.ErrUnexpectedEOF)
+	}
+	err = got.FromBytes(buf.Bytes()[:10])
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrMessageTooSmall {
+		t.Fatalf("FromBytes: wrong error for short header - got %v, "+
+			"want %v", err, ErrMessageTooSmall)
+	}
+
+	// A header with the wrong magic must be rejected.
+	bad := h.Bytes()
+	bad[0] = 'X'
+	err = got.FromBytes(bad)
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrBadMagic {
+		t.Fatalf("FromBytes: wrong error for bad magic - got %v, "+
+			"want %v", err, ErrBadMagic)
+	}
+}
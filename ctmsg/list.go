@@ -0,0 +1,212 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrInvalidCursor is used to indicate that a listing cursor could not be
+// decoded.
+var ErrInvalidCursor = errors.New("ctmsg: invalid cursor")
+
+// ListCursor identifies a position in the ordered listing of stored
+// messages.  Messages are ordered by their timestamp and then by their hash.
+type ListCursor struct {
+	MsgTime time.Time
+	Hash    []byte
+}
+
+// String returns the cursor as an opaque string suitable for handing to
+// clients.  It is the hex encoding of the message time in nanoseconds since
+// the unix epoch (8 bytes big endian) followed by the message hash.
+func (c *ListCursor) String() string {
+	buf := make([]byte, 8+len(c.Hash))
+	binary.BigEndian.PutUint64(buf[:8], uint64(c.MsgTime.UnixNano()))
+	copy(buf[8:], c.Hash)
+	return hex.EncodeToString(buf)
+}
+
+// after returns whether the message with the passed time and hash is ordered
+// after the cursor.
+func (c *ListCursor) after(msgTime time.Time, hash []byte) bool {
+	if !msgTime.Equal(c.MsgTime) {
+		return msgTime.After(c.MsgTime)
+	}
+	return bytes.Compare(hash, c.Hash) > 0
+}
+
+// ParseListCursor decodes a cursor previously returned by ListCursor.String.
+func ParseListCursor(s string) (*ListCursor, error) {
+	buf, err := hex.DecodeString(s)
+	if err != nil || len(buf) <= 8 {
+		return nil, ErrInvalidCursor
+	}
+	return &ListCursor{
+		MsgTime: time.Unix(0, int64(binary.BigEndian.Uint64(buf[:8]))),
+		Hash:    buf[8:],
+	}, nil
+}
+
+// MessageList is a single page of a message listing.
+type MessageList struct {
+	// Total is the number of stored messages in the requested time range,
+	// independent of the limit.  Counting the messages requires visiting
+	// all of them, so it is only counted for the first page of a listing
+	// and is -1 when resuming a listing from a cursor.
+	Total int
+
+	// Hashes are the hashes of the listed messages in listing order.
+	Hashes [][]byte
+
+	// Next is the cursor from which to resume the listing, or nil when no
+	// messages remain.
+	Next *ListCursor
+}
+
+// ListMessages returns up to limit hashes of messages with a timestamp in the
+// range [since, until), ordered by timestamp and then hash.  When after is
+// non-nil only messages ordered after the cursor are returned.  A limit of
+// zero or less places no bound on the number of hashes returned.
+//
+// Stores which implement OrderedStore are iterated in listing order, so only
+// the messages up to the end of the page are visited.  Other stores require
+// reading and sorting every message in the range.
+func (ctms *CiphrtxtMsgSvc) ListMessages(since, until time.Time, after *ListCursor, limit int) (*MessageList, error) {
+	list := &MessageList{Total: -1}
+	if after == nil {
+		list.Total = 0
+	}
+	if !until.After(since) {
+		return list, nil
+	}
+
+	// Stop just short of the end of the range, as with ListHashes, so
+	// consecutive ranges never overlap.  Only the messages at or after the
+	// cursor need to be visited when resuming a listing.
+	end := until.Add(-time.Nanosecond)
+	start := since
+	if after != nil && after.MsgTime.After(since) {
+		if !until.After(after.MsgTime) {
+			return list, nil
+		}
+		start = after.MsgTime
+	}
+
+	if store, ok := ctms.MStore.(OrderedStore); ok {
+		if after == nil {
+			err := store.ForEachHash(since, end, func(uint64, []byte) bool {
+				list.Total++
+				return true
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		err := listOrdered(store, list, start, end, after, limit)
+		if err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+
+	err := ctms.listUnordered(list, start, end, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// listOrdered adds up to limit hashes of messages with a timestamp in the range
+// [start, end] which are ordered after the passed cursor, if any, to the
+// listing by iterating the store in listing order.
+func listOrdered(store OrderedStore, list *MessageList, start, end time.Time, after *ListCursor, limit int) error {
+	var afterUsec uint64
+	if after != nil {
+		afterUsec = timeKey(after.MsgTime)
+	}
+	var lastUsec uint64
+	err := store.ForEachHash(start, end, func(usec uint64, hash []byte) bool {
+		if after != nil && (usec < afterUsec || (usec == afterUsec &&
+			bytes.Compare(hash, after.Hash) <= 0)) {
+
+			return true
+		}
+
+		// Another message past the end of the page means the listing
+		// continues from the last message of the page.
+		if limit > 0 && len(list.Hashes) == limit {
+			list.Next = &ListCursor{
+				MsgTime: time.Unix(0, int64(lastUsec)*int64(time.Microsecond)),
+				Hash:    list.Hashes[limit-1],
+			}
+			return false
+		}
+		list.Hashes = append(list.Hashes, hash)
+		lastUsec = usec
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if list.Hashes == nil {
+		list.Hashes = [][]byte{}
+	}
+	return nil
+}
+
+// listUnordered adds up to limit hashes of messages with a timestamp in the
+// range [start, end] which are ordered after the passed cursor, if any, to the
+// listing.  Every message in the range is read and sorted, so it is only used
+// for stores which don't implement OrderedStore.
+func (ctms *CiphrtxtMsgSvc) listUnordered(list *MessageList, start, end time.Time, after *ListCursor, limit int) error {
+	hashes, err := ctms.MStore.ListHashesForInterval(start, end)
+	if err != nil {
+		return err
+	}
+	if after == nil {
+		list.Total = len(hashes)
+	}
+
+	msgs := make([]storedMessage, 0, len(hashes))
+	for _, hash := range hashes {
+		hdr, err := ctms.FetchHeader(hash)
+		if err != nil {
+			// The message was most likely pruned since the hashes
+			// were listed.
+			continue
+		}
+		if after != nil && !after.after(hdr.MsgTime, hash) {
+			continue
+		}
+		msgs = append(msgs, storedMessage{hash, hdr})
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		ti, tj := msgs[i].header.MsgTime, msgs[j].header.MsgTime
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return bytes.Compare(msgs[i].hash, msgs[j].hash) < 0
+	})
+
+	if limit > 0 && len(msgs) > limit {
+		msgs = msgs[:limit]
+		last := msgs[limit-1]
+		list.Next = &ListCursor{
+			MsgTime: last.header.MsgTime,
+			Hash:    last.hash,
+		}
+	}
+	list.Hashes = make([][]byte, len(msgs))
+	for i := range msgs {
+		list.Hashes[i] = msgs[i].hash
+	}
+	return nil
+}
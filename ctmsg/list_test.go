@@ -0,0 +1,189 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestListCursor ensures listing cursors round trip through their string
+// encoding, invalid cursors are rejected, and messages are ordered relative
+// to a cursor by time and then hash.
+func TestListCursor(t *testing.T) {
+	c := &ListCursor{
+		MsgTime: time.Unix(1500000000, 123456000),
+		Hash:    []byte{0x01, 0x02, 0x03, 0x04},
+	}
+
+	got, err := ParseListCursor(c.String())
+	if err != nil {
+		t.Fatalf("ParseListCursor: unexpected error %v", err)
+	}
+	if !got.MsgTime.Equal(c.MsgTime) || !bytes.Equal(got.Hash, c.Hash) {
+		t.Fatalf("ParseListCursor: mismatched cursor - got %v %x, "+
+			"want %v %x", got.MsgTime, got.Hash, c.MsgTime, c.Hash)
+	}
+
+	for _, s := range []string{"", "zz", "0102030405060708"} {
+		if _, err := ParseListCursor(s); err != ErrInvalidCursor {
+			t.Errorf("ParseListCursor(%q): wrong error - got %v, "+
+				"want %v", s, err, ErrInvalidCursor)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		time  time.Time
+		hash  []byte
+		after bool
+	}{
+		{"earlier time", c.MsgTime.Add(-time.Microsecond), c.Hash, false},
+		{"later time", c.MsgTime.Add(time.Microsecond), c.Hash, true},
+		{"same message", c.MsgTime, c.Hash, false},
+		{"lower hash", c.MsgTime, []byte{0x01, 0x02, 0x03, 0x03}, false},
+		{"higher hash", c.MsgTime, []byte{0x01, 0x02, 0x03, 0x05}, true},
+	}
+	for _, test := range tests {
+		if got := c.after(test.time, test.hash); got != test.after {
+			t.Errorf("%s: got %v, want %v", test.name, got,
+				test.after)
+		}
+	}
+}
+
+// countingStore is an ordered store which counts the messages visited while
+// iterating it.
+type countingStore struct {
+	*MemStore
+	visited int
+}
+
+// ForEachHash counts the messages visited by the iteration.
+func (s *countingStore) ForEachHash(start, end time.Time, fn func(usec uint64, hash []byte) bool) error {
+	return s.MemStore.ForEachHash(start, end, func(usec uint64, hash []byte) bool {
+		s.visited++
+		return fn(usec, hash)
+	})
+}
+
+// unorderedStore hides the OrderedStore implementation of a store.
+type unorderedStore struct {
+	Store
+}
+
+// TestListMessages ensures messages are listed in pages in order of time and
+// then hash for both ordered and unordered stores, and that resuming a listing
+// of an ordered store only visits the messages of the page.
+func TestListMessages(t *testing.T) {
+	const numMsgs = 10
+	const limit = 3
+
+	base := time.Unix(1500000000, 0)
+	recipient := testKey(0x77).PubKey()
+	var msgs [][]byte
+	for i := 0; i < numMsgs; i++ {
+		// Store pairs of messages with the same timestamp so ties are
+		// ordered by hash.
+		msg, err := NewMessage(recipient, []byte{byte(i)},
+			base.Add(time.Duration(i/2)*time.Second), time.Hour)
+		if err != nil {
+			t.Fatalf("NewMessage: unexpected error %v", err)
+		}
+		msgs = append(msgs, msg.Bytes())
+	}
+
+	counting := &countingStore{MemStore: NewMemStore()}
+	stores := []struct {
+		name  string
+		store Store
+	}{
+		{"ordered", counting},
+		{"unordered", unorderedStore{NewMemStore()}},
+	}
+	var listings [][][]byte
+	for _, test := range stores {
+		ctms, err := New(&Config{Store: test.store})
+		if err != nil {
+			t.Fatalf("%s: New: unexpected error %v", test.name, err)
+		}
+		for _, msg := range msgs {
+			if _, err := test.store.StoreMessage(msg); err != nil {
+				t.Fatalf("%s: StoreMessage: unexpected error %v",
+					test.name, err)
+			}
+		}
+
+		since, until := base, base.Add(time.Hour)
+		var listed [][]byte
+		var cursor *ListCursor
+		for page := 0; ; page++ {
+			counting.visited = 0
+			list, err := ctms.ListMessages(since, until, cursor, limit)
+			if err != nil {
+				t.Fatalf("%s: ListMessages: unexpected error %v",
+					test.name, err)
+			}
+			wantTotal := -1
+			if cursor == nil {
+				wantTotal = numMsgs
+			}
+			if list.Total != wantTotal {
+				t.Fatalf("%s: page %d: got total %d, want %d",
+					test.name, page, list.Total, wantTotal)
+			}
+			if len(list.Hashes) > limit {
+				t.Fatalf("%s: page %d: got %d hashes, want at most "+
+					"%d", test.name, page, len(list.Hashes), limit)
+			}
+
+			// Resumed pages of the ordered store only visit the
+			// two messages sharing the timestamp of the cursor, the
+			// page and the message following it.
+			if test.store == Store(counting) && cursor != nil &&
+				counting.visited > 2+limit+1 {
+
+				t.Fatalf("%s: page %d: visited %d messages",
+					test.name, page, counting.visited)
+			}
+
+			listed = append(listed, list.Hashes...)
+			if list.Next == nil {
+				break
+			}
+			cursor = list.Next
+		}
+		if len(listed) != numMsgs {
+			t.Fatalf("%s: listed %d messages, want %d", test.name,
+				len(listed), numMsgs)
+		}
+		for i := 1; i < len(listed); i++ {
+			prev, err := test.store.FetchHeader(listed[i-1])
+			if err != nil {
+				t.Fatalf("%s: FetchHeader: unexpected error %v",
+					test.name, err)
+			}
+			c := &ListCursor{MsgTime: prev.MsgTime, Hash: listed[i-1]}
+			hdr, err := test.store.FetchHeader(listed[i])
+			if err != nil {
+				t.Fatalf("%s: FetchHeader: unexpected error %v",
+					test.name, err)
+			}
+			if !c.after(hdr.MsgTime, listed[i]) {
+				t.Fatalf("%s: message %d is listed out of order",
+					test.name, i)
+			}
+		}
+		listings = append(listings, listed)
+	}
+
+	for i := range listings[0] {
+		if !bytes.Equal(listings[0][i], listings[1][i]) {
+			t.Fatalf("message %d: ordered and unordered listings "+
+				"differ", i)
+		}
+	}
+}
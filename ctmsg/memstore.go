@@ -0,0 +1,168 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// memStoreEntry is a message held by a MemStore.
+type memStoreEntry struct {
+	msg  []byte
+	usec uint64
+}
+
+// MemStore is a Store which keeps messages in memory.  It is intended for
+// tests and ephemeral nodes.  Messages are identified by MessageHash.
+type MemStore struct {
+	mtx  sync.RWMutex
+	msgs map[string]memStoreEntry
+}
+
+// Ensure MemStore implements the OrderedStore interface.
+var _ OrderedStore = (*MemStore)(nil)
+
+// NewMemStore returns an empty in-memory message store.
+func NewMemStore() *MemStore {
+	return &MemStore{msgs: make(map[string]memStoreEntry)}
+}
+
+// FetchMessage returns the serialized message identified by the passed hash.
+//
+// This is part of the Store interface.
+func (s *MemStore) FetchMessage(hash []byte) ([]byte, error) {
+	s.mtx.RLock()
+	entry, ok := s.msgs[string(hash)]
+	s.mtx.RUnlock()
+	if !ok {
+		return nil, ErrMessageNotFound
+	}
+
+	msg := make([]byte, len(entry.msg))
+	copy(msg, entry.msg)
+	return msg, nil
+}
+
+// FetchHeader returns the header of the message identified by the passed
+// hash.
+//
+// This is part of the Store interface.
+func (s *MemStore) FetchHeader(hash []byte) (*MessageHeader, error) {
+	s.mtx.RLock()
+	entry, ok := s.msgs[string(hash)]
+	s.mtx.RUnlock()
+	if !ok {
+		return nil, ErrMessageNotFound
+	}
+
+	var hdr MessageHeader
+	if err := hdr.FromBytes(entry.msg); err != nil {
+		return nil, err
+	}
+	return &hdr, nil
+}
+
+// StoreMessage adds the passed serialized message to the store.
+//
+// This is part of the Store interface.
+func (s *MemStore) StoreMessage(msg []byte) ([]byte, error) {
+	hdr, err := parseStoredMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := MessageHash(msg)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.msgs[string(hash)]; ok {
+		return hash, ErrDuplicateMessage
+	}
+	entry := memStoreEntry{
+		msg:  make([]byte, len(msg)),
+		usec: timeKey(hdr.MsgTime),
+	}
+	copy(entry.msg, msg)
+	s.msgs[string(hash)] = entry
+	return hash, nil
+}
+
+// ListHashesForInterval returns the hashes of the stored messages with a
+// timestamp in the range [start, end].
+//
+// This is part of the Store interface.
+func (s *MemStore) ListHashesForInterval(start, end time.Time) ([][]byte, error) {
+	if end.Before(start) {
+		return nil, nil
+	}
+
+	var hashes [][]byte
+	startKey, endKey := timeKey(start), timeKey(end)
+	s.mtx.RLock()
+	for hash, entry := range s.msgs {
+		if entry.usec >= startKey && entry.usec <= endKey {
+			hashes = append(hashes, []byte(hash))
+		}
+	}
+	s.mtx.RUnlock()
+	return hashes, nil
+}
+
+// ForEachHash calls fn with the timestamp and hash of each stored message with
+// a timestamp in the range [start, end] in order of timestamp and then hash.
+// The messages in the range are sorted on every call since the store is not
+// indexed by time.
+//
+// This is part of the OrderedStore interface.
+func (s *MemStore) ForEachHash(start, end time.Time, fn func(usec uint64, hash []byte) bool) error {
+	if end.Before(start) {
+		return nil
+	}
+
+	type orderedHash struct {
+		usec uint64
+		hash string
+	}
+	var ordered []orderedHash
+	startKey, endKey := timeKey(start), timeKey(end)
+	s.mtx.RLock()
+	for hash, entry := range s.msgs {
+		if entry.usec >= startKey && entry.usec <= endKey {
+			ordered = append(ordered, orderedHash{entry.usec, hash})
+		}
+	}
+	s.mtx.RUnlock()
+
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].usec != ordered[j].usec {
+			return ordered[i].usec < ordered[j].usec
+		}
+		return ordered[i].hash < ordered[j].hash
+	})
+	for _, o := range ordered {
+		if !fn(o.usec, []byte(o.hash)) {
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteMessage removes the message identified by the passed hash.
+//
+// This is part of the Store interface.
+func (s *MemStore) DeleteMessage(hash []byte) error {
+	s.mtx.Lock()
+	delete(s.msgs, string(hash))
+	s.mtx.Unlock()
+	return nil
+}
+
+// Close does nothing for an in-memory store.
+//
+// This is part of the Store interface.
+func (s *MemStore) Close() error {
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/btcec"
+)
+
+const (
+	// ciphertextKeyOffset is the offset of the ephemeral public key within
+	// a ciphertext produced by btcec.Encrypt.  It follows the 16 byte
+	// initialization vector.
+	ciphertextKeyOffset = 16
+
+	// minCiphertextSize is the size of the smallest ciphertext produced by
+	// btcec.Encrypt: IV 16 bytes + public key 70 bytes + one AES block 16
+	// bytes + HMAC-SHA-256 32 bytes.
+	minCiphertextSize = 16 + 70 + 16 + 32
+)
+
+// Message is a complete ciphrtxt message composed with the cttd encryption
+// scheme.  It serializes to the layout of a ctgo MessageFile: the fixed size
+// header immediately followed by the ciphertext.
+//
+// The cttd scheme is NOT the ctgo scheme.  Only the layout is shared.  The
+// payload is encrypted to the recipient with btcec.Encrypt and the header
+// points are derived as described below.  Neither has been checked against
+// ctgo, so ctgo clients can't decrypt these messages and this package can't
+// decrypt messages composed by ctgo.  Messages exchanged with ctgo clients must
+// still be composed and decrypted with ctgo.
+//
+// The header points let a recipient recognize messages addressed to it
+// without attempting decryption:
+//
+//	I = r*G            where r is a random ephemeral scalar
+//	J = H(I || r*P)*G  where P is the recipient public key
+//	K                  the ephemeral public key of the ciphertext
+//
+// H is SHA-256 interpreted as a scalar and all points are compressed.  The
+// recipient, holding the private key d, recomputes J from d*I.  K only has to
+// match the ciphertext, which anyone can copy, so it does not bind the header
+// to the ciphertext.  The proof of work commits to the ciphertext instead, as
+// described by PowHash.
+type Message struct {
+	Header     MessageHeader
+	Ciphertext []byte
+}
+
+// serializePoint returns the compressed encoding of the point (x, y).
+func serializePoint(x, y *big.Int) []byte {
+	pk := btcec.PublicKey{Curve: btcec.S256(), X: x, Y: y}
+	return pk.SerializeCompressed()
+}
+
+// recipientTag returns the J point for the ephemeral point I given the shared
+// point r*P = d*I.
+func recipientTag(i []byte, sx, sy *big.Int) [PointSize]byte {
+	curve := btcec.S256()
+	h := sha256.New()
+	h.Write(i)
+	h.Write(serializePoint(sx, sy))
+	t := new(big.Int).SetBytes(h.Sum(nil))
+	t.Mod(t, curve.N)
+
+	var tag [PointSize]byte
+	copy(tag[:], serializePoint(curve.ScalarBaseMult(t.Bytes())))
+	return tag
+}
+
+// ciphertextKey returns the compressed ephemeral public key embedded in a
+// ciphertext produced by btcec.Encrypt.
+func ciphertextKey(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < minCiphertextSize {
+		str := fmt.Sprintf("ciphertext is %d bytes, want at least %d",
+			len(ciphertext), minCiphertextSize)
+		return nil, ruleError(ErrBadCiphertext, str)
+	}
+
+	// The key is encoded as curve id (2) + X length (2) + X (32) +
+	// Y length (2) + Y (32).
+	key := ciphertext[ciphertextKeyOffset : ciphertextKeyOffset+70]
+	uncompressed := make([]byte, 65)
+	uncompressed[0] = 0x04
+	copy(uncompressed[1:33], key[4:36])
+	copy(uncompressed[33:], key[38:70])
+	pk, err := btcec.ParsePubKey(uncompressed, btcec.S256())
+	if err != nil {
+		str := fmt.Sprintf("ciphertext has invalid ephemeral key: %v",
+			err)
+		return nil, ruleError(ErrBadCiphertext, str)
+	}
+	return pk.SerializeCompressed(), nil
+}
+
+// NewMessage composes a message which carries plaintext encrypted to the
+// recipient public key with the cttd scheme, which ctgo clients can't decrypt.
+// The message is created at msgTime and expires after the passed duration.
+// The header nonce is left at zero, so callers which need to satisfy a proof
+// of work requirement must call SolveProofOfWork on the header and ciphertext
+// before serializing the message.
+func NewMessage(recipient *btcec.PublicKey, plaintext []byte, msgTime time.Time, expire time.Duration) (*Message, error) {
+	ciphertext, err := btcec.Encrypt(recipient, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) > math.MaxUint32-MessageHeaderSize {
+		str := fmt.Sprintf("message of %d bytes exceeds maximum %d",
+			len(ciphertext)+MessageHeaderSize, uint32(math.MaxUint32))
+		return nil, ruleError(ErrMessageTooLarge, str)
+	}
+	k, err := ciphertextKey(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeral, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	i := ephemeral.PubKey().SerializeCompressed()
+	sx, sy := btcec.S256().ScalarMult(recipient.X, recipient.Y,
+		ephemeral.D.Bytes())
+
+	msg := &Message{
+		Header: MessageHeader{
+			Version: MessageVersion,
+			MsgLen:  uint32(MessageHeaderSize + len(ciphertext)),
+			MsgTime: msgTime.Truncate(time.Microsecond),
+			Expire:  expire.Truncate(time.Second),
+			J:       recipientTag(i, sx, sy),
+		},
+		Ciphertext: ciphertext,
+	}
+	copy(msg.Header.I[:], i)
+	copy(msg.Header.K[:], k)
+	return msg, nil
+}
+
+// Serialize encodes the message to w.
+func (m *Message) Serialize(w io.Writer) error {
+	if err := m.Header.Serialize(w); err != nil {
+		return err
+	}
+	_, err := w.Write(m.Ciphertext)
+	return err
+}
+
+// Bytes returns the serialized message.
+func (m *Message) Bytes() []byte {
+	buf := make([]byte, MessageHeaderSize+len(m.Ciphertext))
+	m.Header.serializeTo(buf)
+	copy(buf[MessageHeaderSize:], m.Ciphertext)
+	return buf
+}
+
+// ParseMessage decodes a serialized message composed with the cttd scheme.  The
+// length of the data must match the message length in the header and the K
+// point must match the ephemeral key of the ciphertext, so messages composed by
+// ctgo are rejected.  Violations are returned as a RuleError.
+func ParseMessage(b []byte) (*Message, error) {
+	var msg Message
+	if err := msg.Header.FromBytes(b); err != nil {
+		return nil, err
+	}
+	if msg.Header.MsgLen < MessageHeaderSize {
+		str := fmt.Sprintf("message length %d is smaller than the %d "+
+			"byte header", msg.Header.MsgLen, MessageHeaderSize)
+		return nil, ruleError(ErrMessageTooSmall, str)
+	}
+	if uint64(len(b)) != uint64(msg.Header.MsgLen) {
+		str := fmt.Sprintf("message is %d bytes, header announces %d",
+			len(b), msg.Header.MsgLen)
+		return nil, ruleError(ErrBadMessageLength, str)
+	}
+
+	msg.Ciphertext = make([]byte, len(b)-MessageHeaderSize)
+	copy(msg.Ciphertext, b[MessageHeaderSize:])
+	k, err := ciphertextKey(msg.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(k, msg.Header.K[:]) {
+		str := fmt.Sprintf("header point K %x does not match ciphertext "+
+			"key %x", msg.Header.K, k)
+		return nil, ruleError(ErrBadCiphertext, str)
+	}
+	return &msg, nil
+}
+
+// IsAddressedTo returns whether the message was encrypted to the public key
+// of the passed private key.
+func (m *Message) IsAddressedTo(priv *btcec.PrivateKey) bool {
+	i, err := btcec.ParsePubKey(m.Header.I[:], btcec.S256())
+	if err != nil {
+		return false
+	}
+	sx, sy := btcec.S256().ScalarMult(i.X, i.Y, priv.D.Bytes())
+	return recipientTag(m.Header.I[:], sx, sy) == m.Header.J
+}
+
+// Decrypt returns the plaintext of the message, which must have been composed
+// with the cttd scheme, using the private key of the recipient.  A RuleError
+// with ErrNotRecipient is returned when the message is not addressed to the
+// key and ErrBadCiphertext when the ciphertext fails to decrypt.
+func (m *Message) Decrypt(priv *btcec.PrivateKey) ([]byte, error) {
+	if !m.IsAddressedTo(priv) {
+		return nil, ruleError(ErrNotRecipient, "message is not "+
+			"addressed to the private key")
+	}
+	plaintext, err := btcec.Decrypt(priv, m.Ciphertext)
+	if err != nil {
+		str := fmt.Sprintf("failed to decrypt message: %v", err)
+		return nil, ruleError(ErrBadCiphertext, str)
+	}
+	return plaintext, nil
+}
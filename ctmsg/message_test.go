@@ -0,0 +1,255 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/btcec"
+)
+
+// testKey returns a private key whose scalar is the passed byte repeated.
+func testKey(b byte) *btcec.PrivateKey {
+	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{b}, 32))
+	return priv
+}
+
+// hexToBytes converts the passed hex string into bytes and will panic if
+// there is an error.  This is only provided for the hard-coded constants so
+// errors in the source code can be detected.  It will only (and must only) be
+// called with hard-coded values.
+func hexToBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("invalid hex in source file: " + s)
+	}
+	return b
+}
+
+// messageVectors are serialized messages in the MessageFile layout (header
+// followed by ciphertext) along with the recipient key and plaintext.  They
+// were produced by this package and guard its serialization against
+// regressions.  They are not ctgo vectors since the encryption is not
+// interoperable with ctgo.
+var messageVectors = []struct {
+	name      string
+	key       *btcec.PrivateKey
+	plaintext string
+	version   uint16
+	msgTime   time.Time
+	expire    time.Duration
+	powBits   uint8
+	message   []byte
+}{
+	{
+		name:      "8 bit proof of work",
+		key:       testKey(0x11),
+		plaintext: "ciphrtxt test vector",
+		version:   MessageVersion,
+		msgTime:   time.Unix(1500000000, 123456000),
+		expire:    24 * time.Hour,
+		powBits:   8,
+		message: hexToBytes("43544d0201000001160005543df72ba24000015180" +
+			"036b286fbe0c9fdb031caf93dd75efa17fe7f25cafd92405d6a7bd95" +
+			"1a8d331eeb0385200ec61759ce6e8051e87e7b1232b2d89620a59def" +
+			"ebd936b47e17ab73fc1203bb14c6896d081bb9952bf31b912930f13c" +
+			"633fe7d59e7a7898b8b73272e7a3bc00000000000002567165d8bf68" +
+			"22acecf4a64a1ebfb4dd0402ca0020bb14c6896d081bb9952bf31b91" +
+			"2930f13c633fe7d59e7a7898b8b73272e7a3bc00207ece8d61d57eb3" +
+			"48ef994916247c26c9b72baaa87353925dce2f7bbe861b15672f48dc" +
+			"a8bb90976fb504a55d6cbef4bacdf1ccf9644049b8116fa1f88a7787" +
+			"66c5160dfb0830d3cafb53d39db3e91fdcb28a566437b63597ce4743" +
+			"23672d1f03"),
+	},
+	{
+		name:      "8 bit header proof of work",
+		key:       testKey(0x11),
+		plaintext: "ciphrtxt test vector",
+		version:   HeaderWorkMessageVersion,
+		msgTime:   time.Unix(1500000000, 123456000),
+		expire:    24 * time.Hour,
+		powBits:   8,
+		message: hexToBytes("43544d0200000001160005543df72ba24000015180" +
+			"036b286fbe0c9fdb031caf93dd75efa17fe7f25cafd92405d6a7bd95" +
+			"1a8d331eeb0385200ec61759ce6e8051e87e7b1232b2d89620a59def" +
+			"ebd936b47e17ab73fc1203bb14c6896d081bb9952bf31b912930f13c" +
+			"633fe7d59e7a7898b8b73272e7a3bc00000000000001607165d8bf68" +
+			"22acecf4a64a1ebfb4dd0402ca0020bb14c6896d081bb9952bf31b91" +
+			"2930f13c633fe7d59e7a7898b8b73272e7a3bc00207ece8d61d57eb3" +
+			"48ef994916247c26c9b72baaa87353925dce2f7bbe861b15672f48dc" +
+			"a8bb90976fb504a55d6cbef4bacdf1ccf9644049b8116fa1f88a7787" +
+			"66c5160dfb0830d3cafb53d39db3e91fdcb28a566437b63597ce4743" +
+			"23672d1f03"),
+	},
+}
+
+// TestMessageVectors ensures the serialized test vectors parse, carry the
+// expected header fields and decrypt to the expected plaintext.
+func TestMessageVectors(t *testing.T) {
+	for _, test := range messageVectors {
+		msg, err := ParseMessage(test.message)
+		if err != nil {
+			t.Errorf("%s: ParseMessage: unexpected error %v", test.name,
+				err)
+			continue
+		}
+		if !bytes.Equal(msg.Bytes(), test.message) {
+			t.Errorf("%s: Bytes: mismatched serialization - got %x, "+
+				"want %x", test.name, msg.Bytes(), test.message)
+			continue
+		}
+
+		hdr := &msg.Header
+		if hdr.Version != test.version ||
+			int(hdr.MsgLen) != len(test.message) ||
+			!hdr.MsgTime.Equal(test.msgTime) ||
+			hdr.Expire != test.expire {
+
+			t.Errorf("%s: unexpected header %+v", test.name, hdr)
+			continue
+		}
+		err = CheckProofOfWork(hdr, msg.Ciphertext, test.powBits)
+		if err != nil {
+			t.Errorf("%s: CheckProofOfWork: unexpected error %v",
+				test.name, err)
+			continue
+		}
+
+		plaintext, err := msg.Decrypt(test.key)
+		if err != nil {
+			t.Errorf("%s: Decrypt: unexpected error %v", test.name, err)
+			continue
+		}
+		if string(plaintext) != test.plaintext {
+			t.Errorf("%s: Decrypt: wrong plaintext - got %q, want %q",
+				test.name, plaintext, test.plaintext)
+		}
+	}
+}
+
+// TestMessageRoundTrip ensures composed messages survive serialization, are
+// only recognized and decrypted by the recipient and that tampering is
+// detected.
+func TestMessageRoundTrip(t *testing.T) {
+	recipient := testKey(0x22)
+	other := testKey(0x33)
+	plaintext := []byte("hello from a pure Go sender")
+	now := time.Unix(1500000000, 987654321)
+
+	msg, err := NewMessage(recipient.PubKey(), plaintext, now, time.Hour)
+	if err != nil {
+		t.Fatalf("NewMessage: unexpected error %v", err)
+	}
+	if !SolveProofOfWork(&msg.Header, msg.Ciphertext, 4, nil) {
+		t.Fatalf("SolveProofOfWork: failed to solve")
+	}
+	policy := DefaultMessagePolicy()
+	policy.PowBits = 4
+	if err := policy.CheckHeader(&msg.Header, now); err != nil {
+		t.Fatalf("CheckHeader: unexpected error %v", err)
+	}
+	err = policy.CheckProofOfWork(&msg.Header, msg.Ciphertext)
+	if err != nil {
+		t.Fatalf("CheckProofOfWork: unexpected error %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), msg.Bytes()) {
+		t.Fatalf("Bytes: mismatched serialization - got %x, want %x",
+			msg.Bytes(), buf.Bytes())
+	}
+	if int(msg.Header.MsgLen) != buf.Len() {
+		t.Fatalf("wrong message length - got %d, want %d",
+			msg.Header.MsgLen, buf.Len())
+	}
+
+	got, err := ParseMessage(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseMessage: unexpected error %v", err)
+	}
+	if !got.IsAddressedTo(recipient) {
+		t.Fatalf("IsAddressedTo: message not addressed to recipient")
+	}
+	if got.IsAddressedTo(other) {
+		t.Fatalf("IsAddressedTo: message addressed to other key")
+	}
+	decrypted, err := got.Decrypt(recipient)
+	if err != nil {
+		t.Fatalf("Decrypt: unexpected error %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("Decrypt: wrong plaintext - got %q, want %q", decrypted,
+			plaintext)
+	}
+
+	// Decrypting with the wrong key must be refused.
+	_, err = got.Decrypt(other)
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrNotRecipient {
+		t.Fatalf("Decrypt: wrong error for other key - got %v, want %v",
+			err, ErrNotRecipient)
+	}
+
+	// Malformed messages must be rejected with the expected error.
+	tests := []struct {
+		name   string
+		mutate func([]byte) []byte
+		code   ErrorCode
+	}{
+		{
+			name:   "truncated",
+			mutate: func(b []byte) []byte { return b[:len(b)-1] },
+			code:   ErrBadMessageLength,
+		},
+		{
+			name:   "trailing data",
+			mutate: func(b []byte) []byte { return append(b, 0x00) },
+			code:   ErrBadMessageLength,
+		},
+		{
+			name: "header only",
+			mutate: func(b []byte) []byte {
+				b = b[:MessageHeaderSize]
+				b[5], b[6], b[7], b[8] = 0, 0, 0, MessageHeaderSize
+				return b
+			},
+			code: ErrBadCiphertext,
+		},
+		{
+			name: "K does not match ciphertext",
+			mutate: func(b []byte) []byte {
+				copy(b[87:120], b[21:54])
+				return b
+			},
+			code: ErrBadCiphertext,
+		},
+	}
+	for _, test := range tests {
+		b := test.mutate(msg.Bytes())
+		_, err := ParseMessage(b)
+		if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != test.code {
+			t.Errorf("%s: ParseMessage: wrong error - got %v, want %v",
+				test.name, err, test.code)
+		}
+	}
+
+	// A corrupted ciphertext must fail authentication.
+	b := msg.Bytes()
+	b[len(b)-40] ^= 0xff
+	got, err = ParseMessage(b)
+	if err != nil {
+		t.Fatalf("ParseMessage: unexpected error %v", err)
+	}
+	_, err = got.Decrypt(recipient)
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrBadCiphertext {
+		t.Fatalf("Decrypt: wrong error for corrupted ciphertext - got "+
+			"%v, want %v", err, ErrBadCiphertext)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"fmt"
+)
+
+// NotificationType represents the type of a notification message.
+type NotificationType int
+
+// NotificationCallback is used for a caller to provide a callback for
+// notifications about various message service events.
+type NotificationCallback func(*Notification)
+
+// Constants for the type of a notification message.
+const (
+	// NTMessageAccepted indicates the associated message was accepted
+	// into the message store.
+	NTMessageAccepted NotificationType = iota
+)
+
+// notificationTypeStrings is a map of notification types back to their constant
+// names for pretty printing.
+var notificationTypeStrings = map[NotificationType]string{
+	NTMessageAccepted: "NTMessageAccepted",
+}
+
+// String returns the NotificationType in human-readable form.
+func (n NotificationType) String() string {
+	if s, ok := notificationTypeStrings[n]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown Notification Type (%d)", int(n))
+}
+
+// Notification defines notification that is sent to the caller via the
+// callbacks registered with Subscribe and consists of a notification type as
+// well as associated data that depends on the type as follows:
+// 	- NTMessageAccepted:   *AcceptedMessage
+type Notification struct {
+	Type NotificationType
+	Data interface{}
+}
+
+// AcceptedMessage describes a message which was accepted into the message
+// store.
+type AcceptedMessage struct {
+	Hash   []byte
+	Header *MessageHeader
+}
+
+// Subscribe to message service notifications. Registers a callback to be
+// executed when various events take place. See the documentation on
+// Notification and NotificationType for details on the types and contents of
+// notifications.
+func (ctms *CiphrtxtMsgSvc) Subscribe(callback NotificationCallback) {
+	ctms.notificationsLock.Lock()
+	ctms.notifications = append(ctms.notifications, callback)
+	ctms.notificationsLock.Unlock()
+}
+
+// sendNotification sends a notification with the passed type and data to all
+// subscribed callbacks.
+func (ctms *CiphrtxtMsgSvc) sendNotification(typ NotificationType, data interface{}) {
+	// Generate and send the notification.
+	n := Notification{Type: typ, Data: data}
+	ctms.notificationsLock.RLock()
+	for _, callback := range ctms.notifications {
+		callback(&n)
+	}
+	ctms.notificationsLock.RUnlock()
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultMaxMessageSize is the default maximum size of an accepted
+	// message, including the header.
+	DefaultMaxMessageSize = 1024 * 1024
+
+	// DefaultMinExpire is the default minimum lifetime of an accepted
+	// message.
+	DefaultMinExpire = time.Hour
+
+	// DefaultMaxExpire is the default maximum lifetime of an accepted
+	// message.
+	DefaultMaxExpire = 30 * 24 * time.Hour
+
+	// DefaultMaxTimeOffset is the default maximum amount of time a
+	// message timestamp may be ahead of the local clock.
+	DefaultMaxTimeOffset = 2 * time.Hour
+)
+
+// MessagePolicy houses the policy (configuration parameters) which is used to
+// decide whether a message is accepted into the message store.  Any field
+// left at its zero value is replaced by the corresponding default when the
+// message service is created.
+type MessagePolicy struct {
+	// MaxMessageSize is the maximum size in bytes of an accepted message,
+	// including the header.
+	MaxMessageSize uint32
+
+	// MinExpire is the minimum lifetime of an accepted message.
+	MinExpire time.Duration
+
+	// MaxExpire is the maximum lifetime of an accepted message.
+	MaxExpire time.Duration
+
+	// MaxTimeOffset is the maximum amount of time the timestamp of an
+	// accepted message may be ahead of the local clock.
+	MaxTimeOffset time.Duration
+
+	// PowBits is the number of leading zero bits required in the proof of
+	// work hash of the message as described by MessageHeader.PowHash.
+	// Unlike the other fields, zero is not replaced by
+	// a default and disables the proof of work requirement.
+	PowBits uint8
+}
+
+// DefaultMessagePolicy returns a message policy populated with the default
+// values.
+func DefaultMessagePolicy() MessagePolicy {
+	return MessagePolicy{
+		MaxMessageSize: DefaultMaxMessageSize,
+		MinExpire:      DefaultMinExpire,
+		MaxExpire:      DefaultMaxExpire,
+		MaxTimeOffset:  DefaultMaxTimeOffset,
+	}
+}
+
+// withDefaults returns a copy of the policy with all unset fields replaced by
+// their defaults.
+func (p MessagePolicy) withDefaults() MessagePolicy {
+	defaults := DefaultMessagePolicy()
+	if p.MaxMessageSize == 0 {
+		p.MaxMessageSize = defaults.MaxMessageSize
+	}
+	if p.MinExpire == 0 {
+		p.MinExpire = defaults.MinExpire
+	}
+	if p.MaxExpire == 0 {
+		p.MaxExpire = defaults.MaxExpire
+	}
+	if p.MaxTimeOffset == 0 {
+		p.MaxTimeOffset = defaults.MaxTimeOffset
+	}
+	return p
+}
+
+// CheckHeader performs checks on the message header to ensure it is
+// acceptable under the policy at the passed time.  It is intended to be run
+// before the body of the message is received so that unacceptable messages
+// can be refused cheaply.  The proof of work commits to the body of the
+// message, so it is checked separately by CheckProofOfWork once the body has
+// been received.
+func (p *MessagePolicy) CheckHeader(h *MessageHeader, now time.Time) error {
+	if h.Version != MessageVersion && h.Version != HeaderWorkMessageVersion {
+		str := fmt.Sprintf("message version %#04x is not supported",
+			h.Version)
+		return ruleError(ErrBadVersion, str)
+	}
+
+	if h.MsgLen < MessageHeaderSize {
+		str := fmt.Sprintf("message length %d is less than the header "+
+			"size %d", h.MsgLen, MessageHeaderSize)
+		return ruleError(ErrMessageTooSmall, str)
+	}
+	if h.MsgLen > p.MaxMessageSize {
+		str := fmt.Sprintf("message length %d exceeds the maximum "+
+			"allowed size %d", h.MsgLen, p.MaxMessageSize)
+		return ruleError(ErrMessageTooLarge, str)
+	}
+
+	if h.Expire < p.MinExpire || h.Expire > p.MaxExpire {
+		str := fmt.Sprintf("message lifetime %v is outside the allowed "+
+			"range [%v, %v]", h.Expire, p.MinExpire, p.MaxExpire)
+		return ruleError(ErrBadExpiry, str)
+	}
+
+	if h.MsgTime.After(now.Add(p.MaxTimeOffset)) {
+		str := fmt.Sprintf("message time %v is too far in the future",
+			h.MsgTime)
+		return ruleError(ErrTimeTooNew, str)
+	}
+	if !h.ExpireTime().After(now) {
+		str := fmt.Sprintf("message expired at %v", h.ExpireTime())
+		return ruleError(ErrExpired, str)
+	}
+	return nil
+}
+
+// CheckProofOfWork ensures the message with the passed header and ciphertext
+// satisfies the proof of work requirement of the policy.
+func (p *MessagePolicy) CheckProofOfWork(h *MessageHeader, ciphertext []byte) error {
+	return CheckProofOfWork(h, ciphertext, p.PowBits)
+}
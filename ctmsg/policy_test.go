@@ -0,0 +1,143 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCheckHeader ensures message headers are accepted or rejected according
+// to the message policy.
+func TestCheckHeader(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	policy := DefaultMessagePolicy()
+
+	tests := []struct {
+		name   string
+		modify func(h *MessageHeader)
+		code   ErrorCode
+		valid  bool
+	}{
+		{
+			name:   "valid header",
+			modify: func(h *MessageHeader) {},
+			valid:  true,
+		},
+		{
+			name: "header work version",
+			modify: func(h *MessageHeader) {
+				h.Version = HeaderWorkMessageVersion
+			},
+			valid: true,
+		},
+		{
+			name:   "unsupported version",
+			modify: func(h *MessageHeader) { h.Version = 0x0100 },
+			code:   ErrBadVersion,
+		},
+		{
+			name:   "shorter than header",
+			modify: func(h *MessageHeader) { h.MsgLen = MessageHeaderSize - 1 },
+			code:   ErrMessageTooSmall,
+		},
+		{
+			name:   "header only",
+			modify: func(h *MessageHeader) { h.MsgLen = MessageHeaderSize },
+			valid:  true,
+		},
+		{
+			name: "maximum size",
+			modify: func(h *MessageHeader) {
+				h.MsgLen = policy.MaxMessageSize
+			},
+			valid: true,
+		},
+		{
+			name: "too large",
+			modify: func(h *MessageHeader) {
+				h.MsgLen = policy.MaxMessageSize + 1
+			},
+			code: ErrMessageTooLarge,
+		},
+		{
+			name: "lifetime too short",
+			modify: func(h *MessageHeader) {
+				h.Expire = policy.MinExpire - time.Second
+			},
+			code: ErrBadExpiry,
+		},
+		{
+			name: "lifetime too long",
+			modify: func(h *MessageHeader) {
+				h.Expire = policy.MaxExpire + time.Second
+			},
+			code: ErrBadExpiry,
+		},
+		{
+			name: "time too far in future",
+			modify: func(h *MessageHeader) {
+				h.MsgTime = now.Add(policy.MaxTimeOffset + time.Second)
+			},
+			code: ErrTimeTooNew,
+		},
+		{
+			name: "time slightly in future",
+			modify: func(h *MessageHeader) {
+				h.MsgTime = now.Add(policy.MaxTimeOffset)
+			},
+			valid: true,
+		},
+		{
+			name: "expired",
+			modify: func(h *MessageHeader) {
+				h.MsgTime = now.Add(-h.Expire)
+			},
+			code: ErrExpired,
+		},
+	}
+
+	for _, test := range tests {
+		h := testHeader(now)
+		test.modify(h)
+		err := policy.CheckHeader(h, now)
+		if test.valid {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", test.name, err)
+			}
+			continue
+		}
+		rerr, ok := err.(RuleError)
+		if !ok {
+			t.Errorf("%s: expected RuleError, got %v", test.name, err)
+			continue
+		}
+		if rerr.ErrorCode != test.code {
+			t.Errorf("%s: wrong error code - got %v, want %v",
+				test.name, rerr.ErrorCode, test.code)
+		}
+	}
+}
+
+// TestMessagePolicyDefaults ensures unset policy fields are replaced by their
+// defaults while explicitly set fields are preserved.
+func TestMessagePolicyDefaults(t *testing.T) {
+	p := MessagePolicy{MaxMessageSize: 4096}.withDefaults()
+	if p.MaxMessageSize != 4096 {
+		t.Errorf("MaxMessageSize: got %d, want %d", p.MaxMessageSize, 4096)
+	}
+	if p.MinExpire != DefaultMinExpire {
+		t.Errorf("MinExpire: got %v, want %v", p.MinExpire,
+			DefaultMinExpire)
+	}
+	if p.MaxExpire != DefaultMaxExpire {
+		t.Errorf("MaxExpire: got %v, want %v", p.MaxExpire,
+			DefaultMaxExpire)
+	}
+	if p.MaxTimeOffset != DefaultMaxTimeOffset {
+		t.Errorf("MaxTimeOffset: got %v, want %v", p.MaxTimeOffset,
+			DefaultMaxTimeOffset)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+)
+
+// powPreimageSize is the size of the data hashed for the proof of work of a
+// MessageVersion message: the serialized header followed by the SHA-256 hash of
+// the ciphertext.
+const powPreimageSize = MessageHeaderSize + sha256.Size
+
+// powPreimage returns the data hashed for the proof of work of a message with
+// the passed header and ciphertext.  Committing to the ciphertext prevents a
+// solved header from being reused for a different message body, while keeping
+// the cost of each attempt independent of the message size.  Messages of the
+// HeaderWorkMessageVersion only commit to the header.
+func powPreimage(h *MessageHeader, ciphertext []byte) []byte {
+	if h.Version == HeaderWorkMessageVersion {
+		return h.Bytes()
+	}
+
+	buf := make([]byte, powPreimageSize)
+	h.serializeTo(buf)
+	ciphertextHash := sha256.Sum256(ciphertext)
+	copy(buf[MessageHeaderSize:], ciphertextHash[:])
+	return buf
+}
+
+// PowHash returns the hash which is used to verify the proof of work of the
+// message: the double SHA-256 hash of the serialized header followed by the
+// SHA-256 hash of the passed ciphertext.  The ciphertext is ignored for
+// messages of the HeaderWorkMessageVersion, whose proof of work hash is the
+// double SHA-256 hash of the serialized header alone.
+func (h *MessageHeader) PowHash(ciphertext []byte) chainhash.Hash {
+	return chainhash.DoubleHashH(powPreimage(h, ciphertext))
+}
+
+// leadingZeroBits returns the number of leading zero bits of the hash when
+// interpreted as a big endian bit string, in the manner of hashcash.
+func leadingZeroBits(hash *chainhash.Hash) int {
+	n := 0
+	for _, b := range hash {
+		if b != 0 {
+			for b&0x80 == 0 {
+				n++
+				b <<= 1
+			}
+			return n
+		}
+		n += 8
+	}
+	return n
+}
+
+// CheckProofOfWork ensures the proof of work hash of the message with the
+// passed header and ciphertext, as described by PowHash, has at least the
+// passed number of leading zero bits.
+func CheckProofOfWork(h *MessageHeader, ciphertext []byte, powBits uint8) error {
+	if powBits == 0 {
+		return nil
+	}
+
+	hash := h.PowHash(ciphertext)
+	if zeros := leadingZeroBits(&hash); zeros < int(powBits) {
+		str := fmt.Sprintf("message proof of work hash %v has %d leading "+
+			"zero bits, want at least %d", hash, zeros, powBits)
+		return ruleError(ErrInsufficientWork, str)
+	}
+	return nil
+}
+
+// SolveProofOfWork searches for a nonce which satisfies the passed proof of
+// work requirement for the message with the passed header and ciphertext and
+// stores it in the header.  The search is aborted and false returned when the
+// quit channel is closed or the nonce space is exhausted.
+func SolveProofOfWork(h *MessageHeader, ciphertext []byte, powBits uint8, quit <-chan struct{}) bool {
+	buf := powPreimage(h, ciphertext)
+	nonceBytes := buf[MessageHeaderSize-8 : MessageHeaderSize]
+
+	for nonce := uint64(0); ; nonce++ {
+		// Check for a request to quit periodically so solving can be
+		// interrupted without slowing down the search.
+		if nonce&0xffff == 0 {
+			select {
+			case <-quit:
+				return false
+			default:
+			}
+		}
+
+		binary.BigEndian.PutUint64(nonceBytes, nonce)
+		hash := chainhash.DoubleHashH(buf)
+		if leadingZeroBits(&hash) >= int(powBits) {
+			h.Nonce = nonce
+			return true
+		}
+
+		if nonce == math.MaxUint64 {
+			return false
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+)
+
+// TestLeadingZeroBits ensures leading zero bits are counted from the first
+// byte of the hash.
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		name  string
+		bytes []byte
+		want  int
+	}{
+		{"high bit set", []byte{0x80}, 0},
+		{"one zero bit", []byte{0x40}, 1},
+		{"one zero byte", []byte{0x00, 0xff}, 8},
+		{"mixed", []byte{0x00, 0x00, 0x0f}, 20},
+		{"all zero", nil, chainhash.HashSize * 8},
+	}
+
+	for _, test := range tests {
+		var hash chainhash.Hash
+		copy(hash[:], test.bytes)
+		if got := leadingZeroBits(&hash); got != test.want {
+			t.Errorf("%s: wrong count - got %d, want %d", test.name,
+				got, test.want)
+		}
+	}
+}
+
+// TestProofOfWork ensures solved messages satisfy the proof of work check and
+// messages with too little work are rejected.
+func TestProofOfWork(t *testing.T) {
+	const powBits = 12
+
+	h := testHeader(time.Unix(1500000000, 0))
+	ciphertext := []byte("ciphertext")
+	if !SolveProofOfWork(h, ciphertext, powBits, nil) {
+		t.Fatal("SolveProofOfWork: failed to find a solution")
+	}
+	if err := CheckProofOfWork(h, ciphertext, powBits); err != nil {
+		t.Fatalf("CheckProofOfWork: unexpected error %v", err)
+	}
+
+	// Require one more bit than the solution provides.
+	hash := h.PowHash(ciphertext)
+	zeros := leadingZeroBits(&hash)
+	err := CheckProofOfWork(h, ciphertext, uint8(zeros+1))
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrInsufficientWork {
+		t.Fatalf("CheckProofOfWork: wrong error - got %v, want %v", err,
+			ErrInsufficientWork)
+	}
+
+	// The policy must enforce the requirement.
+	policy := DefaultMessagePolicy()
+	policy.PowBits = uint8(zeros + 1)
+	err = policy.CheckProofOfWork(h, ciphertext)
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrInsufficientWork {
+		t.Fatalf("CheckProofOfWork: wrong error - got %v, want %v", err,
+			ErrInsufficientWork)
+	}
+
+	// A closed quit channel must abort the search.
+	quit := make(chan struct{})
+	close(quit)
+	if SolveProofOfWork(h, ciphertext, 255, quit) {
+		t.Fatal("SolveProofOfWork: solved despite quit request")
+	}
+}
+
+// TestProofOfWorkReuse ensures a solved header can't be reused for a message
+// with a different body, both by the proof of work check and at ingestion.
+func TestProofOfWorkReuse(t *testing.T) {
+	const powBits = 16
+
+	now := time.Now()
+	recipient := testKey(0x55).PubKey()
+	msg, err := NewMessage(recipient, []byte("original"), now, time.Hour)
+	if err != nil {
+		t.Fatalf("NewMessage: unexpected error %v", err)
+	}
+	if !SolveProofOfWork(&msg.Header, msg.Ciphertext, powBits, nil) {
+		t.Fatal("SolveProofOfWork: failed to find a solution")
+	}
+
+	// Replace the body while keeping the solved header, which remains
+	// consistent with the new ciphertext otherwise.
+	reused := *msg
+	reused.Ciphertext = append([]byte{}, msg.Ciphertext...)
+	reused.Ciphertext[len(reused.Ciphertext)-1] ^= 0x01
+	err = CheckProofOfWork(&reused.Header, reused.Ciphertext, powBits)
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrInsufficientWork {
+		t.Fatalf("CheckProofOfWork: wrong error for reused header - "+
+			"got %v, want %v", err, ErrInsufficientWork)
+	}
+
+	policy := DefaultMessagePolicy()
+	policy.PowBits = powBits
+	ctms, err := New(&Config{Store: NewMemStore(), Policy: policy})
+	if err != nil {
+		t.Fatalf("New: unexpected error %v", err)
+	}
+	defer ctms.MStore.Close()
+
+	_, err = ctms.IngestMessage(bytes.NewReader(reused.Bytes()))
+	if rerr, ok := err.(RuleError); !ok || rerr.ErrorCode != ErrInsufficientWork {
+		t.Fatalf("IngestMessage: wrong error for reused header - got "+
+			"%v, want %v", err, ErrInsufficientWork)
+	}
+	if _, err := ctms.IngestMessage(bytes.NewReader(msg.Bytes())); err != nil {
+		t.Fatalf("IngestMessage: unexpected error %v", err)
+	}
+}
+
+// TestHeaderWorkVersion ensures the proof of work of messages of the
+// HeaderWorkMessageVersion only covers the header while the proof of work of
+// messages of the current version also covers the ciphertext.
+func TestHeaderWorkVersion(t *testing.T) {
+	ciphertext := []byte("ciphertext")
+	modified := []byte("Ciphertext")
+
+	h := testHeader(time.Unix(1500000000, 0))
+	h.Version = HeaderWorkMessageVersion
+	if h.PowHash(ciphertext) != chainhash.DoubleHashH(h.Bytes()) {
+		t.Fatal("PowHash: header work hash does not match the header " +
+			"hash")
+	}
+	if h.PowHash(ciphertext) != h.PowHash(modified) {
+		t.Fatal("PowHash: header work hash depends on the ciphertext")
+	}
+
+	h.Version = MessageVersion
+	if h.PowHash(ciphertext) == h.PowHash(modified) {
+		t.Fatal("PowHash: hash does not depend on the ciphertext")
+	}
+}
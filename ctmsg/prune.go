@@ -0,0 +1,224 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultPruneInterval is the default amount of time between runs of
+	// the message pruner.
+	DefaultPruneInterval = 10 * time.Minute
+)
+
+// storedMessage describes a message in the message store for the purposes of
+// pruning.
+type storedMessage struct {
+	hash   []byte
+	header *MessageHeader
+}
+
+// PruneStats describes the messages evicted by a single run of the pruner.
+type PruneStats struct {
+	// Expired is the number of messages evicted because they expired.
+	Expired int
+
+	// Retention is the number of messages evicted because they were older
+	// than the retention window.
+	Retention int
+
+	// Quota is the number of messages evicted to bring the message store
+	// below its size quota.
+	Quota int
+
+	// Bytes is the total size of all evicted messages.
+	Bytes int64
+
+	// Remaining is the number of messages left in the store.
+	Remaining int
+
+	// RemainingBytes is the total size of the messages left in the store.
+	RemainingBytes int64
+}
+
+// Evicted returns the total number of messages evicted.
+func (s *PruneStats) Evicted() int {
+	return s.Expired + s.Retention + s.Quota
+}
+
+// checkMessageAge ensures the message with the passed header is not older than
+// the messages kept in the store at the passed time, so messages which would
+// be evicted by the next run of the pruner are refused instead of being
+// stored and fetched from peers over and over again.
+func (ctms *CiphrtxtMsgSvc) checkMessageAge(h *MessageHeader, now time.Time) error {
+	if ctms.retention > 0 && h.MsgTime.Before(now.Add(-ctms.retention)) {
+		str := fmt.Sprintf("message time %v is older than the retention "+
+			"window of %v", h.MsgTime, ctms.retention)
+		return ruleError(ErrTimeTooOld, str)
+	}
+
+	ctms.cutoffLock.Lock()
+	cutoff := ctms.quotaCutoff
+	ctms.cutoffLock.Unlock()
+	if !cutoff.IsZero() && !h.MsgTime.After(cutoff) {
+		str := fmt.Sprintf("message time %v is not newer than the "+
+			"messages evicted to stay within the store size quota "+
+			"(%v)", h.MsgTime, cutoff)
+		return ruleError(ErrTimeTooOld, str)
+	}
+	return nil
+}
+
+// removeMessage deletes the message identified by hash from the message store.
+func (ctms *CiphrtxtMsgSvc) removeMessage(hash []byte) error {
+	ctms.ingestLock.Lock()
+	defer ctms.ingestLock.Unlock()
+	return ctms.MStore.DeleteMessage(hash)
+}
+
+// PruneMessages evicts every message which has expired as of the passed time
+// or which is older than the retention window, followed by the oldest
+// remaining messages until the store is within its size quota.  Messages
+// which can not be read back from the store are left alone and retried on the
+// next run.  Messages no newer than those evicted to stay within the quota are
+// refused from then on.
+func (ctms *CiphrtxtMsgSvc) PruneMessages(now time.Time) (*PruneStats, error) {
+	// Messages may be stamped up to MaxTimeOffset in the future, so make
+	// sure they are included in the listing.  The messages stored from
+	// now on are collected so the totals of the store can be refreshed
+	// from the messages which are kept once done.
+	ctms.ingestLock.Lock()
+	hashes, err := ctms.MStore.ListHashesForInterval(time.Unix(0, 0),
+		now.Add(ctms.policy.MaxTimeOffset))
+	if err == nil {
+		ctms.pruning = true
+		ctms.storedWhilePruning = nil
+	}
+	ctms.ingestLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var stats PruneStats
+	var cutoff time.Time
+	if ctms.retention > 0 {
+		cutoff = now.Add(-ctms.retention)
+	}
+	kept := make([]storedMessage, 0, len(hashes))
+	var failed []*MessageHeader
+	for _, hash := range hashes {
+		hdr, err := ctms.FetchHeader(hash)
+		if err != nil {
+			log.Debugf("Unable to read header of message %x: %v",
+				hash, err)
+			continue
+		}
+
+		var counter *int
+		switch {
+		case !hdr.ExpireTime().After(now):
+			counter = &stats.Expired
+		case !cutoff.IsZero() && hdr.MsgTime.Before(cutoff):
+			counter = &stats.Retention
+		default:
+			kept = append(kept, storedMessage{hash, hdr})
+			stats.RemainingBytes += int64(hdr.MsgLen)
+			continue
+		}
+
+		if err := ctms.removeMessage(hash); err != nil {
+			log.Warnf("Unable to remove message %x: %v", hash, err)
+			failed = append(failed, hdr)
+			continue
+		}
+		*counter++
+		stats.Bytes += int64(hdr.MsgLen)
+	}
+
+	// Evict the oldest messages until the store fits within the quota.
+	if ctms.maxStoreSize > 0 && stats.RemainingBytes > ctms.maxStoreSize {
+		sort.Slice(kept, func(i, j int) bool {
+			return kept[i].header.MsgTime.Before(kept[j].header.MsgTime)
+		})
+		for len(kept) > 0 && stats.RemainingBytes > ctms.maxStoreSize {
+			m := kept[0]
+			kept = kept[1:]
+			if err := ctms.removeMessage(m.hash); err != nil {
+				log.Warnf("Unable to remove message %x: %v",
+					m.hash, err)
+				failed = append(failed, m.header)
+				continue
+			}
+			stats.Quota++
+			stats.Bytes += int64(m.header.MsgLen)
+			stats.RemainingBytes -= int64(m.header.MsgLen)
+
+			ctms.cutoffLock.Lock()
+			if m.header.MsgTime.After(ctms.quotaCutoff) {
+				ctms.quotaCutoff = m.header.MsgTime
+			}
+			ctms.cutoffLock.Unlock()
+		}
+	}
+	stats.Remaining = len(kept)
+
+	// Refresh the totals from the messages left in the store, including
+	// those which failed to be removed and those stored in the meantime.
+	ctms.ingestLock.Lock()
+	var totals storeTotals
+	for _, m := range kept {
+		totals.add(m.header)
+	}
+	for _, hdr := range failed {
+		totals.add(hdr)
+	}
+	for _, hdr := range ctms.storedWhilePruning {
+		totals.add(hdr)
+	}
+	ctms.totals = totals
+	ctms.pruning = false
+	ctms.storedWhilePruning = nil
+	ctms.ingestLock.Unlock()
+
+	return &stats, nil
+}
+
+// pruneHandler periodically evicts expired and excess messages from the
+// message store.  It must be run as a goroutine.
+func (ctms *CiphrtxtMsgSvc) pruneHandler() {
+	ticker := time.NewTicker(ctms.pruneInterval)
+	defer ticker.Stop()
+
+out:
+	for {
+		// Prune immediately on startup so a node which has been offline
+		// for a while does not serve stale messages.
+		stats, err := ctms.PruneMessages(time.Now())
+		if err != nil {
+			log.Errorf("Unable to prune message store: %v", err)
+		} else if stats.Evicted() > 0 {
+			log.Infof("Pruned %d messages (%d expired, %d past "+
+				"retention, %d over quota) freeing %d bytes; %d "+
+				"messages (%d bytes) remain", stats.Evicted(),
+				stats.Expired, stats.Retention, stats.Quota,
+				stats.Bytes, stats.Remaining, stats.RemainingBytes)
+		} else {
+			log.Debugf("No messages pruned; %d messages (%d bytes) "+
+				"in store", stats.Remaining, stats.RemainingBytes)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctms.quit:
+			break out
+		}
+	}
+
+	ctms.wg.Done()
+	log.Trace("Message prune handler done")
+}
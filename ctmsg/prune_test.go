@@ -0,0 +1,125 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestPruneMessages ensures expired messages, messages older than the
+// retention window and the oldest messages over the store size quota are
+// evicted, and that such messages are refused afterwards instead of being
+// stored again.
+func TestPruneMessages(t *testing.T) {
+	now := time.Now()
+	recipient := testKey(0x66).PubKey()
+	newMessage := func(age, expire time.Duration) *Message {
+		t.Helper()
+		msg, err := NewMessage(recipient, []byte("prune"), now.Add(-age),
+			expire)
+		if err != nil {
+			t.Fatalf("NewMessage: unexpected error %v", err)
+		}
+		return msg
+	}
+
+	expired := newMessage(2*time.Hour, time.Hour)
+	old := newMessage(48*time.Hour, 72*time.Hour)
+	fresh := []*Message{
+		newMessage(3*time.Hour, 24*time.Hour),
+		newMessage(2*time.Hour, 24*time.Hour),
+		newMessage(time.Hour, 24*time.Hour),
+	}
+	msgSize := int64(fresh[0].Header.MsgLen)
+
+	ctms, err := New(&Config{
+		Store:           NewMemStore(),
+		RetentionWindow: 24 * time.Hour,
+		MaxStoreSize:    2 * msgSize,
+	})
+	if err != nil {
+		t.Fatalf("New: unexpected error %v", err)
+	}
+	defer ctms.MStore.Close()
+
+	for _, msg := range append([]*Message{expired, old}, fresh...) {
+		if _, err := ctms.MStore.StoreMessage(msg.Bytes()); err != nil {
+			t.Fatalf("StoreMessage: unexpected error %v", err)
+		}
+	}
+
+	stats, err := ctms.PruneMessages(now)
+	if err != nil {
+		t.Fatalf("PruneMessages: unexpected error %v", err)
+	}
+	want := PruneStats{
+		Expired:        1,
+		Retention:      1,
+		Quota:          1,
+		Bytes:          int64(expired.Header.MsgLen+old.Header.MsgLen) + msgSize,
+		Remaining:      2,
+		RemainingBytes: 2 * msgSize,
+	}
+	if *stats != want {
+		t.Fatalf("PruneMessages: got stats %+v, want %+v", *stats, want)
+	}
+
+	// The oldest message within the retention window was evicted to stay
+	// within the quota.
+	for i, msg := range fresh {
+		have := ctms.HaveMessage(MessageHash(msg.Bytes()))
+		if wantHave := i > 0; have != wantHave {
+			t.Fatalf("fresh message %d: stored %v, want %v", i, have,
+				wantHave)
+		}
+	}
+
+	// Pruning again evicts nothing.
+	stats, err = ctms.PruneMessages(now)
+	if err != nil {
+		t.Fatalf("PruneMessages: unexpected error %v", err)
+	}
+	if stats.Evicted() != 0 || stats.Remaining != 2 {
+		t.Fatalf("PruneMessages: got stats %+v on second run, want no "+
+			"evictions", *stats)
+	}
+
+	// Messages older than the retention window or no newer than the
+	// messages evicted for the quota are refused.
+	tests := []struct {
+		name string
+		msg  *Message
+		code ErrorCode
+		ok   bool
+	}{
+		{"past retention", old, ErrTimeTooOld, false},
+		{"evicted for quota", fresh[0], ErrTimeTooOld, false},
+		{"older than quota cutoff", newMessage(4*time.Hour, 24*time.Hour),
+			ErrTimeTooOld, false},
+		{"newer than quota cutoff", newMessage(30*time.Minute,
+			24*time.Hour), 0, true},
+	}
+	for _, test := range tests {
+		hash, err := ctms.IngestMessage(bytes.NewReader(test.msg.Bytes()))
+		if test.ok {
+			if err != nil {
+				t.Fatalf("%s: IngestMessage: unexpected error %v",
+					test.name, err)
+			}
+			if !bytes.Equal(hash, MessageHash(test.msg.Bytes())) {
+				t.Fatalf("%s: IngestMessage: wrong hash %x",
+					test.name, hash)
+			}
+			continue
+		}
+		rerr, ok := err.(RuleError)
+		if !ok || rerr.ErrorCode != test.code {
+			t.Fatalf("%s: IngestMessage: wrong error - got %v, want "+
+				"%v", test.name, err, test.code)
+		}
+	}
+}
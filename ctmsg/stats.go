@@ -0,0 +1,255 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// IngestRateWindow is the period over which the recent ingestion rate
+	// of the message service is measured.
+	IngestRateWindow = time.Hour
+
+	// ingestRateBuckets is the number of buckets the accepted messages
+	// within IngestRateWindow are counted in.
+	ingestRateBuckets = 60
+
+	// ingestRateBucketSize is the period covered by each bucket.
+	ingestRateBucketSize = IngestRateWindow / ingestRateBuckets
+)
+
+// StoreStats describes the contents and limits of the message store.
+type StoreStats struct {
+	// Messages is the number of stored messages.
+	Messages int
+
+	// Bytes is the total size of the stored messages.
+	Bytes int64
+
+	// Oldest and Newest are the timestamps of the oldest and newest
+	// stored messages.  They are the zero time when the store is empty.
+	Oldest time.Time
+	Newest time.Time
+
+	// RetentionWindow is the maximum age of a stored message.  Zero means
+	// messages are kept until they expire.
+	RetentionWindow time.Duration
+
+	// MaxStoreSize is the maximum total size of the stored messages.  Zero
+	// means the store size is not limited.
+	MaxStoreSize int64
+}
+
+// storeTotals tracks the number, size and time range of the stored messages.
+type storeTotals struct {
+	messages int
+	bytes    int64
+	oldest   time.Time
+	newest   time.Time
+}
+
+// add accounts for a message with the passed header.
+func (t *storeTotals) add(h *MessageHeader) {
+	t.messages++
+	t.bytes += int64(h.MsgLen)
+	if t.oldest.IsZero() || h.MsgTime.Before(t.oldest) {
+		t.oldest = h.MsgTime
+	}
+	if h.MsgTime.After(t.newest) {
+		t.newest = h.MsgTime
+	}
+}
+
+// countStored accounts for a message which was just added to the store.
+//
+// This function MUST be called with the ingest lock held.
+func (ctms *CiphrtxtMsgSvc) countStored(h *MessageHeader) {
+	ctms.totals.add(h)
+	if ctms.pruning {
+		ctms.storedWhilePruning = append(ctms.storedWhilePruning, h)
+	}
+}
+
+// scanStoreTotals returns the totals of the messages held in the message
+// store.  Messages which can not be read back from the store are not counted.
+func (ctms *CiphrtxtMsgSvc) scanStoreTotals() (storeTotals, error) {
+	var totals storeTotals
+	hashes, err := ctms.MStore.ListHashesForInterval(time.Unix(0, 0),
+		time.Unix(0, math.MaxInt64))
+	if err != nil {
+		return totals, err
+	}
+	for _, hash := range hashes {
+		hdr, err := ctms.FetchHeader(hash)
+		if err != nil {
+			continue
+		}
+		totals.add(hdr)
+	}
+	return totals, nil
+}
+
+// StoreStats returns statistics describing the messages held in the message
+// store.  They are maintained as messages are ingested and refreshed from the
+// store by every run of the pruner, so the store isn't scanned on each call.
+//
+// This function is safe for concurrent access.
+func (ctms *CiphrtxtMsgSvc) StoreStats() *StoreStats {
+	ctms.ingestLock.Lock()
+	totals := ctms.totals
+	ctms.ingestLock.Unlock()
+
+	stats := StoreStats{
+		Messages:        totals.messages,
+		Bytes:           totals.bytes,
+		RetentionWindow: ctms.retention,
+		MaxStoreSize:    ctms.maxStoreSize,
+	}
+	if totals.messages > 0 {
+		stats.Oldest = totals.oldest
+		stats.Newest = totals.newest
+	}
+	return &stats
+}
+
+// IngestStats describes the messages submitted to the message service, by
+// peers and local clients alike, since it was created.
+type IngestStats struct {
+	// Since is the time the message service was created.
+	Since time.Time
+
+	// Accepted is the number of messages added to the message store.
+	Accepted uint64
+
+	// Duplicates is the number of messages which were already stored.
+	Duplicates uint64
+
+	// Invalid is the number of messages which could not be parsed.
+	Invalid uint64
+
+	// Rejected is the number of messages which violated a message
+	// acceptance rule, keyed by the rule which was violated.
+	Rejected map[ErrorCode]uint64
+
+	// RecentAccepted is the number of messages accepted within the last
+	// IngestRateWindow, or since the service was created when that is
+	// more recent.
+	RecentAccepted uint64
+
+	// RateWindow is the period RecentAccepted covers.
+	RateWindow time.Duration
+}
+
+// TotalRejected returns the number of messages which violated any message
+// acceptance rule.
+func (s *IngestStats) TotalRejected() uint64 {
+	var total uint64
+	for _, n := range s.Rejected {
+		total += n
+	}
+	return total
+}
+
+// Rate returns the recent ingestion rate in accepted messages per second.
+func (s *IngestStats) Rate() float64 {
+	if s.RateWindow <= 0 {
+		return 0
+	}
+	return float64(s.RecentAccepted) / s.RateWindow.Seconds()
+}
+
+// ingestCounters tracks the outcome of every message submitted to the message
+// service.  Accepted messages are additionally counted in buckets covering
+// ingestRateBucketSize each so the recent ingestion rate can be determined.
+type ingestCounters struct {
+	sync.Mutex
+	since      time.Time
+	accepted   uint64
+	duplicates uint64
+	invalid    uint64
+	rejected   map[ErrorCode]uint64
+
+	// buckets counts the messages accepted in the period identified by
+	// the same index of bucketTimes.
+	buckets     [ingestRateBuckets]uint64
+	bucketTimes [ingestRateBuckets]int64
+}
+
+// newIngestCounters returns ingestion counters starting at the passed time.
+func newIngestCounters(now time.Time) *ingestCounters {
+	return &ingestCounters{
+		since:    now,
+		rejected: make(map[ErrorCode]uint64),
+	}
+}
+
+// record counts the outcome of ingesting a message at the passed time given
+// the error returned by the ingestion.
+func (c *ingestCounters) record(err error, now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if rerr, ok := err.(RuleError); ok {
+		c.rejected[rerr.ErrorCode]++
+		return
+	}
+	switch err {
+	case nil:
+		c.accepted++
+		period := now.UnixNano() / int64(ingestRateBucketSize)
+		i := period % ingestRateBuckets
+		if c.bucketTimes[i] != period {
+			c.bucketTimes[i] = period
+			c.buckets[i] = 0
+		}
+		c.buckets[i]++
+
+	case ErrDuplicateMessage:
+		c.duplicates++
+
+	case ErrInvalidMessage:
+		c.invalid++
+	}
+}
+
+// stats returns a snapshot of the counters as of the passed time.
+func (c *ingestCounters) stats(now time.Time) *IngestStats {
+	c.Lock()
+	defer c.Unlock()
+
+	stats := IngestStats{
+		Since:      c.since,
+		Accepted:   c.accepted,
+		Duplicates: c.duplicates,
+		Invalid:    c.invalid,
+		Rejected:   make(map[ErrorCode]uint64, len(c.rejected)),
+		RateWindow: IngestRateWindow,
+	}
+	for code, n := range c.rejected {
+		stats.Rejected[code] = n
+	}
+
+	// Only count the buckets which fall within the window ending at the
+	// current period.
+	period := now.UnixNano() / int64(ingestRateBucketSize)
+	for i := range c.buckets {
+		if period-c.bucketTimes[i] < ingestRateBuckets {
+			stats.RecentAccepted += c.buckets[i]
+		}
+	}
+	if uptime := now.Sub(c.since); uptime < IngestRateWindow {
+		stats.RateWindow = uptime
+	}
+	return &stats
+}
+
+// IngestStats returns statistics describing the messages submitted to the
+// message service as of the passed time.
+func (ctms *CiphrtxtMsgSvc) IngestStats(now time.Time) *IngestStats {
+	return ctms.ingestCounters.stats(now)
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestIngestCounters ensures the outcomes of ingested messages are counted
+// and that the recent ingestion rate only covers the rate window.
+func TestIngestCounters(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	c := newIngestCounters(start)
+
+	// Accept two messages in the first minute and one half an hour later.
+	c.record(nil, start)
+	c.record(nil, start.Add(30*time.Second))
+	c.record(nil, start.Add(30*time.Minute))
+	c.record(ErrDuplicateMessage, start.Add(time.Minute))
+	c.record(ErrInvalidMessage, start.Add(time.Minute))
+	c.record(ruleError(ErrExpired, ""), start.Add(time.Minute))
+	c.record(ruleError(ErrExpired, ""), start.Add(time.Minute))
+	c.record(ruleError(ErrInsufficientWork, ""), start.Add(time.Minute))
+
+	// Unexpected errors are not attributed to the message.
+	c.record(errors.New("disk full"), start.Add(time.Minute))
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		recent     uint64
+		rateWindow time.Duration
+	}{
+		{"within first hour", start.Add(40 * time.Minute), 3, 40 * time.Minute},
+		{"first minute aged out", start.Add(61 * time.Minute), 1, IngestRateWindow},
+		{"all aged out", start.Add(2 * time.Hour), 0, IngestRateWindow},
+	}
+	for _, test := range tests {
+		stats := c.stats(test.now)
+		if stats.Accepted != 3 || stats.Duplicates != 1 ||
+			stats.Invalid != 1 || stats.TotalRejected() != 3 ||
+			stats.Rejected[ErrExpired] != 2 ||
+			stats.Rejected[ErrInsufficientWork] != 1 {
+
+			t.Errorf("%s: unexpected counters %+v", test.name, stats)
+			continue
+		}
+		if stats.RecentAccepted != test.recent {
+			t.Errorf("%s: wrong recent accepted - got %d, want %d",
+				test.name, stats.RecentAccepted, test.recent)
+		}
+		if stats.RateWindow != test.rateWindow {
+			t.Errorf("%s: wrong rate window - got %v, want %v",
+				test.name, stats.RateWindow, test.rateWindow)
+		}
+		want := float64(test.recent) / test.rateWindow.Seconds()
+		if stats.Rate() != want {
+			t.Errorf("%s: wrong rate - got %v, want %v", test.name,
+				stats.Rate(), want)
+		}
+	}
+}
+
+// listCountingStore is a store which counts how often its messages are
+// listed.
+type listCountingStore struct {
+	*MemStore
+	lists int
+}
+
+// ListHashesForInterval counts the listing.
+func (s *listCountingStore) ListHashesForInterval(start, end time.Time) ([][]byte, error) {
+	s.lists++
+	return s.MemStore.ListHashesForInterval(start, end)
+}
+
+// TestStoreStats ensures the store statistics account for the messages found
+// in the store on startup, ingested messages and pruned messages without
+// scanning the store on each request.
+func TestStoreStats(t *testing.T) {
+	now := time.Now()
+	recipient := testKey(0x88).PubKey()
+	newMessage := func(age, expire time.Duration) *Message {
+		t.Helper()
+		msg, err := NewMessage(recipient, []byte("stats"), now.Add(-age),
+			expire)
+		if err != nil {
+			t.Fatalf("NewMessage: unexpected error %v", err)
+		}
+		return msg
+	}
+	var ctms *CiphrtxtMsgSvc
+	checkStats := func(name string, msgs ...*Message) {
+		t.Helper()
+		want := StoreStats{Messages: len(msgs)}
+		for _, msg := range msgs {
+			want.Bytes += int64(msg.Header.MsgLen)
+		}
+		if len(msgs) > 0 {
+			want.Oldest = msgs[0].Header.MsgTime
+			want.Newest = msgs[len(msgs)-1].Header.MsgTime
+		}
+		got := *ctms.StoreStats()
+		if got.Messages != want.Messages || got.Bytes != want.Bytes ||
+			!got.Oldest.Equal(want.Oldest) ||
+			!got.Newest.Equal(want.Newest) {
+
+			t.Fatalf("%s: got stats %+v, want %+v", name, got, want)
+		}
+	}
+
+	expiring := newMessage(2*time.Hour, 90*time.Minute)
+	stored := newMessage(time.Hour, 24*time.Hour)
+	store := &listCountingStore{MemStore: NewMemStore()}
+	for _, msg := range []*Message{expiring, stored} {
+		if _, err := store.StoreMessage(msg.Bytes()); err != nil {
+			t.Fatalf("StoreMessage: unexpected error %v", err)
+		}
+	}
+	ctms, err := New(&Config{Store: store})
+	if err != nil {
+		t.Fatalf("New: unexpected error %v", err)
+	}
+	checkStats("startup", expiring, stored)
+
+	ingested := newMessage(time.Minute, 24*time.Hour)
+	if _, err := ctms.IngestMessage(bytes.NewReader(ingested.Bytes())); err != nil {
+		t.Fatalf("IngestMessage: unexpected error %v", err)
+	}
+	checkStats("ingested", expiring, stored, ingested)
+
+	lists := store.lists
+	ctms.StoreStats()
+	if store.lists != lists {
+		t.Fatal("StoreStats scanned the message store")
+	}
+
+	if _, err := ctms.PruneMessages(now.Add(time.Hour)); err != nil {
+		t.Fatalf("PruneMessages: unexpected error %v", err)
+	}
+	checkStats("pruned", stored, ingested)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"crypto/sha256"
+	"time"
+)
+
+// Store is the interface of a message store backend.  Messages are identified
+// by a hash chosen by the backend and are handed to and returned from the
+// store in their serialized form: the message header followed by the
+// ciphertext.
+//
+// Implementations must be safe for concurrent access.
+type Store interface {
+	// FetchMessage returns the serialized message identified by the passed
+	// hash.  ErrMessageNotFound is returned if the message is not stored.
+	FetchMessage(hash []byte) ([]byte, error)
+
+	// FetchHeader returns the header of the message identified by the
+	// passed hash.  ErrMessageNotFound is returned if the message is not
+	// stored.
+	FetchHeader(hash []byte) (*MessageHeader, error)
+
+	// StoreMessage adds the passed serialized message to the store and
+	// returns its hash.  ErrInvalidMessage is returned if the message can
+	// not be parsed, and ErrDuplicateMessage (along with the hash) if the
+	// message is already stored.
+	StoreMessage(msg []byte) ([]byte, error)
+
+	// ListHashesForInterval returns the hashes of the stored messages with
+	// a timestamp in the range [start, end], in no particular order.
+	ListHashesForInterval(start, end time.Time) ([][]byte, error)
+
+	// DeleteMessage removes the message identified by the passed hash from
+	// the store.
+	DeleteMessage(hash []byte) error
+
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// OrderedStore is implemented by message stores which index their messages by
+// time and can therefore visit them in listing order without reading every
+// message in a time range.
+type OrderedStore interface {
+	Store
+
+	// ForEachHash calls fn with the timestamp, in microseconds since the
+	// unix epoch, and hash of each stored message with a timestamp in the
+	// range [start, end].  Messages are visited in order of timestamp and
+	// then hash, and the iteration stops early when fn returns false.
+	// The store must not be modified from fn.
+	ForEachHash(start, end time.Time, fn func(usec uint64, hash []byte) bool) error
+}
+
+// MessageHash returns the hash which identifies the passed serialized message
+// in the message stores implemented in Go: the SHA-256 hash of the complete
+// message.
+func MessageHash(msg []byte) []byte {
+	hash := sha256.Sum256(msg)
+	return hash[:]
+}
+
+// parseStoredMessage returns the header of a serialized message about to be
+// stored.  ErrInvalidMessage is returned when the message is malformed.
+func parseStoredMessage(msg []byte) (*MessageHeader, error) {
+	var hdr MessageHeader
+	if err := hdr.FromBytes(msg); err != nil {
+		return nil, ErrInvalidMessage
+	}
+	if uint64(hdr.MsgLen) != uint64(len(msg)) {
+		return nil, ErrInvalidMessage
+	}
+	return &hdr, nil
+}
+
+// timeKey returns the key of a message in a time index: its timestamp in
+// microseconds since the unix epoch, clamped to zero.
+func timeKey(t time.Time) uint64 {
+	usec := t.UnixNano() / int64(time.Microsecond)
+	if usec < 0 {
+		return 0
+	}
+	return uint64(usec)
+}
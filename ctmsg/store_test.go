@@ -0,0 +1,193 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/database"
+	_ "github.com/jadeblaquiere/cttd/database/ffldb"
+	"github.com/jadeblaquiere/cttd/wire"
+)
+
+// sortedHashes returns the passed hashes sorted for comparison.
+func sortedHashes(hashes [][]byte) [][]byte {
+	sorted := make([][]byte, len(hashes))
+	copy(sorted, hashes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+	return sorted
+}
+
+// testStore exercises the passed empty message store.
+func testStore(t *testing.T, name string, s Store) {
+	base := time.Unix(1500000000, 0)
+	recipient := testKey(0x44).PubKey()
+
+	// Store messages one second apart.
+	var msgs [][]byte
+	var hashes [][]byte
+	for i := 0; i < 4; i++ {
+		msg, err := NewMessage(recipient, []byte{byte(i)},
+			base.Add(time.Duration(i)*time.Second), time.Hour)
+		if err != nil {
+			t.Fatalf("%s: NewMessage: unexpected error %v", name, err)
+		}
+		hash, err := s.StoreMessage(msg.Bytes())
+		if err != nil {
+			t.Fatalf("%s: StoreMessage: unexpected error %v", name, err)
+		}
+		if !bytes.Equal(hash, MessageHash(msg.Bytes())) {
+			t.Fatalf("%s: StoreMessage: wrong hash %x", name, hash)
+		}
+		msgs = append(msgs, msg.Bytes())
+		hashes = append(hashes, hash)
+	}
+
+	// Storing a message again is reported as a duplicate.
+	hash, err := s.StoreMessage(msgs[0])
+	if err != ErrDuplicateMessage || !bytes.Equal(hash, hashes[0]) {
+		t.Fatalf("%s: StoreMessage: wrong duplicate result %x, %v", name,
+			hash, err)
+	}
+
+	// Malformed messages are refused.
+	for _, bad := range [][]byte{nil, msgs[0][:MessageHeaderSize],
+		append(append([]byte{}, msgs[0]...), 0x00)} {
+
+		if _, err := s.StoreMessage(bad); err != ErrInvalidMessage {
+			t.Fatalf("%s: StoreMessage: wrong error for malformed "+
+				"message - got %v, want %v", name, err,
+				ErrInvalidMessage)
+		}
+	}
+
+	// Messages and headers are returned as stored.
+	for i, hash := range hashes {
+		msg, err := s.FetchMessage(hash)
+		if err != nil || !bytes.Equal(msg, msgs[i]) {
+			t.Fatalf("%s: FetchMessage: mismatched message %d: %v",
+				name, i, err)
+		}
+		hdr, err := s.FetchHeader(hash)
+		if err != nil || !bytes.Equal(hdr.Bytes(), msgs[i][:MessageHeaderSize]) {
+			t.Fatalf("%s: FetchHeader: mismatched header %d: %v",
+				name, i, err)
+		}
+	}
+
+	// Intervals include both of their end points.
+	tests := []struct {
+		start, end time.Time
+		want       [][]byte
+	}{
+		{base, base.Add(3 * time.Second), hashes},
+		{base.Add(time.Second), base.Add(2 * time.Second), hashes[1:3]},
+		{base.Add(time.Second), base.Add(2*time.Second - time.Nanosecond), hashes[1:2]},
+		{base.Add(time.Hour), base.Add(2 * time.Hour), nil},
+		{base.Add(time.Second), base, nil},
+	}
+	for i, test := range tests {
+		got, err := s.ListHashesForInterval(test.start, test.end)
+		if err != nil {
+			t.Fatalf("%s: ListHashesForInterval #%d: unexpected "+
+				"error %v", name, i, err)
+		}
+		got, want := sortedHashes(got), sortedHashes(test.want)
+		if len(got) != len(want) {
+			t.Errorf("%s: ListHashesForInterval #%d: got %d hashes, "+
+				"want %d", name, i, len(got), len(want))
+			continue
+		}
+		for j := range got {
+			if !bytes.Equal(got[j], want[j]) {
+				t.Errorf("%s: ListHashesForInterval #%d: "+
+					"mismatched hash %x", name, i, got[j])
+			}
+		}
+	}
+
+	// Ordered stores visit the messages in order of time, and the
+	// iteration stops when requested.
+	if ordered, ok := s.(OrderedStore); ok {
+		var got [][]byte
+		err := ordered.ForEachHash(base, base.Add(time.Hour),
+			func(usec uint64, hash []byte) bool {
+				got = append(got, hash)
+				return len(got) < 3
+			})
+		if err != nil {
+			t.Fatalf("%s: ForEachHash: unexpected error %v", name,
+				err)
+		}
+		if len(got) != 3 {
+			t.Fatalf("%s: ForEachHash: visited %d hashes, want 3",
+				name, len(got))
+		}
+		for i := range got {
+			if !bytes.Equal(got[i], hashes[i]) {
+				t.Fatalf("%s: ForEachHash: visited %x at %d, "+
+					"want %x", name, got[i], i, hashes[i])
+			}
+		}
+	}
+
+	// Deleted messages are no longer returned or listed.
+	if err := s.DeleteMessage(hashes[1]); err != nil {
+		t.Fatalf("%s: DeleteMessage: unexpected error %v", name, err)
+	}
+	if _, err := s.FetchMessage(hashes[1]); err != ErrMessageNotFound {
+		t.Fatalf("%s: FetchMessage: wrong error for deleted message - "+
+			"got %v, want %v", name, err, ErrMessageNotFound)
+	}
+	if _, err := s.FetchHeader(hashes[1]); err != ErrMessageNotFound {
+		t.Fatalf("%s: FetchHeader: wrong error for deleted message - "+
+			"got %v, want %v", name, err, ErrMessageNotFound)
+	}
+	got, err := s.ListHashesForInterval(base, base.Add(time.Hour))
+	if err != nil || len(got) != 3 {
+		t.Fatalf("%s: ListHashesForInterval: got %d hashes after "+
+			"delete, want 3: %v", name, len(got), err)
+	}
+	if err := s.DeleteMessage(hashes[1]); err != nil {
+		t.Fatalf("%s: DeleteMessage: unexpected error deleting missing "+
+			"message %v", name, err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("%s: Close: unexpected error %v", name, err)
+	}
+}
+
+// TestStores ensures the message stores implemented in Go behave as described
+// by the Store interface.
+func TestStores(t *testing.T) {
+	testStore(t, "MemStore", NewMemStore())
+
+	dbPath, err := ioutil.TempDir("", "ctmsgstore")
+	if err != nil {
+		t.Fatalf("TempDir: unexpected error %v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	db, err := database.Create("ffldb", filepath.Join(dbPath, "db"),
+		wire.MainNet)
+	if err != nil {
+		t.Fatalf("database.Create: unexpected error %v", err)
+	}
+	defer db.Close()
+
+	s, err := NewDBStore(db)
+	if err != nil {
+		t.Fatalf("NewDBStore: unexpected error %v", err)
+	}
+	testStore(t, "DBStore", s)
+}
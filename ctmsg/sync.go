@@ -0,0 +1,162 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+)
+
+const (
+	// SyncWindow is how far back in time message stores are reconciled
+	// when a peer connects.
+	SyncWindow = 30 * 24 * time.Hour
+
+	// DigestFanout is the number of intervals a time range which differs
+	// between two peers is split into for the next round of
+	// reconciliation.
+	DigestFanout = 16
+
+	// MaxReconcileCount is the number of messages in an interval at or
+	// below which peers exchange the full list of message hashes for the
+	// interval instead of splitting it further.
+	MaxReconcileCount = 256
+)
+
+// IntervalDigest summarizes the set of message hashes stored for the time
+// interval [Start, End), expressed in seconds since the unix epoch.
+type IntervalDigest struct {
+	Start  int64
+	End    int64
+	Count  uint32
+	Digest chainhash.Hash
+}
+
+// DigestHashes returns the number of hashes and a digest committing to the
+// passed set of message hashes.  The digest is independent of the order of
+// the hashes.  An empty set results in a zero digest.
+func DigestHashes(hashes [][]byte) (uint32, chainhash.Hash) {
+	if len(hashes) == 0 {
+		return 0, chainhash.Hash{}
+	}
+
+	sorted := make([][]byte, len(hashes))
+	copy(sorted, hashes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+	return uint32(len(sorted)), chainhash.HashH(bytes.Join(sorted, nil))
+}
+
+// SplitInterval splits the time range [start, end), expressed in seconds since
+// the unix epoch, into at most n contiguous intervals of (nearly) equal
+// length and returns the n+1 interval boundaries.  Since intervals are never
+// shorter than one second, fewer intervals are returned for short ranges.
+// Nil is returned for an empty range.
+//
+// The range may span the full int64 range and n must not exceed
+// wire.MaxCtDigestsPerMsg, so the boundaries are calculated without
+// overflowing for any range a peer can request.
+func SplitInterval(start, end int64, n int) []int64 {
+	if end <= start || n < 1 {
+		return nil
+	}
+
+	// The span of the range doesn't necessarily fit in an int64, but it
+	// always fits in a uint64.
+	span := uint64(end) - uint64(start)
+	if span < uint64(n) {
+		n = int(span)
+	}
+
+	// Divide before multiplying so the intermediate results never exceed
+	// the span.  The remainder is less than n, so scaling it by at most n
+	// can't overflow either.
+	bounds := make([]int64, n+1)
+	step, rem := span/uint64(n), span%uint64(n)
+	for i := 0; i <= n; i++ {
+		offset := step*uint64(i) + rem*uint64(i)/uint64(n)
+		bounds[i] = int64(uint64(start) + offset)
+	}
+	return bounds
+}
+
+// syncRange returns the time range, expressed in seconds since the unix epoch,
+// of the messages which are made available to peers at the passed time.  It
+// extends back to the retention window, or to the sync window when there is no
+// retention window, and up to the latest message timestamp the policy
+// accepts.
+func (ctms *CiphrtxtMsgSvc) syncRange(now time.Time) (int64, int64) {
+	window := ctms.retention
+	if window <= 0 {
+		window = SyncWindow
+	}
+	earliest := now.Add(-window).Unix()
+	latest := now.Add(ctms.policy.MaxTimeOffset).Unix() + 1
+	return earliest, latest
+}
+
+// ListHashes returns the hashes of the messages stored for the time range
+// [start, end), expressed in seconds since the unix epoch.  The range is
+// clamped to the messages made available to peers, so requests for arbitrary
+// ranges never scan more of the store than that.
+func (ctms *CiphrtxtMsgSvc) ListHashes(start, end int64) ([][]byte, error) {
+	return ctms.listHashes(start, end, time.Now())
+}
+
+// listHashes returns the hashes of the messages stored for the time range
+// [start, end) clamped to the messages made available to peers at the passed
+// time.
+func (ctms *CiphrtxtMsgSvc) listHashes(start, end int64, now time.Time) ([][]byte, error) {
+	earliest, latest := ctms.syncRange(now)
+	if start < earliest {
+		start = earliest
+	}
+	if end > latest {
+		end = latest
+	}
+	if end <= start {
+		return nil, nil
+	}
+
+	// Stop just short of the end of the range so a message on a boundary
+	// is only ever counted in one interval, regardless of whether the
+	// store treats the end of an interval as inclusive.
+	return ctms.MStore.ListHashesForInterval(time.Unix(start, 0),
+		time.Unix(end, 0).Add(-time.Nanosecond))
+}
+
+// IntervalDigests splits the time range [start, end) into at most n intervals
+// as described by SplitInterval and returns the digest of the locally stored
+// message hashes for each interval.  Only the messages made available to peers
+// are included in the digests as described by ListHashes.
+func (ctms *CiphrtxtMsgSvc) IntervalDigests(start, end int64, n int) ([]IntervalDigest, error) {
+	return ctms.intervalDigests(start, end, n, time.Now())
+}
+
+// intervalDigests returns the digests described by IntervalDigests of the
+// messages made available to peers at the passed time.
+func (ctms *CiphrtxtMsgSvc) intervalDigests(start, end int64, n int, now time.Time) ([]IntervalDigest, error) {
+	bounds := SplitInterval(start, end, n)
+	if len(bounds) == 0 {
+		return nil, nil
+	}
+
+	digests := make([]IntervalDigest, len(bounds)-1)
+	for i := range digests {
+		hashes, err := ctms.listHashes(bounds[i], bounds[i+1], now)
+		if err != nil {
+			return nil, err
+		}
+		d := &digests[i]
+		d.Start = bounds[i]
+		d.End = bounds[i+1]
+		d.Count, d.Digest = DigestHashes(hashes)
+	}
+	return digests, nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ctmsg
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+)
+
+// TestSplitInterval ensures time ranges are split into the expected interval
+// boundaries.
+func TestSplitInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end int64
+		n          int
+		want       []int64
+	}{
+		{"empty range", 10, 10, 4, nil},
+		{"reversed range", 10, 5, 4, nil},
+		{"no intervals", 0, 100, 0, nil},
+		{"single interval", 0, 100, 1, []int64{0, 100}},
+		{"even split", 0, 100, 4, []int64{0, 25, 50, 75, 100}},
+		{"uneven split", 0, 10, 3, []int64{0, 3, 6, 10}},
+		{"short range", 100, 103, 16, []int64{100, 101, 102, 103}},
+		{"negative range", -10, 10, 4, []int64{-10, -5, 0, 5, 10}},
+	}
+
+	for _, test := range tests {
+		got := SplitInterval(test.start, test.end, test.n)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: unexpected bounds - got %v, want %v",
+				test.name, got, test.want)
+		}
+	}
+}
+
+// TestSplitIntervalExtremes ensures ranges up to the full int64 range are split
+// into increasing intervals covering the whole range without overflowing.
+func TestSplitIntervalExtremes(t *testing.T) {
+	tests := []struct {
+		start, end int64
+	}{
+		{math.MinInt64, math.MaxInt64},
+		{math.MinInt64, 0},
+		{0, math.MaxInt64},
+		{math.MaxInt64 - 1000, math.MaxInt64},
+		{math.MinInt64, math.MinInt64 + 1000},
+	}
+
+	for _, test := range tests {
+		for _, n := range []int{1, 3, DigestFanout, 256} {
+			bounds := SplitInterval(test.start, test.end, n)
+			if len(bounds) != n+1 || bounds[0] != test.start ||
+				bounds[n] != test.end {
+
+				t.Fatalf("[%d, %d) in %d: bounds %v don't cover "+
+					"the range", test.start, test.end, n, bounds)
+			}
+			for i := 1; i < len(bounds); i++ {
+				if bounds[i] <= bounds[i-1] {
+					t.Fatalf("[%d, %d) in %d: bounds %v are "+
+						"not increasing", test.start,
+						test.end, n, bounds)
+				}
+			}
+		}
+	}
+}
+
+// TestSyncRangeClamped ensures only the messages within the retention window
+// are listed and digested for peers, regardless of the requested range.
+func TestSyncRangeClamped(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	ctms, err := New(&Config{
+		Store:           NewMemStore(),
+		RetentionWindow: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New: unexpected error %v", err)
+	}
+	defer ctms.MStore.Close()
+
+	recipient := testKey(0x44).PubKey()
+	var recent []byte
+	for _, age := range []time.Duration{48 * time.Hour, time.Hour} {
+		msg, err := NewMessage(recipient, []byte("clamp"), now.Add(-age),
+			time.Hour)
+		if err != nil {
+			t.Fatalf("NewMessage: unexpected error %v", err)
+		}
+		recent, err = ctms.MStore.StoreMessage(msg.Bytes())
+		if err != nil {
+			t.Fatalf("StoreMessage: unexpected error %v", err)
+		}
+	}
+
+	hashes, err := ctms.listHashes(math.MinInt64, math.MaxInt64, now)
+	if err != nil {
+		t.Fatalf("listHashes: unexpected error %v", err)
+	}
+	if len(hashes) != 1 || !bytes.Equal(hashes[0], recent) {
+		t.Fatalf("listHashes: got %x, want only %x", hashes, recent)
+	}
+
+	digests, err := ctms.intervalDigests(math.MinInt64, math.MaxInt64,
+		DigestFanout, now)
+	if err != nil {
+		t.Fatalf("intervalDigests: unexpected error %v", err)
+	}
+	var count uint32
+	for _, d := range digests {
+		count += d.Count
+	}
+	if len(digests) != DigestFanout || count != 1 {
+		t.Fatalf("intervalDigests: got %d intervals holding %d "+
+			"messages, want %d holding 1", len(digests), count,
+			DigestFanout)
+	}
+
+	// Ranges entirely outside of the window are empty.
+	hashes, err = ctms.listHashes(math.MinInt64, now.Add(-25*time.Hour).Unix(),
+		now)
+	if err != nil || len(hashes) != 0 {
+		t.Fatalf("listHashes: got %x, %v for expired range, want none",
+			hashes, err)
+	}
+}
+
+// TestDigestHashes ensures the digest of a set of hashes is independent of
+// their order and changes when the set does.
+func TestDigestHashes(t *testing.T) {
+	h1 := chainhash.HashB([]byte("one"))
+	h2 := chainhash.HashB([]byte("two"))
+	h3 := chainhash.HashB([]byte("three"))
+
+	count, digest := DigestHashes(nil)
+	if count != 0 || digest != (chainhash.Hash{}) {
+		t.Fatalf("empty set: got count %d digest %v, want zero", count,
+			digest)
+	}
+
+	count, d123 := DigestHashes([][]byte{h1, h2, h3})
+	if count != 3 {
+		t.Fatalf("unexpected count - got %d, want 3", count)
+	}
+	_, d321 := DigestHashes([][]byte{h3, h2, h1})
+	if d123 != d321 {
+		t.Fatalf("digest depends on hash order: %v != %v", d123, d321)
+	}
+	_, d12 := DigestHashes([][]byte{h1, h2})
+	if d12 == d123 {
+		t.Fatalf("digest did not change when set changed")
+	}
+}
@@ -18,7 +18,6 @@ import (
 	"github.com/jadeblaquiere/cttd/blockchain/indexers"
 	"github.com/jadeblaquiere/cttd/database"
 	"github.com/jadeblaquiere/cttd/limits"
-	"github.com/jadeblaquiere/cttd/txscript"
 )
 
 const (
@@ -76,11 +75,6 @@ func btcdMain(serverChan chan<- *server) error {
 		}()
 	}
 
-	if cfg.CtBlueNet {
-		txscript.EnableCTExtendedOpcodes()
-		btcdLog.Infof("Enabling ciphrtxt Extended Opcodes: OP_REGISTERNAK, OP_REGISTERNAME")
-	}
-
 	// Write cpu profile if requested.
 	if cfg.CPUProfile != "" {
 		f, err := os.Create(cfg.CPUProfile)
@@ -149,6 +143,14 @@ func btcdMain(serverChan chan<- *server) error {
 
 		return nil
 	}
+	if cfg.DropNAKIndex {
+		if err := indexers.DropNAKIndex(db, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
 
 	// Create server and start it.
 	server, err := newServer(cfg.Listeners, db, activeNetParams.Params,
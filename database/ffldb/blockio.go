@@ -13,8 +13,11 @@ import (
 	"fmt"
 	"hash/crc32"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
@@ -164,6 +167,11 @@ type blockStore struct {
 	fileNumToLRUElem map[uint32]*list.Element
 	openBlockFiles   map[uint32]*lockableFile
 
+	// firstFileNum is the number of the oldest block file which has not
+	// been pruned.  It is protected by obfMutex since pruned files are
+	// closed under it.
+	firstFileNum uint32
+
 	// writeCursor houses the state for the current file and location that
 	// new blocks are written to.
 	writeCursor *writeCursor
@@ -307,6 +315,55 @@ func (s *blockStore) deleteFile(fileNum uint32) error {
 	return nil
 }
 
+// pruneFiles closes and removes the passed block files, which MUST be the
+// oldest files of the store in ascending order, and advances the first file
+// past the removed ones.  The blocks they contain MUST no longer be referenced
+// by the block index.  Removal stops at the first file which fails to be
+// removed, which is only logged since the file does not contain any referenced
+// blocks and thus is removed again by the next prune.
+func (s *blockStore) pruneFiles(fileNums []uint32) {
+	s.obfMutex.Lock()
+	defer s.obfMutex.Unlock()
+
+	for _, fileNum := range fileNums {
+		// Close the file under the write lock for the file in case any
+		// readers are currently reading from it.
+		if obf, ok := s.openBlockFiles[fileNum]; ok {
+			s.lruMutex.Lock()
+			s.openBlocksLRU.Remove(s.fileNumToLRUElem[fileNum])
+			delete(s.fileNumToLRUElem, fileNum)
+			s.lruMutex.Unlock()
+
+			obf.Lock()
+			_ = obf.file.Close()
+			obf.Unlock()
+			delete(s.openBlockFiles, fileNum)
+		}
+
+		log.Debugf("Pruning block file %d", fileNum)
+		if err := s.deleteFileFunc(fileNum); err != nil {
+			log.Warnf("Failed to remove pruned block file %d: %v",
+				fileNum, err)
+			return
+		}
+		s.firstFileNum = fileNum + 1
+	}
+}
+
+// fileSize returns the size in bytes of the passed block file.  Files which do
+// not exist have a size of zero.
+func (s *blockStore) fileSize(fileNum uint32) (uint64, error) {
+	st, err := os.Stat(blockFilePath(s.basePath, fileNum))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, makeDbErr(database.ErrDriverSpecific, err.Error(), err)
+	}
+
+	return uint64(st.Size()), nil
+}
+
 // blockFile attempts to return an existing file handle for the passed flat file
 // number if it is already open as well as marking it as most recently used.  It
 // will also open the file when it's not already open subject to the rules
@@ -713,14 +770,36 @@ func (s *blockStore) handleRollback(oldBlockFileNum, oldBlockOffset uint32) {
 }
 
 // scanBlockFiles searches the database directory for all flat block files to
-// find the end of the most recent file.  This position is considered the
-// current write cursor which is also stored in the metadata.  Thus, it is used
-// to detect unexpected shutdowns in the middle of writes so the block files
-// can be reconciled.
-func scanBlockFiles(dbPath string) (int, uint32) {
+// find the oldest file which has not been pruned as well as the end of the most
+// recent file.  This position is considered the current write cursor which is
+// also stored in the metadata.  Thus, it is used to detect unexpected shutdowns
+// in the middle of writes so the block files can be reconciled.
+func scanBlockFiles(dbPath string) (int, int, uint32) {
+	// Pruning removes the oldest files, so the files do not necessarily
+	// start at zero.
+	firstFile := -1
+	entries, _ := ioutil.ReadDir(dbPath)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".fdb") {
+			continue
+		}
+		fileNum, err := strconv.ParseUint(strings.TrimSuffix(name,
+			".fdb"), 10, 32)
+		if err != nil || fmt.Sprintf(blockFilenameTemplate, fileNum) != name {
+			continue
+		}
+		if firstFile == -1 || int(fileNum) < firstFile {
+			firstFile = int(fileNum)
+		}
+	}
+	if firstFile == -1 {
+		return -1, -1, 0
+	}
+
 	lastFile := -1
 	fileLen := uint32(0)
-	for i := 0; ; i++ {
+	for i := firstFile; ; i++ {
 		filePath := blockFilePath(dbPath, uint32(i))
 		st, err := os.Stat(filePath)
 		if err != nil {
@@ -731,9 +810,9 @@ func scanBlockFiles(dbPath string) (int, uint32) {
 		fileLen = uint32(st.Size())
 	}
 
-	log.Tracef("Scan found latest block file #%d with length %d", lastFile,
-		fileLen)
-	return lastFile, fileLen
+	log.Tracef("Scan found block files #%d to #%d with length %d",
+		firstFile, lastFile, fileLen)
+	return firstFile, lastFile, fileLen
 }
 
 // newBlockStore returns a new block store with the current block file number
@@ -742,8 +821,9 @@ func newBlockStore(basePath string, network wire.BitcoinNet) *blockStore {
 	// Look for the end of the latest block to file to determine what the
 	// write cursor position is from the viewpoing of the block files on
 	// disk.
-	fileNum, fileOff := scanBlockFiles(basePath)
+	firstFileNum, fileNum, fileOff := scanBlockFiles(basePath)
 	if fileNum == -1 {
+		firstFileNum = 0
 		fileNum = 0
 		fileOff = 0
 	}
@@ -755,6 +835,7 @@ func newBlockStore(basePath string, network wire.BitcoinNet) *blockStore {
 		openBlockFiles:   make(map[uint32]*lockableFile),
 		openBlocksLRU:    list.New(),
 		fileNumToLRUElem: make(map[uint32]*list.Element),
+		firstFileNum:     uint32(firstFileNum),
 
 		writeCursor: &writeCursor{
 			curFile:    &lockableFile{},
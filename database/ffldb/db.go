@@ -74,6 +74,11 @@ var (
 	// writeLocKeyName is the key used to store the current write file
 	// location.
 	writeLocKeyName = []byte("ffldb-writeloc")
+
+	// prunedKeyName is the key used to record that blocks have been pruned.
+	// It is stored along with the removal of the pruned blocks from the
+	// block index since their files are not necessarily removed.
+	prunedKeyName = []byte("ffldb-pruned")
 )
 
 // Common error strings.
@@ -966,6 +971,10 @@ type transaction struct {
 	pendingBlocks    map[chainhash.Hash]int
 	pendingBlockData []pendingBlock
 
+	// Block files that need to be removed on commit since all of the
+	// blocks they contain have been pruned.
+	pendingPrunedFiles []uint32
+
 	// Keys that need to be stored or deleted on commit.
 	pendingKeys   *treap.Mutable
 	pendingRemove *treap.Mutable
@@ -1593,6 +1602,9 @@ func (tx *transaction) close() {
 	tx.pendingBlocks = nil
 	tx.pendingBlockData = nil
 
+	// Clear pending block files that would have been removed on commit.
+	tx.pendingPrunedFiles = nil
+
 	// Clear pending keys that would have been written or deleted on commit.
 	tx.pendingKeys = nil
 	tx.pendingRemove = nil
@@ -1666,7 +1678,152 @@ func (tx *transaction) writePendingAndCommit() error {
 
 	// Atomically update the database cache.  The cache automatically
 	// handles flushing to the underlying persistent storage database.
-	return tx.db.cache.commitTx(tx)
+	if err := tx.db.cache.commitTx(tx); err != nil {
+		return err
+	}
+	if len(tx.pendingPrunedFiles) == 0 {
+		return nil
+	}
+
+	// The removal of the pruned blocks from the block index must reach
+	// persistent storage before their files are removed since the index
+	// would refer to missing data after an unexpected shutdown otherwise.
+	if err := tx.db.cache.flush(); err != nil {
+		return err
+	}
+	tx.db.store.pruneFiles(tx.pendingPrunedFiles)
+	return nil
+}
+
+// PruneBlocks removes the oldest block files, along with the entries of the
+// blocks they contain from the block index, until the total size of the block
+// files no longer exceeds the passed target size in bytes.  The file currently
+// being written to is never removed.  Pruning stops at the first file which
+// contains a block the passed function refuses to prune.  The hashes of the
+// pruned blocks are returned.
+//
+// Returns the following errors as required by the interface contract:
+//   - ErrTxNotWritable if attempted against a read-only transaction
+//   - ErrTxClosed if the transaction has already been closed
+//
+// This function is part of the database.Tx interface implementation.
+func (tx *transaction) PruneBlocks(targetSize uint64, canPrune func(hash *chainhash.Hash) bool) ([]chainhash.Hash, error) {
+	// Ensure transaction state is valid.
+	if err := tx.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	// Ensure the transaction is writable.
+	if !tx.writable {
+		str := "prune blocks requires a writable database transaction"
+		return nil, makeDbErr(database.ErrTxNotWritable, str, nil)
+	}
+
+	// Files already pruned by this transaction are only removed on commit.
+	store := tx.db.store
+	store.obfMutex.RLock()
+	firstFileNum := store.firstFileNum
+	store.obfMutex.RUnlock()
+	if n := len(tx.pendingPrunedFiles); n > 0 {
+		firstFileNum = tx.pendingPrunedFiles[n-1] + 1
+	}
+	wc := store.writeCursor
+	wc.RLock()
+	curFileNum, curOffset := wc.curFileNum, wc.curOffset
+	wc.RUnlock()
+
+	// Determine the oldest files which need to be removed to reach the
+	// target size.
+	totalSize := uint64(curOffset)
+	fileSizes := make(map[uint32]uint64)
+	for fileNum := firstFileNum; fileNum < curFileNum; fileNum++ {
+		size, err := store.fileSize(fileNum)
+		if err != nil {
+			return nil, err
+		}
+		fileSizes[fileNum] = size
+		totalSize += size
+	}
+	lastFileNum := firstFileNum
+	for ; lastFileNum < curFileNum && totalSize > targetSize; lastFileNum++ {
+		totalSize -= fileSizes[lastFileNum]
+	}
+	if lastFileNum == firstFileNum {
+		return nil, nil
+	}
+
+	// Avoid scanning the whole block index while the block which stopped
+	// the last prune that removed nothing still must not be pruned.  That
+	// block is in the oldest file since no files have been pruned since.
+	if blocker := tx.db.pruneBlocker; blocker != nil && !canPrune(blocker) {
+		return nil, nil
+	}
+	tx.db.pruneBlocker = nil
+
+	// Gather the blocks contained in those files from the block index.
+	fileBlocks := make(map[uint32][]chainhash.Hash)
+	err := tx.blockIdxBucket.ForEach(func(k, v []byte) error {
+		loc := deserializeBlockLoc(v)
+		if loc.blockFileNum < lastFileNum {
+			var hash chainhash.Hash
+			copy(hash[:], k)
+			fileBlocks[loc.blockFileNum] = append(
+				fileBlocks[loc.blockFileNum], hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the files in order until one of them contains a block which
+	// must not be pruned.
+	var pruned []chainhash.Hash
+out:
+	for fileNum := firstFileNum; fileNum < lastFileNum; fileNum++ {
+		hashes := fileBlocks[fileNum]
+		for i := range hashes {
+			if !canPrune(&hashes[i]) {
+				if fileNum == firstFileNum &&
+					len(tx.pendingPrunedFiles) == 0 {
+
+					blocker := hashes[i]
+					tx.db.pruneBlocker = &blocker
+				}
+				break out
+			}
+		}
+		for i := range hashes {
+			if err := tx.blockIdxBucket.Delete(hashes[i][:]); err != nil {
+				return nil, err
+			}
+		}
+		pruned = append(pruned, hashes...)
+		tx.pendingPrunedFiles = append(tx.pendingPrunedFiles, fileNum)
+	}
+	if len(tx.pendingPrunedFiles) > 0 {
+		if err := tx.metaBucket.Put(prunedKeyName, []byte{1}); err != nil {
+			return nil, err
+		}
+	}
+
+	return pruned, nil
+}
+
+// BeenPruned returns whether or not any blocks have been removed from the block
+// index by PruneBlocks.
+//
+// Returns the following errors as required by the interface contract:
+//   - ErrTxClosed if the transaction has already been closed
+//
+// This function is part of the database.Tx interface implementation.
+func (tx *transaction) BeenPruned() (bool, error) {
+	// Ensure transaction state is valid.
+	if err := tx.checkClosed(); err != nil {
+		return false, err
+	}
+
+	return tx.metaBucket.Get(prunedKeyName) != nil, nil
 }
 
 // Commit commits all changes that have been made to the root metadata bucket
@@ -1732,6 +1889,10 @@ type db struct {
 	closed    bool         // Is the database closed?
 	store     *blockStore  // Handles read/writing blocks to flat files.
 	cache     *dbCache     // Cache layer which wraps underlying leveldb DB.
+
+	// pruneBlocker is the block which stopped the last prune that removed
+	// nothing.  It is only accessed by write transactions.
+	pruneBlocker *chainhash.Hash
 }
 
 // Enforce db implements the database.DB interface.
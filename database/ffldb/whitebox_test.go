@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/jadeblaquiere/cttd/chaincfg"
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
 	"github.com/jadeblaquiere/cttd/database"
 	"github.com/jadeblaquiere/cttd/wire"
 	"github.com/jadeblaquiere/cttutil"
@@ -704,3 +705,215 @@ func TestFailureScenarios(t *testing.T) {
 	// Test various corruption scenarios.
 	testCorruption(tc)
 }
+
+// TestPruneBlocks ensures pruning removes the oldest block files along with the
+// blocks they contain, stops at blocks which must not be pruned, and that the
+// pruned state survives reopening the database.
+func TestPruneBlocks(t *testing.T) {
+	t.Parallel()
+
+	// Create a new database to run tests against.
+	dbPath := filepath.Join(os.TempDir(), "ffldb-pruneblocks")
+	_ = os.RemoveAll(dbPath)
+	idb, err := database.Create(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Errorf("Failed to create test database (%s) %v", dbType, err)
+		return
+	}
+	defer os.RemoveAll(dbPath)
+	defer func() { idb.Close() }()
+
+	// Change the maximum file size to a small value to spread the test
+	// blocks over many flat files.
+	idb.(*db).store.maxBlockFileSize = 1024 // 1KiB
+	blocks, err := loadBlocks(t, blockDataFile, blockDataNet)
+	if err != nil {
+		t.Errorf("loadBlocks: Unexpected error: %v", err)
+		return
+	}
+	err = idb.Update(func(tx database.Tx) error {
+		for _, block := range blocks {
+			if err := tx.StoreBlock(block); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("StoreBlock: unexpected error: %v", err)
+		return
+	}
+
+	// checkPruned ensures exactly the blocks before the passed index have
+	// been pruned and that the remaining ones are still readable.
+	checkPruned := func(testName string, numPruned int) bool {
+		err := idb.View(func(tx database.Tx) error {
+			pruned, err := tx.BeenPruned()
+			if err != nil || pruned != (numPruned > 0) {
+				return fmt.Errorf("BeenPruned: got %v, want %v: %v",
+					pruned, numPruned > 0, err)
+			}
+			for i, block := range blocks {
+				has, err := tx.HasBlock(block.Hash())
+				if err != nil || has != (i >= numPruned) {
+					return fmt.Errorf("HasBlock #%d: got %v, want "+
+						"%v: %v", i, has, i >= numPruned, err)
+				}
+			}
+			_, err = tx.FetchBlock(blocks[numPruned].Hash())
+			return err
+		})
+		if err != nil {
+			t.Errorf("%s: %v", testName, err)
+			return false
+		}
+		return true
+	}
+	if !checkPruned("initial", 0) {
+		return
+	}
+
+	// Ensure attempting to prune blocks with a read-only transaction
+	// returns the expected error.
+	testName := "PruneBlocks on read-only tx"
+	err = idb.View(func(tx database.Tx) error {
+		_, err := tx.PruneBlocks(0, func(*chainhash.Hash) bool {
+			return true
+		})
+		return err
+	})
+	if !checkDbError(t, testName, err, database.ErrTxNotWritable) {
+		return
+	}
+
+	// pruneBlocks prunes the blocks down to the passed target size while
+	// refusing to prune the block at the passed index and returns the
+	// total number of pruned blocks.  The blocks are stored in order, so
+	// the newly pruned blocks must directly follow the ones pruned before.
+	pruneBlocks := func(testName string, targetSize uint64, keep, numPruned int) (int, bool) {
+		var pruned []chainhash.Hash
+		err := idb.Update(func(tx database.Tx) error {
+			var err error
+			pruned, err = tx.PruneBlocks(targetSize,
+				func(hash *chainhash.Hash) bool {
+					return *hash != *blocks[keep].Hash()
+				})
+			return err
+		})
+		if err != nil {
+			t.Errorf("%s: PruneBlocks: unexpected error: %v", testName,
+				err)
+			return 0, false
+		}
+		prunedSet := make(map[chainhash.Hash]struct{})
+		for _, hash := range pruned {
+			prunedSet[hash] = struct{}{}
+		}
+		for i := numPruned; i < numPruned+len(pruned); i++ {
+			if _, ok := prunedSet[*blocks[i].Hash()]; !ok {
+				t.Errorf("%s: PruneBlocks: block %d was not pruned "+
+					"while %d blocks were", testName, i,
+					len(pruned))
+				return 0, false
+			}
+		}
+		return numPruned + len(pruned), true
+	}
+
+	// Pruning down to a target size which is already met does nothing.
+	numPruned, ok := pruneBlocks("large target", 1<<30, 0, 0)
+	if !ok {
+		return
+	}
+	if numPruned != 0 {
+		t.Errorf("large target: pruned %d blocks", numPruned)
+		return
+	}
+
+	// Pruning stops at the file containing a block which must be kept.
+	numPruned, ok = pruneBlocks("keep block", 0, 100, 0)
+	if !ok {
+		return
+	}
+	if numPruned == 0 || numPruned > 100 || numPruned < 90 {
+		t.Errorf("keep block: pruned %d blocks", numPruned)
+		return
+	}
+	if _, err := os.Stat(blockFilePath(dbPath, 0)); !os.IsNotExist(err) {
+		t.Errorf("keep block: pruned block file still exists: %v", err)
+		return
+	}
+	if !checkPruned("keep block", numPruned) {
+		return
+	}
+
+	// Ensure the pruned state is the same after reopening the database.
+	idb.Close()
+	idb, err = database.Open(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Errorf("Failed to reopen test database (%s) %v", dbType, err)
+		return
+	}
+	if !checkPruned("reopen", numPruned) {
+		return
+	}
+
+	// Pruning again while the same block must be kept prunes nothing.
+	numKept := numPruned
+	numPruned, ok = pruneBlocks("keep block again", 0, 100, numPruned)
+	if !ok {
+		return
+	}
+	if numPruned != numKept {
+		t.Errorf("keep block again: pruned %d blocks, want %d",
+			numPruned-numKept, 0)
+		return
+	}
+
+	// A file which fails to be removed stops the removal of the files
+	// after it, and is removed by the next prune since the blocks it
+	// contains are no longer referenced.
+	store := idb.(*db).store
+	failFileNum := store.firstFileNum + 1
+	deleteFile := store.deleteFileFunc
+	store.deleteFileFunc = func(fileNum uint32) error {
+		if fileNum == failFileNum {
+			return makeDbErr(database.ErrDriverSpecific, "test",
+				nil)
+		}
+		return deleteFile(fileNum)
+	}
+	numPruned, ok = pruneBlocks("delete failure", 0, 100+25, numPruned)
+	if !ok {
+		return
+	}
+	if store.firstFileNum != failFileNum {
+		t.Errorf("delete failure: first file %d, want %d",
+			store.firstFileNum, failFileNum)
+		return
+	}
+	if _, err := os.Stat(blockFilePath(dbPath, failFileNum)); err != nil {
+		t.Errorf("delete failure: block file %d was removed: %v",
+			failFileNum, err)
+		return
+	}
+	if !checkPruned("delete failure", numPruned) {
+		return
+	}
+	store.deleteFileFunc = deleteFile
+
+	// Pruning everything keeps the file currently being written to.
+	numPruned, ok = pruneBlocks("all", 0, 0, numPruned)
+	if !ok {
+		return
+	}
+	if numPruned <= 100 || numPruned >= len(blocks) {
+		t.Errorf("all: pruned %d blocks", numPruned)
+		return
+	}
+	if _, err := os.Stat(blockFilePath(dbPath, failFileNum)); !os.IsNotExist(err) {
+		t.Errorf("all: block file %d still exists: %v", failFileNum, err)
+		return
+	}
+	checkPruned("all", numPruned)
+}
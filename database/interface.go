@@ -389,6 +389,32 @@ type Tx interface {
 	// implementations.
 	FetchBlockRegions(regions []BlockRegion) ([][]byte, error)
 
+	// PruneBlocks removes the oldest stored blocks until the space used by
+	// the block storage no longer exceeds the passed target size in bytes.
+	// Blocks are removed in the units the backend stores them in, so more
+	// blocks than strictly necessary may be removed.  The passed function
+	// is called for every block about to be removed, and pruning stops
+	// without removing the block when it returns false.  The hashes of the
+	// removed blocks are returned.
+	//
+	// The blocks are no longer available from this transaction once they
+	// have been pruned, and the space is only reclaimed once the
+	// transaction is committed.
+	//
+	// The interface contract guarantees at least the following errors will
+	// be returned (other implementation-specific errors are possible):
+	//   - ErrTxNotWritable if attempted against a read-only transaction
+	//   - ErrTxClosed if the transaction has already been closed
+	PruneBlocks(targetSize uint64, canPrune func(hash *chainhash.Hash) bool) ([]chainhash.Hash, error)
+
+	// BeenPruned returns whether or not blocks have ever been removed from
+	// the block storage by PruneBlocks.
+	//
+	// The interface contract guarantees at least the following errors will
+	// be returned (other implementation-specific errors are possible):
+	//   - ErrTxClosed if the transaction has already been closed
+	BeenPruned() (bool, error)
+
 	// ******************************************************************
 	// Methods related to both atomic metadata storage and block storage.
 	// ******************************************************************
@@ -24,6 +24,20 @@ Application Options:
   -V, --version             Display version information and exit
   -C, --configfile=         Path to configuration file
       --ctmxdir=            Directory to store ciphrtxt message data
+      --ctmaxmsgsize=       Maximum size in bytes of an accepted ciphrtxt
+                            message, including the header (1048576)
+      --ctminmsgexpire=     Minimum lifetime of an accepted ciphrtxt message.
+                            Valid time units are {s, m, h} (1h0m0s)
+      --ctmaxmsgexpire=     Maximum lifetime of an accepted ciphrtxt message.
+                            Valid time units are {s, m, h} (720h0m0s)
+      --ctretention=        Evict stored ciphrtxt messages older than this,
+                            even if they have not expired -- 0 keeps messages
+                            until they expire.  Valid time units are {s, m, h}
+      --ctmaxstoresize=     Evict the oldest ciphrtxt messages once the message
+                            store exceeds this size in MiB -- 0 disables the
+                            limit
+      --ctmsgstore=         Backend for the ciphrtxt message store {ctgo, db}
+                            -- db keeps messages in the block database (ctgo)
   -b, --datadir=            Directory to store data
       --logdir=             Directory to log output.
   -a, --addpeer=            Add a peer to connect with at startup
@@ -61,6 +75,20 @@ Application Options:
                             rpclimituser/rpclimitpass is specified
       --notls               Disable TLS for the RPC server -- NOTE: This is only
                             allowed if the RPC server is bound to localhost
+      --restlisten=         Add an interface/port to listen for REST connections
+                            (default port bluenet: 17764)
+      --restnotls           Disable TLS for the REST server (e.g. when it is
+                            served behind a TLS terminating proxy)
+      --resttoken=          Add an API token required for REST write requests
+                            -- NOTE: REST write requests are not authenticated
+                            if no tokens are specified
+      --restmaxclients=     Max number of concurrent REST requests -- 0 disables
+                            the limit (100)
+      --restratelimit=      Max number of REST requests per second from each IP
+                            address -- 0 disables the limit (20)
+      --restbytelimit=      Max number of KiB per second transferred by REST
+                            requests from each IP address -- 0 disables the
+                            limit (1024)
       --nodnsseed           Disable DNS seeding for peers
       --externalip=         Add an ip to the list of local addresses we claim to
                             listen on to peers
@@ -114,8 +142,22 @@ Application Options:
                             when creating a block (50000)
       --nopeerbloomfilters  Disable bloom filtering support.
       --nocfilters          Disable committed filtering (CF) support.
+      --nakindex            Maintain an index of the network access keys
+                            registered with OP_REGISTERNAK which makes the
+                            getnak and listnaks RPCs available
+      --dropnakindex        Deletes the network access key index from the
+                            database on start up and then exits.
       --sigcachemaxsize=    The maximum number of entries in the signature
                             verification cache.
+      --utxocachemaxsize=   The maximum size in MiB of the UTXO cache --
+                            Modifications of the UTXO set are written to the
+                            database once it is full (250)
+      --prune=              Delete the oldest blocks once they are buried by
+                            288 blocks to keep the stored blocks below the
+                            given size in MiB -- The node no longer serves the
+                            full block chain and can not maintain the
+                            transaction and address indexes (0 disables
+                            pruning, the minimum is 550)
       --blocksonly          Do not accept transactions from remote peers.
       --relaynonstd         Relay non-standard transactions regardless of the
                             default settings for the active network.
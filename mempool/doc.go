@@ -40,6 +40,8 @@ be an exhaustive list.
    - Reject non-fully-spent duplicate transactions
    - Reject coinbase transactions
    - Reject double spends (both from the chain and other transactions in pool)
+   - Reject registrations of names owned in the chain or registered by other
+     transactions in pool, and renewals which do not spend the registration
    - Reject invalid transactions according to the network consensus rules
    - Full script execution and validation with signature cache support
    - Individual transaction query support
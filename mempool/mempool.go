@@ -5,6 +5,7 @@
 package mempool
 
 import (
+	"bytes"
 	"container/list"
 	"fmt"
 	"math"
@@ -79,6 +80,10 @@ type Config struct {
 	// into the mempool or not.
 	IsDeploymentActive func(deploymentID uint32) (bool, error)
 
+	// FetchNameRegistrations defines the function to use to fetch the
+	// registrations of a name in the main chain from the name registry.
+	FetchNameRegistrations func(name []byte) ([]blockchain.NameRegistration, error)
+
 	// SigCache defines a signature cache to use.
 	SigCache *txscript.SigCache
 
@@ -167,6 +172,7 @@ type TxPool struct {
 	orphans       map[chainhash.Hash]*orphanTx
 	orphansByPrev map[wire.OutPoint]map[chainhash.Hash]*btcutil.Tx
 	outpoints     map[wire.OutPoint]*btcutil.Tx
+	names         map[string]*btcutil.Tx
 	pennyTotal    float64 // exponentially decaying total for penny spends.
 	lastPennyUnix int64   // unix time of last ``penny spend''
 
@@ -475,6 +481,13 @@ func (mp *TxPool) removeTransaction(tx *btcutil.Tx, removeRedeemers bool) {
 		for _, txIn := range txDesc.Tx.MsgTx().TxIn {
 			delete(mp.outpoints, txIn.PreviousOutPoint)
 		}
+
+		// Release the names registered by the transaction.
+		for _, name := range txNameRegistrations(txDesc.Tx) {
+			if mp.names[string(name)] == txDesc.Tx {
+				delete(mp.names, string(name))
+			}
+		}
 		delete(mp.pool, *txHash)
 		atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
 	}
@@ -513,6 +526,27 @@ func (mp *TxPool) RemoveDoubleSpends(tx *btcutil.Tx) {
 	mp.mtx.Unlock()
 }
 
+// RemoveNameConflicts removes all transactions which register names registered
+// by the passed transaction from the memory pool.  Removing those transactions
+// then leads to removing all transactions which rely on them, recursively.
+// This is necessary when a block is connected to the main chain because the
+// transactions in the pool which register the same names as the block are no
+// longer able to be mined.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) RemoveNameConflicts(tx *btcutil.Tx) {
+	// Protect concurrent access.
+	mp.mtx.Lock()
+	for _, name := range txNameRegistrations(tx) {
+		if txRegisterer, ok := mp.names[string(name)]; ok {
+			if !txRegisterer.Hash().IsEqual(tx.Hash()) {
+				mp.removeTransaction(txRegisterer, true)
+			}
+		}
+	}
+	mp.mtx.Unlock()
+}
+
 // addTransaction adds the passed transaction to the memory pool.  It should
 // not be called directly as it doesn't perform any validation.  This is a
 // helper for maybeAcceptTransaction.
@@ -536,6 +570,9 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *btcutil
 	for _, txIn := range tx.MsgTx().TxIn {
 		mp.outpoints[txIn.PreviousOutPoint] = tx
 	}
+	for _, name := range txNameRegistrations(tx) {
+		mp.names[string(name)] = tx
+	}
 	atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
 
 	// Add unconfirmed address index entries associated with the transaction
@@ -571,6 +608,101 @@ func (mp *TxPool) checkPoolDoubleSpend(tx *btcutil.Tx) error {
 	return nil
 }
 
+// txNameRegistrations returns the names registered by the outputs of the
+// passed transaction.  Malformed registrations are ignored since transactions
+// which contain them are not accepted into the pool.
+func txNameRegistrations(tx *btcutil.Tx) [][]byte {
+	var names [][]byte
+	for _, txOut := range tx.MsgTx().TxOut {
+		name, _, err := txscript.ExtractNameRegistration(txOut.PkScript)
+		if err != nil || name == nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// checkNameRegistrations ensures the names registered by the passed
+// transaction are not registered by other transactions in the pool and, as of
+// the block at the passed height, are either not registered in the main chain
+// or are renewed by spending their current registration and registering them
+// to the same key.  These are the rules blockchain enforces on the name
+// registrations in a block, so a transaction which violates them could not be
+// mined.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) checkNameRegistrations(tx *btcutil.Tx, height int32) error {
+	expiryBlocks := mp.cfg.ChainParams.NameExpiryBlocks
+	registered := make(map[string]struct{})
+	for i, txOut := range tx.MsgTx().TxOut {
+		name, owner, err := txscript.ExtractNameRegistration(
+			txOut.PkScript)
+		if err != nil {
+			str := fmt.Sprintf("output %d of transaction %v has an "+
+				"invalid name registration: %v", i, tx.Hash(),
+				err)
+			return txRuleError(wire.RejectInvalid, str)
+		}
+		if name == nil {
+			continue
+		}
+
+		// A name registered more than once by the same transaction
+		// would need to be renewed by spending an output of the
+		// transaction itself, which is impossible.
+		if _, exists := registered[string(name)]; exists {
+			str := fmt.Sprintf("transaction %v registers name %q "+
+				"more than once", tx.Hash(), name)
+			return txRuleError(wire.RejectInvalid, str)
+		}
+		registered[string(name)] = struct{}{}
+
+		if txR, exists := mp.names[string(name)]; exists {
+			str := fmt.Sprintf("name %q already registered by "+
+				"transaction %v in the memory pool", name,
+				txR.Hash())
+			return txRuleError(wire.RejectDuplicate, str)
+		}
+
+		regs, err := mp.cfg.FetchNameRegistrations(name)
+		if err != nil {
+			return err
+		}
+		current := blockchain.CurrentNameRegistration(regs,
+			expiryBlocks, height)
+		if current == nil {
+			continue
+		}
+		if !bytes.Equal(current.Owner, owner) {
+			str := fmt.Sprintf("output %d of transaction %v "+
+				"registers name %q which is owned by %x", i,
+				tx.Hash(), name, current.Owner)
+			return txRuleError(wire.RejectInvalid, str)
+		}
+		if !spendsOutPoint(tx, current.OutPoint) {
+			str := fmt.Sprintf("output %d of transaction %v renews "+
+				"name %q without spending its current "+
+				"registration %v", i, tx.Hash(), name,
+				current.OutPoint)
+			return txRuleError(wire.RejectInvalid, str)
+		}
+	}
+
+	return nil
+}
+
+// spendsOutPoint returns whether the passed transaction has an input which
+// spends the passed outpoint.
+func spendsOutPoint(tx *btcutil.Tx, outPoint wire.OutPoint) bool {
+	for _, txIn := range tx.MsgTx().TxIn {
+		if txIn.PreviousOutPoint == outPoint {
+			return true
+		}
+	}
+	return false
+}
+
 // CheckSpend checks whether the passed outpoint is already spent by a
 // transaction in the mempool. If that's the case the spending transaction will
 // be returned, if not nil will be returned.
@@ -692,12 +824,22 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 
 	medianTimePast := mp.cfg.MedianTimePast()
 
+	// The ciphrtxt extended opcodes are only executed once the deployment
+	// which defines them is active.  Until then, they are treated as
+	// upgradable NOPs, which are non-standard, and the registrations they
+	// make are not enforced.
+	ctExtendedActive, err := mp.cfg.IsDeploymentActive(
+		chaincfg.DeploymentCTExtended)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Don't allow non-standard transactions if the network parameters
 	// forbid their acceptance.
 	if !mp.cfg.Policy.AcceptNonStd {
 		err = checkTransactionStandard(tx, nextBlockHeight,
 			medianTimePast, mp.cfg.Policy.MinRelayTxFee,
-			mp.cfg.Policy.MaxTxVersion)
+			mp.cfg.Policy.MaxTxVersion, ctExtendedActive)
 		if err != nil {
 			// Attempt to extract a reject code from the error so
 			// it can be retained.  When not possible, fall back to
@@ -798,6 +940,17 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 		return nil, nil, err
 	}
 
+	// Don't allow transactions which register names that are owned by
+	// other keys or already registered by other transactions in the pool,
+	// or which renew names without spending their current registration,
+	// once the name registrations are enforced.
+	if ctExtendedActive {
+		err := mp.checkNameRegistrations(tx, nextBlockHeight)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Don't allow transactions with non-standard inputs if the network
 	// parameters forbid their acceptance.
 	if !mp.cfg.Policy.AcceptNonStd {
@@ -899,11 +1052,17 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit, rejec
 			mp.cfg.Policy.FreeTxRelayLimit*10*1000)
 	}
 
+	// Execute the ciphrtxt extended opcodes once the deployment which
+	// defines them is active.
+	scriptFlags := txscript.StandardVerifyFlags
+	if ctExtendedActive {
+		scriptFlags |= txscript.ScriptVerifyCTExtended
+	}
+
 	// Verify crypto signatures for each input and reject the transaction if
 	// any don't verify.
 	err = blockchain.ValidateTransactionScripts(tx, utxoView,
-		txscript.StandardVerifyFlags, mp.cfg.SigCache,
-		mp.cfg.HashCache)
+		scriptFlags, mp.cfg.SigCache, mp.cfg.HashCache)
 	if err != nil {
 		if cerr, ok := err.(blockchain.RuleError); ok {
 			return nil, nil, chainRuleError(cerr)
@@ -1236,5 +1395,6 @@ func New(cfg *Config) *TxPool {
 		orphansByPrev:  make(map[wire.OutPoint]map[chainhash.Hash]*btcutil.Tx),
 		nextExpireScan: time.Now().Add(orphanExpireScanInterval),
 		outpoints:      make(map[wire.OutPoint]*btcutil.Tx),
+		names:          make(map[string]*btcutil.Tx),
 	}
 }
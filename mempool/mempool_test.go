@@ -29,6 +29,8 @@ type fakeChain struct {
 	utxos          *blockchain.UtxoViewpoint
 	currentHeight  int32
 	medianTimePast time.Time
+	deployments    map[uint32]bool
+	names          map[string][]blockchain.NameRegistration
 }
 
 // FetchUtxoView loads utxo details about the inputs referenced by the passed
@@ -87,6 +89,47 @@ func (s *fakeChain) MedianTimePast() time.Time {
 	return mtp
 }
 
+// IsDeploymentActive returns whether the passed deployment is active for the
+// fake chain instance.  No deployments are active on the fake chain unless they
+// have been activated with SetDeploymentActive.
+func (s *fakeChain) IsDeploymentActive(deploymentID uint32) (bool, error) {
+	s.RLock()
+	active := s.deployments[deploymentID]
+	s.RUnlock()
+	return active, nil
+}
+
+// SetDeploymentActive marks the passed deployment active for the fake chain
+// instance.
+func (s *fakeChain) SetDeploymentActive(deploymentID uint32) {
+	s.Lock()
+	if s.deployments == nil {
+		s.deployments = make(map[uint32]bool)
+	}
+	s.deployments[deploymentID] = true
+	s.Unlock()
+}
+
+// FetchNameRegistrations returns the registrations of the passed name
+// associated with the fake chain instance.
+func (s *fakeChain) FetchNameRegistrations(name []byte) ([]blockchain.NameRegistration, error) {
+	s.RLock()
+	regs := s.names[string(name)]
+	s.RUnlock()
+	return regs, nil
+}
+
+// AddNameRegistration adds the passed registration of the passed name to the
+// fake chain instance.
+func (s *fakeChain) AddNameRegistration(name []byte, reg blockchain.NameRegistration) {
+	s.Lock()
+	if s.names == nil {
+		s.names = make(map[string][]blockchain.NameRegistration)
+	}
+	s.names[string(name)] = append(s.names[string(name)], reg)
+	s.Unlock()
+}
+
 // SetMedianTimePast sets the current median time past associated with the fake
 // chain instance.
 func (s *fakeChain) SetMedianTimePast(mtp time.Time) {
@@ -271,6 +314,39 @@ func (p *poolHarness) CreateTxChain(firstOutput spendableOutput, numTxns uint32)
 	return txChain, nil
 }
 
+// CreateNameTx creates a new signed transaction that spends the provided input,
+// whose public key script is the passed script, and registers the passed name
+// to the passed owner with a single output paying the full input amount to the
+// payment address associated with the harness.
+func (p *poolHarness) CreateNameTx(input spendableOutput, prevScript []byte, name []byte, owner *btcec.PublicKey) (*btcutil.Tx, error) {
+	pkScript, err := txscript.NameRegistrationScript(name, owner,
+		p.payAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: input.outPoint,
+		SignatureScript:  nil,
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	tx.AddTxOut(&wire.TxOut{
+		PkScript: pkScript,
+		Value:    int64(input.amount),
+	})
+
+	// Sign the new transaction.
+	sigScript, err := txscript.SignatureScript(tx, 0, prevScript,
+		txscript.SigHashAll, p.signKey, true)
+	if err != nil {
+		return nil, err
+	}
+	tx.TxIn[0].SignatureScript = sigScript
+
+	return btcutil.NewTx(tx), nil
+}
+
 // newPoolHarness returns a new instance of a pool harness initialized with a
 // fake chain and a TxPool bound to it that is configured with a policy suitable
 // for testing.  Also, the fake chain is populated with the returned spendable
@@ -317,13 +393,15 @@ func newPoolHarness(chainParams *chaincfg.Params) (*poolHarness, []spendableOutp
 				MinRelayTxFee:        1000, // 1 Mystiko per byte
 				MaxTxVersion:         1,
 			},
-			ChainParams:      chainParams,
-			FetchUtxoView:    chain.FetchUtxoView,
-			BestHeight:       chain.BestHeight,
-			MedianTimePast:   chain.MedianTimePast,
-			CalcSequenceLock: chain.CalcSequenceLock,
-			SigCache:         nil,
-			AddrIndex:        nil,
+			ChainParams:            chainParams,
+			FetchUtxoView:          chain.FetchUtxoView,
+			BestHeight:             chain.BestHeight,
+			MedianTimePast:         chain.MedianTimePast,
+			CalcSequenceLock:       chain.CalcSequenceLock,
+			IsDeploymentActive:     chain.IsDeploymentActive,
+			FetchNameRegistrations: chain.FetchNameRegistrations,
+			SigCache:               nil,
+			AddrIndex:              nil,
 		}),
 	}
 
@@ -866,3 +944,131 @@ func TestCheckSpend(t *testing.T) {
 		t.Fatalf("Unexpeced spend found in pool: %v", spend)
 	}
 }
+
+// TestNameRegistrations ensures transactions which register names are only
+// accepted when the names are not owned by other keys or registered by other
+// transactions in the pool, and renewals spend the current registration, and
+// that connecting a block which registers a name removes the transactions in
+// the pool which register it.
+func TestNameRegistrations(t *testing.T) {
+	t.Parallel()
+
+	harness, outputs, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+	harness.chain.SetDeploymentActive(chaincfg.DeploymentCTExtended)
+	owner := harness.signKey.PubKey()
+	otherKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to create key: %v", err)
+	}
+
+	// Split the spendable output provided by the harness into several
+	// confirmed outputs.
+	splitTx, err := harness.CreateSignedTx(outputs, 5)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	height := harness.chain.BestHeight()
+	harness.chain.utxos.AddTxOuts(splitTx, height)
+	var spendable []spendableOutput
+	for i := uint32(0); i < 5; i++ {
+		spendable = append(spendable, txOutToSpendableOut(splitTx, i))
+	}
+
+	// Register a name in the main chain.
+	name := []byte("alice")
+	regTx, err := harness.CreateNameTx(spendable[0], harness.payScript,
+		name, owner)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	harness.chain.utxos.AddTxOuts(regTx, height)
+	harness.chain.AddNameRegistration(name, blockchain.NameRegistration{
+		Owner:    owner.SerializeCompressed(),
+		OutPoint: wire.OutPoint{Hash: *regTx.Hash(), Index: 0},
+		Height:   height,
+	})
+
+	// rejectTx ensures the passed transaction is rejected with the passed
+	// reject code and is not added to the pool.
+	rejectTx := func(tx *btcutil.Tx, wantCode wire.RejectCode) {
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, 0)
+		if err == nil {
+			t.Fatalf("ProcessTransaction: accepted transaction %v",
+				tx.Hash())
+		}
+		code, extracted := extractRejectCode(err)
+		if !extracted {
+			t.Fatalf("ProcessTransaction: failed to extract reject "+
+				"code from error %q", err)
+		}
+		if code != wantCode {
+			t.Fatalf("ProcessTransaction: unexpected reject code "+
+				"-- got %v, want %v", code, wantCode)
+		}
+		testPoolMembership(tc, tx, false, false)
+	}
+
+	// acceptTx ensures the passed transaction is accepted into the pool.
+	acceptTx := func(tx *btcutil.Tx) {
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, 0)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept "+
+				"transaction %v: %v", tx.Hash(), err)
+		}
+		testPoolMembership(tc, tx, false, true)
+	}
+
+	// Ensure a renewal which does not spend the current registration is
+	// rejected.
+	tx, err := harness.CreateNameTx(spendable[1], harness.payScript, name,
+		owner)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	rejectTx(tx, wire.RejectInvalid)
+
+	// Ensure registering the name to another key is rejected.
+	tx, err = harness.CreateNameTx(spendable[1], harness.payScript, name,
+		otherKey.PubKey())
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	rejectTx(tx, wire.RejectInvalid)
+
+	// Ensure a renewal which spends the current registration is accepted.
+	regOut := txOutToSpendableOut(regTx, 0)
+	tx, err = harness.CreateNameTx(regOut, regTx.MsgTx().TxOut[0].PkScript,
+		name, owner)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	acceptTx(tx)
+
+	// Ensure a name which is not registered is accepted, but registering it
+	// again while it is registered by a transaction in the pool is not.
+	newName := []byte("bob")
+	poolTx, err := harness.CreateNameTx(spendable[2], harness.payScript,
+		newName, owner)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	acceptTx(poolTx)
+	blockTx, err := harness.CreateNameTx(spendable[3], harness.payScript,
+		newName, otherKey.PubKey())
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	rejectTx(blockTx, wire.RejectDuplicate)
+
+	// Ensure the transaction in the pool which registers the name is
+	// removed once a block registering it is connected.  The fake chain
+	// does not record the block's registration, so accepting the removed
+	// transaction again shows the pool no longer considers it registered.
+	harness.txPool.RemoveNameConflicts(blockTx)
+	testPoolMembership(tc, poolTx, false, false)
+	acceptTx(poolTx)
+}
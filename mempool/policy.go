@@ -126,8 +126,11 @@ func checkInputsStandard(tx *btcutil.Tx, utxoView *blockchain.UtxoViewpoint) err
 // script (public key script) to ensure it is a "standard" public key script.
 // A standard public key script is one that is a recognized form, and for
 // multi-signature scripts, only contains from 1 to maxStandardMultiSigKeys
-// public keys.
-func checkPkScriptStandard(pkScript []byte, scriptClass txscript.ScriptClass) error {
+// public keys and for registration scripts, registers a valid network access
+// key or name once the ciphrtxt extended opcodes are active.
+func checkPkScriptStandard(pkScript []byte, scriptClass txscript.ScriptClass,
+	ctExtendedActive bool) error {
+
 	switch scriptClass {
 	case txscript.MultiSigTy:
 		numPubKeys, numSigs, err := txscript.CalcMultiSigStats(pkScript)
@@ -164,6 +167,43 @@ func checkPkScriptStandard(pkScript []byte, scriptClass txscript.ScriptClass) er
 			return txRuleError(wire.RejectNonstandard, str)
 		}
 
+	case txscript.NAKRegistrationTy:
+		// Registrations are only standard once the deployment which
+		// defines OP_REGISTERNAK is active since they would not be
+		// enforced by the block chain before then.
+		if !ctExtendedActive {
+			return txRuleError(wire.RejectNonstandard,
+				"network access key registration before the "+
+					"ciphrtxt extended opcodes are active")
+		}
+
+		// A standard network access key registration must register a
+		// well-formed and correctly signed key since the output could
+		// otherwise never be spent.
+		if _, err := txscript.ExtractNAKRegistration(pkScript); err != nil {
+			str := fmt.Sprintf("invalid network access key "+
+				"registration: %v", err)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+
+	case txscript.NameRegistrationTy:
+		// Likewise, names are only registered once the deployment which
+		// defines OP_REGISTERNAME is active.
+		if !ctExtendedActive {
+			return txRuleError(wire.RejectNonstandard,
+				"name registration before the ciphrtxt "+
+					"extended opcodes are active")
+		}
+
+		// A standard name registration must register a valid name to
+		// a valid owner key since the output could otherwise never be
+		// spent.
+		_, _, err := txscript.ExtractNameRegistration(pkScript)
+		if err != nil {
+			str := fmt.Sprintf("invalid name registration: %v", err)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+
 	case txscript.NonStandardTy:
 		return txRuleError(wire.RejectNonstandard,
 			"non-standard script form")
@@ -274,10 +314,12 @@ func isDust(txOut *wire.TxOut, minRelayTxFee btcutil.Amount) bool {
 // "sane" transaction such as having a version in the supported range, being
 // finalized, conforming to more stringent size constraints, having scripts
 // of recognized forms, and not containing "dust" outputs (those that are
-// so small it costs more to process them than they are worth).
+// so small it costs more to process them than they are worth).  Outputs which
+// register network access keys or names are only standard when the passed
+// ctExtendedActive flag reports the deployment defining them is active.
 func checkTransactionStandard(tx *btcutil.Tx, height int32,
 	medianTimePast time.Time, minRelayTxFee btcutil.Amount,
-	maxTxVersion int32) error {
+	maxTxVersion int32, ctExtendedActive bool) error {
 
 	// The transaction must be a currently supported version.
 	msgTx := tx.MsgTx()
@@ -333,7 +375,8 @@ func checkTransactionStandard(tx *btcutil.Tx, height int32,
 	numNullDataOutputs := 0
 	for i, txOut := range msgTx.TxOut {
 		scriptClass := txscript.GetScriptClass(txOut.PkScript)
-		err := checkPkScriptStandard(txOut.PkScript, scriptClass)
+		err := checkPkScriptStandard(txOut.PkScript, scriptClass,
+			ctExtendedActive)
 		if err != nil {
 			// Attempt to extract a reject code from the error so
 			// it can be retained.  When not possible, fall back to
@@ -107,6 +107,22 @@ func TestCheckPkScriptStandard(t *testing.T) {
 		pubKeys = append(pubKeys, pk.PubKey().SerializeCompressed())
 	}
 
+	// Registrations of a correctly signed network access key and a
+	// network access key signed for a different validity period.
+	nakKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("TestCheckPkScriptStandard NewPrivateKey failed: %v",
+			err)
+	}
+	nak, err := txscript.NewNAK(nakKey, time.Unix(1500000000, 0),
+		time.Unix(1600000000, 0))
+	if err != nil {
+		t.Fatalf("TestCheckPkScriptStandard NewNAK failed: %v", err)
+	}
+	forgedNAK := *nak
+	forgedNAK.NotAfter = nak.NotAfter.Add(time.Hour)
+	pkHash := btcutil.Hash160(pubKeys[0])
+
 	tests := []struct {
 		name       string // test description.
 		script     *txscript.ScriptBuilder
@@ -183,6 +199,46 @@ func TestCheckPkScriptStandard(t *testing.T) {
 				AddData(pubKeys[0]).AddData(pubKeys[1]),
 			false,
 		},
+		{
+			"nak registration",
+			txscript.NewScriptBuilder().AddData(nak.Serialize()).
+				AddOp(txscript.OP_REGISTERNAK).AddOp(txscript.OP_DROP).
+				AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).
+				AddData(pkHash).AddOp(txscript.OP_EQUALVERIFY).
+				AddOp(txscript.OP_CHECKSIG),
+			true,
+		},
+		{
+			"nak registration with bad signature",
+			txscript.NewScriptBuilder().AddData(forgedNAK.Serialize()).
+				AddOp(txscript.OP_REGISTERNAK).AddOp(txscript.OP_DROP).
+				AddData(pubKeys[0]).AddOp(txscript.OP_CHECKSIG),
+			false,
+		},
+		{
+			"name registration",
+			txscript.NewScriptBuilder().AddData([]byte("alice")).
+				AddData(pubKeys[1]).AddOp(txscript.OP_REGISTERNAME).
+				AddOp(txscript.OP_2DROP).
+				AddData(pubKeys[0]).AddOp(txscript.OP_CHECKSIG),
+			true,
+		},
+		{
+			"invalid name registration",
+			txscript.NewScriptBuilder().AddData([]byte("Alice")).
+				AddData(pubKeys[1]).AddOp(txscript.OP_REGISTERNAME).
+				AddOp(txscript.OP_2DROP).
+				AddData(pubKeys[0]).AddOp(txscript.OP_CHECKSIG),
+			false,
+		},
+		{
+			"name registration without payment",
+			txscript.NewScriptBuilder().AddData([]byte("alice")).
+				AddData(pubKeys[1]).AddOp(txscript.OP_REGISTERNAME).
+				AddOp(txscript.OP_2DROP).
+				AddOp(txscript.OP_TRUE),
+			false,
+		},
 	}
 
 	for _, test := range tests {
@@ -193,7 +249,7 @@ func TestCheckPkScriptStandard(t *testing.T) {
 			continue
 		}
 		scriptClass := txscript.GetScriptClass(script)
-		got := checkPkScriptStandard(script, scriptClass)
+		got := checkPkScriptStandard(script, scriptClass, true)
 		if (test.isStandard && got != nil) ||
 			(!test.isStandard && got == nil) {
 
@@ -201,6 +257,19 @@ func TestCheckPkScriptStandard(t *testing.T) {
 				test.name)
 			return
 		}
+
+		// Registrations are not standard until the ciphrtxt extended
+		// opcodes are active.
+		got = checkPkScriptStandard(script, scriptClass, false)
+		isRegistration := scriptClass == txscript.NAKRegistrationTy ||
+			scriptClass == txscript.NameRegistrationTy
+		if (test.isStandard && !isRegistration && got != nil) ||
+			((!test.isStandard || isRegistration) && got == nil) {
+
+			t.Fatalf("TestCheckPkScriptStandard test '%s' failed "+
+				"before the extended opcodes are active",
+				test.name)
+		}
 	}
 }
 
@@ -470,7 +539,8 @@ func TestCheckTransactionStandard(t *testing.T) {
 	for _, test := range tests {
 		// Ensure standardness is as expected.
 		err := checkTransactionStandard(btcutil.NewTx(&test.tx),
-			test.height, pastMedianTime, DefaultMinRelayTxFee, 1)
+			test.height, pastMedianTime, DefaultMinRelayTxFee, 1,
+			true)
 		if err == nil && test.isStandard {
 			// Test passes since function returned standard for a
 			// transaction which is intended to be standard.
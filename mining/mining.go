@@ -612,6 +612,19 @@ mempoolLoop:
 	}
 	segwitActive := segwitState == blockchain.ThresholdActive
 
+	// Query the version bits state to see if the ciphrtxt extended
+	// opcodes are active for the block, so the scripts of the transactions
+	// are checked under the same rules the block will be.
+	scriptFlags := txscript.StandardVerifyFlags
+	ctExtendedState, err := g.chain.ThresholdState(
+		chaincfg.DeploymentCTExtended)
+	if err != nil {
+		return nil, err
+	}
+	if ctExtendedState == blockchain.ThresholdActive {
+		scriptFlags |= txscript.ScriptVerifyCTExtended
+	}
+
 	witnessIncluded := false
 
 	// Choose which transactions make it into the block.
@@ -748,8 +761,7 @@ mempoolLoop:
 			continue
 		}
 		err = blockchain.ValidateTransactionScripts(tx, blockUtxos,
-			txscript.StandardVerifyFlags, g.sigCache,
-			g.hashCache)
+			scriptFlags, g.sigCache, g.hashCache)
 		if err != nil {
 			log.Tracef("Skipping tx %s due to error in "+
 				"ValidateTransactionScripts: %v", tx.Hash(), err)
@@ -1252,14 +1252,15 @@ func (sm *SyncManager) handleBlockchainNotification(notification *blockchain.Not
 
 		// Remove all of the transactions (except the coinbase) in the
 		// connected block from the transaction pool.  Secondly, remove any
-		// transactions which are now double spends as a result of these
-		// new transactions.  Finally, remove any transaction that is
-		// no longer an orphan. Transactions which depend on a confirmed
-		// transaction are NOT removed recursively because they are still
-		// valid.
+		// transactions which are now double spends or register the same
+		// names as a result of these new transactions.  Finally, remove
+		// any transaction that is no longer an orphan. Transactions which
+		// depend on a confirmed transaction are NOT removed recursively
+		// because they are still valid.
 		for _, tx := range block.Transactions()[1:] {
 			sm.txMemPool.RemoveTransaction(tx, false)
 			sm.txMemPool.RemoveDoubleSpends(tx)
+			sm.txMemPool.RemoveNameConflicts(tx)
 			sm.txMemPool.RemoveOrphan(tx)
 			sm.peerNotifier.TransactionConfirmed(tx)
 			acceptedTxs := sm.txMemPool.ProcessOrphans(tx)
@@ -135,6 +135,21 @@ type MessageListeners struct {
 	// message.
 	OnCFCheckpt func(p *Peer, msg *wire.MsgCFCheckpt)
 
+	// OnCtMsg is invoked when a peer receives a ctmsg ciphrtxt message.
+	OnCtMsg func(p *Peer, msg *wire.MsgCtMsg)
+
+	// OnGetCtDigests is invoked when a peer receives a getctdigests
+	// ciphrtxt message.
+	OnGetCtDigests func(p *Peer, msg *wire.MsgGetCtDigests)
+
+	// OnCtDigests is invoked when a peer receives a ctdigests ciphrtxt
+	// message.
+	OnCtDigests func(p *Peer, msg *wire.MsgCtDigests)
+
+	// OnGetCtInv is invoked when a peer receives a getctinv ciphrtxt
+	// message.
+	OnGetCtInv func(p *Peer, msg *wire.MsgGetCtInv)
+
 	// OnInv is invoked when a peer receives an inv bitcoin message.
 	OnInv func(p *Peer, msg *wire.MsgInv)
 
@@ -1153,10 +1168,11 @@ func (p *Peer) maybeAddDeadline(pendingResponses map[string]time.Time, msgCmd st
 		pendingResponses[wire.CmdInv] = deadline
 
 	case wire.CmdGetData:
-		// Expects a block, merkleblock, tx, or notfound message.
+		// Expects a block, merkleblock, tx, ctmsg, or notfound message.
 		pendingResponses[wire.CmdBlock] = deadline
 		pendingResponses[wire.CmdMerkleBlock] = deadline
 		pendingResponses[wire.CmdTx] = deadline
+		pendingResponses[wire.CmdCtMsg] = deadline
 		pendingResponses[wire.CmdNotFound] = deadline
 
 	case wire.CmdGetHeaders:
@@ -1216,10 +1232,13 @@ out:
 					fallthrough
 				case wire.CmdTx:
 					fallthrough
+				case wire.CmdCtMsg:
+					fallthrough
 				case wire.CmdNotFound:
 					delete(pendingResponses, wire.CmdBlock)
 					delete(pendingResponses, wire.CmdMerkleBlock)
 					delete(pendingResponses, wire.CmdTx)
+					delete(pendingResponses, wire.CmdCtMsg)
 					delete(pendingResponses, wire.CmdNotFound)
 
 				default:
@@ -1489,6 +1508,26 @@ out:
 				p.cfg.Listeners.OnCFHeaders(p, msg)
 			}
 
+		case *wire.MsgCtMsg:
+			if p.cfg.Listeners.OnCtMsg != nil {
+				p.cfg.Listeners.OnCtMsg(p, msg)
+			}
+
+		case *wire.MsgGetCtDigests:
+			if p.cfg.Listeners.OnGetCtDigests != nil {
+				p.cfg.Listeners.OnGetCtDigests(p, msg)
+			}
+
+		case *wire.MsgCtDigests:
+			if p.cfg.Listeners.OnCtDigests != nil {
+				p.cfg.Listeners.OnCtDigests(p, msg)
+			}
+
+		case *wire.MsgGetCtInv:
+			if p.cfg.Listeners.OnGetCtInv != nil {
+				p.cfg.Listeners.OnGetCtInv(p, msg)
+			}
+
 		case *wire.MsgFeeFilter:
 			if p.cfg.Listeners.OnFeeFilter != nil {
 				p.cfg.Listeners.OnFeeFilter(p, msg)
@@ -0,0 +1,165 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// restRateBurstPeriod is the period of time over which a REST client
+	// may burst.  A client which has been idle may issue up to this
+	// period's worth of requests and bytes at once before being limited.
+	restRateBurstPeriod = time.Second * 10
+
+	// restRateIdleTimeout is the amount of time after which the rate limit
+	// state of an idle client is discarded.  A client which has been idle
+	// this long has a full bucket anyway, so nothing is lost.
+	restRateIdleTimeout = time.Minute * 10
+)
+
+// tokenBucket is a simple token bucket used to rate limit a single resource.
+// Tokens are added continuously at a fixed rate up to a maximum burst.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens accumulated since the bucket was last updated.
+func (b *tokenBucket) refill(now time.Time, rate, burst float64) {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+}
+
+// restClientLimits houses the rate limit state of a single REST client.
+type restClientLimits struct {
+	requests tokenBucket
+	bytes    tokenBucket
+}
+
+// restRateLimiter limits the rate of requests and the rate of bytes transferred
+// by each REST client, as identified by its IP address.  A rate of zero
+// disables the corresponding limit.
+type restRateLimiter struct {
+	requestRate  float64
+	requestBurst float64
+	byteRate     float64
+	byteBurst    float64
+
+	mtx       sync.Mutex
+	clients   map[string]*restClientLimits
+	lastPrune time.Time
+}
+
+// newRESTRateLimiter returns a rate limiter which allows each client the
+// passed number of requests and bytes per second.
+func newRESTRateLimiter(requestRate, byteRate float64) *restRateLimiter {
+	burst := restRateBurstPeriod.Seconds()
+	return &restRateLimiter{
+		requestRate:  requestRate,
+		requestBurst: requestRate * burst,
+		byteRate:     byteRate,
+		byteBurst:    byteRate * burst,
+		clients:      make(map[string]*restClientLimits),
+	}
+}
+
+// clientKey returns the key used to track the client with the passed remote
+// address, which is the IP address without the port.
+func clientKey(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// client returns the rate limit state for the passed client, creating it with
+// full buckets if needed, after refilling its buckets.
+//
+// This function MUST be called with the limiter lock held.
+func (l *restRateLimiter) client(key string, now time.Time) *restClientLimits {
+	c, ok := l.clients[key]
+	if !ok {
+		c = &restClientLimits{
+			requests: tokenBucket{tokens: l.requestBurst, last: now},
+			bytes:    tokenBucket{tokens: l.byteBurst, last: now},
+		}
+		l.clients[key] = c
+	}
+	c.requests.refill(now, l.requestRate, l.requestBurst)
+	c.bytes.refill(now, l.byteRate, l.byteBurst)
+	return c
+}
+
+// prune discards the state of clients which have been idle long enough that
+// their buckets are full.
+//
+// This function MUST be called with the limiter lock held.
+func (l *restRateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < restRateIdleTimeout {
+		return
+	}
+	for key, c := range l.clients {
+		if now.Sub(c.requests.last) > restRateIdleTimeout &&
+			now.Sub(c.bytes.last) > restRateIdleTimeout {
+
+			delete(l.clients, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+// Allow returns whether a new request from the client with the passed remote
+// address may proceed, consuming a request token when it may.  Requests are
+// refused while the client has exhausted either its request or byte
+// allowance.
+//
+// This function is safe for concurrent access.
+func (l *restRateLimiter) Allow(remoteAddr string) bool {
+	if l.requestRate <= 0 && l.byteRate <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.prune(now)
+
+	c := l.client(clientKey(remoteAddr), now)
+	if l.byteRate > 0 && c.bytes.tokens <= 0 {
+		return false
+	}
+	if l.requestRate > 0 {
+		if c.requests.tokens < 1 {
+			return false
+		}
+		c.requests.tokens--
+	}
+	return true
+}
+
+// AddBytes charges the passed number of transferred bytes to the client with
+// the passed remote address.  Since the size of a transfer is only known once
+// it completes, the allowance may be overdrawn, in which case further
+// requests are refused until it recovers.
+//
+// This function is safe for concurrent access.
+func (l *restRateLimiter) AddBytes(remoteAddr string, n int64) {
+	if l.byteRate <= 0 || n == 0 {
+		return
+	}
+
+	now := time.Now()
+	l.mtx.Lock()
+	c := l.client(clientKey(remoteAddr), now)
+	c.bytes.tokens -= float64(n)
+	l.mtx.Unlock()
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRESTRateLimiter ensures the REST rate limiter allows each client to
+// burst up to its allowance, refuses further requests and tracks clients
+// independently.
+func TestRESTRateLimiter(t *testing.T) {
+	// One request per second allows a burst of ten requests.
+	l := newRESTRateLimiter(1, 0)
+	for i := 0; i < 10; i++ {
+		if !l.Allow("10.0.0.1:1234") {
+			t.Fatalf("request %d refused within burst", i)
+		}
+	}
+	if l.Allow("10.0.0.1:1234") {
+		t.Fatal("request allowed after burst was exhausted")
+	}
+
+	// The limit applies to the address regardless of the port.
+	if l.Allow("10.0.0.1:4321") {
+		t.Fatal("request allowed from a different port of a limited " +
+			"address")
+	}
+
+	// Other clients are unaffected.
+	if !l.Allow("10.0.0.2:1234") {
+		t.Fatal("request from another client refused")
+	}
+
+	// One hundred bytes per second allows a burst of a thousand bytes.
+	// The allowance may be overdrawn by a single transfer, after which
+	// requests are refused.
+	l = newRESTRateLimiter(0, 100)
+	if !l.Allow("[::1]:1234") {
+		t.Fatal("first request refused")
+	}
+	l.AddBytes("[::1]:1234", 999)
+	if !l.Allow("[::1]:1234") {
+		t.Fatal("request refused within byte allowance")
+	}
+	l.AddBytes("[::1]:1234", 5000)
+	if l.Allow("[::1]:1234") {
+		t.Fatal("request allowed after byte allowance was exhausted")
+	}
+
+	// No limits allow everything.
+	l = newRESTRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		l.AddBytes("10.0.0.1:1234", 1<<20)
+		if !l.Allow("10.0.0.1:1234") {
+			t.Fatal("request refused with rate limits disabled")
+		}
+	}
+}
+
+// TestRESTRateLimiterPrune ensures the state of idle clients is discarded.
+func TestRESTRateLimiterPrune(t *testing.T) {
+	l := newRESTRateLimiter(1, 1)
+	l.Allow("10.0.0.1:1234")
+	l.Allow("10.0.0.2:1234")
+	if len(l.clients) != 2 {
+		t.Fatalf("unexpected number of clients - got %d, want 2",
+			len(l.clients))
+	}
+
+	// Nothing is discarded before the idle timeout.
+	now := time.Now()
+	l.mtx.Lock()
+	l.prune(now.Add(restRateIdleTimeout / 2))
+	l.mtx.Unlock()
+	if len(l.clients) != 2 {
+		t.Fatalf("unexpected number of clients - got %d, want 2",
+			len(l.clients))
+	}
+
+	l.mtx.Lock()
+	l.client("10.0.0.2", now.Add(restRateIdleTimeout))
+	l.prune(now.Add(restRateIdleTimeout + time.Second))
+	l.mtx.Unlock()
+	if _, ok := l.clients["10.0.0.1"]; ok {
+		t.Fatal("idle client was not discarded")
+	}
+	if _, ok := l.clients["10.0.0.2"]; !ok {
+		t.Fatal("active client was discarded")
+	}
+}
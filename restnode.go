@@ -0,0 +1,370 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jadeblaquiere/cttd/blockchain"
+	"github.com/jadeblaquiere/cttd/btcec"
+	"github.com/jadeblaquiere/cttd/btcjson"
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+	"github.com/jadeblaquiere/cttd/database"
+	"github.com/jadeblaquiere/cttd/wire"
+	"github.com/jadeblaquiere/cttutil"
+)
+
+// chainInfoResult is the response to a chain tip request.
+type chainInfoResult struct {
+	Chain      string  `json:"chain"`
+	Height     int32   `json:"height"`
+	Hash       string  `json:"hash"`
+	Bits       string  `json:"bits"`
+	Difficulty float64 `json:"difficulty"`
+	Time       int64   `json:"time"`
+	MedianTime int64   `json:"mediantime"`
+	TotalTxns  uint64  `json:"totaltxns"`
+}
+
+// blockHashFromVars returns the hash of the main chain block identified by
+// either the hash or the height path variable of the request.  False is
+// returned, after responding with an error, when the block can not be found.
+func (ctrs *ctRestServer) blockHashFromVars(w http.ResponseWriter, r *http.Request) (*chainhash.Hash, int32, bool) {
+	chain := ctrs.cfg.Server.chain
+	vars := mux.Vars(r)
+	if heightStr, ok := vars["height"]; ok {
+		height, err := strconv.ParseInt(heightStr, 10, 32)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid height")
+			return nil, 0, false
+		}
+		hash, err := chain.BlockHashByHeight(int32(height))
+		if err != nil {
+			respondWithError(w, http.StatusNotFound,
+				"Block number out of range")
+			return nil, 0, false
+		}
+		return hash, int32(height), true
+	}
+
+	hash, err := chainhash.NewHashFromStr(vars["hash"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid block hash")
+		return nil, 0, false
+	}
+	height, err := chain.BlockHeightByHash(hash)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Block not found")
+		return nil, 0, false
+	}
+	return hash, height, true
+}
+
+// nextBlockHash returns the hash of the main chain block after the passed
+// height, or an empty string when the height is the chain tip.
+func nextBlockHash(chain *blockchain.BlockChain, height, bestHeight int32) string {
+	if height >= bestHeight {
+		return ""
+	}
+	nextHash, err := chain.BlockHashByHeight(height + 1)
+	if err != nil {
+		// The chain was reorganized underneath the request.
+		return ""
+	}
+	return nextHash.String()
+}
+
+// getChainInfo returns a summary of the current chain tip.
+func (ctrs *ctRestServer) getChainInfo(w http.ResponseWriter, r *http.Request) {
+	s := ctrs.cfg.Server
+	best := s.chain.BestSnapshot()
+	header, err := s.chain.HeaderByHash(&best.Hash)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError,
+			"Failed to fetch best block header")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, &chainInfoResult{
+		Chain:      s.chainParams.Name,
+		Height:     best.Height,
+		Hash:       best.Hash.String(),
+		Bits:       strconv.FormatInt(int64(best.Bits), 16),
+		Difficulty: getDifficultyRatio(best.Bits, s.chainParams),
+		Time:       header.Timestamp.Unix(),
+		MedianTime: best.MedianTime.Unix(),
+		TotalTxns:  best.TotalTxns,
+	})
+}
+
+// getBlock returns a main chain block, identified by hash or height, with the
+// hashes of its transactions.
+func (ctrs *ctRestServer) getBlock(w http.ResponseWriter, r *http.Request) {
+	hash, height, ok := ctrs.blockHashFromVars(w, r)
+	if !ok {
+		return
+	}
+
+	s := ctrs.cfg.Server
+	var blkBytes []byte
+	err := s.db.View(func(dbTx database.Tx) error {
+		var err error
+		blkBytes, err = dbTx.FetchBlock(hash)
+		return err
+	})
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Block not found")
+		return
+	}
+	blk, err := btcutil.NewBlockFromBytes(blkBytes)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError,
+			"Failed to deserialize block")
+		return
+	}
+
+	best := s.chain.BestSnapshot()
+	blockHeader := &blk.MsgBlock().Header
+	transactions := blk.Transactions()
+	txNames := make([]string, len(transactions))
+	for i, tx := range transactions {
+		txNames[i] = tx.Hash().String()
+	}
+	respondWithJSON(w, http.StatusOK, &btcjson.GetBlockVerboseResult{
+		Hash:          hash.String(),
+		Version:       blockHeader.Version,
+		VersionHex:    fmt.Sprintf("%08x", blockHeader.Version),
+		MerkleRoot:    blockHeader.MerkleRoot.String(),
+		PreviousHash:  blockHeader.PrevBlock.String(),
+		Nonce:         blockHeader.Nonce,
+		Time:          blockHeader.Timestamp.Unix(),
+		Confirmations: int64(1 + best.Height - height),
+		Height:        int64(height),
+		Size:          int32(len(blkBytes)),
+		StrippedSize:  int32(blk.MsgBlock().SerializeSizeStripped()),
+		Weight:        int32(blockchain.GetBlockWeight(blk)),
+		Bits:          strconv.FormatInt(int64(blockHeader.Bits), 16),
+		Difficulty:    getDifficultyRatio(blockHeader.Bits, s.chainParams),
+		Tx:            txNames,
+		NextHash:      nextBlockHash(s.chain, height, best.Height),
+	})
+}
+
+// getBlockHeader returns the header of a main chain block identified by hash
+// or height.
+func (ctrs *ctRestServer) getBlockHeader(w http.ResponseWriter, r *http.Request) {
+	hash, height, ok := ctrs.blockHashFromVars(w, r)
+	if !ok {
+		return
+	}
+
+	s := ctrs.cfg.Server
+	blockHeader, err := s.chain.HeaderByHash(hash)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Block not found")
+		return
+	}
+
+	best := s.chain.BestSnapshot()
+	respondWithJSON(w, http.StatusOK, &btcjson.GetBlockHeaderVerboseResult{
+		Hash:          hash.String(),
+		Confirmations: int64(1 + best.Height - height),
+		Height:        height,
+		Version:       blockHeader.Version,
+		VersionHex:    fmt.Sprintf("%08x", blockHeader.Version),
+		MerkleRoot:    blockHeader.MerkleRoot.String(),
+		NextHash:      nextBlockHash(s.chain, height, best.Height),
+		PreviousHash:  blockHeader.PrevBlock.String(),
+		Nonce:         uint64(blockHeader.Nonce),
+		Time:          blockHeader.Timestamp.Unix(),
+		Bits:          strconv.FormatInt(int64(blockHeader.Bits), 16),
+		Difficulty:    getDifficultyRatio(blockHeader.Bits, s.chainParams),
+	})
+}
+
+// getTransaction returns a transaction from the memory pool or, when the
+// transaction index is enabled, from the main chain.
+func (ctrs *ctRestServer) getTransaction(w http.ResponseWriter, r *http.Request) {
+	txHash, err := chainhash.NewHashFromStr(mux.Vars(r)["txid"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	s := ctrs.cfg.Server
+	var mtx *wire.MsgTx
+	var blkHeader *wire.BlockHeader
+	var blkHashStr string
+	var blkHeight, chainHeight int32
+	tx, err := s.txMemPool.FetchTransaction(txHash)
+	if err == nil {
+		mtx = tx.MsgTx()
+	} else {
+		if s.txIndex == nil {
+			respondWithError(w, http.StatusNotFound,
+				"No such mempool transaction.  The transaction "+
+					"index is not enabled.")
+			return
+		}
+
+		blockRegion, err := s.txIndex.TxBlockRegion(txHash)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError,
+				"Failed to retrieve transaction location")
+			return
+		}
+		if blockRegion == nil {
+			respondWithError(w, http.StatusNotFound,
+				"Transaction not found")
+			return
+		}
+
+		var txBytes []byte
+		err = s.db.View(func(dbTx database.Tx) error {
+			var err error
+			txBytes, err = dbTx.FetchBlockRegion(blockRegion)
+			return err
+		})
+		if err != nil {
+			respondWithError(w, http.StatusNotFound,
+				"Transaction not found")
+			return
+		}
+		tx, err := btcutil.NewTxFromBytes(txBytes)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError,
+				"Failed to deserialize transaction")
+			return
+		}
+		mtx = tx.MsgTx()
+
+		blkHeight, err = s.chain.BlockHeightByHash(blockRegion.Hash)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError,
+				"Failed to retrieve block height")
+			return
+		}
+		header, err := s.chain.HeaderByHash(blockRegion.Hash)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError,
+				"Failed to fetch block header")
+			return
+		}
+		blkHeader = &header
+		blkHashStr = blockRegion.Hash.String()
+		chainHeight = s.chain.BestSnapshot().Height
+	}
+
+	rawTxn, err := createTxRawResult(s.chainParams, mtx, txHash.String(),
+		blkHeader, blkHashStr, blkHeight, chainHeight)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError,
+			"Failed to encode transaction")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, rawTxn)
+}
+
+// getMempoolInfo returns a summary of the transaction memory pool.
+func (ctrs *ctRestServer) getMempoolInfo(w http.ResponseWriter, r *http.Request) {
+	mempoolTxns := ctrs.cfg.Server.txMemPool.TxDescs()
+
+	var numBytes int64
+	for _, txD := range mempoolTxns {
+		numBytes += int64(txD.Tx.MsgTx().SerializeSize())
+	}
+
+	respondWithJSON(w, http.StatusOK, &btcjson.GetMempoolInfoResult{
+		Size:  int64(len(mempoolTxns)),
+		Bytes: numBytes,
+	})
+}
+
+// getMsgStoreInfo returns statistics describing the message store and the
+// message acceptance policy.
+func (ctrs *ctRestServer) getMsgStoreInfo(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK,
+		newMessageStoreInfoResult(ctrs.cfg.MsgSvc))
+}
+
+// getNAK returns the registrations of the network access key with the
+// compressed public key in the request path.
+func (ctrs *ctRestServer) getNAK(w http.ResponseWriter, r *http.Request) {
+	s := ctrs.cfg.Server
+	if s.nakIndex == nil {
+		respondWithError(w, http.StatusNotFound,
+			"The network access key index is not enabled")
+		return
+	}
+
+	pubKey, err := hex.DecodeString(mux.Vars(r)["pubkey"])
+	if err != nil || !btcec.IsCompressedPubKey(pubKey) {
+		respondWithError(w, http.StatusBadRequest, "Invalid public key")
+		return
+	}
+
+	regs, err := s.nakIndex.FetchNAK(pubKey)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError,
+			"Failed to fetch network access key")
+		return
+	}
+	if len(regs) == 0 {
+		respondWithError(w, http.StatusNotFound,
+			"Network access key not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, nakResult(pubKey, regs,
+		s.timeSource.AdjustedTime()))
+}
+
+// listNAKs returns the registered network access keys ordered by public key.
+// The optional after query parameter resumes the listing after the passed
+// hex-encoded compressed public key and limit bounds the number of keys
+// returned.
+func (ctrs *ctRestServer) listNAKs(w http.ResponseWriter, r *http.Request) {
+	s := ctrs.cfg.Server
+	if s.nakIndex == nil {
+		respondWithError(w, http.StatusNotFound,
+			"The network access key index is not enabled")
+		return
+	}
+
+	query := r.URL.Query()
+	after, err := hex.DecodeString(query.Get("after"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid after parameter")
+		return
+	}
+	limit := restDefaultListLimit
+	if str := query.Get("limit"); str != "" {
+		limit, err = strconv.Atoi(str)
+		if err != nil || limit <= 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		if limit > restMaxListLimit {
+			limit = restMaxListLimit
+		}
+	}
+
+	entries, err := s.nakIndex.ListNAKs(after, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError,
+			"Failed to list network access keys")
+		return
+	}
+	now := s.timeSource.AdjustedTime()
+	results := make([]btcjson.NAKResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, *nakResult(entry.PubKey,
+			entry.Registrations, now))
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
@@ -5,21 +5,25 @@
 package main
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
-	//"fmt"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"net"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/jadeblaquiere/ctclient/ctgo"
+	"github.com/jadeblaquiere/cttd/btcjson"
+	"github.com/jadeblaquiere/cttd/ctmsg"
 )
 
 // API version constants
@@ -33,6 +37,22 @@ const (
 	// REST server is allowed to stay open without authenticating before it
 	// is closed.
 	restAuthTimeoutSeconds = 10
+
+	// restDefaultListLimit is the number of message hashes returned by a
+	// listing request which does not specify a limit.
+	restDefaultListLimit = 1000
+
+	// restMaxListLimit is the maximum number of message hashes returned by
+	// a single listing request.
+	restMaxListLimit = 10000
+
+	// restMaxHeaderQuery is the maximum number of message hashes which may
+	// be requested by a single header query.
+	restMaxHeaderQuery = 1000
+
+	// restMaxHeaderQuerySize is the maximum size in bytes of the body of a
+	// header query.
+	restMaxHeaderQuerySize = 1024 * 1024
 )
 
 var (
@@ -46,8 +66,22 @@ type restServerConfig struct {
 	// is stopped.
 	Listeners []net.Listener
 
-	MStore *ctgo.MessageStore
+	MsgSvc *ctmsg.CiphrtxtMsgSvc
 	Server *server
+
+	// APITokens are the tokens accepted for write requests.  Write
+	// requests are not authenticated when no tokens are configured.
+	APITokens []string
+
+	// MaxClients is the maximum number of requests served concurrently.
+	// Zero disables the limit.
+	MaxClients int
+
+	// RequestRate and ByteRate are the number of requests and the number
+	// of bytes per second allowed for each client IP address.  Zero
+	// disables the corresponding limit.
+	RequestRate float64
+	ByteRate    float64
 }
 
 type ctRestServer struct {
@@ -55,6 +89,10 @@ type ctRestServer struct {
 	cfg      *restServerConfig
 	wg       sync.WaitGroup
 	shutdown int32
+
+	numClients  int32
+	limiter     *restRateLimiter
+	tokenHashes [][sha256.Size]byte
 }
 
 func respondWithError(w http.ResponseWriter, code int, message string) {
@@ -69,11 +107,117 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Write(response)
 }
 
-func respondWithFileOctetStream(w http.ResponseWriter, code int, bfile *os.File) {
+func respondWithOctetStream(w http.ResponseWriter, code int, data []byte) {
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("Content-Transfer-Encoding", "binary")
 	w.WriteHeader(code)
-	io.Copy(w, bfile)
+	w.Write(data)
+}
+
+// countingReader counts the bytes read from the wrapped reader.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// countingResponseWriter counts the bytes written to the wrapped response
+// writer.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// limitConnections responds with a 503 service unavailable and returns true if
+// adding another client would exceed the maximum allowed REST clients.
+//
+// This function is safe for concurrent access.
+func (ctrs *ctRestServer) limitConnections(w http.ResponseWriter, remoteAddr string) bool {
+	maxClients := ctrs.cfg.MaxClients
+	if maxClients > 0 && int(atomic.LoadInt32(&ctrs.numClients)+1) > maxClients {
+		restLog.Infof("Max REST clients exceeded [%d] - "+
+			"disconnecting client %s", maxClients, remoteAddr)
+		respondWithError(w, http.StatusServiceUnavailable,
+			"Too busy.  Try again later.")
+		return true
+	}
+	return false
+}
+
+// ServeHTTP serves a REST request after applying the connection and rate
+// limits.  The bytes read and written on behalf of the request are charged to
+// the client once the request completes.
+func (ctrs *ctRestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ctrs.limitConnections(w, r.RemoteAddr) {
+		return
+	}
+	atomic.AddInt32(&ctrs.numClients, 1)
+	defer atomic.AddInt32(&ctrs.numClients, -1)
+
+	if !ctrs.limiter.Allow(r.RemoteAddr) {
+		restLog.Debugf("Rate limit exceeded by client %s", r.RemoteAddr)
+		respondWithError(w, http.StatusTooManyRequests,
+			"Rate limit exceeded.  Try again later.")
+		return
+	}
+
+	body := &countingReader{ReadCloser: r.Body}
+	r.Body = body
+	cw := &countingResponseWriter{ResponseWriter: w}
+	ctrs.Router.ServeHTTP(cw, r)
+	ctrs.limiter.AddBytes(r.RemoteAddr, body.n+cw.n)
+}
+
+// checkToken returns whether the request carries one of the configured API
+// tokens as a bearer token in its Authorization header.  All requests are
+// accepted when no tokens are configured.
+func (ctrs *ctRestServer) checkToken(r *http.Request) bool {
+	if len(ctrs.tokenHashes) == 0 {
+		return true
+	}
+
+	const prefix = "Bearer "
+	authhdr := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authhdr, prefix) {
+		return false
+	}
+
+	// Check against all tokens using a constant time comparison on the
+	// hashes to avoid leaking which token (and which prefix of it) was
+	// closest to matching.
+	tokensha := sha256.Sum256([]byte(authhdr[len(prefix):]))
+	valid := 0
+	for i := range ctrs.tokenHashes {
+		valid |= subtle.ConstantTimeCompare(tokensha[:],
+			ctrs.tokenHashes[i][:])
+	}
+	return valid == 1
+}
+
+// requireToken wraps the passed handler so it is only invoked for requests
+// which carry a valid API token.
+func (ctrs *ctRestServer) requireToken(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !ctrs.checkToken(r) {
+			restLog.Debugf("Unauthorized %s %s from %s", r.Method,
+				r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cttd REST"`)
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		h(w, r)
+	}
 }
 
 func (ctrs *ctRestServer) getMessage(w http.ResponseWriter, r *http.Request) {
@@ -84,72 +228,228 @@ func (ctrs *ctRestServer) getMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mf := ctrs.cfg.MStore.GetMessage(mhash)
-	cfile, err := mf.CiphertextFile()
+	msg, err := ctrs.cfg.MsgSvc.FetchMessage(mhash)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Message Not Found")
 		return
 	}
-	defer cfile.Close()
-	respondWithFileOctetStream(w, http.StatusOK, cfile)
+	respondWithOctetStream(w, http.StatusOK, msg)
 }
 
 func (ctrs *ctRestServer) postMessage(w http.ResponseWriter, r *http.Request) {
-	file, err := ioutil.TempFile("", "MStoreg")
-	if err != nil {
-		// failed to create a temp file ? No recovery
-		respondWithError(w, http.StatusInternalServerError, "Error receiving messages")
+	// Never read more than the largest acceptable message.  The message
+	// service reads and validates the header before consuming the body,
+	// so unacceptable messages are refused without buffering them.
+	maxSize := int64(ctrs.cfg.MsgSvc.Policy().MaxMessageSize)
+	body := http.MaxBytesReader(w, r.Body, maxSize+1)
+
+	// Ingesting through the message service (rather than directly into the
+	// store) ensures newly accepted messages are announced to peers.
+	_, err := ctrs.cfg.MsgSvc.IngestMessage(body)
+	if rerr, ok := err.(ctmsg.RuleError); ok {
+		code := http.StatusBadRequest
+		if rerr.ErrorCode == ctmsg.ErrMessageTooLarge {
+			code = http.StatusRequestEntityTooLarge
+		}
+		restLog.Debugf("Rejected message from %s: %v", r.RemoteAddr, err)
+		respondWithError(w, code, rerr.Description)
 		return
 	}
+	switch err {
+	case nil, ctmsg.ErrDuplicateMessage:
+		// A repeated post of a stored message is not an error.
 
-	// todo: preview header and validate, disconnect on header error
+	case ctmsg.ErrInvalidMessage:
+		// failed to import as MessageFile - bad data
+		respondWithError(w, http.StatusBadRequest, "Invalid Message File")
+		return
 
-	io.Copy(file, r.Body)
-	finfo, err := file.Stat()
-	if err != nil {
-		// can't stat temp file ? No recovery
+	default:
+		restLog.Errorf("Failed to ingest message: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Error receiving messages")
 		return
 	}
 
-	filename := finfo.Name()
+	w.WriteHeader(http.StatusOK)
+	//fall through respond
+}
+
+// listMessagesResult is the response to a message listing request.
+type listMessagesResult struct {
+	Total      *int     `json:"total,omitempty"`
+	Messages   []string `json:"messages"`
+	NextCursor string   `json:"nextcursor,omitempty"`
+}
 
-	file.Close()
-	mf, err := ctgo.NewMessageFile(os.TempDir() + "/" + filename)
+// parseTimeParam parses the named query parameter as a unix time in seconds.
+// The passed default is returned when the parameter is absent.
+func parseTimeParam(r *http.Request, name string, def time.Time) (time.Time, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	secs, err := strconv.ParseInt(s, 10, 64)
 	if err != nil {
-		// failed to import as MessageFile - bad data
-		respondWithError(w, http.StatusBadRequest, "Invalid Message File")
+		return time.Time{}, fmt.Errorf("Invalid %s parameter", name)
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// listMessages returns the hashes of the stored messages, ordered by message
+// time and then hash.  The optional since and until query parameters (unix
+// times in seconds) restrict the listing to messages with a timestamp in
+// [since, until), limit bounds the number of hashes returned, and cursor
+// resumes a previous listing from the nextcursor it returned.  The total number
+// of messages in the range is only included in the first page of a listing.
+func (ctrs *ctRestServer) listMessages(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Messages may be stamped slightly in the future, so include them in
+	// the listing by default.
+	now := time.Now()
+	maxUntil := now.Add(ctrs.cfg.MsgSvc.Policy().MaxTimeOffset)
+	since, err := parseTimeParam(r, "since", time.Unix(0, 0))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	until, err := parseTimeParam(r, "until", maxUntil)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	err = ctrs.cfg.MStore.IngestMessageFile(mf)
+	limit := restDefaultListLimit
+	if s := query.Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+		if limit > restMaxListLimit {
+			limit = restMaxListLimit
+		}
+	}
+
+	var cursor *ctmsg.ListCursor
+	if s := query.Get("cursor"); s != "" {
+		cursor, err = ctmsg.ParseListCursor(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor parameter")
+			return
+		}
+	}
+
+	list, err := ctrs.cfg.MsgSvc.ListMessages(since, until, cursor, limit)
 	if err != nil {
-		// path error on Stat() ? No recovery
-		respondWithError(w, http.StatusInternalServerError, "Error receiving messages")
+		respondWithError(w, http.StatusInternalServerError, "Error retreiving messages")
 		return
 	}
+	result := listMessagesResult{
+		Messages: make([]string, len(list.Hashes)),
+	}
+	if list.Total >= 0 {
+		result.Total = &list.Total
+	}
+	for i, h := range list.Hashes {
+		result.Messages[i] = hex.EncodeToString(h)
+	}
+	if list.Next != nil {
+		result.NextCursor = list.Next.String()
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
 
-	w.WriteHeader(http.StatusOK)
-	//fall through respond
+// newMessageHeaderResult returns the JSON representation of the header of
+// the message identified by hash.
+func newMessageHeaderResult(hash []byte, h *ctmsg.MessageHeader) *btcjson.MessageHeaderResult {
+	var nonce [8]byte
+	binary.BigEndian.PutUint64(nonce[:], h.Nonce)
+	return &btcjson.MessageHeaderResult{
+		Hash:       hex.EncodeToString(hash),
+		Version:    h.Version,
+		Size:       h.MsgLen,
+		Time:       h.MsgTime.Unix(),
+		TimeUsec:   h.MsgTime.UnixNano() / int64(time.Microsecond),
+		Expire:     int64(h.Expire / time.Second),
+		ExpireTime: h.ExpireTime().Unix(),
+		I:          hex.EncodeToString(h.I[:]),
+		J:          hex.EncodeToString(h.J[:]),
+		K:          hex.EncodeToString(h.K[:]),
+		Nonce:      hex.EncodeToString(nonce[:]),
+	}
 }
 
-func (ctrs *ctRestServer) listMessages(w http.ResponseWriter, r *http.Request) {
-	hlist, err := ctrs.cfg.MStore.ListHashesForInterval(ctgo.UTimeToTime(0), time.Now())
+// getMessageHeader returns the parsed header of a stored message.
+func (ctrs *ctRestServer) getMessageHeader(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	mhash, err := hex.DecodeString(vars["msgid"])
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error retreiving messages")
+		respondWithError(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+
+	hdr, err := ctrs.cfg.MsgSvc.FetchHeader(mhash)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Message Not Found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newMessageHeaderResult(mhash, hdr))
+}
+
+// queryHeadersRequest is the body of a bulk header query.
+type queryHeadersRequest struct {
+	Hashes []string `json:"hashes"`
+}
+
+// queryHeadersResult is the response to a bulk header query.  Hashes which
+// do not identify a stored message are returned in NotFound.
+type queryHeadersResult struct {
+	Headers  []*btcjson.MessageHeaderResult `json:"headers"`
+	NotFound []string                       `json:"notfound"`
+}
+
+// queryHeaders returns the parsed headers of the requested stored messages.
+func (ctrs *ctRestServer) queryHeaders(w http.ResponseWriter, r *http.Request) {
+	var req queryHeadersRequest
+	body := http.MaxBytesReader(w, r.Body, restMaxHeaderQuerySize)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid header query")
 		return
 	}
-	lmr := make([]string, len(hlist))
-	for i, h := range hlist {
-		lmr[i] = hex.EncodeToString(h)
+	if len(req.Hashes) > restMaxHeaderQuery {
+		str := fmt.Sprintf("Too many hashes in header query (max %d)",
+			restMaxHeaderQuery)
+		respondWithError(w, http.StatusBadRequest, str)
+		return
+	}
+
+	result := queryHeadersResult{
+		Headers:  make([]*btcjson.MessageHeaderResult, 0, len(req.Hashes)),
+		NotFound: make([]string, 0),
 	}
-	respondWithJSON(w, http.StatusOK, lmr)
+	for _, s := range req.Hashes {
+		mhash, err := hex.DecodeString(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid message ID "+s)
+			return
+		}
+		hdr, err := ctrs.cfg.MsgSvc.FetchHeader(mhash)
+		if err != nil {
+			result.NotFound = append(result.NotFound, s)
+			continue
+		}
+		result.Headers = append(result.Headers,
+			newMessageHeaderResult(mhash, hdr))
+	}
+	respondWithJSON(w, http.StatusOK, result)
 }
 
+// PeerInfo describes a connected peer in the response to a peer listing.
 type PeerInfo struct {
-	Address	string	`json:address`
-	Inbound bool `json:inbound`
-	Useragent string `json:useragent`
+	Address   string `json:"address"`
+	Inbound   bool   `json:"inbound"`
+	Useragent string `json:"useragent"`
 }
 
 func (ctrs *ctRestServer) listPeers(w http.ResponseWriter, r *http.Request) {
@@ -157,7 +457,7 @@ func (ctrs *ctRestServer) listPeers(w http.ResponseWriter, r *http.Request) {
 	ctrs.cfg.Server.query <- getPeersMsg{reply: replyChan}
 	serverPeers := <-replyChan
 
-  plist := make([]*PeerInfo, 0)
+	plist := make([]*PeerInfo, 0)
 	for _, sp := range serverPeers {
 		pi := new(PeerInfo)
 		pi.Address = sp.Addr()
@@ -170,15 +470,32 @@ func (ctrs *ctRestServer) listPeers(w http.ResponseWriter, r *http.Request) {
 
 func (ctrs *ctRestServer) initializeRoutes() {
 	ctrs.Router.HandleFunc("/api/v1/messages/{msgid:[0-9abcdefABCDEF]+}", ctrs.getMessage).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/messages/{msgid:[0-9abcdefABCDEF]+}/header", ctrs.getMessageHeader).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/headers/query", ctrs.queryHeaders).Methods("POST")
 	ctrs.Router.HandleFunc("/api/v1/messages/", ctrs.listMessages).Methods("GET")
-	ctrs.Router.HandleFunc("/api/v1/messages/", ctrs.postMessage).Methods("POST")
+	ctrs.Router.HandleFunc("/api/v1/messages/", ctrs.requireToken(ctrs.postMessage)).Methods("POST")
 	ctrs.Router.HandleFunc("/api/v1/peers/", ctrs.listPeers).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/chain/", ctrs.getChainInfo).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/blocks/{hash:[0-9a-fA-F]{64}}", ctrs.getBlock).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/blocks/{hash:[0-9a-fA-F]{64}}/header", ctrs.getBlockHeader).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/blocks/height/{height:[0-9]+}", ctrs.getBlock).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/blocks/height/{height:[0-9]+}/header", ctrs.getBlockHeader).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/tx/{txid:[0-9a-fA-F]{64}}", ctrs.getTransaction).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/mempool/", ctrs.getMempoolInfo).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/msgstore/", ctrs.getMsgStoreInfo).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/naks/", ctrs.listNAKs).Methods("GET")
+	ctrs.Router.HandleFunc("/api/v1/naks/{pubkey:[0-9a-fA-F]{66}}", ctrs.getNAK).Methods("GET")
 }
 
 func newCtRESTServer(cfg *restServerConfig) (ctrs *ctRestServer, err error) {
 	ctrs = new(ctRestServer)
 	ctrs.cfg = cfg
 	ctrs.Router = mux.NewRouter()
+	ctrs.limiter = newRESTRateLimiter(cfg.RequestRate, cfg.ByteRate)
+	for _, token := range cfg.APITokens {
+		ctrs.tokenHashes = append(ctrs.tokenHashes,
+			sha256.Sum256([]byte(token)))
+	}
 	return ctrs, nil
 }
 
@@ -186,7 +503,7 @@ func (ctrs *ctRestServer) Start() {
 	rpcsLog.Trace("Starting ciphrtxt REST API server")
 
 	httpServer := &http.Server{
-		Handler: ctrs.Router,
+		Handler: ctrs,
 
 		// Timeout connections which don't complete the initial
 		// handshake within the allowed timeframe.
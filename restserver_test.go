@@ -7,17 +7,21 @@ package main
 import (
 	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/btcsuite/btclog"
 	"github.com/gorilla/mux"
-	"github.com/jadeblaquiere/ctclient/ctgo"
+	"github.com/jadeblaquiere/cttd/btcec"
+	"github.com/jadeblaquiere/cttd/btcjson"
+	"github.com/jadeblaquiere/cttd/ctmsg"
 )
 
 func executeRequest(req *http.Request, router *mux.Router) *httptest.ResponseRecorder {
@@ -34,13 +38,12 @@ func checkResponseCode(t *testing.T, expected, actual int) {
 }
 
 func TestCtRestServerNew(t *testing.T) {
-	dirname, err := ioutil.TempDir("", "MStoretest")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir, error: %s", err.Error())
-	}
+	restLog.SetLevel(btclog.LevelOff)
+	ctmsg.DisableLog()
 
+	var err error
 	cfg := new(restServerConfig)
-	cfg.MStore, err = ctgo.OpenMessageStore(dirname)
+	cfg.MsgSvc, err = ctmsg.New(&ctmsg.Config{Store: ctmsg.NewMemStore()})
 	if err != nil {
 		t.Fatalf("Failed to open MessageStore, error: %s", err.Error())
 	}
@@ -52,40 +55,45 @@ func TestCtRestServerNew(t *testing.T) {
 	ctrs.initializeRoutes()
 
 	numiter := int(10)
-	sK := make([]*ctgo.SecretKey, numiter)
-	pK := make([]*ctgo.PublicKey, numiter)
-	m := make([]*ctgo.Message, len(sK)*len(sK))
+	pK := make([]*btcec.PublicKey, numiter)
+	m := make([]*ctmsg.Message, numiter*numiter)
 
 	for i := 0; i < numiter; i++ {
-		sK[i] = ctgo.NewSecretKey(0, 0, 0, 0, 0)
-		pK[i] = sK[i].PublicKey()
+		sK, err := btcec.NewPrivateKey(btcec.S256())
+		if err != nil {
+			t.Fatalf("Failed to create private key: %v", err)
+		}
+		pK[i] = sK.PubKey()
 	}
 
-	prate := ctgo.NewPostageRate(10, 65536, 1, (64 * 16777216))
 	ptxt := []byte("Hello, Alice")
 
-	for i := 0; i < len(sK); i++ {
-		for j := 0; j < len(sK); j++ {
+	for i := 0; i < len(pK); i++ {
+		for j := 0; j < len(pK); j++ {
 			if i == j {
 				continue
 			}
-			m[(i*len(sK))+j] = ctgo.EncryptMessage(pK[j], sK[i], time.Now(), time.Duration(7*24*time.Hour), "", ptxt, prate)
-			ctxt := m[(i*len(sK))+j].Ciphertext()
-			bodybuf := bytes.NewBuffer(ctxt[:])
-			req, _ := http.NewRequest("POST", "/messages/", bodybuf)
+			msg, err := ctmsg.NewMessage(pK[j], ptxt, time.Now(), time.Duration(7*24*time.Hour))
+			if err != nil {
+				t.Fatalf("Failed to compose message: %v", err)
+			}
+			m[(i*len(pK))+j] = msg
+			bodybuf := bytes.NewBuffer(msg.Bytes())
+			req, _ := http.NewRequest("POST", "/api/v1/messages/", bodybuf)
 			response := executeRequest(req, ctrs.Router)
 
 			checkResponseCode(t, http.StatusOK, response.Code)
 		}
 	}
 
-	for i := 0; i < len(sK); i++ {
-		for j := 0; j < len(sK); j++ {
+	for i := 0; i < len(pK); i++ {
+		for j := 0; j < len(pK); j++ {
 			if i == j {
 				continue
 			}
-			mhash := m[(i*len(sK))+j].PayloadHash()
-			req, _ := http.NewRequest("GET", "/messages/"+hex.EncodeToString(mhash), nil)
+			mbytes := m[(i*len(pK))+j].Bytes()
+			mhash := ctmsg.MessageHash(mbytes)
+			req, _ := http.NewRequest("GET", "/api/v1/messages/"+hex.EncodeToString(mhash), nil)
 			response := executeRequest(req, ctrs.Router)
 
 			checkResponseCode(t, http.StatusOK, response.Code)
@@ -93,14 +101,54 @@ func TestCtRestServerNew(t *testing.T) {
 			bodybuf := bytes.NewBuffer([]byte{})
 			io.Copy(bodybuf, response.Body)
 			ctxtcp := bodybuf.Bytes()
-			if bytes.Compare(ctxtcp, m[(i*len(sK))+j].Ciphertext()) != 0 {
+			if bytes.Compare(ctxtcp, mbytes) != 0 {
 				t.Errorf("Ciphertext mismatch for i,j = %d,%d\n", i, j)
 			}
 		}
 	}
 
-	req, _ := http.NewRequest("GET", "/messages/", nil)
+	// Fetch the headers individually and in bulk.
+	query := queryHeadersRequest{Hashes: []string{"00"}}
+	for i := 0; i < len(pK); i++ {
+		for j := 0; j < len(pK); j++ {
+			if i == j {
+				continue
+			}
+			msg := m[(i*len(pK))+j].Bytes()
+			mhash := hex.EncodeToString(ctmsg.MessageHash(msg))
+			query.Hashes = append(query.Hashes, mhash)
+			req, _ := http.NewRequest("GET", "/api/v1/messages/"+mhash+"/header", nil)
+			response := executeRequest(req, ctrs.Router)
+			checkResponseCode(t, http.StatusOK, response.Code)
+
+			var hdr btcjson.MessageHeaderResult
+			if err := json.NewDecoder(response.Body).Decode(&hdr); err != nil {
+				t.Fatalf("Get header: error decoding response: %v", err)
+			}
+			if hdr.Hash != mhash || int(hdr.Size) != len(msg) {
+				t.Errorf("Get header: mismatched header %+v for i,j = %d,%d\n", hdr, i, j)
+			}
+		}
+	}
+	req, _ := http.NewRequest("GET", "/api/v1/messages/00/header", nil)
 	response := executeRequest(req, ctrs.Router)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	qbody, _ := json.Marshal(&query)
+	req, _ = http.NewRequest("POST", "/api/v1/headers/query", bytes.NewBuffer(qbody))
+	response = executeRequest(req, ctrs.Router)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var qres queryHeadersResult
+	if err := json.NewDecoder(response.Body).Decode(&qres); err != nil {
+		t.Fatalf("Query headers: error decoding response: %v", err)
+	}
+	if len(qres.Headers) != len(query.Hashes)-1 || len(qres.NotFound) != 1 {
+		t.Errorf("Query headers: got %d headers and %d not found, want %d and 1",
+			len(qres.Headers), len(qres.NotFound), len(query.Hashes)-1)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/messages/", nil)
+	response = executeRequest(req, ctrs.Router)
 
 	checkResponseCode(t, http.StatusOK, response.Code)
 
@@ -111,6 +159,161 @@ func TestCtRestServerNew(t *testing.T) {
 
 	fmt.Println("Response : " + string(jsonbytes))
 
-	cfg.MStore.Close()
-	os.RemoveAll(dirname)
+	// Page through the listing and ensure every message is returned once.
+	nmsgs := numiter * (numiter - 1)
+	seen := make(map[string]struct{})
+	cursor := ""
+	for {
+		url := "/api/v1/messages/?limit=7"
+		if cursor != "" {
+			url += "&cursor=" + cursor
+		}
+		req, _ = http.NewRequest("GET", url, nil)
+		response = executeRequest(req, ctrs.Router)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var page listMessagesResult
+		if err := json.NewDecoder(response.Body).Decode(&page); err != nil {
+			t.Fatalf("List messages page: error decoding response: %v", err)
+		}
+		// The total is only counted for the first page.
+		switch {
+		case cursor == "" && (page.Total == nil || *page.Total != nmsgs):
+			t.Errorf("List messages page: total %v, want %d", page.Total, nmsgs)
+		case cursor != "" && page.Total != nil:
+			t.Errorf("List messages page: total %d on later page", *page.Total)
+		}
+		for _, h := range page.Messages {
+			if _, ok := seen[h]; ok {
+				t.Errorf("List messages page: duplicate hash %s", h)
+			}
+			seen[h] = struct{}{}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(seen) != nmsgs {
+		t.Errorf("List messages pages: got %d hashes, want %d", len(seen), nmsgs)
+	}
+
+	// A range in the future must be empty.
+	future := strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10)
+	req, _ = http.NewRequest("GET", "/api/v1/messages/?since="+future, nil)
+	response = executeRequest(req, ctrs.Router)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("GET", "/api/v1/messages/?limit=x", nil)
+	response = executeRequest(req, ctrs.Router)
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+
+	cfg.MsgSvc.Close()
+}
+
+// TestCtRestServerLimits ensures the REST server enforces API tokens on write
+// requests, the per client rate limit and the maximum number of concurrent
+// requests.
+func TestCtRestServerLimits(t *testing.T) {
+	restLog.SetLevel(btclog.LevelOff)
+
+	ctrs, err := newCtRESTServer(&restServerConfig{
+		APITokens:   []string{"token1", "token2"},
+		MaxClients:  2,
+		RequestRate: 0.4,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create ctRestServer")
+	}
+	ctrs.initializeRoutes()
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.RemoteAddr = "192.0.2.1:1234"
+		rr := httptest.NewRecorder()
+		ctrs.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Posting a message without a valid token is refused.
+	for _, auth := range []string{"", "token1", "Bearer token3",
+		"Bearer token1x"} {
+
+		req, _ := http.NewRequest("POST", "/api/v1/messages/",
+			bytes.NewReader(nil))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rr := serve(req)
+		checkResponseCode(t, http.StatusUnauthorized, rr.Code)
+		if rr.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("Missing WWW-Authenticate header")
+		}
+	}
+
+	// Any of the configured tokens is accepted.
+	for _, token := range []string{"token1", "token2"} {
+		req, _ := http.NewRequest("POST", "/api/v1/messages/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if !ctrs.checkToken(req) {
+			t.Errorf("Token %q refused", token)
+		}
+	}
+
+	// The burst allowance was used up by the requests above (0.4 requests
+	// per second allows a burst of four requests).
+	req, _ := http.NewRequest("GET", "/api/v1/peers/", nil)
+	rr := serve(req)
+	checkResponseCode(t, http.StatusTooManyRequests, rr.Code)
+
+	// Requests beyond the maximum number of concurrent clients are
+	// refused before the rate limit is applied.
+	ctrs.numClients = 2
+	req, _ = http.NewRequest("GET", "/api/v1/peers/", nil)
+	rr = serve(req)
+	checkResponseCode(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+// TestCtRestServerPeerInfoJSON ensures peer listings use the documented
+// lowercase JSON field names.
+func TestCtRestServerPeerInfoJSON(t *testing.T) {
+	pi := &PeerInfo{
+		Address:   "192.0.2.1:17761",
+		Inbound:   true,
+		Useragent: "/cttd:0.12.0/",
+	}
+	got, err := json.Marshal(pi)
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error %v", err)
+	}
+	want := `{"address":"192.0.2.1:17761","inbound":true,` +
+		`"useragent":"/cttd:0.12.0/"}`
+	if string(got) != want {
+		t.Errorf("Mismatched JSON - got %s, want %s", got, want)
+	}
+}
+
+// TestCtRestServerNodeRoutes ensures malformed block and transaction
+// identifiers are rejected by the router before reaching the node.
+func TestCtRestServerNodeRoutes(t *testing.T) {
+	ctrs, err := newCtRESTServer(&restServerConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create ctRestServer")
+	}
+	ctrs.initializeRoutes()
+
+	for _, path := range []string{
+		"/api/v1/blocks/height/-1",
+		"/api/v1/blocks/height/abc/header",
+		"/api/v1/blocks/0123",
+		"/api/v1/blocks/zz00000000000000000000000000000000000000000000000000000000000000",
+		"/api/v1/tx/0123",
+		"/api/v1/naks/0123",
+	} {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := executeRequest(req, ctrs.Router)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s: expected response code %d. Got %d", path,
+				http.StatusNotFound, rr.Code)
+		}
+	}
 }
@@ -249,6 +249,13 @@ func (c *Client) trackRegisteredNtfns(cmd interface{}) {
 
 		}
 
+	case *btcjson.NotifyNewMessagesCmd:
+		c.ntfnState.notifyNewMsgs = true
+		c.ntfnState.notifyNewMsgTags = nil
+		if bcmd.Tags != nil {
+			c.ntfnState.notifyNewMsgTags = *bcmd.Tags
+		}
+
 	case *btcjson.NotifySpentCmd:
 		for _, op := range bcmd.OutPoints {
 			c.ntfnState.notifySpent[op] = struct{}{}
@@ -523,6 +530,16 @@ func (c *Client) reregisterNtfns() error {
 		}
 	}
 
+	// Reregister notifynewmessages if needed.
+	if stateCopy.notifyNewMsgs {
+		log.Debugf("Reregistering [notifynewmessages] (tags=%v)",
+			stateCopy.notifyNewMsgTags)
+		err := c.NotifyNewMessages(stateCopy.notifyNewMsgTags)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Reregister the combination of all previously registered notifyspent
 	// outpoints in one command if needed.
 	nslen := len(stateCopy.notifySpent)
@@ -0,0 +1,89 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpcclient
+
+import (
+	"encoding/json"
+
+	"github.com/jadeblaquiere/cttd/btcjson"
+)
+
+// FutureGetMessageInfoResult is a future promise to deliver the result of a
+// GetMessageInfoAsync RPC invocation (or an applicable error).
+type FutureGetMessageInfoResult chan *response
+
+// Receive waits for the response promised by the future and returns the
+// message ingestion statistics.
+func (r FutureGetMessageInfoResult) Receive() (*btcjson.GetMessageInfoResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a getmessageinfo result object.
+	var infoResult btcjson.GetMessageInfoResult
+	err = json.Unmarshal(res, &infoResult)
+	if err != nil {
+		return nil, err
+	}
+
+	return &infoResult, nil
+}
+
+// GetMessageInfoAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See GetMessageInfo for the blocking version and more details.
+func (c *Client) GetMessageInfoAsync() FutureGetMessageInfoResult {
+	cmd := btcjson.NewGetMessageInfoCmd()
+	return c.sendCmd(cmd)
+}
+
+// GetMessageInfo returns statistics describing the ciphrtxt messages
+// submitted to the server, including the recent ingestion rate and the number
+// of rejected messages.
+func (c *Client) GetMessageInfo() (*btcjson.GetMessageInfoResult, error) {
+	return c.GetMessageInfoAsync().Receive()
+}
+
+// FutureGetMessageStoreInfoResult is a future promise to deliver the result of
+// a GetMessageStoreInfoAsync RPC invocation (or an applicable error).
+type FutureGetMessageStoreInfoResult chan *response
+
+// Receive waits for the response promised by the future and returns the
+// message store statistics.
+func (r FutureGetMessageStoreInfoResult) Receive() (*btcjson.GetMessageStoreInfoResult, error) {
+	res, err := receiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a getmessagestoreinfo result object.
+	var infoResult btcjson.GetMessageStoreInfoResult
+	err = json.Unmarshal(res, &infoResult)
+	if err != nil {
+		return nil, err
+	}
+
+	return &infoResult, nil
+}
+
+// GetMessageStoreInfoAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See GetMessageStoreInfo for the blocking version and more details.
+func (c *Client) GetMessageStoreInfoAsync() FutureGetMessageStoreInfoResult {
+	cmd := btcjson.NewGetMessageStoreInfoCmd()
+	return c.sendCmd(cmd)
+}
+
+// GetMessageStoreInfo returns statistics describing the ciphrtxt message
+// store of the server, such as the number and total size of the stored
+// messages.
+func (c *Client) GetMessageStoreInfo() (*btcjson.GetMessageStoreInfoResult, error) {
+	return c.GetMessageStoreInfoAsync().Receive()
+}
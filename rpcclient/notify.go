@@ -35,6 +35,8 @@ type notificationState struct {
 	notifyBlocks       bool
 	notifyNewTx        bool
 	notifyNewTxVerbose bool
+	notifyNewMsgs      bool
+	notifyNewMsgTags   []string
 	notifyReceived     map[string]struct{}
 	notifySpent        map[btcjson.OutPoint]struct{}
 }
@@ -45,6 +47,8 @@ func (s *notificationState) Copy() *notificationState {
 	stateCopy.notifyBlocks = s.notifyBlocks
 	stateCopy.notifyNewTx = s.notifyNewTx
 	stateCopy.notifyNewTxVerbose = s.notifyNewTxVerbose
+	stateCopy.notifyNewMsgs = s.notifyNewMsgs
+	stateCopy.notifyNewMsgTags = append([]string(nil), s.notifyNewMsgTags...)
 	stateCopy.notifyReceived = make(map[string]struct{})
 	for addr := range s.notifyReceived {
 		stateCopy.notifyReceived[addr] = struct{}{}
@@ -179,6 +183,12 @@ type NotificationHandlers struct {
 	// made to register for the notification and the function is non-nil.
 	OnTxAcceptedVerbose func(txDetails *btcjson.TxRawResult)
 
+	// OnMessageAccepted is invoked when a ciphrtxt message is accepted
+	// into the message store.  It will only be invoked if a preceding call
+	// to NotifyNewMessages has been made to register for the notification
+	// and the function is non-nil.
+	OnMessageAccepted func(header *btcjson.MessageHeaderResult)
+
 	// OnBtcdConnected is invoked when a wallet connects or disconnects from
 	// btcd.
 	//
@@ -409,6 +419,23 @@ func (c *Client) handleNotification(ntfn *rawNotification) {
 
 		c.ntfnHandlers.OnTxAcceptedVerbose(rawTx)
 
+	// OnMessageAccepted
+	case btcjson.MessageAcceptedNtfnMethod:
+		// Ignore the notification if the client is not interested in
+		// it.
+		if c.ntfnHandlers.OnMessageAccepted == nil {
+			return
+		}
+
+		header, err := parseMessageAcceptedNtfnParams(ntfn.Params)
+		if err != nil {
+			log.Warnf("Received invalid message accepted "+
+				"notification: %v", err)
+			return
+		}
+
+		c.ntfnHandlers.OnMessageAccepted(header)
+
 	// OnBtcdConnected
 	case btcjson.BtcdConnectedNtfnMethod:
 		// Ignore the notification if the client is not interested in
@@ -775,6 +802,25 @@ func parseTxAcceptedVerboseNtfnParams(params []json.RawMessage) (*btcjson.TxRawR
 	return &rawTx, nil
 }
 
+// parseMessageAcceptedNtfnParams parses out the message header from the
+// parameters of a messageaccepted notification.
+func parseMessageAcceptedNtfnParams(params []json.RawMessage) (*btcjson.MessageHeaderResult,
+	error) {
+
+	if len(params) != 1 {
+		return nil, wrongNumParams(len(params))
+	}
+
+	// Unmarshal first parameter as a message header result object.
+	var header btcjson.MessageHeaderResult
+	err := json.Unmarshal(params[0], &header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &header, nil
+}
+
 // parseBtcdConnectedNtfnParams parses out the connection status of btcd
 // and btcwallet from the parameters of a btcdconnected notification.
 func parseBtcdConnectedNtfnParams(params []json.RawMessage) (bool, error) {
@@ -1037,6 +1083,57 @@ func (c *Client) NotifyNewTransactions(verbose bool) error {
 	return c.NotifyNewTransactionsAsync(verbose).Receive()
 }
 
+// FutureNotifyNewMessagesResult is a future promise to deliver the result of
+// a NotifyNewMessagesAsync RPC invocation (or an applicable error).
+type FutureNotifyNewMessagesResult chan *response
+
+// Receive waits for the response promised by the future and returns an error
+// if the registration was not successful.
+func (r FutureNotifyNewMessagesResult) Receive() error {
+	_, err := receiveFuture(r)
+	return err
+}
+
+// NotifyNewMessagesAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See NotifyNewMessages for the blocking version and more details.
+//
+// NOTE: This is a cttd extension and requires a websocket connection.
+func (c *Client) NotifyNewMessagesAsync(tags []string) FutureNotifyNewMessagesResult {
+	// Not supported in HTTP POST mode.
+	if c.config.HTTPPostMode {
+		return newFutureError(ErrWebsocketsRequired)
+	}
+
+	// Ignore the notification if the client is not interested in
+	// notifications.
+	if c.ntfnHandlers == nil {
+		return newNilFutureResult()
+	}
+
+	var tagsPtr *[]string
+	if len(tags) > 0 {
+		tagsPtr = &tags
+	}
+	cmd := btcjson.NewNotifyNewMessagesCmd(tagsPtr)
+	return c.sendCmd(cmd)
+}
+
+// NotifyNewMessages registers the client to receive notifications every time
+// a new ciphrtxt message is accepted into the message store.  When tags are
+// passed, only messages with a header I point beginning with one of the
+// hex-encoded tags are delivered.  The notifications are delivered to the
+// OnMessageAccepted notification handler associated with the client.  Calling
+// this function has no effect if there are no notification handlers and will
+// result in an error if the client is configured to run in HTTP POST mode.
+//
+// NOTE: This is a cttd extension and requires a websocket connection.
+func (c *Client) NotifyNewMessages(tags []string) error {
+	return c.NotifyNewMessagesAsync(tags).Receive()
+}
+
 // FutureNotifyReceivedResult is a future promise to deliver the result of a
 // NotifyReceivedAsync RPC invocation (or an applicable error).
 //
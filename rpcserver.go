@@ -34,6 +34,7 @@ import (
 	"github.com/jadeblaquiere/cttd/btcjson"
 	"github.com/jadeblaquiere/cttd/chaincfg"
 	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+	"github.com/jadeblaquiere/cttd/ctmsg"
 	"github.com/jadeblaquiere/cttd/database"
 	"github.com/jadeblaquiere/cttd/mempool"
 	"github.com/jadeblaquiere/cttd/mining"
@@ -144,6 +145,7 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"getblockheader":        handleGetBlockHeader,
 	"getblocktemplate":      handleGetBlockTemplate,
 	"getcfilter":            handleGetCFilter,
+	"getchaintips":          handleGetChainTips,
 	"getcfilterheader":      handleGetCFilterHeader,
 	"getconnectioncount":    handleGetConnectionCount,
 	"getcurrentnet":         handleGetCurrentNet,
@@ -153,7 +155,11 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"getheaders":            handleGetHeaders,
 	"getinfo":               handleGetInfo,
 	"getmempoolinfo":        handleGetMempoolInfo,
+	"getmessageinfo":        handleGetMessageInfo,
+	"getmessagestoreinfo":   handleGetMessageStoreInfo,
 	"getmininginfo":         handleGetMiningInfo,
+	"getnak":                handleGetNAK,
+	"getname":               handleGetName,
 	"getnettotals":          handleGetNetTotals,
 	"getnetworkhashps":      handleGetNetworkHashPS,
 	"getpeerinfo":           handleGetPeerInfo,
@@ -161,8 +167,13 @@ var rpcHandlersBeforeInit = map[string]commandHandler{
 	"getrawtransaction":     handleGetRawTransaction,
 	"gettxout":              handleGetTxOut,
 	"help":                  handleHelp,
+	"invalidateblock":       handleInvalidateBlock,
+	"listnaks":              handleListNAKs,
+	"listnames":             handleListNames,
 	"node":                  handleNode,
 	"ping":                  handlePing,
+	"preciousblock":         handlePreciousBlock,
+	"reconsiderblock":       handleReconsiderBlock,
 	"searchrawtransactions": handleSearchRawTransactions,
 	"sendrawtransaction":    handleSendRawTransaction,
 	"setgenerate":           handleSetGenerate,
@@ -226,13 +237,9 @@ var rpcAskWallet = map[string]struct{}{
 // Commands that are currently unimplemented, but should ultimately be.
 var rpcUnimplemented = map[string]struct{}{
 	"estimatepriority": {},
-	"getchaintips":     {},
 	"getmempoolentry":  {},
 	"getnetworkinfo":   {},
 	"getwork":          {},
-	"invalidateblock":  {},
-	"preciousblock":    {},
-	"reconsiderblock":  {},
 }
 
 // Commands that are available to a limited user
@@ -263,15 +270,22 @@ var rpcLimited = map[string]struct{}{
 	"getblockheader":        {},
 	"getcfilter":            {},
 	"getcfilterheader":      {},
+	"getchaintips":          {},
 	"getcurrentnet":         {},
 	"getdifficulty":         {},
 	"getheaders":            {},
 	"getinfo":               {},
+	"getmessageinfo":        {},
+	"getmessagestoreinfo":   {},
+	"getnak":                {},
+	"getname":               {},
 	"getnettotals":          {},
 	"getnetworkhashps":      {},
 	"getrawmempool":         {},
 	"getrawtransaction":     {},
 	"gettxout":              {},
+	"listnaks":              {},
+	"listnames":             {},
 	"searchrawtransactions": {},
 	"sendrawtransaction":    {},
 	"submitblock":           {},
@@ -851,6 +865,26 @@ func handleDecodeScript(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 	if scriptClass != txscript.ScriptHashTy {
 		reply.P2sh = p2sh.EncodeAddress()
 	}
+
+	// Decode the registration of registration scripts.  Registrations
+	// which are invalid are not decoded.
+	switch scriptClass {
+	case txscript.NameRegistrationTy:
+		name, owner, err := txscript.ExtractNameRegistration(script)
+		if err == nil {
+			reply.Name = string(name)
+			reply.NameOwner = hex.EncodeToString(owner)
+		}
+
+	case txscript.NAKRegistrationTy:
+		nak, err := txscript.ExtractNAKRegistration(script)
+		if err == nil {
+			reply.NAKPubKey = hex.EncodeToString(
+				nak.PubKey.SerializeCompressed())
+			reply.NAKNotBefore = nak.NotBefore.Unix()
+			reply.NAKNotAfter = nak.NotAfter.Unix()
+		}
+	}
 	return reply, nil
 }
 
@@ -1250,6 +1284,9 @@ func handleGetBlockChainInfo(s *rpcServer, cmd interface{}, closeChan <-chan str
 		case chaincfg.DeploymentSegwit:
 			forkName = "segwit"
 
+		case chaincfg.DeploymentCTExtended:
+			forkName = "ctextended"
+
 		default:
 			return nil, &btcjson.RPCError{
 				Code: btcjson.ErrRPCInternal.Code,
@@ -2171,6 +2208,21 @@ func handleGetBlockTemplate(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	}
 }
 
+// handleGetChainTips implements the getchaintips command.
+func handleGetChainTips(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	tips := s.cfg.Chain.ChainTips()
+	results := make([]btcjson.GetChainTipsResult, 0, len(tips))
+	for _, tip := range tips {
+		results = append(results, btcjson.GetChainTipsResult{
+			Height:    tip.Height,
+			Hash:      tip.Hash.String(),
+			BranchLen: tip.BranchLen,
+			Status:    tip.Status.String(),
+		})
+	}
+	return results, nil
+}
+
 // handleGetCFilter implements the getcfilter command.
 func handleGetCFilter(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	if s.cfg.CfIndex == nil {
@@ -2334,6 +2386,69 @@ func handleGetMempoolInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct
 	return ret, nil
 }
 
+// handleGetMessageInfo implements the getmessageinfo command.
+func handleGetMessageInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if s.cfg.MsgSvc == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Message service must be enabled (--bluenet)",
+		}
+	}
+
+	stats := s.cfg.MsgSvc.IngestStats(time.Now())
+	result := &btcjson.GetMessageInfoResult{
+		Since:          stats.Since.Unix(),
+		Accepted:       stats.Accepted,
+		Duplicates:     stats.Duplicates,
+		Invalid:        stats.Invalid,
+		TotalRejected:  stats.TotalRejected(),
+		Rejected:       make(map[string]uint64, len(stats.Rejected)),
+		RecentAccepted: stats.RecentAccepted,
+		RateWindow:     int64(stats.RateWindow / time.Second),
+		IngestRate:     stats.Rate(),
+	}
+	for code, n := range stats.Rejected {
+		result.Rejected[code.String()] = n
+	}
+	return result, nil
+}
+
+// newMessageStoreInfoResult returns statistics describing the message store of
+// the passed message service and its policy as reported by the
+// getmessagestoreinfo command and the REST API.
+func newMessageStoreInfoResult(svc *ctmsg.CiphrtxtMsgSvc) *btcjson.GetMessageStoreInfoResult {
+	stats := svc.StoreStats()
+
+	policy := svc.Policy()
+	result := &btcjson.GetMessageStoreInfoResult{
+		Messages:       stats.Messages,
+		Bytes:          stats.Bytes,
+		MaxMessageSize: policy.MaxMessageSize,
+		MinExpire:      int64(policy.MinExpire / time.Second),
+		MaxExpire:      int64(policy.MaxExpire / time.Second),
+		PowBits:        policy.PowBits,
+		Retention:      int64(stats.RetentionWindow / time.Second),
+		MaxStoreSize:   stats.MaxStoreSize,
+	}
+	if stats.Messages > 0 {
+		result.Oldest = stats.Oldest.Unix()
+		result.Newest = stats.Newest.Unix()
+	}
+	return result
+}
+
+// handleGetMessageStoreInfo implements the getmessagestoreinfo command.
+func handleGetMessageStoreInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if s.cfg.MsgSvc == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Message service must be enabled (--bluenet)",
+		}
+	}
+
+	return newMessageStoreInfoResult(s.cfg.MsgSvc), nil
+}
+
 // handleGetMiningInfo implements the getmininginfo command. We only return the
 // fields that are not related to wallet functionality.
 func handleGetMiningInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -2370,6 +2485,140 @@ func handleGetMiningInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 	return &result, nil
 }
 
+// nakStatus returns the status of the passed network access key registration
+// at the passed time as reported by the getnak and listnaks commands.
+func nakStatus(reg *indexers.NAKRegistration, now time.Time) string {
+	switch {
+	case reg.Revoked():
+		return "revoked"
+	case now.Before(reg.NAK.NotBefore):
+		return "pending"
+	case !now.Before(reg.NAK.NotAfter):
+		return "expired"
+	}
+	return "active"
+}
+
+// nakResult converts the registrations of the passed compressed public key as
+// returned by the network access key index into a result for the getnak and
+// listnaks commands.  The status of each registration is evaluated at the
+// passed time.
+func nakResult(pubKey []byte, regs []indexers.NAKRegistration, now time.Time) *btcjson.NAKResult {
+	result := &btcjson.NAKResult{
+		PubKey:        hex.EncodeToString(pubKey),
+		Registrations: make([]btcjson.NAKRegistrationResult, 0, len(regs)),
+	}
+	for i := range regs {
+		reg := &regs[i]
+		if reg.Active(now) {
+			result.Active = true
+		}
+		result.Registrations = append(result.Registrations,
+			btcjson.NAKRegistrationResult{
+				NAK:           hex.EncodeToString(reg.NAK.Serialize()),
+				TxID:          reg.OutPoint.Hash.String(),
+				Vout:          reg.OutPoint.Index,
+				Height:        reg.Height,
+				NotBefore:     reg.NAK.NotBefore.Unix(),
+				NotAfter:      reg.NAK.NotAfter.Unix(),
+				Status:        nakStatus(reg, now),
+				RevokedHeight: reg.RevokedHeight,
+			})
+	}
+	return result
+}
+
+// handleGetNAK implements the getnak command.
+func handleGetNAK(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if s.cfg.NAKIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNoNAKIndex,
+			Message: "The NAK index must be enabled for this command",
+		}
+	}
+
+	c := cmd.(*btcjson.GetNAKCmd)
+	pubKey, err := hex.DecodeString(c.PubKey)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.PubKey)
+	}
+	if !btcec.IsCompressedPubKey(pubKey) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Public key must be compressed",
+		}
+	}
+
+	regs, err := s.cfg.NAKIndex.FetchNAK(pubKey)
+	if err != nil {
+		context := "Failed to fetch network access key"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if len(regs) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNAKNotFound,
+			Message: "No network access key registered for " + c.PubKey,
+		}
+	}
+
+	return nakResult(pubKey, regs, s.cfg.TimeSource.AdjustedTime()), nil
+}
+
+// nameResult converts the registrations of the passed name as returned by the
+// name registry into a result for the getname and listnames commands.  The owner
+// and expiry height are those of the registration which holds the name as of
+// the block at the passed height.  The owner is empty when the name has
+// expired.
+func nameResult(name string, regs []blockchain.NameRegistration, expiryBlocks, height int32) *btcjson.NameResult {
+	result := &btcjson.NameResult{
+		Name:          name,
+		Registrations: make([]btcjson.NameRegistrationResult, 0, len(regs)),
+	}
+	current := blockchain.CurrentNameRegistration(regs, expiryBlocks, height)
+	if current != nil {
+		result.Owner = hex.EncodeToString(current.Owner)
+		result.ExpiryHeight = blockchain.NameExpiryHeight(current,
+			expiryBlocks)
+	}
+	for _, reg := range regs {
+		result.Registrations = append(result.Registrations,
+			btcjson.NameRegistrationResult{
+				Owner:  hex.EncodeToString(reg.Owner),
+				TxID:   reg.OutPoint.Hash.String(),
+				Vout:   reg.OutPoint.Index,
+				Height: reg.Height,
+			})
+	}
+	return result
+}
+
+// handleGetName implements the getname command.
+func handleGetName(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetNameCmd)
+	if err := txscript.CheckName([]byte(c.Name)); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: err.Error(),
+		}
+	}
+
+	regs, err := s.cfg.Chain.FetchNameRegistrations([]byte(c.Name))
+	if err != nil {
+		context := "Failed to fetch name"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if len(regs) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNameNotFound,
+			Message: "Name is not registered: " + c.Name,
+		}
+	}
+
+	nextHeight := s.cfg.Chain.BestSnapshot().Height + 1
+	return nameResult(c.Name, regs, s.cfg.ChainParams.NameExpiryBlocks,
+		nextHeight), nil
+}
+
 // handleGetNetTotals implements the getnettotals command.
 func handleGetNetTotals(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	totalBytesRecv, totalBytesSent := s.cfg.ConnMgr.NetTotals()
@@ -2406,9 +2655,16 @@ func handleGetNetworkHashPS(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	}
 
 	// Calculate the number of blocks per retarget interval based on the
-	// chain parameters.
+	// chain parameters.  Per-block algorithms adjust the difficulty with
+	// every block, so their difficulty window is used instead once they
+	// are in use at the end height.
 	blocksPerRetarget := int32(s.cfg.ChainParams.TargetTimespan /
 		s.cfg.ChainParams.TargetTimePerBlock)
+	if s.cfg.ChainParams.DifficultyAlgorithm != chaincfg.DiffAlgoBitcoin &&
+		endHeight >= s.cfg.ChainParams.DifficultyAlgorithmHeight {
+
+		blocksPerRetarget = s.cfg.ChainParams.DifficultyWindow
+	}
 
 	// Calculate the starting block height based on the passed number of
 	// blocks.  When the passed value is negative, use the last block the
@@ -2798,6 +3054,112 @@ func handleHelp(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (inter
 	return help, nil
 }
 
+// updateBlockStatus applies the passed change to the validation status of the
+// block identified by the passed hash, such as BlockChain.InvalidateBlock.
+func updateBlockStatus(s *rpcServer, blockHash string, update func(*chainhash.Hash) error) error {
+	hash, err := chainhash.NewHashFromStr(blockHash)
+	if err != nil {
+		return rpcDecodeHexError(blockHash)
+	}
+	if _, err := s.cfg.Chain.HeaderByHash(hash); err != nil {
+		return &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+	if err := update(hash); err != nil {
+		return &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDatabase,
+			Message: err.Error(),
+		}
+	}
+	return nil
+}
+
+// handleInvalidateBlock implements the invalidateblock command.
+func handleInvalidateBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.InvalidateBlockCmd)
+	return nil, updateBlockStatus(s, c.BlockHash, s.cfg.Chain.InvalidateBlock)
+}
+
+// handleListNAKs implements the listnaks command.
+func handleListNAKs(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if s.cfg.NAKIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNoNAKIndex,
+			Message: "The NAK index must be enabled for this command",
+		}
+	}
+
+	c := cmd.(*btcjson.ListNAKsCmd)
+	var after []byte
+	if c.After != nil && *c.After != "" {
+		var err error
+		after, err = hex.DecodeString(*c.After)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.After)
+		}
+	}
+	count := 1000
+	if c.Count != nil {
+		count = *c.Count
+	}
+	if count <= 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Count must be positive",
+		}
+	}
+
+	entries, err := s.cfg.NAKIndex.ListNAKs(after, count)
+	if err != nil {
+		context := "Failed to list network access keys"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	now := s.cfg.TimeSource.AdjustedTime()
+	results := make([]btcjson.NAKResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, *nakResult(entry.PubKey,
+			entry.Registrations, now))
+	}
+	return results, nil
+}
+
+// handleListNames implements the listnames command.
+func handleListNames(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ListNamesCmd)
+	after := ""
+	if c.After != nil {
+		after = *c.After
+	}
+	count := 1000
+	if c.Count != nil {
+		count = *c.Count
+	}
+	if count <= 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Count must be positive",
+		}
+	}
+
+	entries, err := s.cfg.Chain.ListNames([]byte(after), count)
+	if err != nil {
+		context := "Failed to list names"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	nextHeight := s.cfg.Chain.BestSnapshot().Height + 1
+	results := make([]btcjson.NameResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, *nameResult(entry.Name,
+			entry.Registrations, s.cfg.ChainParams.NameExpiryBlocks,
+			nextHeight))
+	}
+	return results, nil
+}
+
 // handlePing implements the ping command.
 func handlePing(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Ask server to ping \o_
@@ -2811,6 +3173,18 @@ func handlePing(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (inter
 	return nil, nil
 }
 
+// handlePreciousBlock implements the preciousblock command.
+func handlePreciousBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.PreciousBlockCmd)
+	return nil, updateBlockStatus(s, c.BlockHash, s.cfg.Chain.PreciousBlock)
+}
+
+// handleReconsiderBlock implements the reconsiderblock command.
+func handleReconsiderBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ReconsiderBlockCmd)
+	return nil, updateBlockStatus(s, c.BlockHash, s.cfg.Chain.ReconsiderBlock)
+}
+
 // retrievedTx represents a transaction that was either loaded from the
 // transaction memory pool or from the database.  When a transaction is loaded
 // from the database, it is loaded with the raw serialized bytes while the
@@ -4267,10 +4641,15 @@ type rpcserverConfig struct {
 	TxIndex   *indexers.TxIndex
 	AddrIndex *indexers.AddrIndex
 	CfIndex   *indexers.CfIndex
+	NAKIndex  *indexers.NAKIndex
 
 	// The fee estimator keeps track of how long transactions are left in
 	// the mempool before they are mined into blocks.
 	FeeEstimator *mempool.FeeEstimator
+
+	// MsgSvc is the ciphrtxt message service.  It is nil when the message
+	// service is not enabled.
+	MsgSvc *ctmsg.CiphrtxtMsgSvc
 }
 
 // newRPCServer returns a new instance of the rpcServer struct.
@@ -4295,6 +4674,9 @@ func newRPCServer(config *rpcserverConfig) (*rpcServer, error) {
 	}
 	rpc.ntfnMgr = newWsNotificationManager(&rpc)
 	rpc.cfg.Chain.Subscribe(rpc.handleBlockchainNotification)
+	if rpc.cfg.MsgSvc != nil {
+		rpc.cfg.MsgSvc.Subscribe(rpc.handleCtMsgNotification)
+	}
 
 	return &rpc, nil
 }
@@ -4337,6 +4719,23 @@ func (s *rpcServer) handleBlockchainNotification(notification *blockchain.Notifi
 	}
 }
 
+// Callback for notifications from the ciphrtxt message service.  It notifies
+// clients that are subscribed to websockets notifications.
+func (s *rpcServer) handleCtMsgNotification(notification *ctmsg.Notification) {
+	switch notification.Type {
+	case ctmsg.NTMessageAccepted:
+		msg, ok := notification.Data.(*ctmsg.AcceptedMessage)
+		if !ok {
+			rpcsLog.Warnf("Message accepted notification is not an " +
+				"accepted message.")
+			break
+		}
+
+		// Notify registered websocket clients of the new message.
+		s.ntfnMgr.NotifyMessageAccepted(msg)
+	}
+}
+
 func init() {
 	rpcHandlers = rpcHandlersBeforeInit
 	rand.Seed(time.Now().UnixNano())
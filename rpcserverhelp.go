@@ -105,11 +105,16 @@ var helpDescsEnUS = map[string]string{
 	"decoderawtransaction-hextx":     "Serialized, hex-encoded transaction",
 
 	// DecodeScriptResult help.
-	"decodescriptresult-asm":       "Disassembly of the script",
-	"decodescriptresult-reqSigs":   "The number of required signatures",
-	"decodescriptresult-type":      "The type of the script (e.g. 'pubkeyhash')",
-	"decodescriptresult-addresses": "The bitcoin addresses associated with this script",
-	"decodescriptresult-p2sh":      "The script hash for use in pay-to-script-hash transactions (only present if the provided redeem script is not already a pay-to-script-hash script)",
+	"decodescriptresult-asm":          "Disassembly of the script",
+	"decodescriptresult-reqSigs":      "The number of required signatures",
+	"decodescriptresult-type":         "The type of the script (e.g. 'pubkeyhash')",
+	"decodescriptresult-addresses":    "The bitcoin addresses associated with this script",
+	"decodescriptresult-p2sh":         "The script hash for use in pay-to-script-hash transactions (only present if the provided redeem script is not already a pay-to-script-hash script)",
+	"decodescriptresult-name":         "The name registered by the script (only present for name registration scripts)",
+	"decodescriptresult-nameowner":    "The hex-encoded public key the name is registered to (only present for name registration scripts)",
+	"decodescriptresult-nakpubkey":    "The hex-encoded public key of the network access key registered by the script (only present for network access key registration scripts)",
+	"decodescriptresult-naknotbefore": "The time the network access key becomes valid in seconds since 1 Jan 1970 GMT (only present for network access key registration scripts)",
+	"decodescriptresult-naknotafter":  "The time the network access key expires in seconds since 1 Jan 1970 GMT (only present for network access key registration scripts)",
 
 	// DecodeScriptCmd help.
 	"decodescript--synopsis": "Returns a JSON object with information about the provided hex-encoded script.",
@@ -332,6 +337,15 @@ var helpDescsEnUS = map[string]string{
 	"getblocktemplate--condition2": "mode=proposal, accepted",
 	"getblocktemplate--result1":    "An error string which represents why the proposal was rejected or nothing if accepted",
 
+	// GetChainTipsResult help.
+	"getchaintipsresult-height":    "The height of the chain tip",
+	"getchaintipsresult-hash":      "The hash of the chain tip",
+	"getchaintipsresult-branchlen": "The number of blocks between the chain tip and the main chain, 0 for the main chain",
+	"getchaintipsresult-status":    "The status of the branch ending at the chain tip (active, valid-fork, valid-headers, headers-only or invalid)",
+
+	// GetChainTipsCmd help.
+	"getchaintips--synopsis": "Returns information about all known tips in the block tree, including the main chain and any side chains.",
+
 	// GetCFilterCmd help.
 	"getcfilter--synopsis":  "Returns a block's committed filter given its hash.",
 	"getcfilter-filtertype": "The type of filter to return (0=regular)",
@@ -410,6 +424,38 @@ var helpDescsEnUS = map[string]string{
 	"getmempoolinforesult-bytes": "Size in bytes of the mempool",
 	"getmempoolinforesult-size":  "Number of transactions in the mempool",
 
+	// GetMessageInfoCmd help.
+	"getmessageinfo--synopsis": "Returns statistics describing the ciphrtxt messages submitted to the server by peers and clients.",
+
+	// GetMessageInfoResult help.
+	"getmessageinforesult-since":           "The time the message service was started (Unix epoch)",
+	"getmessageinforesult-accepted":        "Number of messages accepted into the message store",
+	"getmessageinforesult-duplicates":      "Number of messages which were already stored",
+	"getmessageinforesult-invalid":         "Number of messages which could not be parsed",
+	"getmessageinforesult-totalrejected":   "Number of messages which violated a message acceptance rule",
+	"getmessageinforesult-rejected":        "Number of rejected messages for each violated rule",
+	"getmessageinforesult-rejected--key":   "rule",
+	"getmessageinforesult-rejected--value": "n",
+	"getmessageinforesult-rejected--desc":  "The name of the violated rule as the key and the number of rejected messages as the value",
+	"getmessageinforesult-recentaccepted":  "Number of messages accepted within the rate window",
+	"getmessageinforesult-ratewindow":      "The period in seconds the ingestion rate is measured over",
+	"getmessageinforesult-ingestrate":      "Recent ingestion rate in accepted messages per second",
+
+	// GetMessageStoreInfoCmd help.
+	"getmessagestoreinfo--synopsis": "Returns statistics describing the ciphrtxt message store and the message acceptance policy.",
+
+	// GetMessageStoreInfoResult help.
+	"getmessagestoreinforesult-messages":     "Number of stored messages",
+	"getmessagestoreinforesult-bytes":        "Total size in bytes of the stored messages",
+	"getmessagestoreinforesult-oldest":       "The timestamp of the oldest stored message (Unix epoch, omitted when the store is empty)",
+	"getmessagestoreinforesult-newest":       "The timestamp of the newest stored message (Unix epoch, omitted when the store is empty)",
+	"getmessagestoreinforesult-maxmsgsize":   "Maximum size in bytes of an accepted message",
+	"getmessagestoreinforesult-minexpire":    "Minimum lifetime in seconds of an accepted message",
+	"getmessagestoreinforesult-maxexpire":    "Maximum lifetime in seconds of an accepted message",
+	"getmessagestoreinforesult-powbits":      "Number of leading zero bits of proof of work required of messages",
+	"getmessagestoreinforesult-retention":    "Maximum age in seconds of a stored message (0 when messages are kept until they expire)",
+	"getmessagestoreinforesult-maxstoresize": "Maximum total size in bytes of the stored messages (0 when unlimited)",
+
 	// GetMiningInfoResult help.
 	"getmininginforesult-blocks":             "Height of the latest best block",
 	"getmininginforesult-currentblocksize":   "Size of the latest best block",
@@ -427,6 +473,41 @@ var helpDescsEnUS = map[string]string{
 	// GetMiningInfoCmd help.
 	"getmininginfo--synopsis": "Returns a JSON object containing mining-related information.",
 
+	// GetNAKCmd help.
+	"getnak--synopsis": "Returns the registrations of a network access key registered with OP_REGISTERNAK.",
+	"getnak-pubkey":    "The hex-encoded compressed public key of the network access key",
+
+	// NAKResult help.
+	"nakresult-pubkey":        "The hex-encoded compressed public key of the network access key",
+	"nakresult-active":        "Whether the key has a registration which is currently active",
+	"nakresult-registrations": "The registrations of the key in the order they were mined",
+
+	// NAKRegistrationResult help.
+	"nakregistrationresult-nak":           "The hex-encoded serialized network access key",
+	"nakregistrationresult-txid":          "The hash of the registering transaction",
+	"nakregistrationresult-vout":          "The index of the registering output",
+	"nakregistrationresult-height":        "The height of the block containing the registration",
+	"nakregistrationresult-notbefore":     "The time the key becomes valid in seconds since 1 Jan 1970 GMT",
+	"nakregistrationresult-notafter":      "The time the key expires in seconds since 1 Jan 1970 GMT",
+	"nakregistrationresult-status":        "The status of the registration (pending, active, expired or revoked)",
+	"nakregistrationresult-revokedheight": "The height of the block which revoked the key by spending the registering output",
+
+	// GetNameCmd help.
+	"getname--synopsis": "Returns the owner and registrations of a name registered with OP_REGISTERNAME.",
+	"getname-name":      "The name to look up",
+
+	// NameResult help.
+	"nameresult-name":          "The registered name",
+	"nameresult-owner":         "The hex-encoded compressed public key which owns the name (empty when the registration of the name has expired)",
+	"nameresult-expiryheight":  "The height of the first block for which the current registration of the name has expired (omitted when registrations do not expire)",
+	"nameresult-registrations": "The registrations of the name in the order they were mined",
+
+	// NameRegistrationResult help.
+	"nameregistrationresult-owner":  "The hex-encoded compressed public key the name was registered to",
+	"nameregistrationresult-txid":   "The hash of the registering transaction",
+	"nameregistrationresult-vout":   "The index of the registering output",
+	"nameregistrationresult-height": "The height of the block containing the registration",
+
 	// GetNetworkHashPSCmd help.
 	"getnetworkhashps--synopsis": "Returns the estimated network hashes per second for the block heights provided by the parameters.",
 	"getnetworkhashps-blocks":    "The number of blocks, or -1 for blocks since last difficulty change",
@@ -514,10 +595,35 @@ var helpDescsEnUS = map[string]string{
 	"help--result0":    "List of commands",
 	"help--result1":    "Help for specified command",
 
+	// InvalidateBlockCmd help.
+	"invalidateblock--synopsis": "Permanently marks a block as invalid, as if it violated a consensus rule, along with all of its descendants.\n" +
+		"When the block is in the main chain, the chain is reorganized to the valid chain with the most work.",
+	"invalidateblock-blockhash": "The hash of the block to invalidate",
+
+	// ListNAKsCmd help.
+	"listnaks--synopsis": "Returns the network access keys registered with OP_REGISTERNAK ordered by public key.",
+	"listnaks-after":     "Only return keys whose hex-encoded compressed public key sorts after this key",
+	"listnaks-count":     "The maximum number of keys to return",
+
+	// ListNamesCmd help.
+	"listnames--synopsis": "Returns the names registered with OP_REGISTERNAME in lexicographical order.",
+	"listnames-after":     "Only return names which sort after this name",
+	"listnames-count":     "The maximum number of names to return",
+
 	// PingCmd help.
 	"ping--synopsis": "Queues a ping to be sent to each connected peer.\n" +
 		"Ping times are provided by getpeerinfo via the pingtime and pingwait fields.",
 
+	// PreciousBlockCmd help.
+	"preciousblock--synopsis": "Treats a block as if it were received before other blocks with the same work.\n" +
+		"The chain is reorganized to the block when it has at least as much work as the current best block.",
+	"preciousblock-blockhash": "The hash of the block to mark as precious",
+
+	// ReconsiderBlockCmd help.
+	"reconsiderblock--synopsis": "Removes the invalid status of a block along with its ancestors and descendants, undoing the effects of invalidateblock.\n" +
+		"The chain is then reorganized to the valid chain with the most work.",
+	"reconsiderblock-blockhash": "The hash of the block to reconsider",
+
 	// SearchRawTransactionsCmd help.
 	"searchrawtransactions--synopsis": "Returns raw data for transactions involving the passed address.\n" +
 		"Returned transactions are pulled from both the database, and transactions currently in the mempool.\n" +
@@ -602,6 +708,13 @@ var helpDescsEnUS = map[string]string{
 	"notifynewtransactions--synopsis": "Send either a txaccepted or a txacceptedverbose notification when a new transaction is accepted into the mempool.",
 	"notifynewtransactions-verbose":   "Specifies which type of notification to receive. If verbose is true, then the caller receives txacceptedverbose, otherwise the caller receives txaccepted",
 
+	// NotifyNewMessagesCmd help.
+	"notifynewmessages--synopsis": "Send a messageaccepted notification when a new ciphrtxt message is accepted into the message store.",
+	"notifynewmessages-tags":      "Optional list of hex-encoded tags.  When specified, only messages with a header I point beginning with one of the tags are sent",
+
+	// StopNotifyNewMessagesCmd help.
+	"stopnotifynewmessages--synopsis": "Stop sending messageaccepted notifications when a new ciphrtxt message is accepted into the message store.",
+
 	// StopNotifyNewTransactionsCmd help.
 	"stopnotifynewtransactions--synopsis": "Stop sending either a txaccepted or a txacceptedverbose notification when a new transaction is accepted into the mempool.",
 
@@ -690,6 +803,7 @@ var rpcResultTypes = map[string][]interface{}{
 	"getblockheader":        {(*string)(nil), (*btcjson.GetBlockHeaderVerboseResult)(nil)},
 	"getblocktemplate":      {(*btcjson.GetBlockTemplateResult)(nil), (*string)(nil), nil},
 	"getblockchaininfo":     {(*btcjson.GetBlockChainInfoResult)(nil)},
+	"getchaintips":          {(*[]btcjson.GetChainTipsResult)(nil)},
 	"getcfilter":            {(*string)(nil)},
 	"getcfilterheader":      {(*string)(nil)},
 	"getconnectioncount":    {(*int32)(nil)},
@@ -700,7 +814,11 @@ var rpcResultTypes = map[string][]interface{}{
 	"getheaders":            {(*[]string)(nil)},
 	"getinfo":               {(*btcjson.InfoChainResult)(nil)},
 	"getmempoolinfo":        {(*btcjson.GetMempoolInfoResult)(nil)},
+	"getmessageinfo":        {(*btcjson.GetMessageInfoResult)(nil)},
+	"getmessagestoreinfo":   {(*btcjson.GetMessageStoreInfoResult)(nil)},
 	"getmininginfo":         {(*btcjson.GetMiningInfoResult)(nil)},
+	"getnak":                {(*btcjson.NAKResult)(nil)},
+	"getname":               {(*btcjson.NameResult)(nil)},
 	"getnettotals":          {(*btcjson.GetNetTotalsResult)(nil)},
 	"getnetworkhashps":      {(*int64)(nil)},
 	"getpeerinfo":           {(*[]btcjson.GetPeerInfoResult)(nil)},
@@ -709,7 +827,12 @@ var rpcResultTypes = map[string][]interface{}{
 	"gettxout":              {(*btcjson.GetTxOutResult)(nil)},
 	"node":                  nil,
 	"help":                  {(*string)(nil), (*string)(nil)},
+	"invalidateblock":       nil,
+	"listnaks":              {(*[]btcjson.NAKResult)(nil)},
+	"listnames":             {(*[]btcjson.NameResult)(nil)},
 	"ping":                  nil,
+	"preciousblock":         nil,
+	"reconsiderblock":       nil,
 	"searchrawtransactions": {(*string)(nil), (*[]btcjson.SearchRawTransactionsResult)(nil)},
 	"sendrawtransaction":    {(*string)(nil)},
 	"setgenerate":           nil,
@@ -726,7 +849,9 @@ var rpcResultTypes = map[string][]interface{}{
 	"session":                   {(*btcjson.SessionResult)(nil)},
 	"notifyblocks":              nil,
 	"stopnotifyblocks":          nil,
+	"notifynewmessages":         nil,
 	"notifynewtransactions":     nil,
+	"stopnotifynewmessages":     nil,
 	"stopnotifynewtransactions": nil,
 	"notifyreceived":            nil,
 	"stopnotifyreceived":        nil,
@@ -27,6 +27,7 @@ import (
 	"github.com/jadeblaquiere/cttd/btcjson"
 	"github.com/jadeblaquiere/cttd/chaincfg"
 	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+	"github.com/jadeblaquiere/cttd/ctmsg"
 	"github.com/jadeblaquiere/cttd/database"
 	"github.com/jadeblaquiere/cttd/txscript"
 	"github.com/jadeblaquiere/cttd/wire"
@@ -67,11 +68,13 @@ var wsHandlersBeforeInit = map[string]wsCommandHandler{
 	"loadtxfilter":              handleLoadTxFilter,
 	"help":                      handleWebsocketHelp,
 	"notifyblocks":              handleNotifyBlocks,
+	"notifynewmessages":         handleNotifyNewMessages,
 	"notifynewtransactions":     handleNotifyNewTransactions,
 	"notifyreceived":            handleNotifyReceived,
 	"notifyspent":               handleNotifySpent,
 	"session":                   handleSession,
 	"stopnotifyblocks":          handleStopNotifyBlocks,
+	"stopnotifynewmessages":     handleStopNotifyNewMessages,
 	"stopnotifynewtransactions": handleStopNotifyNewTransactions,
 	"stopnotifyspent":           handleStopNotifySpent,
 	"stopnotifyreceived":        handleStopNotifyReceived,
@@ -247,6 +250,19 @@ func (m *wsNotificationManager) NotifyMempoolTx(tx *btcutil.Tx, isNew bool) {
 	}
 }
 
+// NotifyMessageAccepted passes a ciphrtxt message accepted into the message
+// store to the notification manager for message notification processing.
+func (m *wsNotificationManager) NotifyMessageAccepted(msg *ctmsg.AcceptedMessage) {
+	// As NotifyMessageAccepted will be called by the message service and
+	// the RPC server may no longer be running, use a select statement to
+	// unblock enqueuing the notification once the RPC server has begun
+	// shutting down.
+	select {
+	case m.queueNotification <- (*notificationMessageAccepted)(msg):
+	case <-m.quit:
+	}
+}
+
 // wsClientFilter tracks relevant addresses for each websocket client for
 // the `rescanblocks` extension. It is modified by the `loadtxfilter` command.
 //
@@ -452,6 +468,7 @@ type notificationTxAcceptedByMempool struct {
 	isNew bool
 	tx    *btcutil.Tx
 }
+type notificationMessageAccepted ctmsg.AcceptedMessage
 
 // Notification control requests
 type notificationRegisterClient wsClient
@@ -460,6 +477,11 @@ type notificationRegisterBlocks wsClient
 type notificationUnregisterBlocks wsClient
 type notificationRegisterNewMempoolTxs wsClient
 type notificationUnregisterNewMempoolTxs wsClient
+type notificationRegisterNewMessages struct {
+	wsc  *wsClient
+	tags [][]byte
+}
+type notificationUnregisterNewMessages wsClient
 type notificationRegisterSpent struct {
 	wsc *wsClient
 	ops []*wire.OutPoint
@@ -492,6 +514,7 @@ func (m *wsNotificationManager) notificationHandler() {
 	// since it is quite a bit more efficient than using the entire struct.
 	blockNotifications := make(map[chan struct{}]*wsClient)
 	txNotifications := make(map[chan struct{}]*wsClient)
+	msgNotifications := make(map[chan struct{}]*wsMessageRequest)
 	watchedOutPoints := make(map[wire.OutPoint]map[chan struct{}]*wsClient)
 	watchedAddrs := make(map[string]map[chan struct{}]*wsClient)
 
@@ -540,6 +563,12 @@ out:
 				m.notifyForTx(watchedOutPoints, watchedAddrs, n.tx, nil)
 				m.notifyRelevantTxAccepted(n.tx, clients)
 
+			case *notificationMessageAccepted:
+				if len(msgNotifications) != 0 {
+					msg := (*ctmsg.AcceptedMessage)(n)
+					m.notifyForNewMessage(msgNotifications, msg)
+				}
+
 			case *notificationRegisterBlocks:
 				wsc := (*wsClient)(n)
 				blockNotifications[wsc.quit] = wsc
@@ -558,6 +587,7 @@ out:
 				// the client itself.
 				delete(blockNotifications, wsc.quit)
 				delete(txNotifications, wsc.quit)
+				delete(msgNotifications, wsc.quit)
 				for k := range wsc.spentRequests {
 					op := k
 					m.removeSpentRequest(watchedOutPoints, wsc, &op)
@@ -587,6 +617,16 @@ out:
 				wsc := (*wsClient)(n)
 				delete(txNotifications, wsc.quit)
 
+			case *notificationRegisterNewMessages:
+				msgNotifications[n.wsc.quit] = &wsMessageRequest{
+					wsc:  n.wsc,
+					tags: n.tags,
+				}
+
+			case *notificationUnregisterNewMessages:
+				wsc := (*wsClient)(n)
+				delete(msgNotifications, wsc.quit)
+
 			default:
 				rpcsLog.Warn("Unhandled notification type")
 			}
@@ -626,6 +666,72 @@ func (m *wsNotificationManager) UnregisterBlockUpdates(wsc *wsClient) {
 	m.queueNotification <- (*notificationUnregisterBlocks)(wsc)
 }
 
+// wsMessageRequest tracks a websocket client registered for notifications of
+// new ciphrtxt messages along with the optional tags used to filter them.
+type wsMessageRequest struct {
+	wsc  *wsClient
+	tags [][]byte
+}
+
+// matches returns whether the message with the passed header should be sent
+// to the client.  A message matches when no tags were requested or when its
+// I point begins with any of the requested tags.
+func (r *wsMessageRequest) matches(h *ctmsg.MessageHeader) bool {
+	if len(r.tags) == 0 {
+		return true
+	}
+	for _, tag := range r.tags {
+		if bytes.HasPrefix(h.I[:], tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterNewMessagesUpdates requests notifications to the passed websocket
+// client when new ciphrtxt messages are accepted into the message store.
+// When tags are passed, only messages with an I point beginning with one of
+// the tags are sent.
+func (m *wsNotificationManager) RegisterNewMessagesUpdates(wsc *wsClient, tags [][]byte) {
+	m.queueNotification <- &notificationRegisterNewMessages{
+		wsc:  wsc,
+		tags: tags,
+	}
+}
+
+// UnregisterNewMessagesUpdates removes notifications to the passed websocket
+// client when new ciphrtxt messages are accepted into the message store.
+func (m *wsNotificationManager) UnregisterNewMessagesUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterNewMessages)(wsc)
+}
+
+// notifyForNewMessage notifies websocket clients that have registered for
+// updates when a new ciphrtxt message is accepted into the message store.
+func (*wsNotificationManager) notifyForNewMessage(clients map[chan struct{}]*wsMessageRequest,
+	msg *ctmsg.AcceptedMessage) {
+
+	var marshalledJSON []byte
+	for _, req := range clients {
+		if !req.matches(msg.Header) {
+			continue
+		}
+
+		// Only create the notification once it is known to be needed.
+		if marshalledJSON == nil {
+			header := newMessageHeaderResult(msg.Hash, msg.Header)
+			ntfn := btcjson.NewMessageAcceptedNtfn(*header)
+			var err error
+			marshalledJSON, err = btcjson.MarshalCmd(nil, ntfn)
+			if err != nil {
+				rpcsLog.Errorf("Failed to marshal message accepted "+
+					"notification: %v", err)
+				return
+			}
+		}
+		req.wsc.QueueNotification(marshalledJSON)
+	}
+}
+
 // subscribedClients returns the set of all websocket client quit channels that
 // are registered to receive notifications regarding tx, either due to tx
 // spending a watched output or outputting to a watched address.  Matching
@@ -1876,6 +1982,53 @@ func handleNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface{},
 	return nil, nil
 }
 
+// handleNotifyNewMessages implements the notifynewmessages command extension
+// for websocket connections.
+func handleNotifyNewMessages(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	cmd, ok := icmd.(*btcjson.NotifyNewMessagesCmd)
+	if !ok {
+		return nil, btcjson.ErrRPCInternal
+	}
+
+	// Respond with an error if the message service is not enabled.
+	if wsc.server.cfg.MsgSvc == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Message service must be enabled (--bluenet)",
+		}
+	}
+
+	var tags [][]byte
+	if cmd.Tags != nil {
+		tags = make([][]byte, 0, len(*cmd.Tags))
+		for _, tagHex := range *cmd.Tags {
+			tag, err := hex.DecodeString(tagHex)
+			if err != nil {
+				return nil, rpcDecodeHexError(tagHex)
+			}
+			if len(tag) == 0 || len(tag) > ctmsg.PointSize {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidParameter,
+					Message: fmt.Sprintf("Tag %q must be between "+
+						"1 and %d bytes", tagHex,
+						ctmsg.PointSize),
+				}
+			}
+			tags = append(tags, tag)
+		}
+	}
+
+	wsc.server.ntfnMgr.RegisterNewMessagesUpdates(wsc, tags)
+	return nil, nil
+}
+
+// handleStopNotifyNewMessages implements the stopnotifynewmessages command
+// extension for websocket connections.
+func handleStopNotifyNewMessages(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterNewMessagesUpdates(wsc)
+	return nil, nil
+}
+
 // handleStopNotifyNewTransations implements the stopnotifynewtransactions
 // command extension for websocket connections.
 func handleStopNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface{}, error) {
@@ -187,6 +187,22 @@ func (ps *peerState) forAllPeers(closure func(sp *serverPeer)) {
 	ps.forAllOutboundPeers(closure)
 }
 
+// ctSyncRange is a time range, expressed in seconds since the unix epoch, for
+// which message store digests have been requested from a peer.
+type ctSyncRange struct {
+	start int64
+	end   int64
+}
+
+// maxCtSyncRequests is the maximum number of outstanding message store digest
+// requests to a single peer.
+const maxCtSyncRequests = 1024
+
+// ctMsgRequestTimeout is the amount of time a peer is given to deliver a
+// requested ciphrtxt message before the request is forgotten, which allows the
+// message to be requested again from this or another peer.
+const ctMsgRequestTimeout = 2 * time.Minute
+
 // cfHeaderKV is a tuple of a filter header and its associated block hash. The
 // struct is used to cache cfcheckpt responses.
 type cfHeaderKV struct {
@@ -240,6 +256,7 @@ type server struct {
 	txIndex   *indexers.TxIndex
 	addrIndex *indexers.AddrIndex
 	cfIndex   *indexers.CfIndex
+	nakIndex  *indexers.NAKIndex
 
 	// The fee estimator keeps track of how long transactions are left in
 	// the mempool before they are mined into blocks.
@@ -269,6 +286,8 @@ type serverPeer struct {
 	isWhitelisted  bool
 	filter         *bloom.Filter
 	knownAddresses map[string]struct{}
+	requestedMsgs  map[chainhash.Hash]time.Time
+	ctSyncRanges   map[ctSyncRange]struct{}
 	banScore       connmgr.DynamicBanScore
 	quit           chan struct{}
 	// The following chans are used to sync blockmanager and server.
@@ -284,6 +303,8 @@ func newServerPeer(s *server, isPersistent bool) *serverPeer {
 		persistent:     isPersistent,
 		filter:         bloom.LoadFilter(nil),
 		knownAddresses: make(map[string]struct{}),
+		requestedMsgs:  make(map[chainhash.Hash]time.Time),
+		ctSyncRanges:   make(map[ctSyncRange]struct{}),
 		quit:           make(chan struct{}),
 		txProcessed:    make(chan struct{}, 1),
 		blockProcessed: make(chan struct{}, 1),
@@ -494,6 +515,14 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 
 	// Add valid peer to the server.
 	sp.server.AddPeer(sp)
+
+	// Catch up on any ciphrtxt messages the peer holds which are missing
+	// from the local message store.
+	if sp.server.ctMsgSvc != nil && hasServices(msg.Services, wire.SFNodeCtMsg) {
+		end := time.Now().Unix()
+		start := end - int64(ctmsg.SyncWindow/time.Second)
+		sp.requestCtDigests(start, end)
+	}
 	return nil
 }
 
@@ -603,6 +632,10 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 // accordingly.  We pass the message down to blockmanager which will call
 // QueueMessage with any appropriate responses.
 func (sp *serverPeer) OnInv(_ *peer.Peer, msg *wire.MsgInv) {
+	// Ciphrtxt message inventory is handled directly by the server rather
+	// than the sync manager, so split it out first.
+	msg = sp.handleCtMsgInv(msg)
+
 	if !cfg.BlocksOnly {
 		if len(msg.InvList) > 0 {
 			sp.server.syncManager.QueueInv(msg, sp.Peer)
@@ -641,6 +674,314 @@ func (sp *serverPeer) OnHeaders(_ *peer.Peer, msg *wire.MsgHeaders) {
 	sp.server.syncManager.QueueHeaders(msg, sp.Peer)
 }
 
+// handleCtMsgInv requests any advertised ciphrtxt messages which are not
+// already in the local message store and returns the passed inventory
+// message with the ciphrtxt message vectors removed.  The original message is
+// returned unmodified when it does not advertise any ciphrtxt messages.
+func (sp *serverPeer) handleCtMsgInv(msg *wire.MsgInv) *wire.MsgInv {
+	numCtMsgs := 0
+	for _, iv := range msg.InvList {
+		if iv.Type == wire.InvTypeCtMsg {
+			numCtMsgs++
+		}
+	}
+	if numCtMsgs == 0 {
+		return msg
+	}
+
+	// Forget the requests the peer did not answer in time so the messages
+	// can be requested again.
+	sp.expireCtMsgRequests(time.Now())
+
+	otherInv := wire.NewMsgInvSizeHint(uint(len(msg.InvList) - numCtMsgs))
+	gdmsg := wire.NewMsgGetData()
+	msgSvc := sp.server.ctMsgSvc
+	for _, iv := range msg.InvList {
+		if iv.Type != wire.InvTypeCtMsg {
+			otherInv.AddInvVect(iv)
+			continue
+		}
+
+		// Ignore message inventory when the message service is not
+		// running.
+		if msgSvc == nil {
+			continue
+		}
+
+		sp.AddKnownInventory(iv)
+		if _, exists := sp.requestedMsgs[iv.Hash]; exists {
+			continue
+		}
+		if msgSvc.HaveMessage(iv.Hash[:]) {
+			continue
+		}
+
+		// Limit the number of outstanding requests to the peer.
+		if len(sp.requestedMsgs) >= wire.MaxInvPerMsg {
+			break
+		}
+		sp.requestedMsgs[iv.Hash] = time.Now()
+		gdmsg.AddInvVect(iv)
+	}
+
+	if len(gdmsg.InvList) > 0 {
+		sp.QueueMessage(gdmsg, nil)
+	}
+	return otherInv
+}
+
+// expireCtMsgRequests removes the ciphrtxt message requests to the peer which
+// were made more than ctMsgRequestTimeout before the passed time.
+func (sp *serverPeer) expireCtMsgRequests(now time.Time) {
+	for hash, requested := range sp.requestedMsgs {
+		if now.Sub(requested) >= ctMsgRequestTimeout {
+			peerLog.Debugf("Request for ctmsg %v from %v timed out",
+				hash, sp)
+			delete(sp.requestedMsgs, hash)
+		}
+	}
+}
+
+// OnNotFound is invoked when a peer receives a notfound bitcoin message.  The
+// requests for the ciphrtxt messages the peer can not provide are removed so
+// the messages can be requested again from other peers.
+func (sp *serverPeer) OnNotFound(_ *peer.Peer, msg *wire.MsgNotFound) {
+	for _, iv := range msg.InvList {
+		if iv.Type != wire.InvTypeCtMsg {
+			continue
+		}
+		if _, exists := sp.requestedMsgs[iv.Hash]; !exists {
+			continue
+		}
+		peerLog.Debugf("Peer %v does not have requested ctmsg %v", sp,
+			iv.Hash)
+		delete(sp.requestedMsgs, iv.Hash)
+	}
+}
+
+// isLocalCtMsgRejection returns whether or not a message rejected with the
+// passed error code may be valid to the peer which relayed it.  Messages which
+// expired or appear to come from the future may be the result of clock
+// differences, and messages which are too old for the local store or outside of
+// the size and expiry limits of the local policy may be accepted by peers
+// configured differently.  Only malformed messages and messages without the
+// proof of work the network requires are the fault of the peer.
+func isLocalCtMsgRejection(code ctmsg.ErrorCode) bool {
+	switch code {
+	case ctmsg.ErrExpired, ctmsg.ErrTimeTooNew, ctmsg.ErrTimeTooOld,
+		ctmsg.ErrMessageTooLarge, ctmsg.ErrBadExpiry:
+
+		return true
+	}
+	return false
+}
+
+// OnCtMsg is invoked when a peer receives a ctmsg ciphrtxt message.  The
+// message is added to the local message store which in turn causes it to be
+// announced to the remaining peers.
+func (sp *serverPeer) OnCtMsg(_ *peer.Peer, msg *wire.MsgCtMsg) {
+	msgSvc := sp.server.ctMsgSvc
+	if msgSvc == nil {
+		peerLog.Debugf("Ignoring ctmsg from %v - message service "+
+			"disabled", sp)
+		return
+	}
+
+	hash, err := msgSvc.IngestMessage(bytes.NewReader(msg.Data))
+	if hash != nil {
+		var h chainhash.Hash
+		if h.SetBytes(hash) == nil {
+			// Mark the message as known to the peer so it isn't
+			// announced back to it.
+			if _, exists := sp.requestedMsgs[h]; !exists {
+				peerLog.Debugf("Got unrequested ctmsg %v "+
+					"from %v", h, sp)
+			}
+			delete(sp.requestedMsgs, h)
+			sp.AddKnownInventory(wire.NewInvVect(wire.InvTypeCtMsg, &h))
+		}
+	}
+
+	if rerr, ok := err.(ctmsg.RuleError); ok {
+		if isLocalCtMsgRejection(rerr.ErrorCode) {
+			peerLog.Debugf("Rejected ctmsg from %v: %v", sp, err)
+		} else {
+			sp.addBanScore(10, 0, "invalid ctmsg: "+rerr.Description)
+		}
+		return
+	}
+	switch err {
+	case nil, ctmsg.ErrDuplicateMessage:
+
+	case ctmsg.ErrInvalidMessage:
+		sp.addBanScore(10, 0, "invalid ctmsg")
+
+	default:
+		ctmxLog.Errorf("Failed to ingest message from %v: %v", sp, err)
+	}
+}
+
+// requestCtDigests asks the peer for the digests of the messages it stores for
+// the time range [start, end) split into ctmsg.DigestFanout intervals.
+func (sp *serverPeer) requestCtDigests(start, end int64) {
+	bounds := ctmsg.SplitInterval(start, end, ctmsg.DigestFanout)
+	if len(bounds) == 0 {
+		return
+	}
+	if len(sp.ctSyncRanges) >= maxCtSyncRequests {
+		peerLog.Debugf("Too many outstanding message digest requests "+
+			"to %v -- skipping [%d, %d)", sp, start, end)
+		return
+	}
+
+	sp.ctSyncRanges[ctSyncRange{start: start, end: end}] = struct{}{}
+	sp.QueueMessage(wire.NewMsgGetCtDigests(start, end,
+		uint32(len(bounds)-1)), nil)
+}
+
+// OnGetCtDigests is invoked when a peer receives a getctdigests ciphrtxt
+// message.  It responds with the digests of the locally stored messages for
+// each of the requested intervals.
+func (sp *serverPeer) OnGetCtDigests(_ *peer.Peer, msg *wire.MsgGetCtDigests) {
+	msgSvc := sp.server.ctMsgSvc
+	if msgSvc == nil {
+		return
+	}
+
+	// A decaying ban score increase is applied to prevent exhausting
+	// resources with repeated digest requests, which each require
+	// scanning the message store.
+	sp.addBanScore(0, 2, "getctdigests")
+
+	reply, err := ctDigestsReply(msgSvc, msg)
+	if err != nil {
+		ctmxLog.Errorf("Unable to compute message digests for %v: %v",
+			sp, err)
+		return
+	}
+	sp.QueueMessage(reply, nil)
+}
+
+// ctDigestsReply returns the ctdigests message answering the passed
+// getctdigests request.  Only the messages the service makes available to
+// peers are included in the digests, so requests for arbitrary time ranges
+// don't scan more of the message store than that.
+func ctDigestsReply(msgSvc *ctmsg.CiphrtxtMsgSvc, msg *wire.MsgGetCtDigests) (*wire.MsgCtDigests, error) {
+	digests, err := msgSvc.IntervalDigests(msg.StartTime, msg.EndTime,
+		int(msg.NumIntervals))
+	if err != nil {
+		return nil, err
+	}
+
+	reply := wire.NewMsgCtDigests(msg.StartTime, msg.EndTime)
+	for i := range digests {
+		err := reply.AddDigest(&wire.CtDigest{
+			Count:  digests[i].Count,
+			Digest: digests[i].Digest,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reply, nil
+}
+
+// OnCtDigests is invoked when a peer receives a ctdigests ciphrtxt message in
+// response to a getctdigests request.  Each interval digest is compared to the
+// digest of the local message store.  Differing intervals which hold few
+// messages are reconciled by requesting their message inventory, while larger
+// ones are split further and their digests requested in turn.
+func (sp *serverPeer) OnCtDigests(_ *peer.Peer, msg *wire.MsgCtDigests) {
+	msgSvc := sp.server.ctMsgSvc
+	if msgSvc == nil {
+		return
+	}
+
+	key := ctSyncRange{start: msg.StartTime, end: msg.EndTime}
+	if _, exists := sp.ctSyncRanges[key]; !exists {
+		peerLog.Debugf("Got unrequested message digests for [%d, %d) "+
+			"from %v -- ignoring", msg.StartTime, msg.EndTime, sp)
+		return
+	}
+	delete(sp.ctSyncRanges, key)
+
+	local, err := msgSvc.IntervalDigests(msg.StartTime, msg.EndTime,
+		ctmsg.DigestFanout)
+	if err != nil {
+		ctmxLog.Errorf("Unable to compute message digests: %v", err)
+		return
+	}
+	if len(local) != len(msg.Digests) {
+		sp.addBanScore(10, 0, "ctdigests interval mismatch")
+		return
+	}
+
+	for i, remote := range msg.Digests {
+		// Nothing to fetch when the peer has no messages in the
+		// interval or the stores already agree.
+		if remote.Count == 0 || (remote.Count == local[i].Count &&
+			remote.Digest == local[i].Digest) {
+			continue
+		}
+
+		start, end := local[i].Start, local[i].End
+		if remote.Count <= ctmsg.MaxReconcileCount || end-start <= 1 {
+			sp.QueueMessage(wire.NewMsgGetCtInv(start, end), nil)
+			continue
+		}
+		sp.requestCtDigests(start, end)
+	}
+}
+
+// OnGetCtInv is invoked when a peer receives a getctinv ciphrtxt message.  It
+// responds with an inventory message announcing all of the locally stored
+// messages in the requested time range.
+func (sp *serverPeer) OnGetCtInv(_ *peer.Peer, msg *wire.MsgGetCtInv) {
+	msgSvc := sp.server.ctMsgSvc
+	if msgSvc == nil {
+		return
+	}
+
+	// A decaying ban score increase is applied to prevent flooding.
+	sp.addBanScore(0, 2, "getctinv")
+
+	invMsg, err := ctInvReply(msgSvc, msg)
+	if err != nil {
+		ctmxLog.Errorf("Unable to list messages for %v: %v", sp, err)
+		return
+	}
+
+	for _, iv := range invMsg.InvList {
+		sp.AddKnownInventory(iv)
+	}
+	if len(invMsg.InvList) > 0 {
+		sp.QueueMessage(invMsg, nil)
+	}
+}
+
+// ctInvReply returns the inventory message answering the passed getctinv
+// request.  Only the messages the service makes available to peers are
+// announced, and no more than fit in a single inventory message.
+func ctInvReply(msgSvc *ctmsg.CiphrtxtMsgSvc, msg *wire.MsgGetCtInv) (*wire.MsgInv, error) {
+	hashes, err := msgSvc.ListHashes(msg.StartTime, msg.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	invMsg := wire.NewMsgInvSizeHint(uint(len(hashes)))
+	for _, h := range hashes {
+		hash, err := chainhash.NewHash(h)
+		if err != nil {
+			continue
+		}
+		iv := wire.NewInvVect(wire.InvTypeCtMsg, hash)
+		if invMsg.AddInvVect(iv) != nil {
+			break
+		}
+	}
+	return invMsg, nil
+}
+
 // handleGetData is invoked when a peer receives a getdata bitcoin message and
 // is used to deliver block and transaction information.
 func (sp *serverPeer) OnGetData(_ *peer.Peer, msg *wire.MsgGetData) {
@@ -687,6 +1028,8 @@ func (sp *serverPeer) OnGetData(_ *peer.Peer, msg *wire.MsgGetData) {
 			err = sp.server.pushMerkleBlockMsg(sp, &iv.Hash, c, waitChan, wire.WitnessEncoding)
 		case wire.InvTypeFilteredBlock:
 			err = sp.server.pushMerkleBlockMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
+		case wire.InvTypeCtMsg:
+			err = sp.server.pushCtMsgMsg(sp, &iv.Hash, c, waitChan)
 		default:
 			peerLog.Warnf("Unknown type in inventory request %d",
 				iv.Type)
@@ -1554,6 +1897,59 @@ func (s *server) pushMerkleBlockMsg(sp *serverPeer, hash *chainhash.Hash,
 	return nil
 }
 
+// pushCtMsgMsg sends a ctmsg message for the provided message hash to the
+// connected peer.  An error is returned if the message hash is not known.
+func (s *server) pushCtMsgMsg(sp *serverPeer, hash *chainhash.Hash,
+	doneChan chan<- struct{}, waitChan <-chan struct{}) error {
+
+	var data []byte
+	err := ctmsg.ErrMessageNotFound
+	if s.ctMsgSvc != nil {
+		data, err = s.ctMsgSvc.FetchMessage(hash[:])
+	}
+	if err != nil {
+		peerLog.Tracef("Unable to fetch requested message %v: %v",
+			hash, err)
+
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return err
+	}
+
+	// Once we have fetched data wait for any previous operation to finish.
+	if waitChan != nil {
+		<-waitChan
+	}
+
+	sp.QueueMessage(wire.NewMsgCtMsg(data), doneChan)
+
+	return nil
+}
+
+// handleCtMsgNotification handles notifications from the ciphrtxt message
+// service.  Newly accepted messages are announced to all peers which relay
+// messages.
+func (s *server) handleCtMsgNotification(notification *ctmsg.Notification) {
+	switch notification.Type {
+	case ctmsg.NTMessageAccepted:
+		msg, ok := notification.Data.(*ctmsg.AcceptedMessage)
+		if !ok {
+			ctmxLog.Warnf("Message accepted notification is not an " +
+				"accepted message.")
+			break
+		}
+		msgHash, err := chainhash.NewHash(msg.Hash)
+		if err != nil {
+			ctmxLog.Warnf("Unable to relay message %x: %v", msg.Hash,
+				err)
+			break
+		}
+		iv := wire.NewInvVect(wire.InvTypeCtMsg, msgHash)
+		s.RelayInventory(iv, nil)
+	}
+}
+
 // handleUpdatePeerHeight updates the heights of all peers who were known to
 // announce a block we recently accepted.
 func (s *server) handleUpdatePeerHeights(state *peerState, umsg updatePeerHeightsMsg) {
@@ -1722,6 +2118,13 @@ func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 			return
 		}
 
+		// Only relay ciphrtxt messages to peers which advertise that
+		// they store and relay them.
+		if msg.invVect.Type == wire.InvTypeCtMsg &&
+			!hasServices(sp.Services(), wire.SFNodeCtMsg) {
+			return
+		}
+
 		if msg.invVect.Type == wire.InvTypeTx {
 			// Don't relay the transaction to the peer when it has
 			// transaction relaying disabled.
@@ -1963,6 +2366,11 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 			OnGetCFilters:  sp.OnGetCFilters,
 			OnGetCFHeaders: sp.OnGetCFHeaders,
 			OnGetCFCheckpt: sp.OnGetCFCheckpt,
+			OnNotFound:     sp.OnNotFound,
+			OnCtMsg:        sp.OnCtMsg,
+			OnGetCtDigests: sp.OnGetCtDigests,
+			OnCtDigests:    sp.OnCtDigests,
+			OnGetCtInv:     sp.OnGetCtInv,
 			OnFeeFilter:    sp.OnFeeFilter,
 			OnFilterAdd:    sp.OnFilterAdd,
 			OnFilterClear:  sp.OnFilterClear,
@@ -2126,6 +2534,12 @@ out:
 	s.syncManager.Stop()
 	s.addrManager.Stop()
 
+	// Write the modifications held by the utxo cache to the database now
+	// that no more blocks are connected.
+	if err := s.chain.FlushUtxoCache(); err != nil {
+		srvrLog.Errorf("Unable to flush the utxo cache: %v", err)
+	}
+
 	// Drain channels before exiting so nothing is left waiting around
 	// to send.
 cleanup:
@@ -2309,6 +2723,7 @@ func (s *server) Start() {
 	}
 
 	if cfg.CtBlueNet {
+		s.ctMsgSvc.Start()
 		s.restServer.Start()
 	}
 
@@ -2556,9 +2971,10 @@ func setupRPCListeners() ([]net.Listener, error) {
 // with the REST server depending on the configuration settings for listen
 // addresses and TLS.
 func setupRESTListeners() ([]net.Listener, error) {
-	// Setup TLS if not disabled.
+	// Setup TLS if not disabled.  The REST server shares the certificate
+	// of the RPC server.
 	listenFunc := net.Listen
-	if !cfg.DisableTLS {
+	if !cfg.DisableTLS && !cfg.RESTDisableTLS {
 		// Generate the TLS cert and key file if both don't already
 		// exist.
 		if !fileExists(cfg.RPCKey) && !fileExists(cfg.RPCCert) {
@@ -2612,13 +3028,30 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 	if cfg.NoCFilters {
 		services &^= wire.SFNodeCF
 	}
+	if cfg.CtBlueNet {
+		services |= wire.SFNodeCtMsg
+	}
+
+	// A node which has pruned blocks no longer serves the full block chain,
+	// even once pruning has been disabled again.
+	var beenPruned bool
+	err := db.View(func(dbTx database.Tx) error {
+		var err error
+		beenPruned, err = dbTx.BeenPruned()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Prune != 0 || beenPruned {
+		services &^= wire.SFNodeNetwork
+	}
 
 	amgr := addrmgr.New(cfg.DataDir, btcdLookup)
 
 	var listeners []net.Listener
 	var nat NAT
 	if !cfg.DisableListen {
-		var err error
 		listeners, nat, err = initListeners(amgr, listenAddrs, services)
 		if err != nil {
 			return nil, err
@@ -2680,6 +3113,11 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		s.cfIndex = indexers.NewCfIndex(db, chainParams)
 		indexes = append(indexes, s.cfIndex)
 	}
+	if cfg.NAKIndex {
+		indxLog.Info("Network access key index is enabled")
+		s.nakIndex = indexers.NewNAKIndex(db)
+		indexes = append(indexes, s.nakIndex)
+	}
 
 	// Create an index manager if any of the optional indexes are enabled.
 	var indexManager blockchain.IndexManager
@@ -2694,16 +3132,17 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 	}
 
 	// Create a new block chain instance with the appropriate configuration.
-	var err error
 	s.chain, err = blockchain.New(&blockchain.Config{
-		DB:           s.db,
-		Interrupt:    interrupt,
-		ChainParams:  s.chainParams,
-		Checkpoints:  checkpoints,
-		TimeSource:   s.timeSource,
-		SigCache:     s.sigCache,
-		IndexManager: indexManager,
-		HashCache:    s.hashCache,
+		DB:               s.db,
+		Interrupt:        interrupt,
+		ChainParams:      s.chainParams,
+		Checkpoints:      checkpoints,
+		TimeSource:       s.timeSource,
+		SigCache:         s.sigCache,
+		IndexManager:     indexManager,
+		HashCache:        s.hashCache,
+		UtxoCacheMaxSize: uint64(cfg.UtxoCacheMaxSizeMiB) * 1024 * 1024,
+		Prune:            cfg.Prune * 1024 * 1024,
 	})
 	if err != nil {
 		return nil, err
@@ -2757,11 +3196,12 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		CalcSequenceLock: func(tx *btcutil.Tx, view *blockchain.UtxoViewpoint) (*blockchain.SequenceLock, error) {
 			return s.chain.CalcSequenceLock(tx, view, true)
 		},
-		IsDeploymentActive: s.chain.IsDeploymentActive,
-		SigCache:           s.sigCache,
-		HashCache:          s.hashCache,
-		AddrIndex:          s.addrIndex,
-		FeeEstimator:       s.feeEstimator,
+		IsDeploymentActive:     s.chain.IsDeploymentActive,
+		FetchNameRegistrations: s.chain.FetchNameRegistrations,
+		SigCache:               s.sigCache,
+		HashCache:              s.hashCache,
+		AddrIndex:              s.addrIndex,
+		FeeEstimator:           s.feeEstimator,
 	}
 	s.txMemPool = mempool.New(&txC)
 
@@ -2885,6 +3325,40 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		})
 	}
 
+	// The message service is loaded before the RPC server so websocket
+	// clients can be notified of new messages.
+	if cfg.CtBlueNet {
+		// Messages are kept in the ctgo message store under the ctmx
+		// directory unless the block database was selected instead.
+		var store ctmsg.Store
+		if cfg.CtMsgStore == "db" {
+			store, err = ctmsg.NewDBStore(s.db)
+			if err != nil {
+				btcdLog.Errorf("%v", err)
+				return nil, err
+			}
+		}
+
+		// Load the ciphrtxt message service.
+		s.ctMsgSvc, err = ctmsg.New(&ctmsg.Config{
+			MessageStoreRootDir: cfg.CtmxDir,
+			Store:               store,
+			Policy: ctmsg.MessagePolicy{
+				MaxMessageSize: cfg.CtMaxMsgSize,
+				MinExpire:      cfg.CtMinMsgExpire,
+				MaxExpire:      cfg.CtMaxMsgExpire,
+				PowBits:        activeNetParams.CtMsgPowBits,
+			},
+			RetentionWindow: cfg.CtRetention,
+			MaxStoreSize:    int64(cfg.CtMaxStoreSize) * 1024 * 1024,
+		})
+		if err != nil {
+			btcdLog.Errorf("%v", err)
+			return nil, err
+		}
+		s.ctMsgSvc.Subscribe(s.handleCtMsgNotification)
+	}
+
 	if !cfg.DisableRPC {
 		// Setup listeners for the configured RPC listen addresses and
 		// TLS settings.
@@ -2911,7 +3385,9 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 			TxIndex:      s.txIndex,
 			AddrIndex:    s.addrIndex,
 			CfIndex:      s.cfIndex,
+			NAKIndex:     s.nakIndex,
 			FeeEstimator: s.feeEstimator,
+			MsgSvc:       s.ctMsgSvc,
 		})
 		if err != nil {
 			return nil, err
@@ -2925,17 +3401,7 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 	}
 
 	if cfg.CtBlueNet {
-		// Load the ciphrtxt message service.
-		s.ctMsgSvc, err = ctmsg.New(&ctmsg.Config{
-			MessageStoreRootDir: cfg.CtmxDir,
-		})
-		if err != nil {
-			btcdLog.Errorf("%v", err)
-			return nil, err
-		}
-
-		// Setup listeners for the configured RPC listen addresses and
-		// TLS settings.
+		// Setup listeners for the configured REST listen addresses.
 		restListeners, err := setupRESTListeners()
 		if err != nil {
 			return nil, err
@@ -2945,9 +3411,13 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		}
 
 		s.restServer, err = newCtRESTServer(&restServerConfig{
-			Listeners: restListeners,
-			MStore:    s.ctMsgSvc.MStore,
-			Server:		 &s,
+			Listeners:   restListeners,
+			MsgSvc:      s.ctMsgSvc,
+			Server:      &s,
+			APITokens:   cfg.RESTTokens,
+			MaxClients:  cfg.RESTMaxClients,
+			RequestRate: cfg.RESTRateLimit,
+			ByteRate:    cfg.RESTByteRateLimit * 1024,
 		})
 		if err != nil {
 			return nil, err
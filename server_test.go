@@ -0,0 +1,151 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/btcec"
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+	"github.com/jadeblaquiere/cttd/ctmsg"
+	"github.com/jadeblaquiere/cttd/wire"
+)
+
+// TestCtMsgRequests ensures outstanding ciphrtxt message requests are forgotten
+// when the peer reports it does not have the message or does not deliver it in
+// time.
+func TestCtMsgRequests(t *testing.T) {
+	sp := newServerPeer(&server{}, false)
+	now := time.Now()
+	stale := chainhash.Hash{0x01}
+	missing := chainhash.Hash{0x02}
+	pending := chainhash.Hash{0x03}
+	sp.requestedMsgs[stale] = now.Add(-ctMsgRequestTimeout)
+	sp.requestedMsgs[missing] = now
+	sp.requestedMsgs[pending] = now
+
+	sp.expireCtMsgRequests(now)
+	if _, ok := sp.requestedMsgs[stale]; ok {
+		t.Fatal("timed out request was not forgotten")
+	}
+	if len(sp.requestedMsgs) != 2 {
+		t.Fatalf("got %d outstanding requests after expiry, want 2",
+			len(sp.requestedMsgs))
+	}
+
+	// Only ciphrtxt message vectors answer the requests.
+	notFound := wire.NewMsgNotFound()
+	notFound.AddInvVect(wire.NewInvVect(wire.InvTypeCtMsg, &missing))
+	notFound.AddInvVect(wire.NewInvVect(wire.InvTypeTx, &pending))
+	sp.OnNotFound(nil, notFound)
+	if _, ok := sp.requestedMsgs[missing]; ok {
+		t.Fatal("request for a message the peer does not have was not " +
+			"forgotten")
+	}
+	if _, ok := sp.requestedMsgs[pending]; !ok {
+		t.Fatal("request was forgotten for a notfound transaction")
+	}
+}
+
+// TestLocalCtMsgRejection ensures peers are only penalized for relaying
+// messages which are invalid regardless of the local message policy.
+func TestLocalCtMsgRejection(t *testing.T) {
+	tests := []struct {
+		code  ctmsg.ErrorCode
+		local bool
+	}{
+		{ctmsg.ErrBadMagic, false},
+		{ctmsg.ErrBadVersion, false},
+		{ctmsg.ErrMessageTooSmall, false},
+		{ctmsg.ErrMessageTooLarge, true},
+		{ctmsg.ErrBadMessageLength, false},
+		{ctmsg.ErrBadExpiry, true},
+		{ctmsg.ErrExpired, true},
+		{ctmsg.ErrTimeTooNew, true},
+		{ctmsg.ErrTimeTooOld, true},
+		{ctmsg.ErrInsufficientWork, false},
+		{ctmsg.ErrBadCiphertext, false},
+	}
+	for _, test := range tests {
+		if local := isLocalCtMsgRejection(test.code); local != test.local {
+			t.Errorf("%v: got local rejection %v, want %v", test.code,
+				local, test.local)
+		}
+	}
+}
+
+// TestCtSyncReplies ensures requests for message digests and inventory over
+// arbitrary time ranges are answered with the messages within the retention
+// window only.
+func TestCtSyncReplies(t *testing.T) {
+	msgSvc, err := ctmsg.New(&ctmsg.Config{
+		Store:           ctmsg.NewMemStore(),
+		RetentionWindow: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New: unexpected error %v", err)
+	}
+	defer msgSvc.MStore.Close()
+
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("NewPrivateKey: unexpected error %v", err)
+	}
+	now := time.Now()
+	var recent *chainhash.Hash
+	for _, age := range []time.Duration{48 * time.Hour, time.Hour} {
+		msg, err := ctmsg.NewMessage(key.PubKey(), []byte("sync"),
+			now.Add(-age), time.Hour)
+		if err != nil {
+			t.Fatalf("NewMessage: unexpected error %v", err)
+		}
+		hash, err := msgSvc.MStore.StoreMessage(msg.Bytes())
+		if err != nil {
+			t.Fatalf("StoreMessage: unexpected error %v", err)
+		}
+		recent, _ = chainhash.NewHash(hash)
+	}
+
+	tests := []struct {
+		name       string
+		start, end int64
+	}{
+		{"full range", math.MinInt64, math.MaxInt64},
+		{"from epoch", 0, math.MaxInt64},
+		{"sync window", now.Add(-ctmsg.SyncWindow).Unix(), now.Unix()},
+	}
+	for _, test := range tests {
+		digests, err := ctDigestsReply(msgSvc, wire.NewMsgGetCtDigests(
+			test.start, test.end, wire.MaxCtDigestsPerMsg))
+		if err != nil {
+			t.Fatalf("%s: ctDigestsReply: unexpected error %v",
+				test.name, err)
+		}
+		var count uint32
+		for _, d := range digests.Digests {
+			count += d.Count
+		}
+		if len(digests.Digests) != wire.MaxCtDigestsPerMsg || count != 1 {
+			t.Fatalf("%s: got %d digests of %d messages, want %d "+
+				"of 1", test.name, len(digests.Digests), count,
+				wire.MaxCtDigestsPerMsg)
+		}
+
+		inv, err := ctInvReply(msgSvc, wire.NewMsgGetCtInv(test.start,
+			test.end))
+		if err != nil {
+			t.Fatalf("%s: ctInvReply: unexpected error %v",
+				test.name, err)
+		}
+		if len(inv.InvList) != 1 || inv.InvList[0].Type !=
+			wire.InvTypeCtMsg || inv.InvList[0].Hash != *recent {
+
+			t.Fatalf("%s: got inventory %v, want only %x",
+				test.name, inv.InvList, recent)
+		}
+	}
+}
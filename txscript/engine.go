@@ -94,6 +94,11 @@ const (
 	// operation whose public key isn't serialized in a compressed format
 	// non-standard.
 	ScriptVerifyWitnessPubKeyType
+
+	// ScriptVerifyCTExtended defines whether to execute the ciphrtxt
+	// extended opcodes OP_REGISTERNAK and OP_REGISTERNAME.  When the flag is
+	// not set they are treated as OP_NOP7 and OP_NOP8.
+	ScriptVerifyCTExtended
 )
 
 const (
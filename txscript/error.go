@@ -338,6 +338,28 @@ const (
 	// serialized in a compressed format.
 	ErrWitnessPubKeyType
 
+	// -----------------------------------------------------
+	// Failures related to the ciphrtxt extended opcodes.
+	// -----------------------------------------------------
+
+	// ErrMalformedNAK is returned when the network access key consumed by
+	// OP_REGISTERNAK can not be parsed or describes an empty validity
+	// period.
+	ErrMalformedNAK
+
+	// ErrNAKSignature is returned when the signature of the network access
+	// key consumed by OP_REGISTERNAK does not verify against its public
+	// key.
+	ErrNAKSignature
+
+	// ErrInvalidName is returned when the name consumed by OP_REGISTERNAME
+	// is empty, too long or contains invalid characters.
+	ErrInvalidName
+
+	// ErrInvalidNameOwner is returned when the owner key consumed by
+	// OP_REGISTERNAME is not a valid compressed public key.
+	ErrInvalidNameOwner
+
 	// numErrorCodes is the maximum error code number used in tests.  This
 	// entry MUST be the last entry in the enum.
 	numErrorCodes
@@ -409,6 +431,10 @@ var errorCodeStrings = map[ErrorCode]string{
 	ErrMinimalIf:                          "ErrMinimalIf",
 	ErrWitnessPubKeyType:                  "ErrWitnessPubKeyType",
 	ErrDiscourageUpgradableWitnessProgram: "ErrDiscourageUpgradableWitnessProgram",
+	ErrMalformedNAK:                       "ErrMalformedNAK",
+	ErrNAKSignature:                       "ErrNAKSignature",
+	ErrInvalidName:                        "ErrInvalidName",
+	ErrInvalidNameOwner:                   "ErrInvalidNameOwner",
 }
 
 // String returns the ErrorCode as a human-readable name.
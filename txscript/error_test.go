@@ -80,6 +80,10 @@ func TestErrorCodeStringer(t *testing.T) {
 		{ErrMinimalIf, "ErrMinimalIf"},
 		{ErrWitnessPubKeyType, "ErrWitnessPubKeyType"},
 		{ErrDiscourageUpgradableWitnessProgram, "ErrDiscourageUpgradableWitnessProgram"},
+		{ErrMalformedNAK, "ErrMalformedNAK"},
+		{ErrNAKSignature, "ErrNAKSignature"},
+		{ErrInvalidName, "ErrInvalidName"},
+		{ErrInvalidNameOwner, "ErrInvalidNameOwner"},
 		{0xffff, "Unknown ErrorCode (65535)"},
 	}
 
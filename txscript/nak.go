@@ -0,0 +1,176 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/btcec"
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+)
+
+const (
+	// NAKVersion is the version of the serialized network access keys
+	// understood by OP_REGISTERNAK.
+	NAKVersion = 1
+
+	// nakSignedLen is the length of the signed portion of a serialized
+	// network access key: version (1 byte) + compressed public key (33
+	// bytes) + not before time (4 bytes) + not after time (4 bytes).
+	nakSignedLen = 1 + 33 + 4 + 4
+
+	// MaxNAKSize is the maximum length of a serialized network access key,
+	// which is the signed portion followed by a DER signature of at most
+	// 72 bytes.
+	MaxNAKSize = nakSignedLen + 72
+)
+
+// NetworkAccessKey is a public key registered on the block chain with
+// OP_REGISTERNAK to identify its holder to the ciphrtxt network.  The key is
+// valid from NotBefore until NotAfter and is self-signed to prove the
+// registrant holds the matching private key.
+//
+// The serialized form is:
+//
+//   version (1 byte) || compressed pubkey (33 bytes) ||
+//   not before (4 bytes) || not after (4 bytes) || DER signature
+//
+// where the times are big endian unix timestamps and the signature commits to
+// the double sha256 hash of all of the fields preceding it.
+type NetworkAccessKey struct {
+	PubKey    *btcec.PublicKey
+	NotBefore time.Time
+	NotAfter  time.Time
+	Signature *btcec.Signature
+}
+
+// NewNAK returns a network access key for the public key of the passed private
+// key, valid over the passed period, signed with the private key.
+func NewNAK(privKey *btcec.PrivateKey, notBefore, notAfter time.Time) (*NetworkAccessKey, error) {
+	nak := &NetworkAccessKey{
+		PubKey:    privKey.PubKey(),
+		NotBefore: time.Unix(notBefore.Unix(), 0),
+		NotAfter:  time.Unix(notAfter.Unix(), 0),
+	}
+	sig, err := privKey.Sign(nak.SigHash())
+	if err != nil {
+		return nil, err
+	}
+	nak.Signature = sig
+	return nak, nil
+}
+
+// signedBytes returns the serialized portion of the key covered by its
+// signature.
+func (k *NetworkAccessKey) signedBytes() []byte {
+	b := make([]byte, nakSignedLen)
+	b[0] = NAKVersion
+	copy(b[1:34], k.PubKey.SerializeCompressed())
+	binary.BigEndian.PutUint32(b[34:38], uint32(k.NotBefore.Unix()))
+	binary.BigEndian.PutUint32(b[38:42], uint32(k.NotAfter.Unix()))
+	return b
+}
+
+// SigHash returns the hash committed to by the signature of the key.
+func (k *NetworkAccessKey) SigHash() []byte {
+	return chainhash.DoubleHashB(k.signedBytes())
+}
+
+// Verify returns whether the signature of the key is valid.
+func (k *NetworkAccessKey) Verify() bool {
+	return k.Signature != nil && k.Signature.Verify(k.SigHash(), k.PubKey)
+}
+
+// Serialize returns the serialized key as consumed by OP_REGISTERNAK.
+func (k *NetworkAccessKey) Serialize() []byte {
+	return append(k.signedBytes(), k.Signature.Serialize()...)
+}
+
+// ParseNAK parses a serialized network access key.  An error with the
+// ErrMalformedNAK code is returned when the key can not be parsed or its
+// validity period is empty.  The signature is parsed but not verified.
+func ParseNAK(b []byte) (*NetworkAccessKey, error) {
+	if len(b) <= nakSignedLen || len(b) > MaxNAKSize {
+		str := fmt.Sprintf("network access key length %d is outside "+
+			"of the valid range (%d, %d]", len(b), nakSignedLen,
+			MaxNAKSize)
+		return nil, scriptError(ErrMalformedNAK, str)
+	}
+	if b[0] != NAKVersion {
+		str := fmt.Sprintf("unsupported network access key version %d",
+			b[0])
+		return nil, scriptError(ErrMalformedNAK, str)
+	}
+
+	pkBytes := b[1:34]
+	if !btcec.IsCompressedPubKey(pkBytes) {
+		return nil, scriptError(ErrMalformedNAK, "network access key "+
+			"public key is not compressed")
+	}
+	pubKey, err := btcec.ParsePubKey(pkBytes, btcec.S256())
+	if err != nil {
+		str := fmt.Sprintf("invalid network access key public key: %v",
+			err)
+		return nil, scriptError(ErrMalformedNAK, str)
+	}
+
+	notBefore := binary.BigEndian.Uint32(b[34:38])
+	notAfter := binary.BigEndian.Uint32(b[38:42])
+	if notAfter <= notBefore {
+		str := fmt.Sprintf("network access key not after time %d is "+
+			"not after its not before time %d", notAfter, notBefore)
+		return nil, scriptError(ErrMalformedNAK, str)
+	}
+
+	sig, err := btcec.ParseDERSignature(b[nakSignedLen:], btcec.S256())
+	if err != nil {
+		str := fmt.Sprintf("invalid network access key signature: %v",
+			err)
+		return nil, scriptError(ErrMalformedNAK, str)
+	}
+
+	return &NetworkAccessKey{
+		PubKey:    pubKey,
+		NotBefore: time.Unix(int64(notBefore), 0),
+		NotAfter:  time.Unix(int64(notAfter), 0),
+		Signature: sig,
+	}, nil
+}
+
+// ExtractNAKRegistration returns the network access key registered by the
+// passed public key script.  A script registers a network access key when it
+// begins with a data push of the serialized key followed by OP_REGISTERNAK and
+// OP_DROP, which removes the key left on the stack by OP_REGISTERNAK, typically
+// followed by the conditions to spend the output:
+//
+//   <nak> OP_REGISTERNAK OP_DROP [spend conditions]
+//
+// Nil is returned when the script does not register a network access key.  An
+// error is returned when the script has the form of a registration but the key
+// is malformed or its signature does not verify, in which case the output
+// could never be spent.
+func ExtractNAKRegistration(pkScript []byte) (*NetworkAccessKey, error) {
+	pops, err := parseScript(pkScript)
+	if err != nil || len(pops) < 3 || pops[1].opcode.value != OP_REGISTERNAK ||
+		pops[2].opcode.value != OP_DROP {
+
+		return nil, nil
+	}
+	if !isPushOnly(pops[:1]) {
+		return nil, nil
+	}
+
+	nak, err := ParseNAK(pops[0].data)
+	if err != nil {
+		return nil, err
+	}
+	if !nak.Verify() {
+		return nil, scriptError(ErrNAKSignature,
+			"network access key signature verification failed")
+	}
+	return nak, nil
+}
@@ -0,0 +1,305 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jadeblaquiere/cttd/btcec"
+	"github.com/jadeblaquiere/cttd/chaincfg"
+	"github.com/jadeblaquiere/cttd/wire"
+	"github.com/jadeblaquiere/cttutil"
+)
+
+// testNAK returns a network access key signed by a deterministic private key
+// along with the private key.
+func testNAK(t *testing.T) (*NetworkAccessKey, *btcec.PrivateKey) {
+	privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat(
+		[]byte{0x42}, 32))
+	nak, err := NewNAK(privKey, time.Unix(1500000000, 0),
+		time.Unix(1600000000, 0))
+	if err != nil {
+		t.Fatalf("NewNAK: unexpected error %v", err)
+	}
+	return nak, privKey
+}
+
+// TestNAKSerialization ensures network access keys round trip through their
+// serialized form and malformed keys are rejected.
+func TestNAKSerialization(t *testing.T) {
+	nak, _ := testNAK(t)
+	serialized := nak.Serialize()
+
+	got, err := ParseNAK(serialized)
+	if err != nil {
+		t.Fatalf("ParseNAK: unexpected error %v", err)
+	}
+	if !got.PubKey.IsEqual(nak.PubKey) ||
+		!got.NotBefore.Equal(nak.NotBefore) ||
+		!got.NotAfter.Equal(nak.NotAfter) ||
+		!bytes.Equal(got.Serialize(), serialized) {
+
+		t.Fatalf("ParseNAK: mismatched key - got %x, want %x",
+			got.Serialize(), serialized)
+	}
+	if !got.Verify() {
+		t.Fatal("Verify: valid signature failed to verify")
+	}
+
+	munge := func(f func(b []byte) []byte) []byte {
+		b := make([]byte, len(serialized))
+		copy(b, serialized)
+		return f(b)
+	}
+	tests := []struct {
+		name string
+		nak  []byte
+	}{
+		{"empty", nil},
+		{"no signature", serialized[:nakSignedLen]},
+		{"too long", append(munge(func(b []byte) []byte { return b }),
+			make([]byte, MaxNAKSize)...)},
+		{"bad version", munge(func(b []byte) []byte {
+			b[0] = NAKVersion + 1
+			return b
+		})},
+		{"uncompressed pubkey prefix", munge(func(b []byte) []byte {
+			b[1] = 0x04
+			return b
+		})},
+		{"pubkey not on curve", munge(func(b []byte) []byte {
+			for i := 2; i < 34; i++ {
+				b[i] = 0xff
+			}
+			return b
+		})},
+		{"empty validity period", munge(func(b []byte) []byte {
+			copy(b[38:42], b[34:38])
+			return b
+		})},
+		{"truncated signature", serialized[:len(serialized)-1]},
+	}
+	for _, test := range tests {
+		_, err := ParseNAK(test.nak)
+		if !IsErrorCode(err, ErrMalformedNAK) {
+			t.Errorf("%s: wrong error - got %v, want %v", test.name,
+				err, ErrMalformedNAK)
+		}
+	}
+}
+
+// TestOpcodeRegisterNAK ensures OP_REGISTERNAK accepts a validly signed network
+// access key, leaving it on the stack, and fails on malformed or incorrectly signed keys when the
+// ciphrtxt extended opcodes are enabled and otherwise behaves as OP_NOP7.
+func TestOpcodeRegisterNAK(t *testing.T) {
+	nak, _ := testNAK(t)
+	otherKey, _ := btcec.NewPrivateKey(btcec.S256())
+
+	// A key signed by a different private key.
+	forged := *nak
+	sig, err := otherKey.Sign(forged.SigHash())
+	if err != nil {
+		t.Fatalf("Sign: unexpected error %v", err)
+	}
+	forged.Signature = sig
+
+	// A key with a validity period other than the one signed.
+	extended := *nak
+	extended.NotAfter = nak.NotAfter.Add(time.Hour)
+
+	const (
+		ctFlags        = ScriptVerifyCTExtended
+		discourageNops = ScriptDiscourageUpgradableNops
+	)
+	tests := []struct {
+		name  string
+		flags ScriptFlags
+		stack [][]byte
+		depth int
+		err   ErrorCode
+	}{
+		{"valid", ctFlags, [][]byte{nak.Serialize()}, 1, -1},
+		{"empty stack", ctFlags, nil, 0, ErrInvalidStackOperation},
+		{"malformed", ctFlags, [][]byte{{0x01, 0x02, 0x03}}, 0,
+			ErrMalformedNAK},
+		{"forged signature", ctFlags, [][]byte{forged.Serialize()}, 0,
+			ErrNAKSignature},
+		{"modified validity", ctFlags, [][]byte{extended.Serialize()},
+			0, ErrNAKSignature},
+		{"not enabled", 0, [][]byte{{0x01, 0x02, 0x03}}, 1, -1},
+		{"not enabled discourage nops", discourageNops,
+			[][]byte{nak.Serialize()}, 1, ErrDiscourageUpgradableNOPs},
+	}
+
+	op := &parsedOpcode{opcode: &opcodeArray[OP_REGISTERNAK]}
+	for _, test := range tests {
+		vm := &Engine{flags: test.flags}
+		for _, item := range test.stack {
+			vm.dstack.PushByteArray(item)
+		}
+
+		err := opcodeRegisterNAK(op, vm)
+		if test.err < 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", test.name,
+					err)
+			}
+			if int(vm.dstack.Depth()) != test.depth {
+				t.Errorf("%s: wrong stack depth - got %d, want %d",
+					test.name, vm.dstack.Depth(), test.depth)
+			}
+			continue
+		}
+		if !IsErrorCode(err, test.err) {
+			t.Errorf("%s: wrong error - got %v, want %v",
+				test.name, err, test.err)
+		}
+	}
+}
+
+// TestExtractNAKRegistration ensures network access keys are extracted from
+// public key scripts which register them and invalid registrations are
+// rejected.
+func TestExtractNAKRegistration(t *testing.T) {
+	nak, _ := testNAK(t)
+	unsigned := *nak
+	unsigned.NotAfter = nak.NotAfter.Add(time.Hour)
+
+	script := func(nak []byte, tail ...byte) []byte {
+		s, err := NewScriptBuilder().AddData(nak).
+			AddOp(OP_REGISTERNAK).AddOp(OP_DROP).Script()
+		if err != nil {
+			t.Fatalf("Script: unexpected error %v", err)
+		}
+		return append(s, tail...)
+	}
+	pushed := script(nak.Serialize())
+	pushed = pushed[:len(pushed)-2]
+	notDropped := append(pushed, OP_REGISTERNAK, OP_TRUE)
+
+	tests := []struct {
+		name   string
+		script []byte
+		want   bool
+		err    ErrorCode
+	}{
+		{"registration", script(nak.Serialize(), OP_TRUE), true, -1},
+		{"no spend conditions", script(nak.Serialize()), true, -1},
+		{"empty", nil, false, -1},
+		{"not push only", []byte{OP_TRUE, OP_DUP, OP_REGISTERNAK,
+			OP_DROP}, false, -1},
+		{"missing opcode", pushed, false, -1},
+		{"missing drop", notDropped, false, -1},
+		{"malformed", script([]byte{0x01, 0x02, 0x03}, OP_TRUE), false,
+			ErrMalformedNAK},
+		{"bad signature", script(unsigned.Serialize(), OP_TRUE), false,
+			ErrNAKSignature},
+	}
+
+	for _, test := range tests {
+		got, err := ExtractNAKRegistration(test.script)
+		if test.err >= 0 {
+			if !IsErrorCode(err, test.err) {
+				t.Errorf("%s: wrong error - got %v, want %v",
+					test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if (got != nil) != test.want {
+			t.Errorf("%s: unexpected registration %v", test.name,
+				got)
+			continue
+		}
+		if got != nil && !bytes.Equal(got.Serialize(), nak.Serialize()) {
+			t.Errorf("%s: mismatched key - got %x, want %x",
+				test.name, got.Serialize(), nak.Serialize())
+		}
+	}
+}
+
+// checkRegistrationSpend ensures an input spending the passed registration
+// script can be signed with the passed key and the resulting signature script
+// executes successfully with the ciphrtxt extended opcodes enabled.
+func checkRegistrationSpend(t *testing.T, pkScript []byte, key *btcec.PrivateKey) {
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(&wire.TxIn{})
+	tx.AddTxOut(wire.NewTxOut(1, nil))
+
+	kdb := KeyClosure(func(addr btcutil.Address) (*btcec.PrivateKey, bool, error) {
+		return key, true, nil
+	})
+	sigScript, err := SignTxOutput(&chaincfg.MainNetParams, tx, 0,
+		pkScript, SigHashAll, kdb, nil, nil)
+	if err != nil {
+		t.Fatalf("SignTxOutput: unexpected error %v", err)
+	}
+	tx.TxIn[0].SignatureScript = sigScript
+
+	flags := ScriptBip16 | ScriptVerifyDERSignatures |
+		ScriptVerifyCleanStack | ScriptVerifyCTExtended
+	vm, err := NewEngine(pkScript, tx, 0, flags, nil, nil, 1)
+	if err != nil {
+		t.Fatalf("NewEngine: unexpected error %v", err)
+	}
+	if err := vm.Execute(); err != nil {
+		t.Fatalf("Execute: unexpected error %v", err)
+	}
+}
+
+// TestNAKRegistrationScript ensures NAKRegistrationScript creates standard
+// scripts registering the passed key which can be spent by the payee and
+// rejects incorrectly signed keys and unsupported addresses.
+func TestNAKRegistrationScript(t *testing.T) {
+	nak, privKey := testNAK(t)
+	pubKeyHash := btcutil.Hash160(privKey.PubKey().SerializeCompressed())
+	addr, err := btcutil.NewAddressPubKeyHash(pubKeyHash,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error %v", err)
+	}
+
+	script, err := NAKRegistrationScript(nak, addr)
+	if err != nil {
+		t.Fatalf("NAKRegistrationScript: unexpected error %v", err)
+	}
+	if class := GetScriptClass(script); class != NAKRegistrationTy {
+		t.Fatalf("GetScriptClass: wrong class - got %v, want %v",
+			class, NAKRegistrationTy)
+	}
+	got, err := ExtractNAKRegistration(script)
+	if err != nil {
+		t.Fatalf("ExtractNAKRegistration: unexpected error %v", err)
+	}
+	if got == nil || !bytes.Equal(got.Serialize(), nak.Serialize()) {
+		t.Fatalf("ExtractNAKRegistration: mismatched key - got %v, "+
+			"want %v", got, nak)
+	}
+	checkRegistrationSpend(t, script, privKey)
+
+	unsigned := *nak
+	unsigned.NotAfter = nak.NotAfter.Add(time.Hour)
+	_, err = NAKRegistrationScript(&unsigned, addr)
+	if !IsErrorCode(err, ErrNAKSignature) {
+		t.Fatalf("NAKRegistrationScript: wrong error for unsigned key "+
+			"- got %v, want %v", err, ErrNAKSignature)
+	}
+
+	p2sh, err := btcutil.NewAddressScriptHashFromHash(pubKeyHash,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressScriptHashFromHash: unexpected error %v", err)
+	}
+	_, err = NAKRegistrationScript(nak, p2sh)
+	if !IsErrorCode(err, ErrUnsupportedAddress) {
+		t.Fatalf("NAKRegistrationScript: wrong error for script hash "+
+			"address - got %v, want %v", err, ErrUnsupportedAddress)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"fmt"
+
+	"github.com/jadeblaquiere/cttd/btcec"
+)
+
+// MaxNameLen is the maximum length in bytes of a name registered with
+// OP_REGISTERNAME.
+const MaxNameLen = 64
+
+// CheckName returns an error with the ErrInvalidName code if the passed name
+// can not be registered.  Valid names are 1 to MaxNameLen bytes of lowercase
+// ASCII letters, digits, hyphens and dots which neither begin nor end with a
+// hyphen or dot.
+func CheckName(name []byte) error {
+	if len(name) == 0 || len(name) > MaxNameLen {
+		str := fmt.Sprintf("name length %d is outside of the valid "+
+			"range [1, %d]", len(name), MaxNameLen)
+		return scriptError(ErrInvalidName, str)
+	}
+
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			continue
+		case (c == '-' || c == '.') && i != 0 && i != len(name)-1:
+			continue
+		}
+		str := fmt.Sprintf("name %q contains invalid character %q at "+
+			"position %d", name, c, i)
+		return scriptError(ErrInvalidName, str)
+	}
+	return nil
+}
+
+// checkNameOwner returns an error with the ErrInvalidNameOwner code if the
+// passed key is not a valid compressed public key.
+func checkNameOwner(owner []byte) error {
+	if !btcec.IsCompressedPubKey(owner) {
+		return scriptError(ErrInvalidNameOwner, "name owner is not a "+
+			"compressed public key")
+	}
+	if _, err := btcec.ParsePubKey(owner, btcec.S256()); err != nil {
+		str := fmt.Sprintf("invalid name owner public key: %v", err)
+		return scriptError(ErrInvalidNameOwner, str)
+	}
+	return nil
+}
+
+// ExtractNameRegistration returns the name and owner public key registered by
+// the passed public key script.  A script registers a name when it begins
+// with data pushes of the name and the compressed owner public key followed
+// by OP_REGISTERNAME and OP_2DROP, which removes the operands left on the stack
+// by OP_REGISTERNAME, typically followed by the conditions to spend the output:
+//
+//   <name> <owner pubkey> OP_REGISTERNAME OP_2DROP [spend conditions]
+//
+// Nil values are returned when the script does not register a name.  An error
+// is returned when the script has the form of a registration but the name or
+// owner key is invalid, in which case the output could never be spent.
+func ExtractNameRegistration(pkScript []byte) ([]byte, []byte, error) {
+	pops, err := parseScript(pkScript)
+	if err != nil || len(pops) < 4 || pops[2].opcode.value != OP_REGISTERNAME ||
+		pops[3].opcode.value != OP_2DROP {
+
+		return nil, nil, nil
+	}
+	if !isPushOnly(pops[:2]) {
+		return nil, nil, nil
+	}
+
+	name, owner := pops[0].data, pops[1].data
+	if err := CheckName(name); err != nil {
+		return nil, nil, err
+	}
+	if err := checkNameOwner(owner); err != nil {
+		return nil, nil, err
+	}
+	return name, owner, nil
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jadeblaquiere/cttd/btcec"
+	"github.com/jadeblaquiere/cttd/chaincfg"
+	"github.com/jadeblaquiere/cttutil"
+)
+
+// testNameOwner returns a compressed public key to register names to.
+func testNameOwner() []byte {
+	_, pubKey := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat(
+		[]byte{0x43}, 32))
+	return pubKey.SerializeCompressed()
+}
+
+// TestCheckName ensures only valid names are accepted for registration.
+func TestCheckName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"alice", true},
+		{"a", true},
+		{"alice-bob.example9", true},
+		{strings.Repeat("a", MaxNameLen), true},
+		{"", false},
+		{strings.Repeat("a", MaxNameLen+1), false},
+		{"Alice", false},
+		{"alice bob", false},
+		{"alice_bob", false},
+		{"-alice", false},
+		{"alice.", false},
+		{".", false},
+		{"caf\xc3\xa9", false},
+	}
+
+	for _, test := range tests {
+		err := CheckName([]byte(test.name))
+		if test.valid && err != nil {
+			t.Errorf("%q: unexpected error %v", test.name, err)
+			continue
+		}
+		if !test.valid && !IsErrorCode(err, ErrInvalidName) {
+			t.Errorf("%q: wrong error - got %v, want %v", test.name,
+				err, ErrInvalidName)
+		}
+	}
+}
+
+// TestExtractNameRegistration ensures name registrations are extracted from
+// public key scripts which register names and invalid registrations are
+// rejected.
+func TestExtractNameRegistration(t *testing.T) {
+	owner := testNameOwner()
+	uncompressed, _ := btcec.ParsePubKey(owner, btcec.S256())
+
+	script := func(name, owner []byte, tail ...byte) []byte {
+		s, err := NewScriptBuilder().AddData(name).AddData(owner).
+			AddOp(OP_REGISTERNAME).AddOp(OP_2DROP).Script()
+		if err != nil {
+			t.Fatalf("Script: unexpected error %v", err)
+		}
+		return append(s, tail...)
+	}
+
+	p2pk, err := payToPubKeyScript(owner)
+	if err != nil {
+		t.Fatalf("payToPubKeyScript: unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		script   []byte
+		wantName []byte
+		err      ErrorCode
+	}{
+		{"registration", script([]byte("alice"), owner, OP_TRUE),
+			[]byte("alice"), -1},
+		{"no spend conditions", script([]byte("alice"), owner),
+			[]byte("alice"), -1},
+		{"pay to pubkey", p2pk, nil, -1},
+		{"empty", nil, nil, -1},
+		{"not push only", []byte{OP_TRUE, OP_DUP, OP_REGISTERNAME,
+			OP_2DROP}, nil, -1},
+		{"missing drop", append(script([]byte("alice"), owner)[:len(
+			script([]byte("alice"), owner))-1], OP_TRUE), nil, -1},
+		{"invalid name", script([]byte("Alice"), owner, OP_TRUE), nil,
+			ErrInvalidName},
+		{"uncompressed owner", script([]byte("alice"),
+			uncompressed.SerializeUncompressed(), OP_TRUE), nil,
+			ErrInvalidNameOwner},
+		{"owner not on curve", script([]byte("alice"),
+			append([]byte{0x02}, bytes.Repeat([]byte{0xff}, 32)...),
+			OP_TRUE), nil, ErrInvalidNameOwner},
+	}
+
+	for _, test := range tests {
+		name, gotOwner, err := ExtractNameRegistration(test.script)
+		if test.err >= 0 {
+			if !IsErrorCode(err, test.err) {
+				t.Errorf("%s: wrong error - got %v, want %v",
+					test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if !bytes.Equal(name, test.wantName) {
+			t.Errorf("%s: mismatched name - got %q, want %q",
+				test.name, name, test.wantName)
+		}
+		if name != nil && !bytes.Equal(gotOwner, owner) {
+			t.Errorf("%s: mismatched owner - got %x, want %x",
+				test.name, gotOwner, owner)
+		}
+	}
+}
+
+// TestOpcodeRegisterName ensures OP_REGISTERNAME accepts a valid name and owner
+// key, leaving them on the stack, and fails on invalid ones when the ciphrtxt extended opcodes are
+// enabled and otherwise behaves as OP_NOP8.
+func TestOpcodeRegisterName(t *testing.T) {
+	owner := testNameOwner()
+
+	const (
+		ctFlags        = ScriptVerifyCTExtended
+		discourageNops = ScriptDiscourageUpgradableNops
+	)
+	tests := []struct {
+		name  string
+		flags ScriptFlags
+		stack [][]byte
+		depth int
+		err   ErrorCode
+	}{
+		{"valid", ctFlags, [][]byte{[]byte("alice"), owner}, 2, -1},
+		{"empty stack", ctFlags, nil, 0, ErrInvalidStackOperation},
+		{"missing name", ctFlags, [][]byte{owner}, 0,
+			ErrInvalidStackOperation},
+		{"invalid name", ctFlags, [][]byte{[]byte("Alice"), owner}, 0,
+			ErrInvalidName},
+		{"invalid owner", ctFlags, [][]byte{[]byte("alice"), owner[1:]},
+			0, ErrInvalidNameOwner},
+		{"not enabled", 0, [][]byte{[]byte("Alice"), owner}, 2, -1},
+		{"not enabled discourage nops", discourageNops,
+			[][]byte{[]byte("alice"), owner}, 2,
+			ErrDiscourageUpgradableNOPs},
+	}
+
+	op := &parsedOpcode{opcode: &opcodeArray[OP_REGISTERNAME]}
+	for _, test := range tests {
+		vm := &Engine{flags: test.flags}
+		for _, item := range test.stack {
+			vm.dstack.PushByteArray(item)
+		}
+
+		err := opcodeRegisterName(op, vm)
+		if test.err < 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", test.name,
+					err)
+			}
+			if int(vm.dstack.Depth()) != test.depth {
+				t.Errorf("%s: wrong stack depth - got %d, want %d",
+					test.name, vm.dstack.Depth(), test.depth)
+			}
+			continue
+		}
+		if !IsErrorCode(err, test.err) {
+			t.Errorf("%s: wrong error - got %v, want %v",
+				test.name, err, test.err)
+		}
+	}
+}
+
+// TestNameRegistrationScript ensures NameRegistrationScript creates standard
+// scripts registering the passed name which can be spent by the payee and
+// rejects invalid names.
+func TestNameRegistrationScript(t *testing.T) {
+	privKey, pubKey := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat(
+		[]byte{0x43}, 32))
+	addr, err := btcutil.NewAddressPubKey(pubKey.SerializeCompressed(),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKey: unexpected error %v", err)
+	}
+
+	script, err := NameRegistrationScript([]byte("alice"), pubKey, addr)
+	if err != nil {
+		t.Fatalf("NameRegistrationScript: unexpected error %v", err)
+	}
+	if class := GetScriptClass(script); class != NameRegistrationTy {
+		t.Fatalf("GetScriptClass: wrong class - got %v, want %v",
+			class, NameRegistrationTy)
+	}
+	name, owner, err := ExtractNameRegistration(script)
+	if err != nil {
+		t.Fatalf("ExtractNameRegistration: unexpected error %v", err)
+	}
+	if string(name) != "alice" || !bytes.Equal(owner, testNameOwner()) {
+		t.Fatalf("ExtractNameRegistration: mismatched registration - "+
+			"got %q owned by %x", name, owner)
+	}
+	checkRegistrationSpend(t, script, privKey)
+
+	_, err = NameRegistrationScript([]byte("Alice"), pubKey, addr)
+	if !IsErrorCode(err, ErrInvalidName) {
+		t.Fatalf("NameRegistrationScript: wrong error for invalid "+
+			"name - got %v, want %v", err, ErrInvalidName)
+	}
+}
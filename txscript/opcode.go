@@ -221,9 +221,9 @@ const (
 	OP_NOP5                = 0xb4 // 180
 	OP_NOP6                = 0xb5 // 181
 	OP_NOP7                = 0xb6 // 182
-	OP_REGISTERNAK         = 0xb6 // 180 - AKA OP_NOP7
+	OP_REGISTERNAK         = 0xb6 // 182 - AKA OP_NOP7
 	OP_NOP8                = 0xb7 // 183
-	OP_REGISTERNAME        = 0xb7 // 180 - AKA OP_NOP8
+	OP_REGISTERNAME        = 0xb7 // 183 - AKA OP_NOP8
 	OP_NOP9                = 0xb8 // 184
 	OP_NOP10               = 0xb9 // 185
 	OP_UNKNOWN186          = 0xba // 186
@@ -421,6 +421,8 @@ var opcodeArray = [256]opcode{
 	OP_RETURN:              {OP_RETURN, "OP_RETURN", 1, opcodeReturn},
 	OP_CHECKLOCKTIMEVERIFY: {OP_CHECKLOCKTIMEVERIFY, "OP_CHECKLOCKTIMEVERIFY", 1, opcodeCheckLockTimeVerify},
 	OP_CHECKSEQUENCEVERIFY: {OP_CHECKSEQUENCEVERIFY, "OP_CHECKSEQUENCEVERIFY", 1, opcodeCheckSequenceVerify},
+	OP_REGISTERNAK:         {OP_REGISTERNAK, "OP_REGISTERNAK", 1, opcodeRegisterNAK},
+	OP_REGISTERNAME:        {OP_REGISTERNAME, "OP_REGISTERNAME", 1, opcodeRegisterName},
 
 	// Stack opcodes.
 	OP_TOALTSTACK:   {OP_TOALTSTACK, "OP_TOALTSTACK", 1, opcodeToAltStack},
@@ -506,8 +508,6 @@ var opcodeArray = [256]opcode{
 	OP_NOP4:  {OP_NOP4, "OP_NOP4", 1, opcodeNop},
 	OP_NOP5:  {OP_NOP5, "OP_NOP5", 1, opcodeNop},
 	OP_NOP6:  {OP_NOP6, "OP_NOP6", 1, opcodeNop},
-	OP_NOP7:  {OP_NOP7, "OP_NOP7", 1, opcodeNop},
-	OP_NOP8:  {OP_NOP8, "OP_NOP8", 1, opcodeNop},
 	OP_NOP9:  {OP_NOP9, "OP_NOP9", 1, opcodeNop},
 	OP_NOP10: {OP_NOP10, "OP_NOP10", 1, opcodeNop},
 
@@ -910,7 +910,7 @@ func opcodeN(op *parsedOpcode, vm *Engine) error {
 func opcodeNop(op *parsedOpcode, vm *Engine) error {
 	switch op.opcode.value {
 	case OP_NOP1, OP_NOP4, OP_NOP5,
-		OP_NOP6, OP_NOP7, OP_NOP8, OP_NOP9, OP_NOP10:
+		OP_NOP6, OP_NOP9, OP_NOP10:
 		if vm.hasFlag(ScriptDiscourageUpgradableNops) {
 			str := fmt.Sprintf("OP_NOP%d reserved for soft-fork "+
 				"upgrades", op.opcode.value-(OP_NOP1-1))
@@ -1101,17 +1101,78 @@ func opcodeReturn(op *parsedOpcode, vm *Engine) error {
 }
 
 // opcodeRegisterNAK registers a Network Access Key with the blockchain.
-// the NAK includes notBefore and notAfter times for which it is valid
+// The NAK includes notBefore and notAfter times for which it is valid and is
+// signed by its own key.  The script fails if the NAK is malformed or its
+// signature does not verify.  See NetworkAccessKey for the serialized format.
+//
+// Like OP_CHECKLOCKTIMEVERIFY, the opcode only verifies its operand and leaves
+// it on the stack, so scripts which were valid while it was OP_NOP7 can only
+// become invalid, which keeps its activation a soft fork.  Scripts drop the key
+// themselves.
+//
+// Stack transformation: [... nak] -> [... nak]
 func opcodeRegisterNAK(op *parsedOpcode, vm *Engine) error {
-	//TODO : Validate ECDSA signature on NAK
+	// If the ScriptVerifyCTExtended script flag is not set, treat opcode
+	// as OP_NOP7 instead.
+	if !vm.hasFlag(ScriptVerifyCTExtended) {
+		if vm.hasFlag(ScriptDiscourageUpgradableNops) {
+			return scriptError(ErrDiscourageUpgradableNOPs,
+				"OP_NOP7 reserved for soft-fork upgrades")
+		}
+		return nil
+	}
+
+	nakBytes, err := vm.dstack.PeekByteArray(0)
+	if err != nil {
+		return err
+	}
+
+	nak, err := ParseNAK(nakBytes)
+	if err != nil {
+		return err
+	}
+	if !nak.Verify() {
+		return scriptError(ErrNAKSignature,
+			"network access key signature verification failed")
+	}
 	return nil
 }
 
-// opcodeRegisterName registers a name on the blockchain.
+// opcodeRegisterName registers a name on the blockchain to an owner public
+// key.  The script fails if the name is not valid as defined by CheckName or
+// the owner is not a compressed public key.  Ensuring the name is not already
+// registered to a different key requires the state of the block chain, so it
+// is enforced when the registering output is added to a block rather than by
+// the script engine.
+//
+// Like OP_REGISTERNAK, the opcode only verifies its operands and leaves them on
+// the stack so its activation is a soft fork.  Scripts drop them themselves.
+//
+// Stack transformation: [... name owner] -> [... name owner]
 func opcodeRegisterName(op *parsedOpcode, vm *Engine) error {
-	//TODO : Validate Name is not currently registered to a different
-	//signature key
-	return nil
+	// If the ScriptVerifyCTExtended script flag is not set, treat opcode
+	// as OP_NOP8 instead.
+	if !vm.hasFlag(ScriptVerifyCTExtended) {
+		if vm.hasFlag(ScriptDiscourageUpgradableNops) {
+			return scriptError(ErrDiscourageUpgradableNOPs,
+				"OP_NOP8 reserved for soft-fork upgrades")
+		}
+		return nil
+	}
+
+	owner, err := vm.dstack.PeekByteArray(0)
+	if err != nil {
+		return err
+	}
+	name, err := vm.dstack.PeekByteArray(1)
+	if err != nil {
+		return err
+	}
+
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	return checkNameOwner(owner)
 }
 
 // verifyLockTime is a helper function used to validate locktimes.
@@ -2450,9 +2511,10 @@ var OpcodeByName = make(map[string]byte)
 
 func init() {
 	// Initialize the opcode name to value map using the contents of the
-	// opcode array.  Also add entries for "OP_FALSE", "OP_TRUE", and
-	// "OP_NOP2" since they are aliases for "OP_0", "OP_1",
-	// and "OP_CHECKLOCKTIMEVERIFY" respectively.
+	// opcode array.  Also add entries for "OP_FALSE", "OP_TRUE", "OP_NOP2",
+	// "OP_NOP3", "OP_NOP7" and "OP_NOP8" since they are aliases for "OP_0",
+	// "OP_1", "OP_CHECKLOCKTIMEVERIFY", "OP_CHECKSEQUENCEVERIFY",
+	// "OP_REGISTERNAK" and "OP_REGISTERNAME" respectively.
 	for _, op := range opcodeArray {
 		OpcodeByName[op.name] = op.value
 	}
@@ -2460,21 +2522,6 @@ func init() {
 	OpcodeByName["OP_TRUE"] = OP_TRUE
 	OpcodeByName["OP_NOP2"] = OP_CHECKLOCKTIMEVERIFY
 	OpcodeByName["OP_NOP3"] = OP_CHECKSEQUENCEVERIFY
-}
-
-func EnableCTExtendedOpcodes() {
-	for _, op := range opcodeArray {
-		switch op.value {
-		case OP_REGISTERNAK:
-			op.name = "OP_REGISTERNAK"
-			op.length = 1
-			op.opfunc = opcodeRegisterNAK
-		case OP_REGISTERNAME:
-			op.name = "OP_REGISTERNAME"
-			op.length = 1
-			op.opfunc = opcodeRegisterName
-		}
-	}
-	OpcodeByName["OP_REGISTERNAK"] = OP_REGISTERNAK
-	OpcodeByName["OP_REGISTERNAME"] = OP_REGISTERNAME
+	OpcodeByName["OP_NOP7"] = OP_REGISTERNAK
+	OpcodeByName["OP_NOP8"] = OP_REGISTERNAME
 }
@@ -117,6 +117,12 @@ func TestOpcodeDisasm(t *testing.T) {
 			case 0xb2:
 				// OP_NOP3 is an alias of OP_CHECKSEQUENCEVERIFY
 				expectedStr = "OP_CHECKSEQUENCEVERIFY"
+			case 0xb6:
+				// OP_NOP7 is an alias of OP_REGISTERNAK
+				expectedStr = "OP_REGISTERNAK"
+			case 0xb7:
+				// OP_NOP8 is an alias of OP_REGISTERNAME
+				expectedStr = "OP_REGISTERNAME"
 			default:
 				val := byte(opcodeVal - (0xb0 - 1))
 				expectedStr = "OP_NOP" + strconv.Itoa(int(val))
@@ -183,6 +189,12 @@ func TestOpcodeDisasm(t *testing.T) {
 			case 0xb2:
 				// OP_NOP3 is an alias of OP_CHECKSEQUENCEVERIFY
 				expectedStr = "OP_CHECKSEQUENCEVERIFY"
+			case 0xb6:
+				// OP_NOP7 is an alias of OP_REGISTERNAK
+				expectedStr = "OP_REGISTERNAK"
+			case 0xb7:
+				// OP_NOP8 is an alias of OP_REGISTERNAME
+				expectedStr = "OP_REGISTERNAME"
 			default:
 				val := byte(opcodeVal - (0xb0 - 1))
 				expectedStr = "OP_NOP" + strconv.Itoa(int(val))
@@ -203,6 +203,28 @@ func sign(chainParams *chaincfg.Params, tx *wire.MsgTx, idx int,
 		script, _ := signMultiSig(tx, idx, subScript, hashType,
 			addresses, nrequired, kdb)
 		return script, class, addresses, nrequired, nil
+	case NAKRegistrationTy, NameRegistrationTy:
+		// The registration data is pushed by the script itself, so
+		// only the payment portion of the script needs to be signed.
+		// The signature still commits to the entire script.
+		key, compressed, err := kdb.GetKey(addresses[0])
+		if err != nil {
+			return nil, class, nil, 0, err
+		}
+
+		var script []byte
+		if _, ok := addresses[0].(*btcutil.AddressPubKey); ok {
+			script, err = p2pkSignatureScript(tx, idx, subScript,
+				hashType, key)
+		} else {
+			script, err = SignatureScript(tx, idx, subScript,
+				hashType, key, compressed)
+		}
+		if err != nil {
+			return nil, class, nil, 0, err
+		}
+
+		return script, class, addresses, nrequired, nil
 	case NullDataTy:
 		return nil, class, nil, 0,
 			errors.New("can't sign NULLDATA transactions")
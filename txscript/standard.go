@@ -7,6 +7,7 @@ package txscript
 import (
 	"fmt"
 
+	"github.com/jadeblaquiere/cttd/btcec"
 	"github.com/jadeblaquiere/cttd/chaincfg"
 	"github.com/jadeblaquiere/cttd/wire"
 	"github.com/jadeblaquiere/cttutil"
@@ -58,6 +59,8 @@ const (
 	WitnessV0ScriptHashTy                    // Pay to witness script hash.
 	MultiSigTy                               // Multi signature.
 	NullDataTy                               // Empty data-only (provably prunable).
+	NAKRegistrationTy                        // Network access key registration.
+	NameRegistrationTy                       // Name registration.
 )
 
 // scriptClassToName houses the human-readable strings which describe each
@@ -71,6 +74,8 @@ var scriptClassToName = []string{
 	WitnessV0ScriptHashTy: "witness_v0_scripthash",
 	MultiSigTy:            "multisig",
 	NullDataTy:            "nulldata",
+	NAKRegistrationTy:     "nakregistration",
+	NameRegistrationTy:    "nameregistration",
 }
 
 // String implements the Stringer interface by returning the name of
@@ -156,6 +161,60 @@ func isNullData(pops []parsedOpcode) bool {
 		len(pops[1].data) <= MaxDataCarrierSize
 }
 
+// isRegistrationPayment returns true if the passed script, which follows the
+// registration data of a registration script, is a standard way to pay the
+// output.  Only scripts which are satisfied by a single signature of the
+// registrant are allowed since the remainder of a registration script is never
+// evaluated as a pay-to-script-hash or witness program.
+func isRegistrationPayment(pops []parsedOpcode) bool {
+	return isPubkey(pops) || isPubkeyHash(pops)
+}
+
+// isNAKRegistration returns true if the passed script is a standard network
+// access key registration, false otherwise.  A standard registration is of
+// the form:
+//
+//   <nak> OP_REGISTERNAK OP_DROP
+//       <pay-to-pubkey or pay-to-pubkey-hash script>
+//
+// The key itself is not validated.
+func isNAKRegistration(pops []parsedOpcode) bool {
+	return len(pops) > 3 &&
+		pops[1].opcode.value == OP_REGISTERNAK &&
+		pops[2].opcode.value == OP_DROP &&
+		pops[0].opcode.value >= OP_DATA_1 &&
+		pops[0].opcode.value <= OP_PUSHDATA4 &&
+		len(pops[0].data) <= MaxNAKSize &&
+		isRegistrationPayment(pops[3:])
+}
+
+// isNameRegistration returns true if the passed script is a standard name
+// registration, false otherwise.  A standard registration is of the form:
+//
+//   <name> <owner pubkey> OP_REGISTERNAME OP_2DROP
+//       <pay-to-pubkey or pay-to-pubkey-hash script>
+//
+// The name and owner are not validated.
+func isNameRegistration(pops []parsedOpcode) bool {
+	return len(pops) > 4 &&
+		pops[2].opcode.value == OP_REGISTERNAME &&
+		pops[3].opcode.value == OP_2DROP &&
+		pops[0].opcode.value >= OP_DATA_1 &&
+		pops[0].opcode.value <= OP_PUSHDATA4 &&
+		len(pops[0].data) <= MaxNameLen &&
+		pops[1].opcode.value == OP_DATA_33 &&
+		isRegistrationPayment(pops[4:])
+}
+
+// registrationPayment returns the opcodes of the passed registration script
+// which pay the output.  The passed class MUST be the class of the script.
+func registrationPayment(pops []parsedOpcode, class ScriptClass) []parsedOpcode {
+	if class == NAKRegistrationTy {
+		return pops[3:]
+	}
+	return pops[4:]
+}
+
 // scriptType returns the type of the script being inspected from the known
 // standard types.
 func typeOfScript(pops []parsedOpcode) ScriptClass {
@@ -173,6 +232,10 @@ func typeOfScript(pops []parsedOpcode) ScriptClass {
 		return MultiSigTy
 	} else if isNullData(pops) {
 		return NullDataTy
+	} else if isNAKRegistration(pops) {
+		return NAKRegistrationTy
+	} else if isNameRegistration(pops) {
+		return NameRegistrationTy
 	}
 	return NonStandardTy
 }
@@ -222,6 +285,12 @@ func expectedInputs(pops []parsedOpcode, class ScriptClass) int {
 		// for the extra push that is required to compensate.
 		return asSmallInt(pops[0].opcode) + 1
 
+	case NAKRegistrationTy, NameRegistrationTy:
+		// The registration data is pushed by the script itself, so
+		// only the inputs of the payment script are required.
+		payment := registrationPayment(pops, class)
+		return expectedInputs(payment, typeOfScript(payment))
+
 	case NullDataTy:
 		fallthrough
 	default:
@@ -476,6 +545,58 @@ func NullDataScript(data []byte) ([]byte, error) {
 	return NewScriptBuilder().AddOp(OP_RETURN).AddData(data).Script()
 }
 
+// NAKRegistrationScript creates a standard script registering the passed
+// network access key which pays to the passed address.  The address must be a
+// pay-to-pubkey or pay-to-pubkey-hash address.  An Error with the error code
+// ErrNAKSignature will be returned if the key is not correctly signed.
+func NAKRegistrationScript(nak *NetworkAccessKey, addr btcutil.Address) ([]byte, error) {
+	if !nak.Verify() {
+		return nil, scriptError(ErrNAKSignature,
+			"network access key signature verification failed")
+	}
+	payment, err := registrationPaymentScript(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewScriptBuilder().AddData(nak.Serialize()).
+		AddOp(OP_REGISTERNAK).AddOp(OP_DROP).AddOps(payment).Script()
+}
+
+// NameRegistrationScript creates a standard script registering the passed name
+// to the passed owner public key which pays to the passed address.  The address
+// must be a pay-to-pubkey or pay-to-pubkey-hash address.  An Error with the
+// error code ErrInvalidName will be returned if the name can not be registered
+// as defined by CheckName.
+func NameRegistrationScript(name []byte, owner *btcec.PublicKey, addr btcutil.Address) ([]byte, error) {
+	if err := CheckName(name); err != nil {
+		return nil, err
+	}
+	payment, err := registrationPaymentScript(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewScriptBuilder().AddData(name).
+		AddData(owner.SerializeCompressed()).AddOp(OP_REGISTERNAME).
+		AddOp(OP_2DROP).AddOps(payment).Script()
+}
+
+// registrationPaymentScript returns the script which pays a registration
+// output to the passed address.  An Error with the error code
+// ErrUnsupportedAddress will be returned for addresses which can not be paid
+// by a standard registration script.
+func registrationPaymentScript(addr btcutil.Address) ([]byte, error) {
+	switch addr.(type) {
+	case *btcutil.AddressPubKeyHash, *btcutil.AddressPubKey:
+		return PayToAddrScript(addr)
+	}
+
+	str := fmt.Sprintf("unable to generate registration script for "+
+		"unsupported address type %T", addr)
+	return nil, scriptError(ErrUnsupportedAddress, str)
+}
+
 // MultiSigScript returns a valid script for a multisignature redemption where
 // nrequired of the keys in pubkeys are required to have signed the transaction
 // for success.  An Error with the error code ErrTooManyRequiredSigs will be
@@ -612,6 +733,21 @@ func ExtractPkScriptAddrs(pkScript []byte, chainParams *chaincfg.Params) (Script
 			}
 		}
 
+	case NAKRegistrationTy, NameRegistrationTy:
+		// A registration script is the registration data followed by a
+		// standard script which pays the output, so the addresses and
+		// required signatures are those of the payment script.
+		payment, err := unparseScript(registrationPayment(pops,
+			scriptClass))
+		if err != nil {
+			return NonStandardTy, nil, 0, err
+		}
+		_, addrs, requiredSigs, err = ExtractPkScriptAddrs(payment,
+			chainParams)
+		if err != nil {
+			return NonStandardTy, nil, 0, err
+		}
+
 	case NullDataTy:
 		// Null data transactions have no addresses or required
 		// signatures.
@@ -326,6 +326,31 @@ func TestExtractPkScriptAddrs(t *testing.T) {
 			reqSigs: 1,
 			class:   MultiSigTy,
 		},
+		{
+			name: "nak registration paying to pubkey hash",
+			script: hexToBytes("0401020304b67576a914ad06dd6ddee55cbc" +
+				"a9a9e3713bd7587509a3056488ac"),
+			addrs: []btcutil.Address{
+				newAddressPubKeyHash(hexToBytes("ad06dd6ddee5" +
+					"5cbca9a9e3713bd7587509a30564")),
+			},
+			reqSigs: 1,
+			class:   NAKRegistrationTy,
+		},
+		{
+			name: "name registration paying to pubkey",
+			script: hexToBytes("05616c6963652102192d74d0cb94344c9569" +
+				"c2e77901573d8d7903c3ebec3a957724895dca52c6b4b7" +
+				"6d2102192d74d0cb94344c9569c2e77901573d8d7903c3" +
+				"ebec3a957724895dca52c6b4ac"),
+			addrs: []btcutil.Address{
+				newAddressPubKey(hexToBytes("02192d74d0cb9434" +
+					"4c9569c2e77901573d8d7903c3ebec3a9577" +
+					"24895dca52c6b4")),
+			},
+			reqSigs: 1,
+			class:   NameRegistrationTy,
+		},
 		{
 			name:    "empty script",
 			script:  []byte{},
@@ -1031,6 +1056,56 @@ var scriptClassTests = []struct {
 		class: NonStandardTy,
 	},
 
+	// Registration script templates.
+	{
+		name: "nak registration",
+		script: "DATA_4 0x01020304 REGISTERNAK DROP DUP HASH160 DATA_20 0x660d4e" +
+			"f3a743e3e696ad990364e555c271ad504b EQUALVERIFY CHECKSIG",
+		class: NAKRegistrationTy,
+	},
+	{
+		name: "nak registration without drop",
+		script: "DATA_4 0x01020304 REGISTERNAK DUP HASH160 DATA_20 0x660d4ef3a743" +
+			"e3e696ad990364e555c271ad504b EQUALVERIFY CHECKSIG",
+		class: NonStandardTy,
+	},
+	{
+		name: "nak registration paying to script hash",
+		script: "DATA_4 0x01020304 REGISTERNAK DROP HASH160 DATA_20 0x433ec2ac1f" +
+			"fa1b7b7d027f564529c57197f9ae88 EQUAL",
+		class: NonStandardTy,
+	},
+	{
+		name: "name registration",
+		script: "DATA_5 0x616c696365 DATA_33 0x0232abdc893e7f0631364d7fd01" +
+			"cb33d24da45329a00357b3a7886211ab414d55a REGISTERNAME 2DROP " +
+			"DATA_33 0x0232abdc893e7f0631364d7fd01cb33d24da45329a00357b" +
+			"3a7886211ab414d55a CHECKSIG",
+		class: NameRegistrationTy,
+	},
+	{
+		name: "name registration without drop",
+		script: "DATA_5 0x616c696365 DATA_33 0x0232abdc893e7f0631364d7fd01" +
+			"cb33d24da45329a00357b3a7886211ab414d55a REGISTERNAME DATA_33 " +
+			"0x0232abdc893e7f0631364d7fd01cb33d24da45329a00357b3a78" +
+			"86211ab414d55a CHECKSIG",
+		class: NonStandardTy,
+	},
+	{
+		name: "name registration with uncompressed owner length",
+		script: "DATA_5 0x616c696365 DATA_32 0x32abdc893e7f0631364d7fd01cb" +
+			"33d24da45329a00357b3a7886211ab414d55a REGISTERNAME 2DROP " +
+			"DATA_33 0x0232abdc893e7f0631364d7fd01cb33d24da45329a00357b" +
+			"3a7886211ab414d55a CHECKSIG",
+		class: NonStandardTy,
+	},
+	{
+		name: "name registration without payment",
+		script: "DATA_5 0x616c696365 DATA_33 0x0232abdc893e7f0631364d7fd01" +
+			"cb33d24da45329a00357b3a7886211ab414d55a REGISTERNAME 2DROP TRUE",
+		class: NonStandardTy,
+	},
+
 	// New standard segwit script templates.
 	{
 		// A pay to witness pub key hash pk script.
@@ -1112,6 +1187,16 @@ func TestStringifyClass(t *testing.T) {
 			class:    NullDataTy,
 			stringed: "nulldata",
 		},
+		{
+			name:     "nakregistrationty",
+			class:    NAKRegistrationTy,
+			stringed: "nakregistration",
+		},
+		{
+			name:     "nameregistrationty",
+			class:    NameRegistrationTy,
+			stringed: "nameregistration",
+		},
 		{
 			name:     "broken",
 			class:    ScriptClass(255),
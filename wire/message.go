@@ -57,6 +57,10 @@ const (
 	CmdCFilter      = "cfilter"
 	CmdCFHeaders    = "cfheaders"
 	CmdCFCheckpt    = "cfcheckpt"
+	CmdCtMsg        = "ctmsg"
+	CmdGetCtDigests = "getctdigests"
+	CmdCtDigests    = "ctdigests"
+	CmdGetCtInv     = "getctinv"
 )
 
 // MessageEncoding represents the wire message encoding format to be used.
@@ -180,6 +184,18 @@ func makeEmptyMessage(command string) (Message, error) {
 	case CmdCFCheckpt:
 		msg = &MsgCFCheckpt{}
 
+	case CmdCtMsg:
+		msg = &MsgCtMsg{}
+
+	case CmdGetCtDigests:
+		msg = &MsgGetCtDigests{}
+
+	case CmdCtDigests:
+		msg = &MsgCtDigests{}
+
+	case CmdGetCtInv:
+		msg = &MsgGetCtInv{}
+
 	default:
 		return nil, fmt.Errorf("unhandled command [%s]", command)
 	}
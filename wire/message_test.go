@@ -76,6 +76,11 @@ func TestMessage(t *testing.T) {
 		[]byte("payload"))
 	msgCFHeaders := NewMsgCFHeaders()
 	msgCFCheckpt := NewMsgCFCheckpt(GCSFilterRegular, &chainhash.Hash{}, 0)
+	msgCtMsg := NewMsgCtMsg([]byte("payload"))
+	msgGetCtDigests := NewMsgGetCtDigests(0, 3600, 16)
+	msgCtDigests := NewMsgCtDigests(0, 3600)
+	msgCtDigests.AddDigest(&CtDigest{Count: 1})
+	msgGetCtInv := NewMsgGetCtInv(0, 3600)
 
 	tests := []struct {
 		in     Message    // Value to encode
@@ -111,6 +116,10 @@ func TestMessage(t *testing.T) {
 		{msgCFilter, msgCFilter, pver, MainNet, 65},
 		{msgCFHeaders, msgCFHeaders, pver, MainNet, 90},
 		{msgCFCheckpt, msgCFCheckpt, pver, MainNet, 58},
+		{msgCtMsg, msgCtMsg, pver, MainNet, 32},
+		{msgGetCtDigests, msgGetCtDigests, pver, MainNet, 44},
+		{msgCtDigests, msgCtDigests, pver, MainNet, 77},
+		{msgGetCtInv, msgGetCtInv, pver, MainNet, 40},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -0,0 +1,139 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jadeblaquiere/cttd/chaincfg/chainhash"
+)
+
+// MaxCtDigestsPerMsg is the maximum number of interval digests that can be in
+// a single ctdigests message.
+const MaxCtDigestsPerMsg = 256
+
+// ctDigestSize is the serialized size of a single CtDigest.
+const ctDigestSize = 4 + chainhash.HashSize
+
+// CtDigest summarizes the set of ciphrtxt message hashes stored for a single
+// time interval by the number of messages and a hash committing to the
+// sorted list of message hashes.
+type CtDigest struct {
+	Count  uint32
+	Digest chainhash.Hash
+}
+
+// MsgCtDigests implements the Message interface and represents a ciphrtxt
+// ctdigests message.  It is sent in response to a getctdigests
+// (MsgGetCtDigests) message and carries one digest for each of the requested
+// intervals, in order.
+type MsgCtDigests struct {
+	StartTime int64
+	EndTime   int64
+	Digests   []*CtDigest
+}
+
+// AddDigest adds a new interval digest to the message.
+func (msg *MsgCtDigests) AddDigest(digest *CtDigest) error {
+	if len(msg.Digests)+1 > MaxCtDigestsPerMsg {
+		str := fmt.Sprintf("too many digests in message [max %v]",
+			MaxCtDigestsPerMsg)
+		return messageError("MsgCtDigests.AddDigest", str)
+	}
+
+	msg.Digests = append(msg.Digests, digest)
+	return nil
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCtDigests) BtcDecode(r io.Reader, pver uint32, _ MessageEncoding) error {
+	err := readElements(r, &msg.StartTime, &msg.EndTime)
+	if err != nil {
+		return err
+	}
+
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+
+	// Limit to max digests per message.
+	if count > MaxCtDigestsPerMsg {
+		str := fmt.Sprintf("too many digests for message "+
+			"[count %v, max %v]", count, MaxCtDigestsPerMsg)
+		return messageError("MsgCtDigests.BtcDecode", str)
+	}
+
+	// Create a contiguous slice of digests to deserialize into in order to
+	// reduce the number of allocations.
+	digests := make([]CtDigest, count)
+	msg.Digests = make([]*CtDigest, 0, count)
+	for i := uint64(0); i < count; i++ {
+		d := &digests[i]
+		err := readElements(r, &d.Count, &d.Digest)
+		if err != nil {
+			return err
+		}
+		msg.AddDigest(d)
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCtDigests) BtcEncode(w io.Writer, pver uint32, _ MessageEncoding) error {
+	count := len(msg.Digests)
+	if count > MaxCtDigestsPerMsg {
+		str := fmt.Sprintf("too many digests for message "+
+			"[count %v, max %v]", count, MaxCtDigestsPerMsg)
+		return messageError("MsgCtDigests.BtcEncode", str)
+	}
+
+	err := writeElements(w, msg.StartTime, msg.EndTime)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	for _, d := range msg.Digests {
+		err := writeElements(w, d.Count, &d.Digest)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCtDigests) Command() string {
+	return CmdCtDigests
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCtDigests) MaxPayloadLength(pver uint32) uint32 {
+	// Start time 8 bytes + end time 8 bytes + num digests (varInt) +
+	// max allowed digests.
+	return 16 + MaxVarIntPayload + (MaxCtDigestsPerMsg * ctDigestSize)
+}
+
+// NewMsgCtDigests returns a new ciphrtxt ctdigests message that conforms to
+// the Message interface.  See MsgCtDigests for details.
+func NewMsgCtDigests(startTime, endTime int64) *MsgCtDigests {
+	return &MsgCtDigests{
+		StartTime: startTime,
+		EndTime:   endTime,
+		Digests:   make([]*CtDigest, 0, MaxCtDigestsPerMsg),
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+)
+
+// MaxCtMsgDataSize is the maximum byte size of a serialized ciphrtxt message
+// which may be relayed between peers.  The maximum size is currently defined
+// as 8MiB.
+const MaxCtMsgDataSize = 8 * 1024 * 1024
+
+// MsgCtMsg implements the Message interface and represents a ciphrtxt ctmsg
+// message.  It is used to deliver a serialized ciphrtxt message (header and
+// ciphertext) in response to a getdata (MsgGetData) message requesting an
+// inventory vector of type InvTypeCtMsg.
+//
+// The message is opaque to the wire package.  Parsing and validation are left
+// to the ciphrtxt message service.
+type MsgCtMsg struct {
+	Data []byte
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCtMsg) BtcDecode(r io.Reader, pver uint32, _ MessageEncoding) error {
+	var err error
+	msg.Data, err = ReadVarBytes(r, pver, MaxCtMsgDataSize, "ctmsg data")
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCtMsg) BtcEncode(w io.Writer, pver uint32, _ MessageEncoding) error {
+	size := len(msg.Data)
+	if size > MaxCtMsgDataSize {
+		str := fmt.Sprintf("ctmsg size too large for message "+
+			"[size %v, max %v]", size, MaxCtMsgDataSize)
+		return messageError("MsgCtMsg.BtcEncode", str)
+	}
+
+	return WriteVarBytes(w, pver, msg.Data)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCtMsg) Command() string {
+	return CmdCtMsg
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCtMsg) MaxPayloadLength(pver uint32) uint32 {
+	return uint32(VarIntSerializeSize(MaxCtMsgDataSize)) + MaxCtMsgDataSize
+}
+
+// NewMsgCtMsg returns a new ciphrtxt ctmsg message that conforms to the
+// Message interface.  See MsgCtMsg for details.
+func NewMsgCtMsg(data []byte) *MsgCtMsg {
+	return &MsgCtMsg{
+		Data: data,
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+)
+
+// MsgGetCtDigests implements the Message interface and represents a ciphrtxt
+// getctdigests message.  It is used to request digests of the message hashes
+// a peer stores for a time range.  The range [StartTime, EndTime), expressed
+// in seconds since the unix epoch, is split into NumIntervals equal intervals
+// and a digest for each interval is returned in a ctdigests (MsgCtDigests)
+// message.
+type MsgGetCtDigests struct {
+	StartTime    int64
+	EndTime      int64
+	NumIntervals uint32
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetCtDigests) BtcDecode(r io.Reader, pver uint32, _ MessageEncoding) error {
+	err := readElements(r, &msg.StartTime, &msg.EndTime, &msg.NumIntervals)
+	if err != nil {
+		return err
+	}
+
+	if msg.NumIntervals > MaxCtDigestsPerMsg {
+		str := fmt.Sprintf("too many intervals requested "+
+			"[count %v, max %v]", msg.NumIntervals,
+			MaxCtDigestsPerMsg)
+		return messageError("MsgGetCtDigests.BtcDecode", str)
+	}
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetCtDigests) BtcEncode(w io.Writer, pver uint32, _ MessageEncoding) error {
+	if msg.NumIntervals > MaxCtDigestsPerMsg {
+		str := fmt.Sprintf("too many intervals requested "+
+			"[count %v, max %v]", msg.NumIntervals,
+			MaxCtDigestsPerMsg)
+		return messageError("MsgGetCtDigests.BtcEncode", str)
+	}
+
+	return writeElements(w, msg.StartTime, msg.EndTime, msg.NumIntervals)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetCtDigests) Command() string {
+	return CmdGetCtDigests
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetCtDigests) MaxPayloadLength(pver uint32) uint32 {
+	// Start time 8 bytes + end time 8 bytes + num intervals 4 bytes.
+	return 20
+}
+
+// NewMsgGetCtDigests returns a new ciphrtxt getctdigests message that
+// conforms to the Message interface using the passed parameters.
+func NewMsgGetCtDigests(startTime, endTime int64, numIntervals uint32) *MsgGetCtDigests {
+	return &MsgGetCtDigests{
+		StartTime:    startTime,
+		EndTime:      endTime,
+		NumIntervals: numIntervals,
+	}
+}
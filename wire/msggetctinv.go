@@ -0,0 +1,53 @@
+// Copyright (c) 2018 The ciphrtxt developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"io"
+)
+
+// MsgGetCtInv implements the Message interface and represents a ciphrtxt
+// getctinv message.  It is used to request the hashes of all messages a peer
+// stores for the time range [StartTime, EndTime), expressed in seconds since
+// the unix epoch.  The peer responds with an inv message containing
+// inventory vectors of type InvTypeCtMsg.
+type MsgGetCtInv struct {
+	StartTime int64
+	EndTime   int64
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetCtInv) BtcDecode(r io.Reader, pver uint32, _ MessageEncoding) error {
+	return readElements(r, &msg.StartTime, &msg.EndTime)
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetCtInv) BtcEncode(w io.Writer, pver uint32, _ MessageEncoding) error {
+	return writeElements(w, msg.StartTime, msg.EndTime)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetCtInv) Command() string {
+	return CmdGetCtInv
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetCtInv) MaxPayloadLength(pver uint32) uint32 {
+	// Start time 8 bytes + end time 8 bytes.
+	return 16
+}
+
+// NewMsgGetCtInv returns a new ciphrtxt getctinv message that conforms to the
+// Message interface using the passed parameters.
+func NewMsgGetCtInv(startTime, endTime int64) *MsgGetCtInv {
+	return &MsgGetCtInv{
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+}
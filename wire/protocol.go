@@ -86,6 +86,10 @@ const (
 	// SFNode2X is a flag used to indicate a peer is running the Segwit2X
 	// software.
 	SFNode2X
+
+	// SFNodeCtMsg is a flag used to indicate a peer stores and relays
+	// ciphrtxt messages.
+	SFNodeCtMsg
 )
 
 // Map of service flags back to their constant names for pretty printing.
@@ -98,6 +102,7 @@ var sfStrings = map[ServiceFlag]string{
 	SFNodeBit5:    "SFNodeBit5",
 	SFNodeCF:      "SFNodeCF",
 	SFNode2X:      "SFNode2X",
+	SFNodeCtMsg:   "SFNodeCtMsg",
 }
 
 // orderedSFStrings is an ordered list of service flags from highest to
@@ -111,6 +116,7 @@ var orderedSFStrings = []ServiceFlag{
 	SFNodeBit5,
 	SFNodeCF,
 	SFNode2X,
+	SFNodeCtMsg,
 }
 
 // String returns the ServiceFlag in human-readable form.